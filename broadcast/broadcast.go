@@ -0,0 +1,37 @@
+// Package broadcast разносит события WebSocket-комнат между экземплярами сервера.
+//
+// Hub не рассылает события комнаты напрямую: он публикует их через Broadcaster и применяет то,
+// что Broadcaster доставил, — в том числе свои же события. Поэтому seq событий комнаты один на все экземпляры.
+package broadcast
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Message — событие комнаты между хабами.
+type Message struct {
+	// Origin — хаб-отправитель: своё событие он уже учёл в состоянии комнаты
+	Origin string    `json:"origin"`
+	RoomID uuid.UUID `json:"room_id"`
+	Type   string    `json:"type"`
+	// Seq присваивает Broadcaster при публикации; у эфемерных сообщений его нет
+	Seq       uint64 `json:"seq,omitempty"`
+	Ephemeral bool   `json:"ephemeral,omitempty"`
+	// UserID — адресат сообщения (kicked)
+	UserID    *uuid.UUID      `json:"user_id,omitempty"`
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Broadcaster доставляет сообщения всем подписанным хабам, включая отправителя.
+// События комнаты доставляются в порядке seq. nil вместо сообщения означает,
+// что часть сообщений могла потеряться (например, при переподключении к БД).
+type Broadcaster interface {
+	// Publish присваивает сообщению следующий seq комнаты (если оно не эфемерное) и рассылает его
+	Publish(msg *Message) error
+	// Subscribe регистрирует получателя; deliver вызывается последовательно, из одной горутины
+	Subscribe(deliver func(*Message))
+	Close() error
+}
@@ -0,0 +1,44 @@
+package broadcast
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Memory — Broadcaster в пределах одного процесса: подходит для одного экземпляра сервера.
+// Сообщения доставляются синхронно, в горутине Publish.
+type Memory struct {
+	mu          sync.Mutex
+	seq         map[uuid.UUID]uint64
+	subscribers []func(*Message)
+}
+
+func NewMemory() *Memory {
+	return &Memory{seq: make(map[uuid.UUID]uint64)}
+}
+
+func (m *Memory) Publish(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !msg.Ephemeral {
+		m.seq[msg.RoomID]++
+		msg.Seq = m.seq[msg.RoomID]
+	}
+	for _, deliver := range m.subscribers {
+		copied := *msg
+		deliver(&copied)
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(deliver func(*Message)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, deliver)
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,61 @@
+package broadcast
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMemorySeqPerRoom(t *testing.T) {
+	m := NewMemory()
+	var got []*Message
+	m.Subscribe(func(msg *Message) { got = append(got, msg) })
+
+	roomA, roomB := uuid.New(), uuid.New()
+	for _, msg := range []*Message{
+		{RoomID: roomA, Type: "swipe"},
+		{RoomID: roomA, Type: "typing", Ephemeral: true},
+		{RoomID: roomB, Type: "swipe"},
+		{RoomID: roomA, Type: "match"},
+	} {
+		if err := m.Publish(msg); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	// Эфемерные сообщения seq не получают и не сдвигают; у каждой комнаты свой счётчик
+	want := []uint64{1, 0, 1, 2}
+	if len(got) != len(want) {
+		t.Fatalf("delivered %d messages, want %d", len(got), len(want))
+	}
+	for i, msg := range got {
+		if msg.Seq != want[i] {
+			t.Errorf("message %d (%s): seq = %d, want %d", i, msg.Type, msg.Seq, want[i])
+		}
+	}
+}
+
+func TestMemoryFanOut(t *testing.T) {
+	m := NewMemory()
+	var first, second []*Message
+	m.Subscribe(func(msg *Message) { first = append(first, msg) })
+	m.Subscribe(func(msg *Message) { second = append(second, msg) })
+
+	msg := &Message{Origin: "hub-a", RoomID: uuid.New(), Type: "swipe"}
+	if err := m.Publish(msg); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	// Отправитель тоже получает своё сообщение — так seq один на все хабы
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("delivered %d and %d messages, want one each", len(first), len(second))
+	}
+	if first[0].Seq != msg.Seq || second[0].Origin != "hub-a" {
+		t.Errorf("delivered %+v and %+v, want copies of %+v", first[0], second[0], msg)
+	}
+	// Каждый получатель владеет своей копией
+	first[0].Type = "changed"
+	if second[0].Type != "swipe" || msg.Type != "swipe" {
+		t.Error("subscribers share one message")
+	}
+}
@@ -0,0 +1,175 @@
+package broadcast
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// notifyChannel — канал LISTEN/NOTIFY для событий комнат
+	notifyChannel = "kinoswipe_ws"
+	// maxNotifyPayload — предел payload у NOTIFY (8000 байт) с запасом; крупные события передаются через таблицу
+	maxNotifyPayload = 7000
+	// listenerPingInterval — как часто проверять соединение LISTEN
+	listenerPingInterval = 90 * time.Second
+)
+
+// Postgres — Broadcaster для нескольких экземпляров сервера с общей БД.
+// Seq комнаты хранится в ws_room_seq и увеличивается в той же транзакции, что и NOTIFY:
+// строка комнаты заблокирована до коммита, а уведомления приходят в порядке коммитов — значит, и в порядке seq.
+type Postgres struct {
+	db          *sql.DB
+	listener    *pq.Listener
+	mu          sync.Mutex
+	subscribers []func(*Message)
+	done        chan struct{}
+}
+
+// NewPostgres подписывается на канал событий; dsn нужен для отдельного соединения LISTEN.
+func NewPostgres(db *sql.DB, dsn string) (*Postgres, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Broadcast listener: %v", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen %s: %w", notifyChannel, err)
+	}
+
+	p := &Postgres{db: db, listener: listener, done: make(chan struct{})}
+	go p.run(listener.Notify)
+	return p, nil
+}
+
+func (p *Postgres) Publish(msg *Message) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if !msg.Ephemeral {
+		query := `
+			INSERT INTO ws_room_seq (room_id, seq) VALUES ($1, 1)
+			ON CONFLICT (room_id) DO UPDATE SET seq = ws_room_seq.seq + 1, updated_at = CURRENT_TIMESTAMP
+			RETURNING seq
+		`
+		if err := tx.QueryRow(query, msg.RoomID).Scan(&msg.Seq); err != nil {
+			return fmt.Errorf("failed to get next room seq: %w", err)
+		}
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		var id int64
+		if err := tx.QueryRow(`INSERT INTO ws_broadcast_payloads (payload) VALUES ($1) RETURNING id`, string(payload)).Scan(&id); err != nil {
+			return fmt.Errorf("failed to store payload: %w", err)
+		}
+		// Хабы читают payload сразу после уведомления, долго хранить его незачем
+		if _, err := tx.Exec(`DELETE FROM ws_broadcast_payloads WHERE created_at < NOW() - INTERVAL '10 minutes'`); err != nil {
+			return fmt.Errorf("failed to clean up payloads: %w", err)
+		}
+		payload, _ = json.Marshal(notification{Ref: id})
+	}
+
+	if _, err := tx.Exec(`SELECT pg_notify($1, $2)`, notifyChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) Subscribe(deliver func(*Message)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscribers = append(p.subscribers, deliver)
+}
+
+func (p *Postgres) Close() error {
+	close(p.done)
+	return p.listener.Close()
+}
+
+// notification — payload уведомления: само сообщение или ссылка на строку ws_broadcast_payloads
+type notification struct {
+	Ref int64 `json:"ref,omitempty"`
+}
+
+// run доставляет уведомления из notify подписчикам, пока канал не закроется или не вызван Close.
+// Раз в listenerPingInterval проверяет соединение LISTEN: обрыв без трафика иначе заметен не сразу.
+func (p *Postgres) run(notify <-chan *pq.Notification) {
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case n, ok := <-notify:
+			if !ok {
+				return
+			}
+			// nil приходит после переподключения: уведомления за время разрыва потеряны
+			if n == nil {
+				p.deliver(nil)
+				continue
+			}
+			msg, err := p.decode(n.Extra)
+			if err != nil {
+				// Сообщение потеряно — получатели должны об этом узнать
+				log.Printf("Broadcast: failed to decode notification: %v", err)
+				p.deliver(nil)
+				continue
+			}
+			p.deliver(msg)
+
+		case <-ticker.C:
+			go p.listener.Ping()
+
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *Postgres) decode(payload string) (*Message, error) {
+	var ref notification
+	if err := json.Unmarshal([]byte(payload), &ref); err != nil {
+		return nil, err
+	}
+	if ref.Ref != 0 {
+		if err := p.db.QueryRow(`SELECT payload FROM ws_broadcast_payloads WHERE id = $1`, ref.Ref).Scan(&payload); err != nil {
+			return nil, fmt.Errorf("failed to load payload %d: %w", ref.Ref, err)
+		}
+	}
+	msg := &Message{}
+	if err := json.Unmarshal([]byte(payload), msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (p *Postgres) deliver(msg *Message) {
+	p.mu.Lock()
+	subscribers := p.subscribers
+	p.mu.Unlock()
+
+	for _, deliver := range subscribers {
+		if msg == nil {
+			deliver(nil)
+			continue
+		}
+		copied := *msg
+		deliver(&copied)
+	}
+}
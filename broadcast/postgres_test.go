@@ -0,0 +1,201 @@
+package broadcast
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// fakeStore — драйвер database/sql с ws_room_seq и ws_broadcast_payloads в памяти;
+// payload каждого pg_notify попадает в notified
+type fakeStore struct {
+	mu       sync.Mutex
+	seq      map[string]int64
+	payloads map[int64]string
+	notified []string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{seq: map[string]int64{}, payloads: map[int64]string{}}
+}
+
+func (s *fakeStore) open() *sql.DB                                { return sql.OpenDB(s) }
+func (s *fakeStore) Connect(context.Context) (driver.Conn, error) { return s, nil }
+func (s *fakeStore) Driver() driver.Driver                        { return nil }
+func (s *fakeStore) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{store: s, query: query}, nil
+}
+func (s *fakeStore) Close() error              { return nil }
+func (s *fakeStore) Begin() (driver.Tx, error) { return s, nil }
+func (s *fakeStore) Commit() error             { return nil }
+func (s *fakeStore) Rollback() error           { return nil }
+
+type fakeStmt struct {
+	store *fakeStore
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if strings.Contains(s.query, "pg_notify") {
+		s.store.notified = append(s.store.notified, args[1].(string))
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	switch {
+	case strings.Contains(s.query, "ws_room_seq"):
+		room := args[0].(string)
+		s.store.seq[room]++
+		return &fakeRows{row: []driver.Value{s.store.seq[room]}}, nil
+	case strings.Contains(s.query, "INSERT INTO ws_broadcast_payloads"):
+		id := int64(len(s.store.payloads) + 1)
+		s.store.payloads[id] = args[0].(string)
+		return &fakeRows{row: []driver.Value{id}}, nil
+	case strings.Contains(s.query, "SELECT payload FROM ws_broadcast_payloads"):
+		if payload, ok := s.store.payloads[args[0].(int64)]; ok {
+			return &fakeRows{row: []driver.Value{payload}}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	row []driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return make([]string, 1) }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.row == nil {
+		return io.EOF
+	}
+	copy(dest, r.row)
+	r.row = nil
+	return nil
+}
+
+func TestPostgresPublishAssignsSeq(t *testing.T) {
+	store := newFakeStore()
+	p := &Postgres{db: store.open()}
+
+	roomID := uuid.New()
+	for _, msg := range []*Message{
+		{RoomID: roomID, Type: "swipe"},
+		{RoomID: roomID, Type: "typing", Ephemeral: true},
+		{RoomID: roomID, Type: "match"},
+	} {
+		if err := p.Publish(msg); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	want := []uint64{1, 0, 2}
+	if len(store.notified) != len(want) {
+		t.Fatalf("notified %d messages, want %d", len(store.notified), len(want))
+	}
+	for i, payload := range store.notified {
+		msg, err := p.decode(payload)
+		if err != nil {
+			t.Fatalf("decode %s: %v", payload, err)
+		}
+		if msg.Seq != want[i] {
+			t.Errorf("message %d (%s): seq = %d, want %d", i, msg.Type, msg.Seq, want[i])
+		}
+	}
+}
+
+// Сообщение больше предела NOTIFY уходит через таблицу и восстанавливается целиком
+func TestPostgresLargePayloadRoundTrip(t *testing.T) {
+	store := newFakeStore()
+	p := &Postgres{db: store.open()}
+
+	data, _ := json.Marshal(map[string]string{"overview": strings.Repeat("а", maxNotifyPayload)})
+	sent := &Message{Origin: "hub-a", RoomID: uuid.New(), Type: "deck", Timestamp: time.Now().Unix(), Data: data}
+	if err := p.Publish(sent); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	if len(store.notified) != 1 || len(store.payloads) != 1 {
+		t.Fatalf("notified %d, stored %d; want one reference to one stored payload", len(store.notified), len(store.payloads))
+	}
+	if payload := store.notified[0]; len(payload) > maxNotifyPayload || !strings.Contains(payload, `"ref"`) {
+		t.Fatalf("notification = %.100s, want a reference", payload)
+	}
+
+	got, err := p.decode(store.notified[0])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Origin != sent.Origin || got.RoomID != sent.RoomID || got.Seq != sent.Seq || string(got.Data) != string(sent.Data) {
+		t.Errorf("got %s/%s seq %d (%d bytes), want %s/%s seq %d (%d bytes)",
+			got.Origin, got.RoomID, got.Seq, len(got.Data), sent.Origin, sent.RoomID, sent.Seq, len(sent.Data))
+	}
+
+	// Payload, которого уже нет в таблице, — ошибка, а не пустое сообщение
+	if _, err := p.decode(`{"ref": 42}`); err == nil {
+		t.Error("decode of missing payload succeeded")
+	}
+}
+
+// После переподключения и на нечитаемом уведомлении получатели получают nil и пересинхронизируются
+func TestPostgresRunSignalsResync(t *testing.T) {
+	store := newFakeStore()
+	p := &Postgres{db: store.open(), done: make(chan struct{})}
+
+	delivered := make(chan *Message, 10)
+	p.Subscribe(func(msg *Message) { delivered <- msg })
+
+	notify := make(chan *pq.Notification)
+	stopped := make(chan struct{})
+	go func() {
+		p.run(notify)
+		close(stopped)
+	}()
+
+	payload, _ := json.Marshal(&Message{RoomID: uuid.New(), Type: "swipe", Seq: 7})
+	notify <- &pq.Notification{Extra: string(payload)}
+	notify <- nil
+	notify <- &pq.Notification{Extra: "{broken"}
+	notify <- &pq.Notification{Extra: `{"ref": 42}`}
+	close(notify)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("run did not stop after the notify channel closed")
+	}
+	close(delivered)
+
+	var got []*Message
+	for msg := range delivered {
+		got = append(got, msg)
+	}
+	if len(got) != 4 {
+		t.Fatalf("delivered %d messages, want 4", len(got))
+	}
+	if got[0] == nil || got[0].Seq != 7 {
+		t.Errorf("first delivery = %+v, want message with seq 7", got[0])
+	}
+	for i, msg := range got[1:] {
+		if msg != nil {
+			t.Errorf("delivery %d = %+v, want nil", i+1, msg)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"kinoswipe/models"
+)
+
+// OMDbDump — сохранённые ответы OMDb API (?t=... или ?i=...): массив объектов или один объект.
+// OMDb отдаёт только английское название — оно становится и title, и title_en.
+type OMDbDump struct{}
+
+type omdbMovie struct {
+	IMDbID     string `json:"imdbID"`
+	Title      string `json:"Title"`
+	Year       string `json:"Year"`
+	Runtime    string `json:"Runtime"`
+	Genre      string `json:"Genre"`
+	Plot       string `json:"Plot"`
+	Poster     string `json:"Poster"`
+	IMDbRating string `json:"imdbRating"`
+	Response   string `json:"Response"`
+}
+
+func (s *OMDbDump) Name() string { return "omdb" }
+
+func (s *OMDbDump) Read(r io.Reader) ([]models.Movie, error) {
+	var items []omdbMovie
+	if err := decodeOneOrMany(r, &items); err != nil {
+		return nil, err
+	}
+
+	movies := []models.Movie{}
+	for _, it := range items {
+		if movie, ok := it.movie(); ok {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, nil
+}
+
+// movie переводит ответ OMDb в фильм; ok = false для ответов «не найдено»
+func (it omdbMovie) movie() (models.Movie, bool) {
+	title := strings.TrimSpace(it.Title)
+	if title == "" || it.Response == "False" {
+		return models.Movie{}, false
+	}
+	plot := strings.TrimSpace(it.Plot)
+	if plot == "N/A" {
+		plot = ""
+	}
+	return models.Movie{
+		Title:       title,
+		TitleEn:     title,
+		Year:        parseLeadingInt(it.Year),
+		Duration:    parseLeadingInt(it.Runtime),
+		Genre:       genresJSON(it.Genre),
+		Description: plot,
+		PosterURL:   cleanURL(it.Poster),
+		IMDbRating:  parseRating(it.IMDbRating),
+	}, true
+}
+
+// KinopoiskDump — выгрузка API kinopoisk.dev: {"docs": [...]} или массив фильмов
+type KinopoiskDump struct{}
+
+type kinopoiskMovie struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	AlternativeName string `json:"alternativeName"`
+	EnName          string `json:"enName"`
+	Year            int    `json:"year"`
+	MovieLength     int    `json:"movieLength"`
+	Description     string `json:"description"`
+	ShortDesc       string `json:"shortDescription"`
+	Rating          struct {
+		KP   float64 `json:"kp"`
+		IMDb float64 `json:"imdb"`
+	} `json:"rating"`
+	Genres []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	Poster struct {
+		URL string `json:"url"`
+	} `json:"poster"`
+	Videos struct {
+		Trailers []struct {
+			URL string `json:"url"`
+		} `json:"trailers"`
+	} `json:"videos"`
+}
+
+func (s *KinopoiskDump) Name() string { return "kinopoisk" }
+
+func (s *KinopoiskDump) Read(r io.Reader) ([]models.Movie, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump: %w", err)
+	}
+	var items []kinopoiskMovie
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var page struct {
+			Docs []kinopoiskMovie `json:"docs"`
+		}
+		if err := json.Unmarshal(trimmed, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode json: %w", err)
+		}
+		items = page.Docs
+	} else if err := json.Unmarshal(trimmed, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode json: %w", err)
+	}
+
+	movies := []models.Movie{}
+	for _, it := range items {
+		if movie, ok := it.movie(); ok {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, nil
+}
+
+// movie переводит фильм kinopoisk.dev в фильм каталога; ok = false, если нет ни одного названия
+func (it kinopoiskMovie) movie() (models.Movie, bool) {
+	titleEn := strings.TrimSpace(it.EnName)
+	if titleEn == "" {
+		titleEn = strings.TrimSpace(it.AlternativeName)
+	}
+	title := strings.TrimSpace(it.Name)
+	if title == "" {
+		title = titleEn
+	}
+	if title == "" {
+		return models.Movie{}, false
+	}
+	description := strings.TrimSpace(it.Description)
+	if description == "" {
+		description = strings.TrimSpace(it.ShortDesc)
+	}
+	genres := make([]string, 0, len(it.Genres))
+	for _, g := range it.Genres {
+		genres = append(genres, g.Name)
+	}
+	trailer := ""
+	if len(it.Videos.Trailers) > 0 {
+		trailer = cleanURL(it.Videos.Trailers[0].URL)
+	}
+	return models.Movie{
+		Title:       title,
+		TitleEn:     titleEn,
+		Year:        it.Year,
+		Duration:    it.MovieLength,
+		Genre:       genresFromList(genres),
+		Description: description,
+		PosterURL:   cleanURL(it.Poster.URL),
+		TrailerURL:  trailer,
+		IMDbRating:  parseRating(strconv.FormatFloat(it.Rating.IMDb, 'f', -1, 64)),
+		KPRating:    parseRating(strconv.FormatFloat(it.Rating.KP, 'f', -1, 64)),
+	}, true
+}
+
+// decodeOneOrMany читает JSON-массив или одиночный объект в срез
+func decodeOneOrMany[T any](r io.Reader, out *[]T) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read dump: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var one T
+		if err := json.Unmarshal(data, &one); err != nil {
+			return fmt.Errorf("failed to decode json: %w", err)
+		}
+		*out = []T{one}
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode json: %w", err)
+	}
+	return nil
+}
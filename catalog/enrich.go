@@ -0,0 +1,146 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"kinoswipe/models"
+)
+
+// enrichField — поле, которое обогащение заполняет, если оно пустое
+type enrichField struct {
+	name  string
+	empty func(m *models.Movie) bool
+	copy  func(dst *models.Movie, src models.Movie) bool // false — у провайдера поля тоже нет
+}
+
+func stringField(name string, field func(m *models.Movie) *string) enrichField {
+	return enrichField{
+		name:  name,
+		empty: func(m *models.Movie) bool { return *field(m) == "" },
+		copy: func(dst *models.Movie, src models.Movie) bool {
+			if v := *field(&src); v != "" {
+				*field(dst) = v
+				return true
+			}
+			return false
+		},
+	}
+}
+
+func ratingField(name string, field func(m *models.Movie) **float64) enrichField {
+	return enrichField{
+		name:  name,
+		empty: func(m *models.Movie) bool { return *field(m) == nil },
+		copy: func(dst *models.Movie, src models.Movie) bool {
+			if v := *field(&src); v != nil {
+				*field(dst) = v
+				return true
+			}
+			return false
+		},
+	}
+}
+
+var enrichFields = []enrichField{
+	stringField("poster_url", func(m *models.Movie) *string { return &m.PosterURL }),
+	ratingField("imdb_rating", func(m *models.Movie) **float64 { return &m.IMDbRating }),
+	ratingField("kp_rating", func(m *models.Movie) **float64 { return &m.KPRating }),
+	stringField("title_en", func(m *models.Movie) *string { return &m.TitleEn }),
+	stringField("trailer_url", func(m *models.Movie) *string { return &m.TrailerURL }),
+	stringField("description", func(m *models.Movie) *string { return &m.Description }),
+	{
+		name:  "duration",
+		empty: func(m *models.Movie) bool { return m.Duration == 0 },
+		copy: func(dst *models.Movie, src models.Movie) bool {
+			if src.Duration > 0 {
+				dst.Duration = src.Duration
+				return true
+			}
+			return false
+		},
+	},
+}
+
+// EnrichFieldNames — поля, которые умеет заполнять обогащение (значения --fields)
+func EnrichFieldNames() []string {
+	names := make([]string, len(enrichFields))
+	for i, f := range enrichFields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// MissingFields — какие из полей обогащения у фильма пустые; only ограничивает набор полей (nil — все)
+func MissingFields(movie models.Movie, only []string) []string {
+	var missing []string
+	for _, f := range enrichFields {
+		if f.empty(&movie) && (only == nil || contains(only, f.name)) {
+			missing = append(missing, f.name)
+		}
+	}
+	return missing
+}
+
+// Enricher опрашивает провайдеров по порядку, пока у фильма не останется пустых полей.
+// Заполненное значение никогда не перезаписывается: первый провайдер, давший поле, и есть его источник.
+type Enricher struct {
+	providers []MetadataProvider
+	fields    []string
+	now       func() time.Time
+}
+
+// NewEnricher — провайдеры в порядке приоритета; fields ограничивает заполняемые поля (nil — все)
+func NewEnricher(fields []string, providers ...MetadataProvider) *Enricher {
+	return &Enricher{providers: providers, fields: fields, now: time.Now}
+}
+
+// Enrich заполняет пустые поля movie и возвращает их происхождение.
+// Ошибки провайдеров не прерывают обход остальных и возвращаются вместе (ErrNotFound ошибкой не считается).
+func (e *Enricher) Enrich(ctx context.Context, movie *models.Movie) ([]models.FieldSource, error) {
+	var sources []models.FieldSource
+	var errs []error
+
+	for _, p := range e.providers {
+		missing := MissingFields(*movie, e.fields)
+		if len(missing) == 0 {
+			break
+		}
+		meta, err := p.Lookup(ctx, *movie)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return sources, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+
+		fetchedAt := e.now()
+		for _, f := range enrichFields {
+			if !contains(missing, f.name) || !f.copy(movie, meta.Movie) {
+				continue
+			}
+			sources = append(sources, models.FieldSource{
+				Field:      f.name,
+				Provider:   meta.Provider,
+				ExternalID: meta.ExternalID,
+				FetchedAt:  fetchedAt,
+			})
+		}
+	}
+	return sources, errors.Join(errs...)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
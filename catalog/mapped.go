@@ -0,0 +1,197 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"kinoswipe/models"
+)
+
+// Fields — поля фильма, которые можно заполнить из файла (ключи в --map)
+var Fields = []string{"title", "title_en", "year", "imdb_rating", "kp_rating", "genre", "duration", "description", "poster_url", "trailer_url"}
+
+// Mapping — поле фильма → колонка CSV или ключ JSON
+type Mapping map[string]string
+
+// ParseMapping разбирает "title=Название,year=Год". Пустая строка — поля называются так же, как в Fields.
+func ParseMapping(s string) (Mapping, error) {
+	m := Mapping{}
+	if strings.TrimSpace(s) == "" {
+		for _, f := range Fields {
+			m[f] = f
+		}
+		return m, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid mapping %q: expected field=column", pair)
+		}
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", field, strings.Join(Fields, ", "))
+		}
+		m[field] = column
+	}
+	if m["title"] == "" {
+		return nil, fmt.Errorf("mapping must include title")
+	}
+	return m, nil
+}
+
+func isField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// movieFromValues собирает фильм из значений полей (ключи — из Fields)
+func movieFromValues(get func(field string) string) models.Movie {
+	return models.Movie{
+		Title:       strings.TrimSpace(get("title")),
+		TitleEn:     strings.TrimSpace(get("title_en")),
+		Year:        parseLeadingInt(get("year")),
+		IMDbRating:  parseRating(get("imdb_rating")),
+		KPRating:    parseRating(get("kp_rating")),
+		Genre:       genresJSON(get("genre")),
+		Duration:    parseLeadingInt(get("duration")),
+		Description: strings.TrimSpace(get("description")),
+		PosterURL:   cleanURL(get("poster_url")),
+		TrailerURL:  cleanURL(get("trailer_url")),
+	}
+}
+
+// MappedCSV — CSV с заголовком; колонки сопоставляются полям через Map (регистр заголовков не важен).
+// Разделитель — запятая или точка с запятой (так сохраняет Excel с русской локалью), определяется по заголовку.
+type MappedCSV struct {
+	Map Mapping
+}
+
+func (s *MappedCSV) Name() string { return "csv" }
+
+func (s *MappedCSV) Read(r io.Reader) ([]models.Movie, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("csv is empty")
+	}
+
+	index := make(map[string]int)
+	for i, h := range rows[0] {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	columns := make(map[string]int)
+	for field, column := range s.Map {
+		if i, ok := index[strings.ToLower(column)]; ok {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("csv has no title column %q", s.Map["title"])
+	}
+
+	movies := []models.Movie{}
+	for _, row := range rows[1:] {
+		movie := movieFromValues(func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return row[i]
+		})
+		if movie.Title != "" {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, nil
+}
+
+// imdbTopMapping — колонки датасета IMDb Top 1000 (imdb_top_1000.csv)
+var imdbTopMapping = Mapping{
+	"title":       "Series_Title",
+	"year":        "Released_Year",
+	"imdb_rating": "IMDB_Rating",
+	"genre":       "Genre",
+	"duration":    "Runtime",
+	"description": "Overview",
+	"poster_url":  "Poster_Link",
+}
+
+// IMDbTopCSV — датасет imdb_top_1000.csv с фиксированными колонками
+type IMDbTopCSV struct{}
+
+func (s *IMDbTopCSV) Name() string { return "imdb-csv" }
+
+func (s *IMDbTopCSV) Read(r io.Reader) ([]models.Movie, error) {
+	return (&MappedCSV{Map: imdbTopMapping}).Read(r)
+}
+
+// MappedJSON — JSON-массив объектов; ключи сопоставляются полям через Map.
+// Жанры могут быть строкой "a, b" или массивом, числа — строкой или числом.
+type MappedJSON struct {
+	Map Mapping
+}
+
+func (s *MappedJSON) Name() string { return "json" }
+
+func (s *MappedJSON) Read(r io.Reader) ([]models.Movie, error) {
+	var items []map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("failed to decode json: %w", err)
+	}
+
+	movies := []models.Movie{}
+	for _, item := range items {
+		movie := movieFromValues(func(field string) string {
+			key, ok := s.Map[field]
+			if !ok {
+				return ""
+			}
+			return jsonString(item[key])
+		})
+		if movie.Title != "" {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, nil
+}
+
+// jsonString приводит значение из JSON к строке; массив склеивается через запятую
+func jsonString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, p := range val {
+			parts = append(parts, jsonString(p))
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,218 @@
+package catalog
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"kinoswipe/models"
+	"kinoswipe/service"
+
+	"github.com/google/uuid"
+)
+
+// Action — что импорт сделает с фильмом
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+)
+
+// FieldChange — изменение одного поля существующего фильма
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Change — фильм из файла, сопоставленный с каталогом
+type Change struct {
+	Action Action
+	Movie  models.Movie // Итоговое состояние: для update — фильм каталога с применёнными полями
+	Fields []FieldChange
+}
+
+// Plan — результат сопоставления файла с каталогом; в режиме --dry-run только печатается
+type Plan struct {
+	Changes []Change
+}
+
+// Store — то, что импорту нужно от repository.MovieRepository
+type Store interface {
+	GetAllForDedup() ([]models.Movie, error)
+	Create(movie *models.Movie) error
+	Update(movie *models.Movie) error
+}
+
+type naturalKey struct {
+	title string
+	year  int
+}
+
+// movieKeys — естественные ключи фильма: нормализованное русское и английское название + год
+func movieKeys(m models.Movie) []naturalKey {
+	var keys []naturalKey
+	for _, title := range []string{m.Title, m.TitleEn} {
+		if norm := service.NormalizeTitle(title); norm != "" {
+			keys = append(keys, naturalKey{norm, m.Year})
+		}
+	}
+	return keys
+}
+
+// BuildPlan сопоставляет фильмы из файла с каталогом по естественному ключу.
+// Найденные фильмы обновляются только непустыми полями файла (название не меняется), остальные создаются.
+// Повторы внутри файла сливаются с первым вхождением.
+func BuildPlan(existing, incoming []models.Movie) *Plan {
+	byKey := make(map[naturalKey]int) // ключ → индекс в changes
+	var changes []Change
+
+	for _, m := range existing {
+		changes = append(changes, Change{Action: ActionUnchanged, Movie: m})
+		for _, k := range movieKeys(m) {
+			if _, ok := byKey[k]; !ok {
+				byKey[k] = len(changes) - 1
+			}
+		}
+	}
+	catalogSize := len(changes)
+	touched := make(map[int]bool)
+
+	for _, m := range incoming {
+		idx, found := -1, false
+		for _, k := range movieKeys(m) {
+			if idx, found = byKey[k]; found {
+				break
+			}
+		}
+		if !found {
+			changes = append(changes, Change{Action: ActionCreate, Movie: m})
+			idx = len(changes) - 1
+		} else {
+			c := &changes[idx]
+			fields := mergeMovie(&c.Movie, m)
+			if c.Action != ActionCreate {
+				c.Fields = append(c.Fields, fields...)
+				if len(c.Fields) > 0 {
+					c.Action = ActionUpdate
+				}
+			}
+		}
+		touched[idx] = true
+		for _, k := range movieKeys(changes[idx].Movie) {
+			if _, ok := byKey[k]; !ok {
+				byKey[k] = idx
+			}
+		}
+	}
+
+	plan := &Plan{}
+	for i, c := range changes {
+		// Фильмы каталога, которых нет в файле, в план не попадают
+		if i < catalogSize && !touched[i] {
+			continue
+		}
+		plan.Changes = append(plan.Changes, c)
+	}
+	return plan
+}
+
+// mergeMovie переносит в dst непустые поля src и возвращает изменившиеся
+func mergeMovie(dst *models.Movie, src models.Movie) []FieldChange {
+	var changes []FieldChange
+	setString := func(field string, dstVal *string, srcVal, empty string) {
+		if srcVal != "" && srcVal != empty && srcVal != *dstVal {
+			changes = append(changes, FieldChange{field, *dstVal, srcVal})
+			*dstVal = srcVal
+		}
+	}
+	setInt := func(field string, dstVal *int, srcVal int) {
+		if srcVal != 0 && srcVal != *dstVal {
+			changes = append(changes, FieldChange{field, strconv.Itoa(*dstVal), strconv.Itoa(srcVal)})
+			*dstVal = srcVal
+		}
+	}
+	setRating := func(field string, dstVal **float64, srcVal *float64) {
+		if srcVal != nil && (*dstVal == nil || **dstVal != *srcVal) {
+			changes = append(changes, FieldChange{field, formatRating(*dstVal), formatRating(srcVal)})
+			v := *srcVal
+			*dstVal = &v
+		}
+	}
+
+	setString("title_en", &dst.TitleEn, src.TitleEn, "")
+	setString("poster_url", &dst.PosterURL, src.PosterURL, "")
+	setRating("imdb_rating", &dst.IMDbRating, src.IMDbRating)
+	setRating("kp_rating", &dst.KPRating, src.KPRating)
+	setString("genre", &dst.Genre, src.Genre, "[]")
+	setInt("duration", &dst.Duration, src.Duration)
+	setString("description", &dst.Description, src.Description, "")
+	setString("trailer_url", &dst.TrailerURL, src.TrailerURL, "")
+	return changes
+}
+
+func formatRating(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// Count — сколько фильмов плана с данным действием
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Apply создаёт и обновляет фильмы плана. Останавливается на первой ошибке.
+func (p *Plan) Apply(store Store) error {
+	for i := range p.Changes {
+		c := &p.Changes[i]
+		switch c.Action {
+		case ActionCreate:
+			c.Movie.ID = uuid.New()
+			if err := store.Create(&c.Movie); err != nil {
+				return fmt.Errorf("failed to create %q (%d): %w", c.Movie.Title, c.Movie.Year, err)
+			}
+		case ActionUpdate:
+			if err := store.Update(&c.Movie); err != nil {
+				return fmt.Errorf("failed to update %q (%d): %w", c.Movie.Title, c.Movie.Year, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteReport печатает разницу: «+» — новый фильм, «~» — обновление с изменёнными полями
+func (p *Plan) WriteReport(w io.Writer) {
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate:
+			fmt.Fprintf(w, "+ %s (%d)\n", c.Movie.Title, c.Movie.Year)
+		case ActionUpdate:
+			fmt.Fprintf(w, "~ %s (%d)\n", c.Movie.Title, c.Movie.Year)
+			for _, f := range c.Fields {
+				fmt.Fprintf(w, "    %s: %q → %q\n", f.Field, shorten(f.Old), shorten(f.New))
+			}
+		}
+	}
+	fmt.Fprintf(w, "Новых: %d, обновлено: %d, без изменений: %d\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionUnchanged))
+}
+
+// shorten обрезает длинные значения (описания) для отчёта
+func shorten(s string) string {
+	const max = 60
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
@@ -0,0 +1,129 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+type memoryStore struct {
+	movies  []models.Movie
+	created []models.Movie
+	updated []models.Movie
+	failOn  string
+}
+
+func (s *memoryStore) GetAllForDedup() ([]models.Movie, error) { return s.movies, nil }
+
+func (s *memoryStore) Create(m *models.Movie) error {
+	if m.Title == s.failOn {
+		return fmt.Errorf("insert failed")
+	}
+	s.created = append(s.created, *m)
+	return nil
+}
+
+func (s *memoryStore) Update(m *models.Movie) error {
+	s.updated = append(s.updated, *m)
+	return nil
+}
+
+func float(v float64) *float64 { return &v }
+
+func catalogFixture() []models.Movie {
+	return []models.Movie{
+		{ID: uuid.New(), Title: "Матрица", TitleEn: "The Matrix", Year: 1999, IMDbRating: float(8.6), Genre: `["фантастика"]`, Duration: 136},
+		{ID: uuid.New(), Title: "Брат", Year: 1997, KPRating: float(8.3), Genre: `["драма"]`},
+		{ID: uuid.New(), Title: "Сталкер", Year: 1979},
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	incoming := []models.Movie{
+		// Совпадает по английскому названию: меняются рейтинг и постер, название каталога остаётся
+		{Title: "The Matrix", TitleEn: "The Matrix", Year: 1999, IMDbRating: float(8.7), PosterURL: "https://img/matrix.jpg", Genre: "[]"},
+		// Совпадает по русскому названию с другим написанием, новых данных нет
+		{Title: "БРАТ!", Year: 1997, KPRating: float(8.3)},
+		// Тот же год не совпал — другой фильм
+		{Title: "Брат", Year: 2000},
+		{Title: "Интерстеллар", TitleEn: "Interstellar", Year: 2014},
+		// Повтор в файле сливается с первым вхождением
+		{Title: "Interstellar", Year: 2014, Duration: 169},
+	}
+
+	plan := BuildPlan(catalogFixture(), incoming)
+	if plan.Count(ActionCreate) != 2 || plan.Count(ActionUpdate) != 1 || plan.Count(ActionUnchanged) != 1 {
+		t.Fatalf("unexpected plan: %+v", plan.Changes)
+	}
+
+	matrix := plan.Changes[0]
+	if matrix.Action != ActionUpdate || matrix.Movie.Title != "Матрица" || *matrix.Movie.IMDbRating != 8.7 {
+		t.Errorf("unexpected update: %+v", matrix)
+	}
+	if matrix.Movie.Genre != `["фантастика"]` || matrix.Movie.Duration != 136 {
+		t.Error("empty fields from the file must not overwrite the catalogue")
+	}
+	if len(matrix.Fields) != 2 || matrix.Fields[0].Field != "poster_url" || matrix.Fields[1] != (FieldChange{"imdb_rating", "8.6", "8.7"}) {
+		t.Errorf("unexpected field changes: %+v", matrix.Fields)
+	}
+
+	for _, c := range plan.Changes {
+		if c.Movie.Title == "Интерстеллар" && c.Movie.Duration != 169 {
+			t.Errorf("duplicate row must be merged into the first one: %+v", c.Movie)
+		}
+		if c.Movie.Title == "Сталкер" {
+			t.Error("catalogue movies missing from the file must not appear in the plan")
+		}
+	}
+}
+
+func TestPlan_Apply(t *testing.T) {
+	store := &memoryStore{movies: catalogFixture()}
+	plan := BuildPlan(store.movies, []models.Movie{
+		{Title: "Матрица", Year: 1999, KPRating: float(8.5)},
+		{Title: "Брат", Year: 1997},
+		{Title: "Начало", Year: 2010},
+	})
+
+	if err := plan.Apply(store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.created) != 1 || store.created[0].Title != "Начало" || store.created[0].ID == uuid.Nil {
+		t.Errorf("expected one created movie with an ID, got %+v", store.created)
+	}
+	if len(store.updated) != 1 || store.updated[0].ID != store.movies[0].ID {
+		t.Errorf("expected the existing movie to be updated in place, got %+v", store.updated)
+	}
+
+	failing := &memoryStore{failOn: "Начало"}
+	err := BuildPlan(nil, []models.Movie{{Title: "Начало", Year: 2010}}).Apply(failing)
+	if err == nil || !strings.Contains(err.Error(), "Начало") {
+		t.Errorf("expected error naming the movie, got %v", err)
+	}
+}
+
+func TestPlan_DryRunReport(t *testing.T) {
+	store := &memoryStore{movies: catalogFixture()}
+	plan := BuildPlan(store.movies, []models.Movie{
+		{Title: "Матрица", Year: 1999, IMDbRating: float(8.7)},
+		{Title: "Начало", Year: 2010},
+	})
+
+	var buf bytes.Buffer
+	plan.WriteReport(&buf)
+	want := "~ Матрица (1999)\n" +
+		"    imdb_rating: \"8.6\" → \"8.7\"\n" +
+		"+ Начало (2010)\n" +
+		"Новых: 1, обновлено: 1, без изменений: 0\n"
+	if buf.String() != want {
+		t.Errorf("unexpected report:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if len(store.created) != 0 || len(store.updated) != 0 {
+		t.Error("building and printing a plan must not write to the store")
+	}
+}
@@ -0,0 +1,104 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"kinoswipe/models"
+)
+
+// ErrNotFound — провайдер не знает такого фильма
+var ErrNotFound = errors.New("movie not found at provider")
+
+// Metadata — то, что провайдер знает о фильме. Пустые поля Movie — провайдер их не дал.
+type Metadata struct {
+	Provider   string
+	ExternalID string
+	Movie      models.Movie
+}
+
+// MetadataProvider ищет метаданные фильма каталога во внешнем API
+type MetadataProvider interface {
+	Name() string
+	Lookup(ctx context.Context, movie models.Movie) (*Metadata, error)
+}
+
+// rateLimited пропускает к провайдеру не чаще одного запроса в interval
+type rateLimited struct {
+	MetadataProvider
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// WithRateLimit ограничивает провайдера perMinute запросами в минуту (0 — без ограничения).
+// Лимит общий для всех горутин, которые пользуются этим провайдером.
+func WithRateLimit(p MetadataProvider, perMinute int) MetadataProvider {
+	if perMinute <= 0 {
+		return p
+	}
+	return &rateLimited{MetadataProvider: p, interval: time.Minute / time.Duration(perMinute)}
+}
+
+func (p *rateLimited) Lookup(ctx context.Context, movie models.Movie) (*Metadata, error) {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+
+	if wait := time.Until(at); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return p.MetadataProvider.Lookup(ctx, movie)
+}
+
+// getJSON выполняет GET и декодирует ответ; 404 — ErrNotFound
+func getJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// searchTitle — название для поиска у зарубежных провайдеров: английское, если известно
+func searchTitle(movie models.Movie) string {
+	if movie.TitleEn != "" {
+		return movie.TitleEn
+	}
+	return movie.Title
+}
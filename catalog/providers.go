@@ -0,0 +1,175 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"kinoswipe/models"
+)
+
+const providerTimeout = 10 * time.Second
+
+// OMDbProvider — www.omdbapi.com: постер, рейтинг IMDb, английское название, описание и длительность.
+// Бесплатный ключ: https://www.omdbapi.com/apikey.aspx (1000 запросов в день).
+type OMDbProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func NewOMDbProvider(apiKey string) *OMDbProvider {
+	return &OMDbProvider{apiKey: apiKey, baseURL: "https://www.omdbapi.com", client: &http.Client{Timeout: providerTimeout}}
+}
+
+func (p *OMDbProvider) Name() string { return "omdb" }
+
+func (p *OMDbProvider) Lookup(ctx context.Context, movie models.Movie) (*Metadata, error) {
+	q := url.Values{}
+	q.Set("apikey", p.apiKey)
+	q.Set("t", searchTitle(movie))
+	q.Set("plot", "short")
+	if movie.Year > 0 {
+		q.Set("y", strconv.Itoa(movie.Year))
+	}
+
+	var data omdbMovie
+	if err := getJSON(ctx, p.client, p.baseURL+"/?"+q.Encode(), nil, &data); err != nil {
+		return nil, err
+	}
+	found, ok := data.movie()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &Metadata{Provider: p.Name(), ExternalID: data.IMDbID, Movie: found}, nil
+}
+
+// KinopoiskProvider — api.kinopoisk.dev: рейтинги КП и IMDb, русское описание, постер, длительность.
+// Ключ выдаёт бот @kinopoiskdev_bot (200 запросов в день на бесплатном тарифе).
+type KinopoiskProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func NewKinopoiskProvider(apiKey string) *KinopoiskProvider {
+	return &KinopoiskProvider{apiKey: apiKey, baseURL: "https://api.kinopoisk.dev", client: &http.Client{Timeout: providerTimeout}}
+}
+
+func (p *KinopoiskProvider) Name() string { return "kinopoisk" }
+
+func (p *KinopoiskProvider) Lookup(ctx context.Context, movie models.Movie) (*Metadata, error) {
+	q := url.Values{}
+	q.Set("query", movie.Title)
+	q.Set("page", "1")
+	q.Set("limit", "10")
+
+	var data struct {
+		Docs []kinopoiskMovie `json:"docs"`
+	}
+	if err := getJSON(ctx, p.client, p.baseURL+"/v1.4/movie/search?"+q.Encode(), map[string]string{"X-API-KEY": p.apiKey}, &data); err != nil {
+		return nil, err
+	}
+	// Поиск нечёткий: берём первый результат того же года
+	for _, doc := range data.Docs {
+		if movie.Year > 0 && doc.Year != movie.Year {
+			continue
+		}
+		if found, ok := doc.movie(); ok {
+			return &Metadata{Provider: p.Name(), ExternalID: strconv.Itoa(doc.ID), Movie: found}, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// TMDBProvider — api.themoviedb.org: постер, трейлер с YouTube, описание на русском, длительность.
+// Оценки TMDB не пишутся в рейтинги IMDb/КП.
+type TMDBProvider struct {
+	apiKey   string
+	baseURL  string
+	imageURL string
+	client   *http.Client
+}
+
+func NewTMDBProvider(apiKey string) *TMDBProvider {
+	return &TMDBProvider{
+		apiKey:   apiKey,
+		baseURL:  "https://api.themoviedb.org",
+		imageURL: "https://image.tmdb.org/t/p/w500",
+		client:   &http.Client{Timeout: providerTimeout},
+	}
+}
+
+func (p *TMDBProvider) Name() string { return "tmdb" }
+
+type tmdbDetails struct {
+	ID               int    `json:"id"`
+	Title            string `json:"title"`
+	OriginalTitle    string `json:"original_title"`
+	OriginalLanguage string `json:"original_language"`
+	Overview         string `json:"overview"`
+	Runtime          int    `json:"runtime"`
+	PosterPath       string `json:"poster_path"`
+	ReleaseDate      string `json:"release_date"`
+	Videos           struct {
+		Results []struct {
+			Site string `json:"site"`
+			Type string `json:"type"`
+			Key  string `json:"key"`
+		} `json:"results"`
+	} `json:"videos"`
+}
+
+func (p *TMDBProvider) Lookup(ctx context.Context, movie models.Movie) (*Metadata, error) {
+	q := url.Values{}
+	q.Set("api_key", p.apiKey)
+	q.Set("query", movie.Title)
+	q.Set("language", "ru-RU")
+	if movie.Year > 0 {
+		q.Set("year", strconv.Itoa(movie.Year))
+	}
+	var search struct {
+		Results []struct {
+			ID int `json:"id"`
+		} `json:"results"`
+	}
+	if err := getJSON(ctx, p.client, p.baseURL+"/3/search/movie?"+q.Encode(), nil, &search); err != nil {
+		return nil, err
+	}
+	if len(search.Results) == 0 {
+		return nil, ErrNotFound
+	}
+
+	q = url.Values{}
+	q.Set("api_key", p.apiKey)
+	q.Set("language", "ru-RU")
+	q.Set("append_to_response", "videos")
+	var d tmdbDetails
+	if err := getJSON(ctx, p.client, fmt.Sprintf("%s/3/movie/%d?%s", p.baseURL, search.Results[0].ID, q.Encode()), nil, &d); err != nil {
+		return nil, err
+	}
+
+	found := models.Movie{
+		Title:       strings.TrimSpace(d.Title),
+		Year:        parseLeadingInt(d.ReleaseDate),
+		Duration:    d.Runtime,
+		Description: strings.TrimSpace(d.Overview),
+	}
+	if d.OriginalLanguage == "en" {
+		found.TitleEn = strings.TrimSpace(d.OriginalTitle)
+	}
+	if d.PosterPath != "" {
+		found.PosterURL = p.imageURL + d.PosterPath
+	}
+	for _, v := range d.Videos.Results {
+		if v.Site == "YouTube" && v.Type == "Trailer" && v.Key != "" {
+			found.TrailerURL = "https://www.youtube.com/watch?v=" + v.Key
+			break
+		}
+	}
+	return &Metadata{Provider: p.Name(), ExternalID: strconv.Itoa(d.ID), Movie: found}, nil
+}
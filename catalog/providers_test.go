@@ -0,0 +1,234 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kinoswipe/models"
+)
+
+// metadataServer — подмена OMDb, kinopoisk.dev и TMDB на одном httptest-сервере; ответы берутся из testdata
+func metadataServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	serve := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, filepath.Join("testdata", name))
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("apikey") != "omdb-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if q.Get("t") != "Alien" || q.Get("y") != "1979" {
+			w.Write([]byte(`{"Response": "False", "Error": "Movie not found!"}`))
+			return
+		}
+		serve("omdb_single.json")(w, r)
+	})
+	mux.HandleFunc("/v1.4/movie/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-KEY") != "kp-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		serve("kinopoisk.json")(w, r)
+	})
+	mux.HandleFunc("/3/search/movie", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("query") != "Интерстеллар" {
+			w.Write([]byte(`{"page": 1, "results": []}`))
+			return
+		}
+		serve("tmdb_search.json")(w, r)
+	})
+	mux.HandleFunc("/3/movie/157336", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("append_to_response") != "videos" {
+			t.Error("details must be requested with videos")
+		}
+		serve("tmdb_movie.json")(w, r)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOMDbProvider(t *testing.T) {
+	srv := metadataServer(t)
+	p := NewOMDbProvider("omdb-key")
+	p.baseURL = srv.URL
+
+	// Ищется по английскому названию, если оно известно
+	meta, err := p.Lookup(context.Background(), models.Movie{Title: "Чужой", TitleEn: "Alien", Year: 1979})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.Provider != "omdb" || meta.ExternalID != "tt0078748" {
+		t.Errorf("unexpected provenance: %+v", meta)
+	}
+	if meta.Movie.TitleEn != "Alien" || meta.Movie.Duration != 117 || rating(meta.Movie.IMDbRating) != 8.5 || meta.Movie.PosterURL != "" {
+		t.Errorf("unexpected metadata: %+v", meta.Movie)
+	}
+
+	if _, err := p.Lookup(context.Background(), models.Movie{Title: "Чужой", Year: 1979}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	p.apiKey = "wrong"
+	if _, err := p.Lookup(context.Background(), models.Movie{TitleEn: "Alien", Year: 1979}); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("auth failure must be an error, got %v", err)
+	}
+}
+
+func TestKinopoiskProvider(t *testing.T) {
+	srv := metadataServer(t)
+	p := NewKinopoiskProvider("kp-key")
+	p.baseURL = srv.URL
+
+	// В выдаче поиска берётся фильм того же года
+	meta, err := p.Lookup(context.Background(), models.Movie{Title: "Служебный роман", Year: 1977})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.Movie.Title != "Служебный роман" || rating(meta.Movie.KPRating) != 8.3 || meta.Movie.Duration != 159 {
+		t.Errorf("unexpected metadata: %+v", meta.Movie)
+	}
+
+	if _, err := p.Lookup(context.Background(), models.Movie{Title: "Служебный роман", Year: 2011}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another year, got %v", err)
+	}
+}
+
+func TestTMDBProvider(t *testing.T) {
+	srv := metadataServer(t)
+	p := NewTMDBProvider("tmdb-key")
+	p.baseURL = srv.URL
+	p.imageURL = "https://img.test/w500"
+
+	meta, err := p.Lookup(context.Background(), models.Movie{Title: "Интерстеллар", Year: 2014})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := meta.Movie
+	if meta.ExternalID != "157336" || m.TitleEn != "Interstellar" || m.Duration != 169 || m.Description == "" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+	if m.PosterURL != "https://img.test/w500/nCbkOyOMTEwlEV0LtCOvCnwEONA.jpg" {
+		t.Errorf("poster: got %q", m.PosterURL)
+	}
+	if m.TrailerURL != "https://www.youtube.com/watch?v=zSWdZVtXT7E" {
+		t.Errorf("trailer must skip teasers, got %q", m.TrailerURL)
+	}
+	if m.IMDbRating != nil || m.KPRating != nil {
+		t.Error("TMDB votes must not be written as IMDb or KP ratings")
+	}
+
+	if _, err := p.Lookup(context.Background(), models.Movie{Title: "Нет такого", Year: 2014}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+type stubProvider struct {
+	name  string
+	meta  *Metadata
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) Lookup(ctx context.Context, movie models.Movie) (*Metadata, error) {
+	p.calls++
+	return p.meta, p.err
+}
+
+func TestEnricher(t *testing.T) {
+	broken := &stubProvider{name: "broken", err: errors.New("timeout")}
+	kp := &stubProvider{name: "kinopoisk", meta: &Metadata{Provider: "kinopoisk", ExternalID: "258687", Movie: models.Movie{
+		KPRating: float(8.6), Description: "Описание КП", Duration: 169,
+	}}}
+	tmdb := &stubProvider{name: "tmdb", meta: &Metadata{Provider: "tmdb", ExternalID: "157336", Movie: models.Movie{
+		PosterURL: "https://img/p.jpg", Description: "Описание TMDB", TrailerURL: "https://yt/t", TitleEn: "Interstellar",
+	}}}
+	missing := &stubProvider{name: "omdb", err: ErrNotFound}
+
+	fetchedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	e := NewEnricher(nil, broken, kp, tmdb, missing)
+	e.now = func() time.Time { return fetchedAt }
+
+	movie := models.Movie{Title: "Интерстеллар", Year: 2014, IMDbRating: float(8.7)}
+	sources, err := e.Enrich(context.Background(), &movie)
+	if err == nil {
+		t.Error("provider errors must be reported")
+	}
+
+	// Первый провайдер, давший поле, — его источник; заполненное не перезаписывается
+	if movie.Description != "Описание КП" || movie.PosterURL != "https://img/p.jpg" || rating(movie.IMDbRating) != 8.7 {
+		t.Errorf("unexpected movie: %+v", movie)
+	}
+	want := map[string]string{
+		"kp_rating": "kinopoisk", "description": "kinopoisk", "duration": "kinopoisk",
+		"poster_url": "tmdb", "title_en": "tmdb", "trailer_url": "tmdb",
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("expected %d field sources, got %+v", len(want), sources)
+	}
+	for _, s := range sources {
+		if want[s.Field] != s.Provider || !s.FetchedAt.Equal(fetchedAt) || s.ExternalID == "" {
+			t.Errorf("unexpected source: %+v", s)
+		}
+	}
+
+	// Поля кончились — остальных провайдеров не спрашиваем
+	before := missing.calls
+	complete := movie
+	complete.KPRating = float(8.6)
+	if _, err := NewEnricher(nil, missing).Enrich(context.Background(), &complete); err != nil || missing.calls != before {
+		t.Error("a complete movie must not hit providers")
+	}
+}
+
+func TestEnricher_OnlyFields(t *testing.T) {
+	p := &stubProvider{name: "tmdb", meta: &Metadata{Provider: "tmdb", Movie: models.Movie{PosterURL: "https://img/p.jpg", Duration: 120}}}
+	movie := models.Movie{Title: "Брат", Year: 1997}
+
+	sources, err := NewEnricher([]string{"poster_url"}, p).Enrich(context.Background(), &movie)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sources) != 1 || sources[0].Field != "poster_url" || movie.Duration != 0 {
+		t.Errorf("only requested fields must be filled, got %+v, %+v", sources, movie)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	p := &stubProvider{name: "omdb", err: ErrNotFound}
+	limited := WithRateLimit(p, 1200) // один запрос в 50 мс
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		limited.Lookup(context.Background(), models.Movie{})
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 requests at 20/s must take at least 100ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := limited.Lookup(ctx, models.Movie{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("waiting must stop on cancelled context, got %v", err)
+	}
+	if p.calls != 3 {
+		t.Errorf("cancelled request must not reach the provider, got %d calls", p.calls)
+	}
+	if WithRateLimit(p, 0) != MetadataProvider(p) {
+		t.Error("zero limit must return the provider as is")
+	}
+}
@@ -0,0 +1,122 @@
+// Package catalog — импорт фильмов в каталог из внешних файлов: источники разбирают файл в models.Movie,
+// план сопоставляет их с каталогом по естественному ключу (нормализованное название + год) и показывает разницу.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kinoswipe/models"
+)
+
+// Source разбирает файл одного формата в фильмы. ID и даты не заполняются — их назначает план.
+type Source interface {
+	Name() string
+	Read(r io.Reader) ([]models.Movie, error)
+}
+
+// SourceNames — имена источников для --source
+var SourceNames = []string{"imdb-csv", "csv", "json", "omdb", "kinopoisk"}
+
+// NewSource возвращает источник по имени; mapping — соответствие полей для csv/json ("title=Название,year=Год"), для остальных игнорируется.
+func NewSource(name, mapping string) (Source, error) {
+	switch name {
+	case "imdb-csv":
+		return &IMDbTopCSV{}, nil
+	case "csv", "json":
+		m, err := ParseMapping(mapping)
+		if err != nil {
+			return nil, err
+		}
+		if name == "csv" {
+			return &MappedCSV{Map: m}, nil
+		}
+		return &MappedJSON{Map: m}, nil
+	case "omdb":
+		return &OMDbDump{}, nil
+	case "kinopoisk":
+		return &KinopoiskDump{}, nil
+	}
+	return nil, fmt.Errorf("unknown source %q (available: %s)", name, strings.Join(SourceNames, ", "))
+}
+
+// TopByRating оставляет n фильмов с наибольшим рейтингом IMDb; фильмы без рейтинга идут последними
+func TopByRating(movies []models.Movie, n int) []models.Movie {
+	rating := func(m models.Movie) float64 {
+		if m.IMDbRating == nil {
+			return -1
+		}
+		return *m.IMDbRating
+	}
+	sort.SliceStable(movies, func(i, j int) bool { return rating(movies[i]) > rating(movies[j]) })
+	if n > 0 && len(movies) > n {
+		movies = movies[:n]
+	}
+	return movies
+}
+
+// genresJSON собирает JSON-массив жанров из строки "Drama, Crime" (разделители — запятая или |)
+func genresJSON(s string) string {
+	parts := strings.FieldsFunc(stripControlChars(s), func(r rune) bool { return r == ',' || r == '|' })
+	return genresFromList(parts)
+}
+
+func genresFromList(list []string) string {
+	genres := []string{}
+	for _, g := range list {
+		g = strings.TrimSpace(stripControlChars(g))
+		if g != "" && g != "N/A" {
+			genres = append(genres, g)
+		}
+	}
+	data, _ := json.Marshal(genres)
+	return string(data)
+}
+
+// parseRating разбирает рейтинг "8.7" или "8,7"; пустое значение и "N/A" — nil
+func parseRating(s string) *float64 {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 || v > 10 {
+		return nil
+	}
+	return &v
+}
+
+// parseLeadingInt берёт первое число из строки: "142 min" → 142, "2005–2008" → 2005
+func parseLeadingInt(s string) int {
+	n, seen := 0, false
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n = n*10 + int(r-'0')
+			seen = true
+		} else if seen {
+			break
+		}
+	}
+	return n
+}
+
+// cleanURL отбрасывает заглушки вроде "N/A"
+func cleanURL(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return ""
+	}
+	return s
+}
+
+// stripControlChars убирает управляющие символы (0–31, 127), которые ломают JSON
+func stripControlChars(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 32 && r != 127 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kinoswipe/models"
+)
+
+func readFixture(t *testing.T, source Source, name string) []models.Movie {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+
+	movies, err := source.Read(f)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", source.Name(), err)
+	}
+	return movies
+}
+
+func rating(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func TestIMDbTopCSV(t *testing.T) {
+	movies := readFixture(t, &IMDbTopCSV{}, "imdb_top.csv")
+	if len(movies) != 3 {
+		t.Fatalf("expected 3 movies (row without title skipped), got %d", len(movies))
+	}
+
+	godfather := movies[1]
+	if godfather.Title != "The Godfather" || godfather.Year != 1972 || godfather.Duration != 175 {
+		t.Errorf("unexpected movie: %+v", godfather)
+	}
+	if godfather.Genre != `["Crime","Drama"]` {
+		t.Errorf("genre: got %s", godfather.Genre)
+	}
+	if rating(godfather.IMDbRating) != 9.2 || godfather.PosterURL == "" || godfather.Description == "" {
+		t.Errorf("rating, poster and overview must be read: %+v", godfather)
+	}
+
+	// В датасете встречаются строки с годом "PG" — год остаётся пустым, фильм не теряется
+	if movies[2].Title != "Apollo 13" || movies[2].Year != 0 || movies[2].PosterURL != "" {
+		t.Errorf("unexpected movie: %+v", movies[2])
+	}
+
+	top := TopByRating(movies, 2)
+	if len(top) != 2 || top[0].Title != "The Shawshank Redemption" || top[1].Title != "The Godfather" {
+		t.Errorf("top by rating: got %+v", top)
+	}
+}
+
+func TestMappedCSV(t *testing.T) {
+	source, err := NewSource("csv", "title=Название, title_en=Оригинал, year=Год, kp_rating=КП, genre=Жанры, duration=Минуты")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	movies := readFixture(t, source, "mapped.csv")
+	if len(movies) != 2 {
+		t.Fatalf("expected 2 movies, got %d", len(movies))
+	}
+
+	matrix := movies[0]
+	if matrix.Title != "Матрица" || matrix.TitleEn != "The Matrix" || matrix.Year != 1999 || matrix.Duration != 136 {
+		t.Errorf("unexpected movie: %+v", matrix)
+	}
+	if rating(matrix.KPRating) != 8.5 || matrix.IMDbRating != nil {
+		t.Errorf("ratings: kp %v, imdb %v", matrix.KPRating, matrix.IMDbRating)
+	}
+	if matrix.Genre != `["фантастика","боевик"]` {
+		t.Errorf("genre: got %s", matrix.Genre)
+	}
+}
+
+func TestMappedJSON(t *testing.T) {
+	source, err := NewSource("json", "title=name,title_en=original,year=released,kp_rating=kp,genre=tags,duration=length,poster_url=poster")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	movies := readFixture(t, source, "mapped.json")
+	if len(movies) != 2 {
+		t.Fatalf("expected 2 movies (item without title skipped), got %d", len(movies))
+	}
+
+	inception := movies[0]
+	if inception.Title != "Начало" || inception.TitleEn != "Inception" || inception.Year != 2010 ||
+		inception.Duration != 148 || rating(inception.KPRating) != 8.7 || inception.Genre != `["фантастика","триллер"]` {
+		t.Errorf("unexpected movie: %+v", inception)
+	}
+
+	// Числа строками и жанры строкой через запятую тоже разбираются
+	stalker := movies[1]
+	if stalker.Year != 1979 || rating(stalker.KPRating) != 8.1 || stalker.Genre != `["драма","фантастика"]` || stalker.PosterURL != "" {
+		t.Errorf("unexpected movie: %+v", stalker)
+	}
+}
+
+func TestParseMapping_Invalid(t *testing.T) {
+	for _, s := range []string{"title", "name=Название", "year=Год", "title="} {
+		if _, err := ParseMapping(s); err == nil {
+			t.Errorf("ParseMapping(%q): expected error", s)
+		}
+	}
+	m, err := ParseMapping("")
+	if err != nil || m["title"] != "title" || m["poster_url"] != "poster_url" {
+		t.Errorf("empty mapping must map fields to themselves, got %v, %v", m, err)
+	}
+}
+
+func TestOMDbDump(t *testing.T) {
+	movies := readFixture(t, &OMDbDump{}, "omdb.json")
+	if len(movies) != 2 {
+		t.Fatalf("expected 2 movies (failed response skipped), got %d", len(movies))
+	}
+
+	matrix := movies[0]
+	if matrix.Title != "The Matrix" || matrix.TitleEn != "The Matrix" || matrix.Year != 1999 || matrix.Duration != 136 ||
+		rating(matrix.IMDbRating) != 8.7 || matrix.Genre != `["Action","Sci-Fi"]` || matrix.PosterURL == "" {
+		t.Errorf("unexpected movie: %+v", matrix)
+	}
+
+	series := movies[1]
+	if series.Year != 2008 || series.Description != "" || series.PosterURL != "" {
+		t.Errorf("year range and N/A placeholders: got %+v", series)
+	}
+
+	single := readFixture(t, &OMDbDump{}, "omdb_single.json")
+	if len(single) != 1 || single[0].Title != "Alien" {
+		t.Errorf("single object dump: got %+v", single)
+	}
+}
+
+func TestKinopoiskDump(t *testing.T) {
+	movies := readFixture(t, &KinopoiskDump{}, "kinopoisk.json")
+	if len(movies) != 2 {
+		t.Fatalf("expected 2 movies, got %d", len(movies))
+	}
+
+	interstellar := movies[0]
+	if interstellar.Title != "Интерстеллар" || interstellar.TitleEn != "Interstellar" || interstellar.Year != 2014 ||
+		interstellar.Duration != 169 || interstellar.Genre != `["фантастика","драма"]` {
+		t.Errorf("unexpected movie: %+v", interstellar)
+	}
+	if rating(interstellar.KPRating) != 8.6 || rating(interstellar.IMDbRating) != 8.7 {
+		t.Errorf("ratings: kp %v, imdb %v", interstellar.KPRating, interstellar.IMDbRating)
+	}
+	if interstellar.PosterURL == "" || interstellar.TrailerURL == "" {
+		t.Errorf("poster and trailer must be read: %+v", interstellar)
+	}
+
+	// Нулевой рейтинг IMDb — нет рейтинга; краткое описание подставляется вместо пустого
+	office := movies[1]
+	if office.IMDbRating != nil || office.Description == "" || office.TitleEn != "" || office.PosterURL != "" {
+		t.Errorf("unexpected movie: %+v", office)
+	}
+}
+
+func TestNewSource_Unknown(t *testing.T) {
+	if _, err := NewSource("xml", ""); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
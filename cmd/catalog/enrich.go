@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"kinoswipe/catalog"
+	"kinoswipe/config"
+)
+
+// runEnrich заполняет пустые поля фильмов (постер, рейтинги, английское название, трейлер, описание, длительность)
+// из внешних API. Провайдеры без ключа пропускаются; лимиты запросов — OMDB_RPM, KINOPOISK_RPM, TMDB_RPM.
+func runEnrich(args []string) error {
+	fs := flag.NewFlagSet("enrich", flag.ExitOnError)
+	fields := fs.String("fields", "", "какие поля заполнять: "+strings.Join(catalog.EnrichFieldNames(), ", ")+" (по умолчанию все)")
+	providerNames := fs.String("providers", "", "порядок провайдеров через запятую (по умолчанию METADATA_PROVIDERS)")
+	limit := fs.Int("limit", 0, "обработать не больше N фильмов")
+	dryRun := fs.Bool("dry-run", false, "только показать, что будет заполнено")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if *providerNames == "" {
+		*providerNames = cfg.Metadata.Providers
+	}
+	providers, err := buildProviders(cfg.Metadata, *providerNames)
+	if err != nil {
+		return err
+	}
+
+	var only []string
+	if *fields != "" {
+		for _, f := range strings.Split(*fields, ",") {
+			f = strings.TrimSpace(f)
+			if !contains(catalog.EnrichFieldNames(), f) {
+				return fmt.Errorf("unknown field %q (available: %s)", f, strings.Join(catalog.EnrichFieldNames(), ", "))
+			}
+			only = append(only, f)
+		}
+	}
+
+	repo, closeDB, err := openMovieRepo()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	movies, err := repo.GetAllForDedup()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	enricher := catalog.NewEnricher(only, providers...)
+	processed, updated := 0, 0
+	for i := range movies {
+		m := &movies[i]
+		if len(catalog.MissingFields(*m, only)) == 0 {
+			continue
+		}
+		if *limit > 0 && processed >= *limit {
+			break
+		}
+		processed++
+
+		sources, err := enricher.Enrich(ctx, m)
+		if ctx.Err() != nil {
+			log.Printf("Прервано. Обработано: %d, обновлено: %d", processed, updated)
+			return nil
+		}
+		if err != nil {
+			log.Printf("%q (%d): %v", m.Title, m.Year, err)
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		for _, s := range sources {
+			fmt.Printf("~ %s (%d) %s ← %s\n", m.Title, m.Year, s.Field, s.Provider)
+		}
+		if *dryRun {
+			updated++
+			continue
+		}
+		if err := repo.SaveEnrichment(m, sources); err != nil {
+			log.Printf("%q (%d): %v", m.Title, m.Year, err)
+			continue
+		}
+		updated++
+	}
+
+	if *dryRun {
+		log.Println("Режим --dry-run: каталог не изменён")
+	}
+	log.Printf("Готово. Обработано фильмов: %d, дополнено: %d", processed, updated)
+	return nil
+}
+
+// buildProviders создаёт провайдеров в заданном порядке; провайдеры без ключа пропускаются
+func buildProviders(cfg config.MetadataConfig, names string) ([]catalog.MetadataProvider, error) {
+	var providers []catalog.MetadataProvider
+	for _, name := range strings.Split(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+			continue
+		case "omdb":
+			if cfg.OMDbKey != "" {
+				providers = append(providers, catalog.WithRateLimit(catalog.NewOMDbProvider(cfg.OMDbKey), cfg.OMDbRPM))
+			}
+		case "kinopoisk":
+			if cfg.KinopoiskKey != "" {
+				providers = append(providers, catalog.WithRateLimit(catalog.NewKinopoiskProvider(cfg.KinopoiskKey), cfg.KinopoiskRPM))
+			}
+		case "tmdb":
+			if cfg.TMDBKey != "" {
+				providers = append(providers, catalog.WithRateLimit(catalog.NewTMDBProvider(cfg.TMDBKey), cfg.TMDBRPM))
+			}
+		default:
+			return nil, fmt.Errorf("unknown provider %q (available: omdb, kinopoisk, tmdb)", name)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no metadata providers configured: set OMDB_API_KEY, KINOPOISK_API_KEY or TMDB_API_KEY")
+	}
+	return providers, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"kinoswipe/catalog"
+)
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	sourceName := fs.String("source", "imdb-csv", "формат файла: "+strings.Join(catalog.SourceNames, ", "))
+	mapping := fs.String("map", "", "соответствие полей для csv/json: title=Колонка,year=Колонка (поля: "+strings.Join(catalog.Fields, ", ")+")")
+	top := fs.Int("top", 0, "оставить N лучших по рейтингу IMDb")
+	dryRun := fs.Bool("dry-run", false, "только показать разницу с каталогом")
+	fs.Parse(args)
+
+	path := "imdb_top_1000.csv"
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	source, err := catalog.NewSource(*sourceName, *mapping)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	movies, err := source.Read(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if *top > 0 {
+		movies = catalog.TopByRating(movies, *top)
+	}
+	log.Printf("Прочитано фильмов из %s (%s): %d", path, source.Name(), len(movies))
+
+	repo, closeDB, err := openMovieRepo()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	existing, err := repo.GetAllForDedup()
+	if err != nil {
+		return err
+	}
+	plan := catalog.BuildPlan(existing, movies)
+	plan.WriteReport(os.Stdout)
+
+	if *dryRun {
+		log.Println("Режим --dry-run: каталог не изменён")
+		return nil
+	}
+	if err := plan.Apply(repo); err != nil {
+		return err
+	}
+	log.Printf("Готово. Добавлено: %d, обновлено: %d", plan.Count(catalog.ActionCreate), plan.Count(catalog.ActionUpdate))
+	return nil
+}
@@ -0,0 +1,70 @@
+// Управление каталогом фильмов из командной строки.
+// Использование:
+//
+//	go run ./cmd/catalog import --source imdb-csv [--top 100] [--dry-run] imdb_top_1000.csv
+//	go run ./cmd/catalog import --source csv --map "title=Название,year=Год,genre=Жанры" films.csv
+//	go run ./cmd/catalog import --source json scripts/seed_popular_movies.json
+//	go run ./cmd/catalog import --source omdb|kinopoisk dump.json
+//	go run ./cmd/catalog enrich [--fields poster_url,trailer_url] [--providers kinopoisk,tmdb,omdb] [--limit 100] [--dry-run]
+//
+// Импорт не очищает таблицу: фильмы сопоставляются с каталогом по названию и году,
+// найденные обновляются, новые добавляются. --dry-run печатает разницу и ничего не пишет.
+// Требуется .env с DATABASE_URL или переменные DB_* (как в основном приложении).
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"kinoswipe/config"
+	"kinoswipe/database"
+	"kinoswipe/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "enrich":
+		err = runEnrich(os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Использование: catalog <команда> [флаги]
+
+Команды:
+  import   импорт фильмов из файла (--source, --map, --top, --dry-run)
+  enrich   заполнить пустые поля фильмов из OMDb, Кинопоиска и TMDB (ключи OMDB_API_KEY, KINOPOISK_API_KEY, TMDB_API_KEY)
+
+Подробнее: catalog <команда> -h`)
+}
+
+// openMovieRepo подключается к БД из конфигурации приложения
+func openMovieRepo() (*repository.MovieRepository, func(), error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("config: %w", err)
+	}
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("database: %w", err)
+	}
+	return repository.NewMovieRepository(db.DB), func() { db.Close() }, nil
+}
@@ -31,6 +31,7 @@ import (
 
 	"kinoswipe/config"
 	"kinoswipe/database"
+	"kinoswipe/pagination"
 	"kinoswipe/repository"
 
 	"github.com/google/uuid"
@@ -63,7 +64,7 @@ func main() {
 
 	repo := repository.NewMovieRepository(db.DB)
 
-	movies, err := repo.GetAll(200)
+	movies, _, err := repo.GetAll(pagination.Page{Limit: 200})
 	if err != nil {
 		log.Fatalf("GetAll: %v", err)
 	}
@@ -18,6 +18,7 @@ import (
 
 	"kinoswipe/config"
 	"kinoswipe/database"
+	"kinoswipe/pagination"
 	"kinoswipe/repository"
 )
 
@@ -36,7 +37,7 @@ func main() {
 
 	repo := repository.NewMovieRepository(db.DB)
 
-	movies, err := repo.GetAll(300)
+	movies, _, err := repo.GetAll(pagination.Page{Limit: 300})
 	if err != nil {
 		log.Fatalf("GetAll: %v", err)
 	}
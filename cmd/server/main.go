@@ -10,6 +10,7 @@ import (
 	"syscall"
 	"time"
 
+	"kinoswipe/broadcast"
 	"kinoswipe/config"
 	"kinoswipe/database"
 	"kinoswipe/handlers"
@@ -44,28 +45,81 @@ func main() {
 	feedbackRepo := repository.NewFeedbackRepository(db.DB)
 	premiereRepo := repository.NewPremiereRepository(db.DB)
 	matchLinkRepo := repository.NewMatchLinkRepository(db.DB)
+	availabilityRepo := repository.NewAvailabilityRepository(db.DB)
+	watchlistRepo := repository.NewWatchlistRepository(db.DB)
+	watchHistoryRepo := repository.NewWatchHistoryRepository(db.DB)
+	ratingRepo := repository.NewRatingRepository(db.DB)
 	refreshTokenRepo := repository.NewRefreshTokenRepository(db.DB)
+	wsTicketRepo := repository.NewWSTicketRepository(db.DB)
 
 	// Инициализация сервисов
 	matchService := service.NewMatchService(matchRepo, swipeRepo, roomRepo, movieRepo, userRepo)
+	matchService.SetAvailability(availabilityRepo)
+	deckService := service.NewDeckService(roomRepo, movieRepo, swipeRepo, filterRepo)
+	deckService.SetWatchHistory(watchHistoryRepo)
+	recommendationService := service.NewRecommendationService(swipeRepo, cfg.Recommendations.Collaborative)
+	recommendationService.SetRatings(ratingRepo)
+	deckService.SetGroupScoring(recommendationService, service.ParseGroupScorers(cfg.Deck.GroupStrategies)...)
+	lifecycleService := service.NewRoomLifecycleService(roomRepo, swipeRepo, matchRepo, movieRepo, deckService)
+	swipeService := service.NewSwipeService(swipeRepo, roomRepo, matchService, lifecycleService, deckService)
 	footballService := service.NewFootballService(cfg.FootballAPI.Key, cfg.FootballAPI.ApiFootballKey)
 
+	// Проверка доступа к комнатам: маршруты /rooms/{room_id}/... и сущности комнат (фильтры, матчи)
+	roomAccess := middleware.NewRoomAccess(roomRepo)
+
 	// Инициализация handlers
 	userHandler := handlers.NewUserHandler(userRepo)
 	authHandler := handlers.NewAuthHandler(userRepo, refreshTokenRepo, cfg)
-	roomHandler := handlers.NewRoomHandler(roomRepo, filterRepo)
-	filterHandler := handlers.NewFilterHandler(filterRepo, roomRepo)
-	movieHandler := handlers.NewMovieHandler(movieRepo, roomRepo, filterRepo)
+	filterHandler := handlers.NewFilterHandler(filterRepo, roomRepo, roomAccess)
+	movieHandler := handlers.NewMovieHandler(movieRepo, roomRepo, deckService, recommendationService)
+	catalogHandler := handlers.NewCatalogHandler(movieRepo)
+	availabilityHandler := handlers.NewAvailabilityHandler(availabilityRepo, movieRepo)
 	// Инициализация WebSocket Hub (до handlers, т.к. SwipeHandler его использует)
 	wsHub := handlers.NewHub()
 	wsHub.SetAuth(userRepo, cfg)
+	wsHub.SetRoomAccess(roomAccess)
+	wsHub.SetReplayBuffer(cfg.WebSocket.ReplayBuffer)
+	wsHub.SetHandshake(cfg.WebSocket)
+	wsHub.SetTickets(wsTicketRepo)
+	switch cfg.WebSocket.Broadcaster {
+	case "memory":
+	case "postgres":
+		// Несколько экземпляров сервера: события комнат расходятся через LISTEN/NOTIFY общей БД
+		wsBroadcaster, err := broadcast.NewPostgres(db.DB, cfg.Database.DSN())
+		if err != nil {
+			log.Fatalf("Failed to start WebSocket broadcaster: %v", err)
+		}
+		defer wsBroadcaster.Close()
+		wsHub.SetBroadcaster(wsBroadcaster)
+	default:
+		log.Fatalf("Invalid WS_BROADCASTER: %q (want memory or postgres)", cfg.WebSocket.Broadcaster)
+	}
 	go wsHub.Run()
+	lifecycleService.SetNotifier(wsHub)
+	swipeService.SetNotifier(wsHub)
+	wsHub.SetCommands(swipeService, lifecycleService)
+
+	// Фоновое завершение простаивающих комнат
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	roomIdleTTL, err := time.ParseDuration(cfg.Rooms.IdleTTL)
+	if err != nil {
+		log.Fatalf("Invalid ROOM_IDLE_TTL: %v", err)
+	}
+	roomSweepInterval, err := time.ParseDuration(cfg.Rooms.SweepInterval)
+	if err != nil {
+		log.Fatalf("Invalid ROOM_SWEEP_INTERVAL: %v", err)
+	}
+	go lifecycleService.RunSweeper(sweeperCtx, roomSweepInterval, roomIdleTTL)
 
-	swipeHandler := handlers.NewSwipeHandler(swipeRepo, matchService, wsHub)
-	matchHandler := handlers.NewMatchHandler(matchRepo, matchService)
+	roomHandler := handlers.NewRoomHandler(roomRepo, filterRepo, lifecycleService, swipeService, wsHub)
+	swipeHandler := handlers.NewSwipeHandler(swipeRepo, swipeService)
+	matchHandler := handlers.NewMatchHandler(matchRepo, matchService, roomAccess)
 	feedbackHandler := handlers.NewFeedbackHandler(feedbackRepo)
 	premiereHandler := handlers.NewPremiereHandler(premiereRepo)
-	matchLinkHandler := handlers.NewMatchLinkHandler(matchLinkRepo)
+	matchLinkHandler := handlers.NewMatchLinkHandler(matchLinkRepo, matchRepo, roomAccess)
+	ratingHandler := handlers.NewRatingHandler(ratingRepo, matchRepo, movieRepo, roomAccess)
+	watchlistHandler := handlers.NewWatchlistHandler(watchlistRepo, watchHistoryRepo, movieRepo, matchRepo, roomRepo, matchService)
 	footballHandler := handlers.NewFootballHandler(footballService)
 	gameScoreRepo := repository.NewGameScoreRepository(db.DB)
 	gameHandler := handlers.NewGameHandler(gameScoreRepo)
@@ -114,14 +168,26 @@ func main() {
 	api.HandleFunc("/users/{id}", userHandler.GetUser).Methods("GET")
 	api.HandleFunc("/users/{id}", userHandler.UpdateUser).Methods("PUT")
 	api.HandleFunc("/users/{id}/statistics", userHandler.GetUserStatistics).Methods("GET")
+	api.HandleFunc("/users/{id}/taste-profile", movieHandler.GetTasteProfile).Methods("GET")
 
-	// Room routes
+	// Room routes (маршруты /rooms/{room_id}/... — только участникам или хосту, см. middleware.RoomAccess)
 	api.HandleFunc("/rooms", roomHandler.CreateRoom).Methods("POST")
 	api.HandleFunc("/rooms", roomHandler.GetAllRooms).Methods("GET")
 	api.HandleFunc("/rooms/code/{code}", roomHandler.GetRoomByCode).Methods("GET")
 	api.HandleFunc("/rooms/code/{code}/join", roomHandler.JoinRoom).Methods("POST")
-	api.HandleFunc("/rooms/{room_id}/start", roomHandler.StartRoom).Methods("POST")
-	api.HandleFunc("/rooms/{room_id}/members", roomHandler.GetRoomMembers).Methods("GET")
+	api.Handle("/rooms/{room_id}/start", roomAccess.RequireHost(http.HandlerFunc(roomHandler.StartRoom))).Methods("POST")
+	api.Handle("/rooms/{room_id}/members", roomAccess.RequireMember(http.HandlerFunc(roomHandler.GetRoomMembers))).Methods("GET")
+	api.Handle("/rooms/{room_id}/finish", roomAccess.RequireHost(http.HandlerFunc(roomHandler.FinishRoom))).Methods("POST")
+	api.Handle("/rooms/{room_id}/summary", roomAccess.RequireMember(http.HandlerFunc(roomHandler.GetRoomSummary))).Methods("GET")
+	api.Handle("/rooms/{room_id}/settings", roomAccess.RequireHost(http.HandlerFunc(roomHandler.UpdateRoomSettings))).Methods("PUT")
+	api.Handle("/rooms/{room_id}/join-requests", roomAccess.RequireHost(http.HandlerFunc(roomHandler.GetJoinRequests))).Methods("GET")
+	api.Handle("/rooms/{room_id}/join-requests/{user_id}/approve", roomAccess.RequireHost(http.HandlerFunc(roomHandler.ApproveJoinRequest))).Methods("POST")
+	api.Handle("/rooms/{room_id}/join-requests/{user_id}/reject", roomAccess.RequireHost(http.HandlerFunc(roomHandler.RejectJoinRequest))).Methods("POST")
+	api.Handle("/rooms/{room_id}/leave", roomAccess.RequireMember(http.HandlerFunc(roomHandler.LeaveRoom))).Methods("POST")
+	api.Handle("/rooms/{room_id}/members/{user_id}/kick", roomAccess.RequireHost(http.HandlerFunc(roomHandler.KickMember))).Methods("POST")
+	api.Handle("/rooms/{room_id}/members/{user_id}/ban", roomAccess.RequireHost(http.HandlerFunc(roomHandler.BanMember))).Methods("POST")
+	api.Handle("/rooms/{room_id}/bans/{user_id}", roomAccess.RequireHost(http.HandlerFunc(roomHandler.UnbanMember))).Methods("DELETE")
+	api.Handle("/rooms/{room_id}/transfer-host", roomAccess.RequireHost(http.HandlerFunc(roomHandler.TransferHost))).Methods("POST")
 
 	// Закомментированы нереализованные методы
 	// api.HandleFunc("/rooms/{id}", roomHandler.GetRoom).Methods("GET")
@@ -129,28 +195,56 @@ func main() {
 	// api.HandleFunc("/rooms/{id}/status", roomHandler.UpdateRoomStatus).Methods("PUT")
 
 	// Filter routes
-	api.HandleFunc("/rooms/{room_id}/filters", filterHandler.CreateFilter).Methods("POST")
+	api.Handle("/rooms/{room_id}/filters", roomAccess.RequireHost(http.HandlerFunc(filterHandler.CreateFilter))).Methods("POST")
 	api.HandleFunc("/filters/{id}", filterHandler.GetFilter).Methods("GET")
-	api.HandleFunc("/rooms/{room_id}/filters", filterHandler.GetRoomFilter).Methods("GET")
+	api.Handle("/rooms/{room_id}/filters", roomAccess.RequireMember(http.HandlerFunc(filterHandler.GetRoomFilter))).Methods("GET")
 
-	// Movie routes
+	// Movie routes (чтение публичное; изменения каталога только для админа)
 	api.HandleFunc("/movies", movieHandler.GetAllMovies).Methods("GET")
-	api.HandleFunc("/movies", movieHandler.CreateMovie).Methods("POST")
+	api.Handle("/movies", middleware.RequireAdmin(http.HandlerFunc(movieHandler.CreateMovie))).Methods("POST")
+	api.Handle("/movies", middleware.RequireAdmin(http.HandlerFunc(catalogHandler.BulkPatchMovies))).Methods("PATCH")
+	api.HandleFunc("/movies/search", movieHandler.SearchMovies).Methods("GET")
+	api.Handle("/movies/duplicates", middleware.RequireAdmin(http.HandlerFunc(catalogHandler.GetDuplicates))).Methods("GET")
 	api.HandleFunc("/movies/{id}", movieHandler.GetMovie).Methods("GET")
-	api.HandleFunc("/movies/{id}", movieHandler.UpdateMovie).Methods("PUT")
-	api.HandleFunc("/rooms/{room_id}/movies", movieHandler.GetRoomMovies).Methods("GET")
+	api.Handle("/movies/{id}", middleware.RequireAdmin(http.HandlerFunc(movieHandler.UpdateMovie))).Methods("PUT")
+	api.Handle("/movies/{id}", middleware.RequireAdmin(http.HandlerFunc(catalogHandler.DeleteMovie))).Methods("DELETE")
+	api.Handle("/movies/{id}/merge", middleware.RequireAdmin(http.HandlerFunc(catalogHandler.MergeMovies))).Methods("POST")
+	api.Handle("/movies/{id}/sources", middleware.RequireAdmin(http.HandlerFunc(catalogHandler.GetFieldSources))).Methods("GET")
+	api.HandleFunc("/movies/{id}/availability", availabilityHandler.GetMovieAvailability).Methods("GET")
+	api.Handle("/movies/{id}/availability", middleware.RequireAdmin(http.HandlerFunc(availabilityHandler.CreateAvailability))).Methods("POST")
+	api.HandleFunc("/movies/{id}/ratings", ratingHandler.GetMovieRatings).Methods("GET")
+	api.HandleFunc("/movies/{id}/rating", ratingHandler.DeleteMyRating).Methods("DELETE")
+	api.Handle("/availability/{id}", middleware.RequireAdmin(http.HandlerFunc(availabilityHandler.UpdateAvailability))).Methods("PUT")
+	api.Handle("/availability/{id}", middleware.RequireAdmin(http.HandlerFunc(availabilityHandler.DeleteAvailability))).Methods("DELETE")
+	api.Handle("/rooms/{room_id}/movies", roomAccess.RequireMember(http.HandlerFunc(movieHandler.GetRoomMovies))).Methods("GET")
 
 	// Swipe routes
-	api.HandleFunc("/rooms/{room_id}/swipes", swipeHandler.CreateSwipe).Methods("POST")
-	api.HandleFunc("/rooms/{room_id}/swipes/undo", swipeHandler.UndoSwipe).Methods("POST")
-	api.HandleFunc("/rooms/{room_id}/swipes", swipeHandler.GetUserSwipes).Methods("GET")
+	api.Handle("/rooms/{room_id}/swipes", roomAccess.RequireMember(http.HandlerFunc(swipeHandler.CreateSwipe))).Methods("POST")
+	api.Handle("/rooms/{room_id}/swipes/undo", roomAccess.RequireMember(http.HandlerFunc(swipeHandler.UndoSwipe))).Methods("POST")
+	api.Handle("/rooms/{room_id}/swipes", roomAccess.RequireMember(http.HandlerFunc(swipeHandler.GetUserSwipes))).Methods("GET")
 
 	// Match routes
 	api.HandleFunc("/matches/{id}", matchHandler.GetMatch).Methods("GET")
-	api.HandleFunc("/rooms/{room_id}/matches", matchHandler.GetRoomMatches).Methods("GET")
-	api.HandleFunc("/rooms/{room_id}/almost-matches", matchHandler.GetRoomAlmostMatches).Methods("GET")
+	api.Handle("/rooms/{room_id}/matches", roomAccess.RequireMember(http.HandlerFunc(matchHandler.GetRoomMatches))).Methods("GET")
+	api.Handle("/rooms/{room_id}/almost-matches", roomAccess.RequireMember(http.HandlerFunc(matchHandler.GetRoomAlmostMatches))).Methods("GET")
+	api.Handle("/rooms/{room_id}/ranking", roomAccess.RequireMember(http.HandlerFunc(matchHandler.GetRoomRanking))).Methods("GET")
 	api.HandleFunc("/matches/{match_id}/links", matchLinkHandler.GetMatchLinks).Methods("GET")
 	api.HandleFunc("/matches/{match_id}/links", matchLinkHandler.CreateMatchLink).Methods("POST")
+	api.HandleFunc("/matches/{match_id}/rating", ratingHandler.RateMatch).Methods("PUT")
+	api.HandleFunc("/matches/{match_id}/ratings", ratingHandler.GetMatchRatings).Methods("GET")
+
+	// Watchlist routes
+	api.HandleFunc("/watchlist", watchlistHandler.GetMyWatchlist).Methods("GET")
+	api.HandleFunc("/watchlist", watchlistHandler.AddToMyWatchlist).Methods("POST")
+	api.HandleFunc("/watchlist/{id}", watchlistHandler.RemoveFromMyWatchlist).Methods("DELETE")
+	api.HandleFunc("/watched", watchlistHandler.GetMyWatchHistory).Methods("GET")
+	api.HandleFunc("/watched", watchlistHandler.LogMyWatched).Methods("POST")
+	api.Handle("/rooms/{room_id}/watchlist", roomAccess.RequireMember(http.HandlerFunc(watchlistHandler.GetRoomWatchlist))).Methods("GET")
+	api.Handle("/rooms/{room_id}/watchlist", roomAccess.RequireMember(http.HandlerFunc(watchlistHandler.AddToRoomWatchlist))).Methods("POST")
+	api.Handle("/rooms/{room_id}/watchlist/almost-matches", roomAccess.RequireMember(http.HandlerFunc(watchlistHandler.AddAlmostMatchesToRoomWatchlist))).Methods("POST")
+	api.Handle("/rooms/{room_id}/watchlist/{id}", roomAccess.RequireMember(http.HandlerFunc(watchlistHandler.RemoveFromRoomWatchlist))).Methods("DELETE")
+	api.Handle("/rooms/{room_id}/watched", roomAccess.RequireMember(http.HandlerFunc(watchlistHandler.GetRoomWatchHistory))).Methods("GET")
+	api.Handle("/rooms/{room_id}/watched", roomAccess.RequireMember(http.HandlerFunc(watchlistHandler.LogRoomWatched))).Methods("POST")
 
 	// Premiere routes (GET публичный; create/update/delete только для админа)
 	api.HandleFunc("/premieres", premiereHandler.GetPremieres).Methods("GET")
@@ -174,9 +268,10 @@ func main() {
 	// Feedback routes
 	api.HandleFunc("/feedbacks", feedbackHandler.CreateFeedback).Methods("POST")
 	api.HandleFunc("/feedbacks/{id}", feedbackHandler.GetFeedback).Methods("GET")
-	api.HandleFunc("/rooms/{room_id}/feedbacks", feedbackHandler.GetRoomFeedbacks).Methods("GET")
+	api.Handle("/rooms/{room_id}/feedbacks", roomAccess.RequireMember(http.HandlerFunc(feedbackHandler.GetRoomFeedbacks))).Methods("GET")
 
-	// WebSocket route
+	// WebSocket route (билет на подключение — чтобы JWT не передавать в URL)
+	api.Handle("/rooms/{room_id}/ws-ticket", roomAccess.RequireMember(http.HandlerFunc(wsHub.IssueTicket))).Methods("POST")
 	api.HandleFunc("/rooms/{room_id}/ws", wsHub.HandleWebSocket).Methods("GET")
 
 	// Раздача фронтенда (для деплоя в один сервис; локально папки web нет — тогда 404)
@@ -218,6 +313,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopSweeper()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
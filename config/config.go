@@ -18,6 +18,10 @@ type Config struct {
 	MovieAPI   MovieAPIConfig
 	FootballAPI FootballAPIConfig
 	WebSocket  WebSocketConfig
+	Rooms      RoomConfig
+	Recommendations RecommendationConfig
+	Deck       DeckConfig
+	Metadata   MetadataConfig
 }
 
 type ServerConfig struct {
@@ -55,6 +59,37 @@ type FootballAPIConfig struct {
 type WebSocketConfig struct {
 	ReadBufferSize  int
 	WriteBufferSize int
+	ReplayBuffer    int    // сколько последних событий комнаты хранится для догона после переподключения (?since=)
+	Broadcaster     string // memory — один экземпляр сервера; postgres — несколько, события расходятся через LISTEN/NOTIFY
+	AllowedOrigins  string // Origin, с которых можно подключаться, через запятую (кроме своего); * — любые
+	StrictAuth      bool   // подключение только по билету или JWT со сроком действия, без user_id / X-User-ID
+}
+
+type RoomConfig struct {
+	IdleTTL       string // комнаты waiting/active без активности дольше TTL завершаются, e.g. "24h"
+	SweepInterval string // как часто искать простаивающие комнаты, e.g. "10m"
+}
+
+type RecommendationConfig struct {
+	Collaborative bool // учитывать лайки пользователей с похожим вкусом (коллаборативная фильтрация)
+}
+
+type DeckConfig struct {
+	// GroupStrategies — стратегии групповой оценки колоды через запятую (deck, average, least_misery);
+	// комнаты делятся между ними поровну (A/B-тест). Пусто — выключено. Групповая оценка заменяет
+	// персональный порядок колоды (PersonalOrder и Rank по вкусу участника не применяются), поэтому включается явно.
+	GroupStrategies string
+}
+
+// MetadataConfig — провайдеры обогащения каталога (go run ./cmd/catalog enrich)
+type MetadataConfig struct {
+	Providers    string // порядок опроса через запятую: первый, давший поле, становится его источником
+	OMDbKey      string
+	KinopoiskKey string
+	TMDBKey      string
+	OMDbRPM      int // лимиты запросов в минуту к каждому провайдеру
+	KinopoiskRPM int
+	TMDBRPM      int
 }
 
 func Load() (*Config, error) {
@@ -97,11 +132,12 @@ func Load() (*Config, error) {
 	// PORT задаёт Railway/Render/Fly; SERVER_HOST 0.0.0.0 нужен для приёма снаружи
 	serverPort := getEnv("PORT", getEnv("SERVER_PORT", "8080"))
 	serverHost := getEnv("SERVER_HOST", "0.0.0.0")
+	env := getEnv("ENV", "development")
 	config := &Config{
 		Server: ServerConfig{
 			Host:         serverHost,
 			Port:         serverPort,
-			Env:          getEnv("ENV", "development"),
+			Env:          env,
 			RateLimitRPM: getEnvAsInt("RATE_LIMIT_RPM", 120),
 		},
 		Database: dbConfig,
@@ -121,6 +157,29 @@ func Load() (*Config, error) {
 		WebSocket: WebSocketConfig{
 			ReadBufferSize:  getEnvAsInt("WS_READ_BUFFER_SIZE", 1024),
 			WriteBufferSize: getEnvAsInt("WS_WRITE_BUFFER_SIZE", 1024),
+			ReplayBuffer:    getEnvAsInt("WS_REPLAY_BUFFER", 256),
+			Broadcaster:     getEnv("WS_BROADCASTER", "memory"),
+			AllowedOrigins:  getEnv("WS_ALLOWED_ORIGINS", ""),
+			StrictAuth:      getEnvAsBool("WS_STRICT_AUTH", env == "production"),
+		},
+		Rooms: RoomConfig{
+			IdleTTL:       getEnv("ROOM_IDLE_TTL", "24h"),
+			SweepInterval: getEnv("ROOM_SWEEP_INTERVAL", "10m"),
+		},
+		Recommendations: RecommendationConfig{
+			Collaborative: getEnvAsBool("RECOMMENDATIONS_COLLABORATIVE", false),
+		},
+		Deck: DeckConfig{
+			GroupStrategies: getEnv("DECK_GROUP_STRATEGIES", ""),
+		},
+		Metadata: MetadataConfig{
+			Providers:    getEnv("METADATA_PROVIDERS", "kinopoisk,tmdb,omdb"),
+			OMDbKey:      getEnv("OMDB_API_KEY", ""),
+			KinopoiskKey: getEnv("KINOPOISK_API_KEY", ""),
+			TMDBKey:      getEnv("TMDB_API_KEY", ""),
+			OMDbRPM:      getEnvAsInt("OMDB_RPM", 60),
+			KinopoiskRPM: getEnvAsInt("KINOPOISK_RPM", 30),
+			TMDBRPM:      getEnvAsInt("TMDB_RPM", 120),
 		},
 	}
 
@@ -179,3 +238,9 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(getEnv(key, "")); err == nil {
+		return value
+	}
+	return defaultValue
+}
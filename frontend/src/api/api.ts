@@ -157,12 +157,13 @@ export interface Movie {
   comic_poster_url?: string;
   imdb_rating?: number;
   kp_rating?: number;
+  community_rating?: number;
+  community_votes?: number;
   genre: string;
   year: number;
   duration: number;
   description?: string;
   trailer_url?: string;
-  streaming_url?: string;
   created_at: string;
   updated_at: string;
 }
@@ -176,6 +177,18 @@ export interface Swipe {
   created_at: string;
 }
 
+export interface MovieAvailability {
+  id: string;
+  movie_id: string;
+  platform: string;
+  url: string;
+  region: string;
+  offer_type: 'subscription' | 'rent' | 'buy' | 'free';
+  price?: number;
+  currency?: string;
+  last_checked_at?: string;
+}
+
 export interface Match {
   id: string;
   room_id: string;
@@ -183,6 +196,44 @@ export interface Match {
   created_at: string;
   movie?: Movie;
   users?: User[];
+  where_to_watch?: MovieAvailability[];
+}
+
+export interface MovieRating {
+  id: string;
+  user_id: string;
+  username?: string;
+  movie_id: string;
+  match_id?: string;
+  rating: number;
+  review?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WatchlistItem {
+  id: string;
+  movie_id: string;
+  user_id?: string;
+  room_id?: string;
+  source: 'manual' | 'match' | 'almost_match';
+  match_id?: string;
+  added_by?: string;
+  note?: string;
+  movie?: Movie;
+  created_at: string;
+}
+
+export interface WatchEvent {
+  id: string;
+  movie_id: string;
+  room_id?: string;
+  match_id?: string;
+  watched_at: string;
+  logged_by?: string;
+  attendees: { user_id: string; username: string }[];
+  movie?: Movie;
+  created_at: string;
 }
 
 export interface MatchNotification {
@@ -303,6 +354,25 @@ export interface BracketV2Stage {
 }
 
 // API методы
+/** Страница списочного эндпоинта: элементы и курсор следующей страницы (нет — страница последняя) */
+export interface Page<T> {
+  items: T[];
+  next_cursor?: string;
+}
+
+/** Загружает все страницы списка, следуя next_cursor */
+async function fetchAllPages<T>(url: string, params: URLSearchParams = new URLSearchParams()): Promise<T[]> {
+  const items: T[] = [];
+  let cursor: string | undefined;
+  do {
+    if (cursor) params.set('cursor', cursor);
+    const response = await api.get<Page<T>>(`${url}?${params.toString()}`);
+    items.push(...(response.data.items || []));
+    cursor = response.data.next_cursor;
+  } while (cursor);
+  return items;
+}
+
 export const apiService = {
   // Пользователи
   createUser: async (username: string, email?: string, phone?: string): Promise<User> => {
@@ -335,8 +405,8 @@ export const apiService = {
     const params = new URLSearchParams();
     if (status) params.append('status', status);
     if (limit) params.append('limit', limit.toString());
-    const response = await api.get<Room[]>(`/rooms?${params.toString()}`);
-    return response.data;
+    const response = await api.get<Page<Room>>(`/rooms?${params.toString()}`);
+    return response.data.items || [];
   },
 
   getRoomByCode: async (code: string): Promise<Room> => {
@@ -354,6 +424,12 @@ export const apiService = {
     return response.data;
   },
 
+  // Одноразовый билет для подключения к WebSocket комнаты (действует 30 секунд)
+  getWebSocketTicket: async (roomId: string): Promise<{ ticket: string; expires_at: string }> => {
+    const response = await api.post<{ ticket: string; expires_at: string }>(`/rooms/${roomId}/ws-ticket`, {});
+    return response.data;
+  },
+
   getRoomMembers: async (roomId: string): Promise<User[]> => {
     const response = await api.get<User[]>(`/rooms/${roomId}/members`);
     return response.data;
@@ -371,8 +447,7 @@ export const apiService = {
   },
 
   getAllMovies: async (): Promise<Movie[]> => {
-    const response = await api.get<Movie[]>('/movies');
-    return response.data;
+    return fetchAllPages<Movie>('/movies');
   },
 
   createMovie: async (movie: Partial<Movie>): Promise<Movie> => {
@@ -402,15 +477,12 @@ export const apiService = {
   },
 
   getUserSwipes: async (roomId: string): Promise<Swipe[]> => {
-    const response = await api.get<Swipe[]>(`/rooms/${roomId}/swipes`);
-    return response.data;
+    return fetchAllPages<Swipe>(`/rooms/${roomId}/swipes`);
   },
 
   // Матчи
   getRoomMatches: async (roomId: string): Promise<Match[]> => {
-    const response = await api.get<Match[]>(`/rooms/${roomId}/matches`);
-    const data = response.data;
-    return Array.isArray(data) ? data : [];
+    return fetchAllPages<Match>(`/rooms/${roomId}/matches`);
   },
 
   getMatch: async (id: string): Promise<Match> => {
@@ -418,6 +490,58 @@ export const apiService = {
     return response.data;
   },
 
+  // Оценки после просмотра
+  rateMatch: async (matchId: string, rating: number, review?: string): Promise<{ rating: MovieRating; movie: Movie }> => {
+    const response = await api.put<{ rating: MovieRating; movie: Movie }>(`/matches/${matchId}/rating`, { rating, review });
+    return response.data;
+  },
+
+  getMatchRatings: async (matchId: string): Promise<MovieRating[]> => {
+    const response = await api.get<MovieRating[]>(`/matches/${matchId}/ratings`);
+    return response.data;
+  },
+
+  getMovieRatings: async (movieId: string): Promise<MovieRating[]> => {
+    return fetchAllPages<MovieRating>(`/movies/${movieId}/ratings`);
+  },
+
+  deleteMyRating: async (movieId: string): Promise<void> => {
+    await api.delete(`/movies/${movieId}/rating`);
+  },
+
+  // Посмотреть позже и журнал просмотров
+  getWatchlist: async (roomId?: string): Promise<WatchlistItem[]> => {
+    const response = await api.get<WatchlistItem[]>(roomId ? `/rooms/${roomId}/watchlist` : '/watchlist');
+    return response.data;
+  },
+
+  addToWatchlist: async (item: { movie_id?: string; match_id?: string; note?: string }, roomId?: string): Promise<WatchlistItem> => {
+    const response = await api.post<WatchlistItem>(roomId ? `/rooms/${roomId}/watchlist` : '/watchlist', item);
+    return response.data;
+  },
+
+  saveAlmostMatches: async (roomId: string): Promise<WatchlistItem[]> => {
+    const response = await api.post<WatchlistItem[]>(`/rooms/${roomId}/watchlist/almost-matches`);
+    return response.data;
+  },
+
+  removeFromWatchlist: async (id: string, roomId?: string): Promise<void> => {
+    await api.delete(roomId ? `/rooms/${roomId}/watchlist/${id}` : `/watchlist/${id}`);
+  },
+
+  getWatchHistory: async (roomId?: string): Promise<WatchEvent[]> => {
+    const response = await api.get<WatchEvent[]>(roomId ? `/rooms/${roomId}/watched` : '/watched');
+    return response.data;
+  },
+
+  logWatched: async (
+    entry: { movie_id?: string; match_id?: string; watched_at?: string; attendee_ids?: string[] },
+    roomId?: string
+  ): Promise<WatchEvent> => {
+    const response = await api.post<WatchEvent>(roomId ? `/rooms/${roomId}/watched` : '/watched', entry);
+    return response.data;
+  },
+
   // Авторизация и регистрация
   register: async (username: string, email: string, password: string, phone?: string): Promise<User> => {
     const response = await api.post<User>('/auth/register', { username, email, password, phone });
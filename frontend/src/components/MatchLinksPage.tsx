@@ -147,6 +147,13 @@ export const MatchLinksPage: React.FC<MatchLinksPageProps> = ({ match, onClose }
                   <span className="rating-value">{Number(safeMovie.kp_rating).toFixed(1)}</span>
                 </div>
               )}
+              {safeMovie.community_rating != null && (
+                <div className="movie-rating">
+                  <span className="rating-label">KinoSwipe:</span>
+                  <span className="rating-value">{Number(safeMovie.community_rating).toFixed(1)}</span>
+                  {safeMovie.community_votes ? <span className="rating-votes"> ({safeMovie.community_votes})</span> : null}
+                </div>
+              )}
               {safeMovie.description != null && String(safeMovie.description).trim() !== '' && (
                 <p className="movie-description">{String(safeMovie.description).substring(0, 150)}{String(safeMovie.description).length > 150 ? '...' : ''}</p>
               )}
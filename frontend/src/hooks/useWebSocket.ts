@@ -1,10 +1,38 @@
 import { useEffect, useRef, useState } from 'react';
-import { authStorage } from '../api/api';
+import { apiService, authStorage } from '../api/api';
 
+// Конверт событий сервера (протокол v1). seq есть только у событий комнаты,
+// служебные hello/resync/pong/kicked приходят без него.
 export interface WebSocketMessage {
+  v: number;
   type: string;
-  payload?: any;
+  room_id: string;
+  seq?: number;
+  id?: string; // в ack/error — id команды
   timestamp: number;
+  data?: any;
+}
+
+// Прогресс участника по колоде: сколько карточек прошёл, без направления свайпов
+export interface DeckProgress {
+  swiped: number;
+  total: number;
+  finished: boolean;
+}
+
+// data событий progress
+export interface ProgressEvent extends DeckProgress {
+  user_id: string;
+  username: string;
+}
+
+// data событий presence и элементы hello.members
+export interface PresenceEvent {
+  user_id: string;
+  username: string;
+  idle: boolean;
+  finished: boolean;
+  progress?: DeckProgress;
 }
 
 export interface UseWebSocketOptions {
@@ -13,6 +41,8 @@ export interface UseWebSocketOptions {
   onMessage?: (message: WebSocketMessage) => void;
   onMatch?: (match: any) => void;
   onError?: (error: Event) => void;
+  // Пропущенные события уже недоступны — состояние комнаты нужно перезагрузить через REST
+  onResync?: () => void;
   enabled?: boolean;
 }
 
@@ -22,15 +52,23 @@ export const useWebSocket = ({
   onMessage,
   onMatch,
   onError,
+  onResync,
   enabled = true,
 }: UseWebSocketOptions) => {
   const [isConnected, setIsConnected] = useState(false);
   const [reconnectAttempts, setReconnectAttempts] = useState(0);
   const wsRef = useRef<WebSocket | null>(null);
   const reconnectTimeoutRef = useRef<NodeJS.Timeout | null>(null);
+  // Последний полученный seq комнаты: при переподключении сервер дошлёт события после него
+  const lastSeqRef = useRef<number | null>(null);
+  // Команды, ждущие ack/error, по id
+  const pendingRef = useRef(new Map<string, { resolve: (data: any) => void; reject: (error: Error) => void }>());
+  const commandSeqRef = useRef(0);
+  // Номер попытки подключения: пока запрашивается билет, хук могли отключить или переподключить
+  const connectAttemptRef = useRef(0);
 
-  // WebSocket URL: user_id (обязательно) + token (для авторизации, fallback на X-User-ID на бэкенде)
-  const getWebSocketURL = (rid: string, uid: string) => {
+  // WebSocket URL: одноразовый билет, чтобы JWT не попадал в URL; без входа — user_id (сервер примет его только вне строгого режима)
+  const getWebSocketURL = async (rid: string, uid: string) => {
     if (!rid || !uid) return '';
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
     const host = window.location.hostname;
@@ -38,17 +76,25 @@ export const useWebSocket = ({
     if (!port) port = window.location.protocol === 'https:' ? '443' : '80';
     const hostPort = (port === '80' || port === '443') ? host : `${host}:${port}`;
     let url = `${protocol}//${hostPort}/api/v1/rooms/${rid}/ws?user_id=${encodeURIComponent(uid)}`;
-    const token = authStorage.getAccessToken();
-    if (token) {
-      url += `&token=${encodeURIComponent(token)}`;
+    if (authStorage.getAccessToken()) {
+      try {
+        const { ticket } = await apiService.getWebSocketTicket(rid);
+        url = `${protocol}//${hostPort}/api/v1/rooms/${rid}/ws?ticket=${encodeURIComponent(ticket)}`;
+      } catch (error) {
+        console.error('Failed to get WebSocket ticket:', error);
+      }
+    }
+    if (lastSeqRef.current !== null) {
+      url += `&since=${lastSeqRef.current}`;
     }
     return url;
   };
 
-  const connect = () => {
+  const connect = async () => {
     if (!roomId || !userId || !enabled) return;
-    const wsUrl = getWebSocketURL(roomId, userId);
-    if (!wsUrl) return;
+    const attempt = ++connectAttemptRef.current;
+    const wsUrl = await getWebSocketURL(roomId, userId);
+    if (!wsUrl || attempt !== connectAttemptRef.current) return;
     try {
       const ws = new WebSocket(wsUrl);
       wsRef.current = ws;
@@ -66,22 +112,36 @@ export const useWebSocket = ({
           const data: WebSocketMessage = JSON.parse(raw);
           if (!data || typeof data !== 'object') return;
 
-          // Прямой формат от BroadcastMatch: { type: "match", match: {...} }
-          if (data.type === 'match') {
-            const match = (data as { match?: unknown }).match;
-            if (match && typeof match === 'object' && (match as { id?: string }).id) {
-              onMatch?.(match);
+          if (data.seq) {
+            // Повтор уже обработанного события (догон после переподключения) пропускаем
+            if (lastSeqRef.current !== null && data.seq <= lastSeqRef.current) return;
+            lastSeqRef.current = data.seq;
+          }
+
+          if ((data.type === 'ack' || data.type === 'error') && data.id) {
+            const pending = pendingRef.current.get(data.id);
+            if (pending) {
+              pendingRef.current.delete(data.id);
+              if (data.type === 'ack') pending.resolve(data.data);
+              else pending.reject(new Error(data.data?.message || 'Command failed'));
             }
+            return;
           }
-          // Обёрнутый формат от broadcastToRoom: { type: "broadcast", payload: { type: "match", match: {...} } }
-          if (data.type === 'broadcast') {
-            const payload = (data as { payload?: unknown }).payload;
-            if (payload && typeof payload === 'object' && (payload as Record<string, unknown>)['type'] === 'match') {
-              const match = (payload as Record<string, unknown>)['match'];
-              if (match && typeof match === 'object' && (match as { id?: string }).id) {
-                onMatch?.(match);
+
+          switch (data.type) {
+            case 'hello':
+              // Первое подключение: дальше считаем от текущего seq комнаты
+              if (lastSeqRef.current === null) lastSeqRef.current = data.data?.seq ?? 0;
+              break;
+            case 'resync':
+              lastSeqRef.current = data.data?.seq ?? null;
+              onResync?.();
+              break;
+            case 'match':
+              if (data.data && typeof data.data === 'object' && data.data.id) {
+                onMatch?.(data.data);
               }
-            }
+              break;
           }
 
           onMessage?.(data);
@@ -98,6 +158,9 @@ export const useWebSocket = ({
       ws.onclose = () => {
         console.log('WebSocket disconnected');
         setIsConnected(false);
+        // Ответы на команды этого соединения уже не придут
+        pendingRef.current.forEach((pending) => pending.reject(new Error('WebSocket disconnected')));
+        pendingRef.current.clear();
         
         // Попытка переподключения (максимум 5 попыток)
         if (reconnectAttempts < 5) {
@@ -115,6 +178,7 @@ export const useWebSocket = ({
   };
 
   const disconnect = () => {
+    connectAttemptRef.current += 1;
     if (reconnectTimeoutRef.current) {
       clearTimeout(reconnectTimeoutRef.current);
     }
@@ -122,10 +186,28 @@ export const useWebSocket = ({
       wsRef.current.close();
       wsRef.current = null;
     }
+    pendingRef.current.forEach((pending) => pending.reject(new Error('WebSocket disconnected')));
+    pendingRef.current.clear();
     setIsConnected(false);
   };
 
+  // Команда серверу (swipe, undo, start_room, ready, typing, reaction); промис завершается по ack/error
+  const command = (type: string, data?: unknown): Promise<any> => {
+    const ws = wsRef.current;
+    if (!ws || ws.readyState !== WebSocket.OPEN) {
+      return Promise.reject(new Error('WebSocket is not connected'));
+    }
+    commandSeqRef.current += 1;
+    const id = `c${commandSeqRef.current}`;
+    return new Promise((resolve, reject) => {
+      pendingRef.current.set(id, { resolve, reject });
+      ws.send(JSON.stringify({ type, id, data }));
+    });
+  };
+
   useEffect(() => {
+    // seq свой у каждой комнаты
+    lastSeqRef.current = null;
     if (roomId && userId && enabled) {
       connect();
     } else {
@@ -145,6 +227,7 @@ export const useWebSocket = ({
         wsRef.current.send(JSON.stringify(data));
       }
     },
+    command,
     disconnect,
   };
 };
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type availabilityRepoInterface interface {
+	ListByMovieID(movieID uuid.UUID, region string, page pagination.Page) ([]models.MovieAvailability, string, error)
+	GetByID(id uuid.UUID) (*models.MovieAvailability, error)
+	Upsert(a *models.MovieAvailability) error
+	Update(a *models.MovieAvailability) error
+	Delete(id uuid.UUID) error
+}
+
+type movieGetterInterface interface {
+	GetByID(id uuid.UUID) (*models.Movie, error)
+}
+
+// AvailabilityHandler — где посмотреть фильм: чтение для всех, правка для администраторов
+type AvailabilityHandler struct {
+	availabilityRepo availabilityRepoInterface
+	movieRepo        movieGetterInterface
+}
+
+func NewAvailabilityHandler(availabilityRepo *repository.AvailabilityRepository, movieRepo *repository.MovieRepository) *AvailabilityHandler {
+	return &AvailabilityHandler{availabilityRepo: availabilityRepo, movieRepo: movieRepo}
+}
+
+// GetMovieAvailability — GET /movies/{id}/availability?region=RU
+func (h *AvailabilityHandler) GetMovieAvailability(w http.ResponseWriter, r *http.Request) {
+	movieID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	offers, next, err := h.availabilityRepo.ListByMovieID(movieID, strings.ToUpper(r.URL.Query().Get("region")), page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get availability")
+		return
+	}
+
+	respondWithPage(w, offers, next, page)
+}
+
+// CreateAvailability — POST /movies/{id}/availability. Повторное предложение той же платформы, региона и типа обновляет ссылку и цену.
+func (h *AvailabilityHandler) CreateAvailability(w http.ResponseWriter, r *http.Request) {
+	movieID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+	if _, err := h.movieRepo.GetByID(movieID); err != nil {
+		if errors.Is(err, repository.ErrMovieNotFound) {
+			respondWithError(w, http.StatusNotFound, "Movie not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to get movie")
+		return
+	}
+
+	offer, msg := decodeAvailability(r)
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	offer.MovieID = movieID
+
+	if err := h.availabilityRepo.Upsert(offer); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save availability")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, offer)
+}
+
+// UpdateAvailability — PUT /availability/{id}
+func (h *AvailabilityHandler) UpdateAvailability(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid availability ID")
+		return
+	}
+	existing, err := h.availabilityRepo.GetByID(id)
+	if errors.Is(err, repository.ErrAvailabilityNotFound) {
+		respondWithError(w, http.StatusNotFound, "Availability not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get availability")
+		return
+	}
+
+	offer, msg := decodeAvailability(r)
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	offer.ID = existing.ID
+	offer.MovieID = existing.MovieID
+	offer.CreatedAt = existing.CreatedAt
+
+	if err := h.availabilityRepo.Update(offer); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrAvailabilityExists):
+			respondWithError(w, http.StatusConflict, "Movie already has an offer for this platform, region and offer type")
+		case errors.Is(err, repository.ErrAvailabilityNotFound):
+			respondWithError(w, http.StatusNotFound, "Availability not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to update availability")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, offer)
+}
+
+// DeleteAvailability — DELETE /availability/{id}
+func (h *AvailabilityHandler) DeleteAvailability(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid availability ID")
+		return
+	}
+
+	if err := h.availabilityRepo.Delete(id); err != nil {
+		if errors.Is(err, repository.ErrAvailabilityNotFound) {
+			respondWithError(w, http.StatusNotFound, "Availability not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete availability")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Availability deleted"})
+}
+
+// decodeAvailability читает и проверяет тело запроса; непустая строка — текст ошибки для клиента
+func decodeAvailability(r *http.Request) (*models.MovieAvailability, string) {
+	var req models.AvailabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, "Invalid request payload"
+	}
+
+	offer := &models.MovieAvailability{
+		Platform:      strings.ToLower(strings.TrimSpace(req.Platform)),
+		URL:           strings.TrimSpace(req.URL),
+		Region:        strings.ToUpper(strings.TrimSpace(req.Region)),
+		OfferType:     req.OfferType,
+		Price:         req.Price,
+		Currency:      strings.ToUpper(strings.TrimSpace(req.Currency)),
+		LastCheckedAt: req.LastCheckedAt,
+	}
+	if offer.Region == "" {
+		offer.Region = "RU"
+	}
+	if offer.OfferType == "" {
+		offer.OfferType = models.OfferSubscription
+	}
+	if offer.Price != nil && offer.Currency == "" {
+		offer.Currency = "RUB"
+	}
+
+	switch {
+	case !models.ValidPlatform(offer.Platform):
+		return nil, "Unknown platform"
+	case !strings.HasPrefix(offer.URL, "http://") && !strings.HasPrefix(offer.URL, "https://"):
+		return nil, "url must be an http(s) link"
+	case len(offer.Region) != 2:
+		return nil, "region must be a two-letter country code"
+	case !offer.OfferType.Valid():
+		return nil, "offer_type must be subscription, rent, buy or free"
+	case offer.Price != nil && *offer.Price < 0:
+		return nil, "price must not be negative"
+	case offer.Currency != "" && len(offer.Currency) != 3:
+		return nil, "currency must be a three-letter code"
+	}
+	return offer, ""
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// fakeAvailabilityStore — предложения в памяти; уникальность (фильм, платформа, регион, тип) как в БД
+type fakeAvailabilityStore struct {
+	availabilityRepoInterface
+	offers map[uuid.UUID]*models.MovieAvailability
+	err    error // ошибка базы для GetByID
+}
+
+func (f *fakeAvailabilityStore) GetByID(id uuid.UUID) (*models.MovieAvailability, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	offer, ok := f.offers[id]
+	if !ok {
+		return nil, repository.ErrAvailabilityNotFound
+	}
+	copied := *offer
+	return &copied, nil
+}
+
+func (f *fakeAvailabilityStore) Update(a *models.MovieAvailability) error {
+	for id, o := range f.offers {
+		if id != a.ID && o.MovieID == a.MovieID && o.Platform == a.Platform && o.Region == a.Region && o.OfferType == a.OfferType {
+			return repository.ErrAvailabilityExists
+		}
+	}
+	f.offers[a.ID] = a
+	return nil
+}
+
+func (f *fakeAvailabilityStore) Delete(id uuid.UUID) error {
+	if _, ok := f.offers[id]; !ok {
+		return repository.ErrAvailabilityNotFound
+	}
+	delete(f.offers, id)
+	return nil
+}
+
+func TestAvailabilityHandler_Update(t *testing.T) {
+	movieID := uuid.New()
+	okko := &models.MovieAvailability{ID: uuid.New(), MovieID: movieID, Platform: "okko", Region: "RU", OfferType: models.OfferSubscription}
+	ivi := &models.MovieAvailability{ID: uuid.New(), MovieID: movieID, Platform: "ivi", Region: "RU", OfferType: models.OfferSubscription}
+
+	cases := []struct {
+		name string
+		id   uuid.UUID
+		body string
+		err  error
+		want int
+	}{
+		{"update", ivi.ID, `{"platform":"ivi","url":"https://ivi.ru/film","offer_type":"rent","price":99}`, nil, http.StatusOK},
+		// Правка превращает предложение в дубль уже существующего
+		{"duplicate offer", ivi.ID, `{"platform":"okko","url":"https://okko.tv/film"}`, nil, http.StatusConflict},
+		{"unknown offer", uuid.New(), `{"platform":"okko","url":"https://okko.tv/film"}`, nil, http.StatusNotFound},
+		{"database error", ivi.ID, `{"platform":"okko","url":"https://okko.tv/film"}`, errors.New("connection refused"), http.StatusInternalServerError},
+		{"invalid body", ivi.ID, `{"platform":"vhs","url":"https://vhs.example"}`, nil, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeAvailabilityStore{offers: map[uuid.UUID]*models.MovieAvailability{}, err: tc.err}
+			for _, o := range []*models.MovieAvailability{okko, ivi} {
+				copied := *o
+				store.offers[o.ID] = &copied
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/availability/"+tc.id.String(), strings.NewReader(tc.body))
+			req = mux.SetURLVars(req, map[string]string{"id": tc.id.String()})
+			rec := httptest.NewRecorder()
+
+			(&AvailabilityHandler{availabilityRepo: store}).UpdateAvailability(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+			if tc.want == http.StatusConflict && store.offers[ivi.ID].Platform != "ivi" {
+				t.Errorf("conflicting update changed the offer: %+v", store.offers[ivi.ID])
+			}
+		})
+	}
+}
+
+func TestAvailabilityHandler_Delete(t *testing.T) {
+	offer := &models.MovieAvailability{ID: uuid.New()}
+	store := &fakeAvailabilityStore{offers: map[uuid.UUID]*models.MovieAvailability{offer.ID: offer}}
+
+	for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodDelete, "/availability/"+offer.ID.String(), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": offer.ID.String()})
+		rec := httptest.NewRecorder()
+
+		(&AvailabilityHandler{availabilityRepo: store}).DeleteAvailability(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("status = %d, want %d", rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+	"kinoswipe/service"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxBulkPatch — сколько фильмов можно поправить одним запросом
+const maxBulkPatch = 500
+
+// CatalogHandler — управление каталогом фильмов для администраторов (маршруты под middleware.RequireAdmin).
+type CatalogHandler struct {
+	movieRepo *repository.MovieRepository
+}
+
+func NewCatalogHandler(movieRepo *repository.MovieRepository) *CatalogHandler {
+	return &CatalogHandler{movieRepo: movieRepo}
+}
+
+// DeleteMovie — DELETE /movies/{id}
+func (h *CatalogHandler) DeleteMovie(w http.ResponseWriter, r *http.Request) {
+	movieID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	if err := h.movieRepo.Delete(movieID); err != nil {
+		if errors.Is(err, repository.ErrMovieNotFound) {
+			respondWithError(w, http.StatusNotFound, "Movie not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete movie")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Movie deleted"})
+}
+
+// MergeMovies — POST /movies/{id}/merge: дубль source_id сливается в фильм из пути и удаляется.
+func (h *CatalogHandler) MergeMovies(w http.ResponseWriter, r *http.Request) {
+	targetID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	var req models.MergeMoviesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SourceID == uuid.Nil {
+		respondWithError(w, http.StatusBadRequest, "source_id is required")
+		return
+	}
+	if req.SourceID == targetID {
+		respondWithError(w, http.StatusBadRequest, "Cannot merge a movie into itself")
+		return
+	}
+
+	result, err := h.movieRepo.Merge(targetID, req.SourceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMovieNotFound) {
+			respondWithError(w, http.StatusNotFound, "Movie not found")
+			return
+		}
+		log.Printf("MergeMovies: failed to merge %s into %s: %v", req.SourceID, targetID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to merge movies")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// BulkPatchMovies — PATCH /movies: пакетная правка жанров и рейтингов. Всё или ничего.
+func (h *CatalogHandler) BulkPatchMovies(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkMoviePatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if len(req.Movies) == 0 {
+		respondWithError(w, http.StatusBadRequest, "movies is required")
+		return
+	}
+	if len(req.Movies) > maxBulkPatch {
+		respondWithError(w, http.StatusBadRequest, "Too many movies in one request")
+		return
+	}
+	for _, p := range req.Movies {
+		if p.ID == uuid.Nil {
+			respondWithError(w, http.StatusBadRequest, "Movie id is required")
+			return
+		}
+		if !validRating(p.IMDbRating) || !validRating(p.KPRating) {
+			respondWithError(w, http.StatusBadRequest, "Rating must be between 0 and 10")
+			return
+		}
+	}
+
+	updated, missing, err := h.movieRepo.BulkPatch(req.Movies)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update movies")
+		return
+	}
+	if len(missing) > 0 {
+		respondWithJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error":   "Movies not found",
+			"missing": missing,
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.BulkMoviePatchResponse{Updated: updated})
+}
+
+// GetDuplicates — GET /movies/duplicates: группы фильмов с одинаковым нормализованным названием и годом.
+func (h *CatalogHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	movies, err := h.movieRepo.GetAllForDedup()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get movies")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, service.FindDuplicates(movies))
+}
+
+// GetFieldSources — GET /movies/{id}/sources: какие поля фильма заполнены обогащением и из какого провайдера
+func (h *CatalogHandler) GetFieldSources(w http.ResponseWriter, r *http.Request) {
+	movieID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	if _, err := h.movieRepo.GetByID(movieID); err != nil {
+		if errors.Is(err, repository.ErrMovieNotFound) {
+			respondWithError(w, http.StatusNotFound, "Movie not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to get movie")
+		return
+	}
+	sources, err := h.movieRepo.GetFieldSources(movieID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get field sources")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sources)
+}
+
+func validRating(v *float64) bool {
+	return v == nil || (*v >= 0 && *v <= 10)
+}
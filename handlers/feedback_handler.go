@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 	"kinoswipe/repository"
 
 	"github.com/google/uuid"
@@ -76,12 +77,21 @@ func (h *FeedbackHandler) GetRoomFeedbacks(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	feedbacks, err := h.feedbackRepo.GetByRoomID(roomID)
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	feedbacks, next, err := h.feedbackRepo.GetByRoomID(roomID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get feedbacks")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, feedbacks)
+	respondWithPage(w, feedbacks, next, page)
 }
 
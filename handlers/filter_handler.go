@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"kinoswipe/middleware"
 	"kinoswipe/models"
 	"kinoswipe/repository"
 
@@ -14,12 +15,14 @@ import (
 type FilterHandler struct {
 	filterRepo *repository.FilterRepository
 	roomRepo   *repository.RoomRepository
+	roomAccess *middleware.RoomAccess
 }
 
-func NewFilterHandler(filterRepo *repository.FilterRepository, roomRepo *repository.RoomRepository) *FilterHandler {
+func NewFilterHandler(filterRepo *repository.FilterRepository, roomRepo *repository.RoomRepository, roomAccess *middleware.RoomAccess) *FilterHandler {
 	return &FilterHandler{
 		filterRepo: filterRepo,
 		roomRepo:   roomRepo,
+		roomAccess: roomAccess,
 	}
 }
 
@@ -39,6 +42,12 @@ func (h *FilterHandler) CreateFilter(w http.ResponseWriter, r *http.Request) {
 
 	// Преобразуем жанры в JSON
 	genresJSON := repository.GenresToJSON(req.Genres)
+	for _, p := range req.Platforms {
+		if !models.ValidPlatform(p) {
+			respondWithError(w, http.StatusBadRequest, "Unknown platform: "+p)
+			return
+		}
+	}
 
 	filter := &models.Filter{
 		ID:          uuid.New(),
@@ -50,6 +59,7 @@ func (h *FilterHandler) CreateFilter(w http.ResponseWriter, r *http.Request) {
 		DurationMax: req.DurationMax,
 		MinRating:   req.MinRating,
 		Mood:        req.Mood,
+		Platforms:   repository.StringsToJSON(req.Platforms),
 	}
 
 	if err := h.filterRepo.Create(filter); err != nil {
@@ -84,6 +94,10 @@ func (h *FilterHandler) GetFilter(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusNotFound, "Filter not found")
 		return
 	}
+	// Фильтр комнаты видят только её участники
+	if filter.RoomID != nil && !requireRoomMember(w, r, h.roomAccess, *filter.RoomID) {
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, filter)
 }
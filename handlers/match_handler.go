@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
+	"kinoswipe/middleware"
+	"kinoswipe/pagination"
 	"kinoswipe/repository"
 	"kinoswipe/service"
 
@@ -13,12 +16,14 @@ import (
 type MatchHandler struct {
 	matchRepo   *repository.MatchRepository
 	matchService *service.MatchService
+	roomAccess  *middleware.RoomAccess
 }
 
-func NewMatchHandler(matchRepo *repository.MatchRepository, matchService *service.MatchService) *MatchHandler {
+func NewMatchHandler(matchRepo *repository.MatchRepository, matchService *service.MatchService, roomAccess *middleware.RoomAccess) *MatchHandler {
 	return &MatchHandler{
 		matchRepo:   matchRepo,
 		matchService: matchService,
+		roomAccess:  roomAccess,
 	}
 }
 
@@ -30,6 +35,15 @@ func (h *MatchHandler) GetMatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	match, err := h.matchRepo.GetByID(matchID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Match not found")
+		return
+	}
+	if !requireRoomMember(w, r, h.roomAccess, match.RoomID) {
+		return
+	}
+
 	matchDetails, err := h.matchService.GetMatchWithDetails(matchID)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Match not found")
@@ -47,16 +61,25 @@ func (h *MatchHandler) GetRoomMatches(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches, err := h.matchRepo.GetByRoomID(roomID)
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	matches, next, err := h.matchRepo.ListByRoomID(roomID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get matches")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, matches)
+	respondWithPage(w, matches, next, page)
 }
 
-// GetRoomAlmostMatches возвращает фильмы, которые лайкнули все активные участники кроме одного (N-1).
+// GetRoomAlmostMatches возвращает фильмы, которым до матча по правилу комнаты не хватает одного лайка.
 func (h *MatchHandler) GetRoomAlmostMatches(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomID, err := uuid.Parse(vars["room_id"])
@@ -65,12 +88,49 @@ func (h *MatchHandler) GetRoomAlmostMatches(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	list, err := h.matchService.GetAlmostMatches(roomID)
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	list, next, err := h.matchService.ListAlmostMatches(roomID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get almost matches")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, list)
+	respondWithPage(w, list, next, page)
 }
 
+
+// GetRoomRanking возвращает рейтинг «лучшего компромисса»: все свайпнутые фильмы с лайками,
+// отсортированные по очкам, с голосом каждого участника. Параметр limit — размер списка.
+func (h *MatchHandler) GetRoomRanking(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, err := uuid.Parse(vars["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	ranking, err := h.matchService.GetRanking(roomID, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get ranking")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ranking)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 
+	"kinoswipe/middleware"
 	"kinoswipe/models"
 	"kinoswipe/repository"
 
@@ -13,18 +14,18 @@ import (
 )
 
 type MatchLinkHandler struct {
-	linkRepo *repository.MatchLinkRepository
+	linkRepo   *repository.MatchLinkRepository
+	matchRepo  *repository.MatchRepository
+	roomAccess *middleware.RoomAccess
 }
 
-func NewMatchLinkHandler(linkRepo *repository.MatchLinkRepository) *MatchLinkHandler {
-	return &MatchLinkHandler{linkRepo: linkRepo}
+func NewMatchLinkHandler(linkRepo *repository.MatchLinkRepository, matchRepo *repository.MatchRepository, roomAccess *middleware.RoomAccess) *MatchLinkHandler {
+	return &MatchLinkHandler{linkRepo: linkRepo, matchRepo: matchRepo, roomAccess: roomAccess}
 }
 
 func (h *MatchLinkHandler) GetMatchLinks(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	matchID, err := uuid.Parse(vars["match_id"])
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+	matchID, ok := h.requireMatchMember(w, r)
+	if !ok {
 		return
 	}
 
@@ -39,10 +40,8 @@ func (h *MatchLinkHandler) GetMatchLinks(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *MatchLinkHandler) CreateMatchLink(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	matchID, err := uuid.Parse(vars["match_id"])
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+	matchID, ok := h.requireMatchMember(w, r)
+	if !ok {
 		return
 	}
 
@@ -68,3 +67,22 @@ func (h *MatchLinkHandler) CreateMatchLink(w http.ResponseWriter, r *http.Reques
 
 	respondWithJSON(w, http.StatusCreated, link)
 }
+
+// requireMatchMember загружает матч из пути и пропускает только участников его комнаты.
+func (h *MatchLinkHandler) requireMatchMember(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	matchID, err := uuid.Parse(mux.Vars(r)["match_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+		return uuid.Nil, false
+	}
+
+	match, err := h.matchRepo.GetByID(matchID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Match not found")
+		return uuid.Nil, false
+	}
+	if !requireRoomMember(w, r, h.roomAccess, match.RoomID) {
+		return uuid.Nil, false
+	}
+	return matchID, true
+}
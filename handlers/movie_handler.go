@@ -2,45 +2,130 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 	"kinoswipe/repository"
+	"kinoswipe/service"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type MovieHandler struct {
-	movieRepo  *repository.MovieRepository
-	roomRepo   *repository.RoomRepository
-	filterRepo *repository.FilterRepository
+	movieRepo             *repository.MovieRepository
+	roomRepo              *repository.RoomRepository
+	deckService           *service.DeckService
+	recommendationService *service.RecommendationService
 }
 
-func NewMovieHandler(movieRepo *repository.MovieRepository, roomRepo *repository.RoomRepository, filterRepo *repository.FilterRepository) *MovieHandler {
+func NewMovieHandler(
+	movieRepo *repository.MovieRepository,
+	roomRepo *repository.RoomRepository,
+	deckService *service.DeckService,
+	recommendationService *service.RecommendationService,
+) *MovieHandler {
 	return &MovieHandler{
-		movieRepo:  movieRepo,
-		roomRepo:   roomRepo,
-		filterRepo: filterRepo,
+		movieRepo:             movieRepo,
+		roomRepo:              roomRepo,
+		deckService:           deckService,
+		recommendationService: recommendationService,
 	}
 }
 
 func (h *MovieHandler) GetAllMovies(w http.ResponseWriter, r *http.Request) {
-	limit := 1000 // Большой лимит для библиотеки
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		fmt.Sscanf(limitStr, "%d", &limit)
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
 	}
 
-	movies, err := h.movieRepo.GetAll(limit)
+	movies, next, err := h.movieRepo.GetAll(page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get movies")
 		return
 	}
-	if movies == nil {
-		movies = []models.Movie{}
+	respondWithPage(w, movies, next, page)
+}
+
+// defaultSearchLimit — размер страницы поиска по умолчанию (сетка библиотеки)
+const defaultSearchLimit = 24
+
+// SearchMovies — полнотекстовый поиск по каталогу с фасетами (жанр, десятилетие, рейтинг), сортировкой и курсором.
+// GET /movies/search?q=&genre=&decade=&rating=&sort=&cursor=&limit=&fields=; фасеты можно повторять или перечислять через запятую.
+func (h *MovieHandler) SearchMovies(w http.ResponseWriter, r *http.Request) {
+	page, ok := parsePage(w, r, defaultSearchLimit)
+	if !ok {
+		return
 	}
-	respondWithJSON(w, http.StatusOK, movies)
+
+	query := r.URL.Query()
+	params := &models.MovieSearchParams{
+		Query:       strings.TrimSpace(query.Get("q")),
+		Genres:      queryList(query, "genre"),
+		RatingBands: queryList(query, "rating"),
+		Sort:        models.MovieSearchSort(query.Get("sort")),
+		Cursor:      page.Cursor,
+		Limit:       page.Limit,
+	}
+
+	if params.Sort == "" {
+		params.Sort = models.MovieSearchSortRating
+		if params.Query != "" {
+			params.Sort = models.MovieSearchSortRelevance
+		}
+	}
+	if !params.Sort.Valid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid sort")
+		return
+	}
+	for _, d := range queryList(query, "decade") {
+		decade, err := strconv.Atoi(strings.TrimSuffix(d, "s"))
+		if err != nil || decade%10 != 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid decade")
+			return
+		}
+		params.Decades = append(params.Decades, decade)
+	}
+
+	result, err := h.movieRepo.Search(params)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("SearchMovies: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to search movies")
+		return
+	}
+
+	envelope, err := pagination.NewEnvelope(result.Movies, result.NextCursor, page.Fields)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to build response")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, models.MovieSearchPage{Envelope: envelope, Total: result.Total, Facets: result.Facets})
+}
+
+// queryList собирает значения параметра: ?genre=a&genre=b и ?genre=a,b равнозначны.
+func queryList(query url.Values, key string) []string {
+	var values []string
+	for _, raw := range query[key] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
 }
 
 func (h *MovieHandler) GetMovie(w http.ResponseWriter, r *http.Request) {
@@ -60,6 +145,9 @@ func (h *MovieHandler) GetMovie(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, movie)
 }
 
+// defaultRoomMoviesLimit — сколько карточек колоды отдавать, если клиент не передал limit
+const defaultRoomMoviesLimit = 100
+
 func (h *MovieHandler) GetRoomMovies(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomID, err := uuid.Parse(vars["room_id"])
@@ -73,23 +161,47 @@ func (h *MovieHandler) GetRoomMovies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	limit := 100 // По умолчанию
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		fmt.Sscanf(limitStr, "%d", &limit)
+	// Колода отдаётся по порядку без курсора, из пагинации нужен только limit (не больше pagination.MaxLimit)
+	page, ok := parsePage(w, r, defaultRoomMoviesLimit)
+	if !ok {
+		return
 	}
+	limit := page.Limit
 
-	// Получаем фильмы, которые пользователь еще не свайпнул
-	movies, err := h.movieRepo.GetNotSwipedByUser(roomID, userID, limit)
-	if err != nil || len(movies) == 0 {
-		// Если ошибка или все фильмы уже свайпнуты — возвращаем все фильмы
-		allMovies, allErr := h.movieRepo.GetAll(limit)
-		if allErr == nil && len(allMovies) > 0 {
-			respondWithJSON(w, http.StatusOK, allMovies)
-			return
+	room, ok := loadRoom(w, r, h.roomRepo, roomID)
+	if !ok {
+		return
+	}
+
+	// Колода комнаты (собирается при старте); до старта — фильмы, которые пользователь еще не свайпнул, с учётом фильтра
+	movies, hasDeck, err := h.deckService.NextCards(room, userID, limit)
+	if err == nil && !hasDeck {
+		movies, err = h.movieRepo.GetDeckForUser(roomID, userID, h.deckService.RoomFilter(room), limit)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get movies")
+		return
+	}
+
+	// Персональный порядок по вкусу пользователя; в общей колоде (shared) все видят один порядок,
+	// а если колоду упорядочивает групповая стратегия, личный вкус уже учтён в ней
+	if !hasDeck || (room.DeckMode == models.DeckModePersonal && h.deckService.GroupStrategy(room) == "") {
+		if ranked, err := h.recommendationService.Rank(userID, movies); err == nil {
+			movies = ranked
+		} else {
+			log.Printf("GetRoomMovies: failed to rank movies for user %s: %v", userID, err)
 		}
 	}
 
-	if movies == nil {
+	if hasDeck {
+		if strategy := h.deckService.GroupStrategy(room); strategy != "" {
+			w.Header().Set("X-Deck-Strategy", strategy)
+		}
+	}
+
+	if len(movies) == 0 {
+		// Колода закончилась — сообщаем клиенту явно, а не подменяем её всей библиотекой
+		w.Header().Set("X-Deck-Exhausted", "true")
 		movies = []models.Movie{}
 	}
 	respondWithJSON(w, http.StatusOK, movies)
@@ -186,3 +298,33 @@ func (h *MovieHandler) UpdateMovie(w http.ResponseWriter, r *http.Request) {
 
 	respondWithJSON(w, http.StatusOK, existingMovie)
 }
+
+// GetTasteProfile возвращает профиль вкуса пользователя, по которому упорядочивается колода.
+func (h *MovieHandler) GetTasteProfile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	// Профиль вкусов строится по свайпам и оценкам — его видят только сам пользователь и админ
+	callerID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+	if callerID != userID {
+		if caller := MustGetUser(r); caller == nil || caller.UserType != models.UserTypeAdmin {
+			respondWithError(w, http.StatusForbidden, "Access denied")
+			return
+		}
+	}
+
+	profile, err := h.recommendationService.Profile(userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to build taste profile")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, profile)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kinoswipe/middleware"
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestMovieHandler_TasteProfileIsPrivate(t *testing.T) {
+	ownerID := uuid.New()
+
+	cases := []struct {
+		name    string
+		caller  *models.User
+		xUserID string
+		want    int
+	}{
+		{"anonymous", nil, "", http.StatusUnauthorized},
+		{"other user", &models.User{ID: uuid.New(), UserType: models.UserTypeRegular}, "", http.StatusForbidden},
+		{"other host", &models.User{ID: uuid.New(), UserType: models.UserTypeHost}, "", http.StatusForbidden},
+		// X-User-ID не подтверждает роль, поэтому чужой профиль по нему не отдаётся
+		{"other user by X-User-ID", nil, uuid.New().String(), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/"+ownerID.String()+"/taste-profile", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": ownerID.String()})
+			if tc.xUserID != "" {
+				req.Header.Set("X-User-ID", tc.xUserID)
+			}
+			if tc.caller != nil {
+				req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, tc.caller))
+			}
+			rec := httptest.NewRecorder()
+
+			// Без recommendationService: до построения профиля запрос дойти не должен
+			(&MovieHandler{}).GetTasteProfile(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMovieHandler_RoomMoviesRejectsInvalidLimit(t *testing.T) {
+	roomID := uuid.New()
+	for _, limit := range []string{"abc", "0", "-5"} {
+		req := httptest.NewRequest(http.MethodGet, "/rooms/"+roomID.String()+"/movies?limit="+limit, nil)
+		req = mux.SetURLVars(req, map[string]string{"room_id": roomID.String()})
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: uuid.New()}))
+		rec := httptest.NewRecorder()
+
+		// Без репозиториев: неверный limit отклоняется до загрузки комнаты
+		(&MovieHandler{}).GetRoomMovies(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s: status = %d, want 400", limit, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"kinoswipe/middleware"
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type ratingRepoInterface interface {
+	Upsert(rating *models.MovieRating) error
+	Delete(userID, movieID uuid.UUID) error
+	GetByMatchID(matchID uuid.UUID, page pagination.Page) ([]models.MovieRating, string, error)
+	GetByMovieID(movieID uuid.UUID, page pagination.Page) ([]models.MovieRating, string, error)
+}
+
+type ratingMatchRepoInterface interface {
+	GetByID(id uuid.UUID) (*models.Match, error)
+}
+
+// RatingHandler — оценки и рецензии фильмов после просмотра
+type RatingHandler struct {
+	ratingRepo ratingRepoInterface
+	matchRepo  ratingMatchRepoInterface
+	movieRepo  movieGetterInterface
+	roomAccess roomAccessChecker
+}
+
+func NewRatingHandler(ratingRepo *repository.RatingRepository, matchRepo *repository.MatchRepository, movieRepo *repository.MovieRepository, roomAccess *middleware.RoomAccess) *RatingHandler {
+	return &RatingHandler{ratingRepo: ratingRepo, matchRepo: matchRepo, movieRepo: movieRepo, roomAccess: roomAccess}
+}
+
+// RateMatch — PUT /matches/{match_id}/rating: участник комнаты оценивает фильм матча.
+// Повторный запрос заменяет оценку; в ответе — оценка и обновлённый фильм с оценкой сообщества.
+func (h *RatingHandler) RateMatch(w http.ResponseWriter, r *http.Request) {
+	match, userID, ok := h.requireMatchMember(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RateMovieRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	req.Review = strings.TrimSpace(req.Review)
+	if req.Rating < models.MinMovieRating || req.Rating > models.MaxMovieRating {
+		respondWithError(w, http.StatusBadRequest, "rating must be between 1 and 10")
+		return
+	}
+	if utf8.RuneCountInString(req.Review) > models.MaxReviewLength {
+		respondWithError(w, http.StatusBadRequest, "review is too long")
+		return
+	}
+
+	rating := &models.MovieRating{
+		UserID:  userID,
+		MovieID: match.MovieID,
+		MatchID: &match.ID,
+		Rating:  req.Rating,
+		Review:  req.Review,
+	}
+	if err := h.ratingRepo.Upsert(rating); err != nil {
+		log.Printf("Error saving rating: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save rating")
+		return
+	}
+
+	movie, err := h.movieRepo.GetByID(match.MovieID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get movie")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"rating": rating, "movie": movie})
+}
+
+// GetMatchRatings — GET /matches/{match_id}/ratings: оценки участников комнаты после этого матча
+func (h *RatingHandler) GetMatchRatings(w http.ResponseWriter, r *http.Request) {
+	match, _, ok := h.requireMatchMember(w, r)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	ratings, next, err := h.ratingRepo.GetByMatchID(match.ID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting match ratings: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get ratings")
+		return
+	}
+	respondWithPage(w, ratings, next, page)
+}
+
+// GetMovieRatings — GET /movies/{id}/ratings: оценки и рецензии фильма, постранично
+func (h *RatingHandler) GetMovieRatings(w http.ResponseWriter, r *http.Request) {
+	movieID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	ratings, next, err := h.ratingRepo.GetByMovieID(movieID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get ratings")
+		return
+	}
+
+	respondWithPage(w, ratings, next, page)
+}
+
+// DeleteMyRating — DELETE /movies/{id}/rating: убрать свою оценку фильма
+func (h *RatingHandler) DeleteMyRating(w http.ResponseWriter, r *http.Request) {
+	movieID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.ratingRepo.Delete(userID, movieID); err != nil {
+		if errors.Is(err, repository.ErrRatingNotFound) {
+			respondWithError(w, http.StatusNotFound, "Rating not found")
+			return
+		}
+		log.Printf("Error deleting rating: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete rating")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Rating deleted"})
+}
+
+// requireMatchMember загружает матч из {match_id} и проверяет через RoomAccess, что пользователь —
+// участник его комнаты (хост проходит, даже если его записи в room_members нет).
+func (h *RatingHandler) requireMatchMember(w http.ResponseWriter, r *http.Request) (*models.Match, uuid.UUID, bool) {
+	matchID, err := uuid.Parse(mux.Vars(r)["match_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+		return nil, uuid.Nil, false
+	}
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return nil, uuid.Nil, false
+	}
+
+	match, err := h.matchRepo.GetByID(matchID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Match not found")
+		return nil, uuid.Nil, false
+	}
+	if !requireRoomMember(w, r, h.roomAccess, match.RoomID) {
+		return nil, uuid.Nil, false
+	}
+	return match, userID, true
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kinoswipe/middleware"
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// fakeRatingStore — оценки в памяти, ключ — пользователь и фильм
+type fakeRatingStore struct {
+	ratings map[[2]uuid.UUID]models.MovieRating
+	err     error
+}
+
+func (f *fakeRatingStore) Upsert(rating *models.MovieRating) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.ratings[[2]uuid.UUID{rating.UserID, rating.MovieID}] = *rating
+	return nil
+}
+
+func (f *fakeRatingStore) Delete(userID, movieID uuid.UUID) error {
+	if f.err != nil {
+		return f.err
+	}
+	key := [2]uuid.UUID{userID, movieID}
+	if _, ok := f.ratings[key]; !ok {
+		return repository.ErrRatingNotFound
+	}
+	delete(f.ratings, key)
+	return nil
+}
+
+func (f *fakeRatingStore) GetByMatchID(matchID uuid.UUID, page pagination.Page) ([]models.MovieRating, string, error) {
+	if page.Cursor != nil && page.Cursor.Key == "broken" {
+		return nil, "", pagination.ErrInvalidCursor
+	}
+	var ratings []models.MovieRating
+	for _, rt := range f.ratings {
+		if rt.MatchID != nil && *rt.MatchID == matchID {
+			ratings = append(ratings, rt)
+		}
+	}
+	return ratings, "", nil
+}
+
+func (f *fakeRatingStore) GetByMovieID(movieID uuid.UUID, page pagination.Page) ([]models.MovieRating, string, error) {
+	return nil, "", nil
+}
+
+// fakeRoomAccess повторяет правило RoomAccess.Check: хост проходит всегда, остальные — по списку участников
+type fakeRoomAccess struct {
+	rooms   map[uuid.UUID]*models.Room
+	members map[uuid.UUID][]uuid.UUID
+	err     error
+}
+
+func (f *fakeRoomAccess) Check(roomID, userID uuid.UUID, hostOnly bool) (*models.Room, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	room, ok := f.rooms[roomID]
+	if !ok {
+		return nil, &middleware.RoomAccessError{Status: http.StatusNotFound, Message: "Room not found"}
+	}
+	if room.HostID == userID {
+		return room, nil
+	}
+	for _, id := range f.members[roomID] {
+		if id == userID {
+			return room, nil
+		}
+	}
+	return nil, &middleware.RoomAccessError{Status: http.StatusForbidden, Message: "Not a room member"}
+}
+
+type ratingFixture struct {
+	handler *RatingHandler
+	ratings *fakeRatingStore
+	access  *fakeRoomAccess
+	match   *models.Match
+	hostID  uuid.UUID
+	member  uuid.UUID
+}
+
+func newRatingFixture() *ratingFixture {
+	room := &models.Room{ID: uuid.New(), HostID: uuid.New()}
+	match := &models.Match{ID: uuid.New(), RoomID: room.ID, MovieID: uuid.New()}
+	f := &ratingFixture{
+		ratings: &fakeRatingStore{ratings: map[[2]uuid.UUID]models.MovieRating{}},
+		// Хоста нет в room_members — доступ ему даёт только RoomAccess
+		access: &fakeRoomAccess{rooms: map[uuid.UUID]*models.Room{room.ID: room}, members: map[uuid.UUID][]uuid.UUID{}},
+		match:  match,
+		hostID: room.HostID,
+		member: uuid.New(),
+	}
+	f.access.members[room.ID] = []uuid.UUID{f.member}
+	f.handler = &RatingHandler{
+		ratingRepo: f.ratings,
+		matchRepo:  fakeMatchGetter{match.ID: match},
+		movieRepo:  fakeMovieGetter{match.MovieID: true},
+		roomAccess: f.access,
+	}
+	return f
+}
+
+func (f *ratingFixture) request(method, target string, vars map[string]string, userID uuid.UUID, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = mux.SetURLVars(req, vars)
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: userID}))
+}
+
+func (f *ratingFixture) rate(userID uuid.UUID, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := f.request(http.MethodPut, "/matches/"+f.match.ID.String()+"/rating", map[string]string{"match_id": f.match.ID.String()}, userID, body)
+	f.handler.RateMatch(rec, req)
+	return rec
+}
+
+func TestRatingHandler_RateMatch(t *testing.T) {
+	cases := []struct {
+		name string
+		user func(f *ratingFixture) uuid.UUID
+		body string
+		want int
+	}{
+		{"member", func(f *ratingFixture) uuid.UUID { return f.member }, `{"rating": 8, "review": "  хорошо  "}`, http.StatusOK},
+		{"host without membership row", func(f *ratingFixture) uuid.UUID { return f.hostID }, `{"rating": 10}`, http.StatusOK},
+		{"stranger", func(f *ratingFixture) uuid.UUID { return uuid.New() }, `{"rating": 8}`, http.StatusForbidden},
+		{"rating too low", func(f *ratingFixture) uuid.UUID { return f.member }, `{"rating": 0}`, http.StatusBadRequest},
+		{"rating too high", func(f *ratingFixture) uuid.UUID { return f.member }, `{"rating": 11}`, http.StatusBadRequest},
+		{"review too long", func(f *ratingFixture) uuid.UUID { return f.member }, `{"rating": 5, "review": "` + strings.Repeat("я", models.MaxReviewLength+1) + `"}`, http.StatusBadRequest},
+		{"broken JSON", func(f *ratingFixture) uuid.UUID { return f.member }, `{`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newRatingFixture()
+			userID := tc.user(f)
+
+			rec := f.rate(userID, tc.body)
+
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+			saved, ok := f.ratings.ratings[[2]uuid.UUID{userID, f.match.MovieID}]
+			if ok != (tc.want == http.StatusOK) {
+				t.Fatalf("rating saved = %v, want %v", ok, tc.want == http.StatusOK)
+			}
+			if ok && (saved.MatchID == nil || *saved.MatchID != f.match.ID || strings.TrimSpace(saved.Review) != saved.Review) {
+				t.Errorf("saved rating = %+v, want match %s and trimmed review", saved, f.match.ID)
+			}
+		})
+	}
+}
+
+func TestRatingHandler_MatchAccessErrors(t *testing.T) {
+	f := newRatingFixture()
+
+	rec := httptest.NewRecorder()
+	unknown := uuid.New().String()
+	f.handler.RateMatch(rec, f.request(http.MethodPut, "/matches/"+unknown+"/rating", map[string]string{"match_id": unknown}, f.member, `{"rating": 5}`))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown match: status = %d, want 404", rec.Code)
+	}
+
+	// Сбой проверки доступа — 500, а не отказ
+	f.access.err = errors.New("connection refused")
+	if rec := f.rate(f.member, `{"rating": 5}`); rec.Code != http.StatusInternalServerError {
+		t.Errorf("access error: status = %d, want 500", rec.Code)
+	}
+}
+
+func TestRatingHandler_GetMatchRatings(t *testing.T) {
+	f := newRatingFixture()
+	if rec := f.rate(f.member, `{"rating": 7}`); rec.Code != http.StatusOK {
+		t.Fatalf("rate: status = %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	vars := map[string]string{"match_id": f.match.ID.String()}
+	rec := httptest.NewRecorder()
+	f.handler.GetMatchRatings(rec, f.request(http.MethodGet, "/matches/"+f.match.ID.String()+"/ratings", vars, f.hostID, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rec.Code, rec.Body.String())
+	}
+	var page struct {
+		Items []models.MovieRating `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || len(page.Items) != 1 || page.Items[0].Rating != 7 {
+		t.Errorf("body = %s, want one rating 7", rec.Body.String())
+	}
+
+	broken := pagination.Cursor{Key: "broken", ID: uuid.New()}
+	rec = httptest.NewRecorder()
+	f.handler.GetMatchRatings(rec, f.request(http.MethodGet, "/matches/"+f.match.ID.String()+"/ratings?cursor="+broken.Encode(), vars, f.member, ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("broken cursor: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestRatingHandler_DeleteMyRating(t *testing.T) {
+	f := newRatingFixture()
+	if rec := f.rate(f.member, `{"rating": 7}`); rec.Code != http.StatusOK {
+		t.Fatalf("rate: status = %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	del := func(userID uuid.UUID) int {
+		rec := httptest.NewRecorder()
+		movieID := f.match.MovieID.String()
+		f.handler.DeleteMyRating(rec, f.request(http.MethodDelete, "/movies/"+movieID+"/rating", map[string]string{"id": movieID}, userID, ""))
+		return rec.Code
+	}
+
+	if code := del(f.member); code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want 200", code)
+	}
+	if code := del(f.member); code != http.StatusNotFound {
+		t.Errorf("delete again: status = %d, want 404", code)
+	}
+
+	// Ошибка базы не выдаётся за отсутствующую оценку
+	f.ratings.err = errors.New("connection refused")
+	if code := del(f.member); code != http.StatusInternalServerError {
+		t.Errorf("repository error: status = %d, want 500", code)
+	}
+}
@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
 	"kinoswipe/middleware"
 	"kinoswipe/models"
+	"kinoswipe/pagination"
+	"kinoswipe/repository"
+	"kinoswipe/service"
 
 	"github.com/google/uuid"
 )
@@ -37,11 +42,120 @@ func RequireUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
 	return id, true
 }
 
+// roomAccessChecker — проверка доступа к комнате (middleware.RoomAccess и фейки в тестах).
+type roomAccessChecker interface {
+	Check(roomID, userID uuid.UUID, hostOnly bool) (*models.Room, error)
+}
+
+// requireRoomMember проверяет через RoomAccess, что пользователь запроса — участник комнаты roomID.
+// Для маршрутов без {room_id} в пути, где комната известна только по самой сущности (фильтр, матч).
+func requireRoomMember(w http.ResponseWriter, r *http.Request, access roomAccessChecker, roomID uuid.UUID) bool {
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return false
+	}
+	if _, err := access.Check(roomID, userID, false); err != nil {
+		respondWithRoomAccessError(w, err)
+		return false
+	}
+	return true
+}
+
+// roomGetter — загрузка комнаты по ID (RoomRepository и фейки в тестах).
+type roomGetter interface {
+	GetByID(id uuid.UUID) (*models.Room, error)
+}
+
+// loadRoom берёт комнату, уже загруженную middleware.RoomAccess, или читает её из репозитория.
+// 404 — только если комнаты нет; прочие ошибки репозитория — 500.
+func loadRoom(w http.ResponseWriter, r *http.Request, rooms roomGetter, roomID uuid.UUID) (*models.Room, bool) {
+	if room := middleware.GetRoomFromRequest(r); room != nil && room.ID == roomID {
+		return room, true
+	}
+	room, err := rooms.GetByID(roomID)
+	if errors.Is(err, repository.ErrRoomNotFound) {
+		respondWithError(w, http.StatusNotFound, "Room not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("loadRoom: failed to get room %s: %v", roomID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get room")
+		return nil, false
+	}
+	return room, true
+}
+
+// respondWithRoomAccessError отвечает статусом отказа RoomAccess; ошибка репозитория — 500.
+func respondWithRoomAccessError(w http.ResponseWriter, err error) {
+	var accessErr *middleware.RoomAccessError
+	if errors.As(err, &accessErr) {
+		respondWithError(w, accessErr.Status, accessErr.Message)
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "Failed to check room access")
+}
+
 // MustGetUser возвращает пользователя из контекста или nil.
 func MustGetUser(r *http.Request) *models.User {
 	return middleware.GetUserFromRequest(r)
 }
 
+// parsePage читает параметры пагинации (?limit=, ?cursor=, ?fields=); при ошибке отвечает 400.
+func parsePage(w http.ResponseWriter, r *http.Request, defaultLimit int) (pagination.Page, bool) {
+	page, err := pagination.FromRequest(r, defaultLimit)
+	switch err {
+	case nil:
+		return page, true
+	case pagination.ErrInvalidLimit:
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+	}
+	return page, false
+}
+
+// respondWithPage отдаёт страницу списка в конверте {items, next_cursor} с проекцией ?fields=.
+func respondWithPage(w http.ResponseWriter, items interface{}, next string, page pagination.Page) {
+	envelope, err := pagination.NewEnvelope(items, next, page.Fields)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to build response")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, envelope)
+}
+
+// serviceErrorStatus переводит ошибку сервиса в HTTP-статус и текст для клиента.
+// Неизвестные ошибки — 500 с fallback, подробности остаются в логе.
+func serviceErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrRoomNotFound):
+		return http.StatusNotFound, "Room not found"
+	case errors.Is(err, service.ErrRoomFinished):
+		return http.StatusConflict, "Room is finished"
+	case errors.Is(err, service.ErrRoomNotWaiting):
+		return http.StatusBadRequest, "Room is already started or finished"
+	case errors.Is(err, service.ErrNotRoomHost):
+		return http.StatusForbidden, "Only room host can do this"
+	case errors.Is(err, service.ErrInvalidDirection):
+		return http.StatusBadRequest, "Invalid swipe direction"
+	case errors.Is(err, service.ErrAlreadySwiped):
+		return http.StatusConflict, "Already swiped this movie"
+	case errors.Is(err, service.ErrInvalidUndoWindow):
+		return http.StatusBadRequest, "within_seconds must be positive"
+	case errors.Is(err, service.ErrNothingToUndo):
+		return http.StatusNotFound, "No swipe found to undo"
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+func respondWithServiceError(w http.ResponseWriter, err error, fallback string) {
+	code, message := serviceErrorStatus(err, fallback)
+	if code == http.StatusInternalServerError {
+		log.Printf("%s: %v", fallback, err)
+	}
+	respondWithError(w, code, message)
+}
+
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
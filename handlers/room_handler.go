@@ -3,26 +3,63 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"math/rand"
 	"net/http"
 	"time"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 	"kinoswipe/repository"
+	"kinoswipe/service"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
 )
 
+type roomHandlerRepoInterface interface {
+	Create(room *models.Room) error
+	GetByID(id uuid.UUID) (*models.Room, error)
+	GetByCode(code string) (*models.Room, error)
+	GetAll(status *models.RoomStatus, page pagination.Page) ([]models.Room, string, error)
+	AddMember(roomID, userID uuid.UUID) error
+	GetMembers(roomID uuid.UUID) ([]models.User, error)
+	CountMembers(roomID uuid.UUID) (int, error)
+	IsMember(roomID, userID uuid.UUID) (bool, error)
+	RemoveMember(roomID, userID uuid.UUID) error
+	BanMember(roomID, userID, bannedBy uuid.UUID) error
+	UnbanMember(roomID, userID uuid.UUID) error
+	IsBanned(roomID, userID uuid.UUID) (bool, error)
+	SetHost(roomID, hostID uuid.UUID) error
+	UpdateSettings(roomID uuid.UUID, settings models.RoomSettings) error
+	CreateJoinRequest(roomID, userID uuid.UUID) (*models.RoomJoinRequest, error)
+	GetJoinRequest(roomID, userID uuid.UUID) (*models.RoomJoinRequest, error)
+	GetPendingJoinRequests(roomID uuid.UUID) ([]models.RoomJoinRequest, error)
+	DecideJoinRequest(roomID, userID uuid.UUID, status models.JoinRequestStatus) error
+}
+
 type RoomHandler struct {
-	roomRepo   *repository.RoomRepository
-	filterRepo *repository.FilterRepository
+	roomRepo         roomHandlerRepoInterface
+	filterRepo       *repository.FilterRepository
+	lifecycleService *service.RoomLifecycleService
+	swipeService     *service.SwipeService
+	hub              *Hub
 }
 
-func NewRoomHandler(roomRepo *repository.RoomRepository, filterRepo *repository.FilterRepository) *RoomHandler {
+func NewRoomHandler(
+	roomRepo *repository.RoomRepository,
+	filterRepo *repository.FilterRepository,
+	lifecycleService *service.RoomLifecycleService,
+	swipeService *service.SwipeService,
+	hub *Hub,
+) *RoomHandler {
 	return &RoomHandler{
-		roomRepo:   roomRepo,
-		filterRepo: filterRepo,
+		roomRepo:         roomRepo,
+		filterRepo:       filterRepo,
+		lifecycleService: lifecycleService,
+		swipeService:     swipeService,
+		hub:              hub,
 	}
 }
 
@@ -38,12 +75,35 @@ func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	switch req.DeckMode {
+	case "":
+		req.DeckMode = models.DeckModeShared
+	case models.DeckModeShared, models.DeckModePersonal:
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid deck mode")
+		return
+	}
+	if err := service.ValidateMatchPolicy(req.MatchPolicy, req.MatchThreshold); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	room := &models.Room{
-		ID:       uuid.New(),
-		Code:     generateRoomCode(),
-		HostID:   hostID,
-		Status:   models.RoomStatusWaiting,
-		FilterID: req.FilterID,
+		ID:             uuid.New(),
+		Code:           generateRoomCode(),
+		HostID:         hostID,
+		Status:         models.RoomStatusWaiting,
+		FilterID:       req.FilterID,
+		DeckMode:       req.DeckMode,
+		MatchPolicy:    req.MatchPolicy,
+		MatchThreshold: req.MatchThreshold,
+		FinishOnMatch:  req.FinishOnMatch,
+	}
+	if req.Settings != nil {
+		if err := applyRoomSettings(&room.Settings, req.Settings); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 	}
 
 	if err := h.roomRepo.Create(room); err != nil {
@@ -90,6 +150,57 @@ func (h *RoomHandler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusNotFound, "Room not found")
 		return
 	}
+	if room.Status == models.RoomStatusFinished {
+		respondWithError(w, http.StatusConflict, "Room is finished")
+		return
+	}
+
+	// Тело необязательно: пароль нужен только комнатам с паролем
+	var req models.JoinRoomRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	banned, err := h.roomRepo.IsBanned(room.ID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to join room")
+		return
+	}
+	if banned {
+		respondWithError(w, http.StatusForbidden, "You are banned from this room")
+		return
+	}
+
+	// Участники и хост входят повторно без проверок настроек
+	isMember, err := h.roomRepo.IsMember(room.ID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to join room")
+		return
+	}
+	if !isMember && room.HostID != userID {
+		if room.Settings.LockAfterStart && room.Status == models.RoomStatusActive {
+			respondWithError(w, http.StatusForbidden, "Room is locked")
+			return
+		}
+		if room.Settings.HasPassword &&
+			bcrypt.CompareHashAndPassword([]byte(room.Settings.PasswordHash), []byte(req.Password)) != nil {
+			respondWithError(w, http.StatusForbidden, "Invalid room password")
+			return
+		}
+		if full, err := h.isRoomFull(room); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to join room")
+			return
+		} else if full {
+			respondWithError(w, http.StatusConflict, "Room is full")
+			return
+		}
+		if room.Settings.RequireApproval {
+			if _, err := h.roomRepo.CreateJoinRequest(room.ID, userID); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to create join request")
+				return
+			}
+			respondWithJSON(w, http.StatusAccepted, models.JoinRoomResponse{Room: *room, Members: []models.User{}, Pending: true})
+			return
+		}
+	}
 
 	// Добавляем пользователя в комнату
 	if err := h.roomRepo.AddMember(room.ID, userID); err != nil {
@@ -105,9 +216,9 @@ func (h *RoomHandler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 
 func (h *RoomHandler) GetAllRooms(w http.ResponseWriter, r *http.Request) {
 	statusStr := r.URL.Query().Get("status")
-	limit := 50 // По умолчанию
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		fmt.Sscanf(limitStr, "%d", &limit)
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
 	}
 
 	var status *models.RoomStatus
@@ -116,15 +227,20 @@ func (h *RoomHandler) GetAllRooms(w http.ResponseWriter, r *http.Request) {
 		status = &roomStatus
 	}
 
-	rooms, err := h.roomRepo.GetAll(status, limit)
+	rooms, next, err := h.roomRepo.GetAll(status, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get rooms")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, rooms)
+	respondWithPage(w, rooms, next, page)
 }
 
+// StartRoom запускает комнату (только хост). Та же логика доступна WebSocket-командой start_room.
 func (h *RoomHandler) StartRoom(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomID, err := uuid.Parse(vars["room_id"])
@@ -138,33 +254,58 @@ func (h *RoomHandler) StartRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Получаем комнату
-	room, err := h.roomRepo.GetByID(roomID)
+	room, err := h.lifecycleService.Start(roomID, userID)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Room not found")
+		respondWithServiceError(w, err, "Failed to start room")
 		return
 	}
 
-	// Проверяем, что пользователь является хостом комнаты
-	if room.HostID != userID {
-		respondWithError(w, http.StatusForbidden, "Only room host can start the room")
+	respondWithJSON(w, http.StatusOK, room)
+}
+
+// FinishRoom завершает сеанс по запросу хоста и возвращает итог. Права хоста проверяет RoomAccess.RequireHost.
+func (h *RoomHandler) FinishRoom(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, err := uuid.Parse(vars["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
 		return
 	}
 
-	// Проверяем, что комната в статусе waiting
-	if room.Status != models.RoomStatusWaiting {
-		respondWithError(w, http.StatusBadRequest, "Room is already started or finished")
+	room, ok := loadRoom(w, r, h.roomRepo, roomID)
+	if !ok {
+		return
+	}
+	if room.Status == models.RoomStatusFinished {
+		respondWithError(w, http.StatusConflict, "Room is already finished")
 		return
 	}
 
-	// Меняем статус на active
-	room.Status = models.RoomStatusActive
-	if err := h.roomRepo.UpdateStatus(roomID, models.RoomStatusActive); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to start room")
+	summary, err := h.lifecycleService.Finish(roomID, models.RoomFinishReasonHost)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to finish room")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, room)
+	respondWithJSON(w, http.StatusOK, summary)
+}
+
+// GetRoomSummary возвращает итог сеанса (для завершённой комнаты — финальный).
+func (h *RoomHandler) GetRoomSummary(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomID, err := uuid.Parse(vars["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	summary, err := h.lifecycleService.Summary(roomID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Room not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, summary)
 }
 
 func (h *RoomHandler) GetRoomMembers(w http.ResponseWriter, r *http.Request) {
@@ -193,6 +334,329 @@ func (h *RoomHandler) GetRoomMembers(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, members)
 }
 
+// UpdateRoomSettings меняет настройки доступа комнаты (только хост).
+func (h *RoomHandler) UpdateRoomSettings(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.requireHostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRoomSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if err := applyRoomSettings(&room.Settings, &req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.roomRepo.UpdateSettings(room.ID, room.Settings); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update room settings")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, room)
+}
+
+// GetJoinRequests возвращает заявки на вход, ожидающие решения хоста.
+func (h *RoomHandler) GetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.requireHostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	requests, err := h.roomRepo.GetPendingJoinRequests(room.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get join requests")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, requests)
+}
+
+// ApproveJoinRequest одобряет заявку: пользователь становится участником комнаты.
+func (h *RoomHandler) ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.decideJoinRequest(w, r, models.JoinRequestStatusApproved)
+}
+
+// RejectJoinRequest отклоняет заявку на вход.
+func (h *RoomHandler) RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.decideJoinRequest(w, r, models.JoinRequestStatusRejected)
+}
+
+func (h *RoomHandler) decideJoinRequest(w http.ResponseWriter, r *http.Request, status models.JoinRequestStatus) {
+	room, ok := h.requireHostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if status == models.JoinRequestStatusApproved {
+		if full, err := h.isRoomFull(room); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to approve join request")
+			return
+		} else if full {
+			respondWithError(w, http.StatusConflict, "Room is full")
+			return
+		}
+	}
+
+	if err := h.roomRepo.DecideJoinRequest(room.ID, userID, status); err != nil {
+		respondWithError(w, http.StatusNotFound, "Join request not found")
+		return
+	}
+
+	joinReq, err := h.roomRepo.GetJoinRequest(room.ID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get join request")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, joinReq)
+}
+
+// LeaveRoom — пользователь выходит из комнаты. Хост должен сначала передать роль,
+// если в комнате остались другие участники; последний вышедший хост завершает комнату.
+func (h *RoomHandler) LeaveRoom(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	room, ok := loadRoom(w, r, h.roomRepo, roomID)
+	if !ok {
+		return
+	}
+
+	if room.HostID == userID {
+		count, err := h.roomRepo.CountMembers(roomID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to leave room")
+			return
+		}
+		if count > 1 {
+			respondWithError(w, http.StatusConflict, "Transfer host role before leaving the room")
+			return
+		}
+	}
+
+	if err := h.roomRepo.RemoveMember(roomID, userID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Not a room member")
+		return
+	}
+	h.afterMemberRemoved(room, userID)
+
+	if room.HostID == userID && room.Status != models.RoomStatusFinished {
+		if _, err := h.lifecycleService.Finish(roomID, models.RoomFinishReasonHost); err != nil {
+			log.Printf("LeaveRoom: failed to finish room %s: %v", roomID, err)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Left room successfully"})
+}
+
+// KickMember исключает участника из комнаты (только хост). Повторно войти по коду он сможет.
+func (h *RoomHandler) KickMember(w http.ResponseWriter, r *http.Request) {
+	room, targetID, ok := h.requireModerationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.roomRepo.RemoveMember(room.ID, targetID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Not a room member")
+		return
+	}
+	h.afterMemberRemoved(room, targetID)
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Member kicked"})
+}
+
+// BanMember исключает пользователя и запрещает ему входить в комнату (только хост).
+func (h *RoomHandler) BanMember(w http.ResponseWriter, r *http.Request) {
+	room, targetID, ok := h.requireModerationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.roomRepo.BanMember(room.ID, targetID, room.HostID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to ban member")
+		return
+	}
+	h.afterMemberRemoved(room, targetID)
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Member banned"})
+}
+
+// UnbanMember снимает бан (только хост).
+func (h *RoomHandler) UnbanMember(w http.ResponseWriter, r *http.Request) {
+	room, targetID, ok := h.requireModerationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.roomRepo.UnbanMember(room.ID, targetID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Ban not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Member unbanned"})
+}
+
+// TransferHost передаёт роль хоста другому участнику (только текущий хост).
+func (h *RoomHandler) TransferHost(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.requireHostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.TransferHostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == uuid.Nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.UserID == room.HostID {
+		respondWithError(w, http.StatusBadRequest, "User is already the host")
+		return
+	}
+
+	isMember, err := h.roomRepo.IsMember(room.ID, req.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to transfer host")
+		return
+	}
+	if !isMember {
+		respondWithError(w, http.StatusNotFound, "Not a room member")
+		return
+	}
+
+	if err := h.roomRepo.SetHost(room.ID, req.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to transfer host")
+		return
+	}
+	room.HostID = req.UserID
+	if h.hub != nil {
+		h.hub.BroadcastHostChanged(room.ID, req.UserID)
+	}
+
+	respondWithJSON(w, http.StatusOK, room)
+}
+
+// requireModerationTarget проверяет права хоста и разбирает user_id из пути; хост не может применить действие к себе.
+func (h *RoomHandler) requireModerationTarget(w http.ResponseWriter, r *http.Request) (*models.Room, uuid.UUID, bool) {
+	room, ok := h.requireHostRoom(w, r)
+	if !ok {
+		return nil, uuid.Nil, false
+	}
+
+	targetID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return nil, uuid.Nil, false
+	}
+	if targetID == room.HostID {
+		respondWithError(w, http.StatusBadRequest, "Host cannot moderate themselves")
+		return nil, uuid.Nil, false
+	}
+
+	return room, targetID, true
+}
+
+// afterMemberRemoved отключает пользователя от WebSocket комнаты и пересчитывает матчи без его свайпов.
+func (h *RoomHandler) afterMemberRemoved(room *models.Room, userID uuid.UUID) {
+	if h.hub != nil {
+		h.hub.DisconnectUser(room.ID, userID)
+	}
+	h.swipeService.ReevaluateRoom(room)
+}
+
+// requireHostRoom загружает комнату из пути и проверяет, что запрос сделал её хост.
+func (h *RoomHandler) requireHostRoom(w http.ResponseWriter, r *http.Request) (*models.Room, bool) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return nil, false
+	}
+
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	// Комнату могла уже загрузить middleware.RoomAccess
+	room, ok := loadRoom(w, r, h.roomRepo, roomID)
+	if !ok {
+		return nil, false
+	}
+	if room.HostID != userID {
+		respondWithError(w, http.StatusForbidden, "Only room host can manage the room")
+		return nil, false
+	}
+
+	return room, true
+}
+
+// isRoomFull — достигнут ли лимит участников (max_members).
+func (h *RoomHandler) isRoomFull(room *models.Room) (bool, error) {
+	if room.Settings.MaxMembers == nil {
+		return false, nil
+	}
+	count, err := h.roomRepo.CountMembers(room.ID)
+	if err != nil {
+		return false, err
+	}
+	return count >= *room.Settings.MaxMembers, nil
+}
+
+// applyRoomSettings применяет изменения настроек; пароль сохраняется только в виде bcrypt-хеша.
+func applyRoomSettings(settings *models.RoomSettings, req *models.UpdateRoomSettingsRequest) error {
+	if req.MaxMembers != nil {
+		switch {
+		case *req.MaxMembers == 0:
+			settings.MaxMembers = nil
+		case *req.MaxMembers < 2:
+			return fmt.Errorf("max_members must be at least 2")
+		default:
+			limit := *req.MaxMembers
+			settings.MaxMembers = &limit
+		}
+	}
+	if req.IsPrivate != nil {
+		settings.IsPrivate = *req.IsPrivate
+	}
+	if req.Password != nil {
+		if *req.Password == "" {
+			settings.PasswordHash = ""
+			settings.HasPassword = false
+		} else {
+			hash, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return fmt.Errorf("failed to hash room password")
+			}
+			settings.PasswordHash = string(hash)
+			settings.HasPassword = true
+		}
+	}
+	if req.RequireApproval != nil {
+		settings.RequireApproval = *req.RequireApproval
+	}
+	if req.LockAfterStart != nil {
+		settings.LockAfterStart = *req.LockAfterStart
+	}
+	return nil
+}
+
 // Генерация читаемого 6-символьного кода комнаты
 func generateRoomCode() string {
 	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kinoswipe/middleware"
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeRoomStore — комнаты в памяти для RoomHandler; методы, не нужные тесту, не реализованы
+type fakeRoomStore struct {
+	roomHandlerRepoInterface
+	rooms        map[uuid.UUID]*models.Room
+	members      map[uuid.UUID][]models.User
+	banned       map[uuid.UUID]bool
+	joinRequests []uuid.UUID
+}
+
+func newFakeRoomStore(rooms ...*models.Room) *fakeRoomStore {
+	f := &fakeRoomStore{rooms: map[uuid.UUID]*models.Room{}, members: map[uuid.UUID][]models.User{}, banned: map[uuid.UUID]bool{}}
+	for _, room := range rooms {
+		f.rooms[room.ID] = room
+	}
+	return f
+}
+
+func (f *fakeRoomStore) GetByID(id uuid.UUID) (*models.Room, error) {
+	room, ok := f.rooms[id]
+	if !ok {
+		return nil, repository.ErrRoomNotFound
+	}
+	copied := *room
+	return &copied, nil
+}
+
+func (f *fakeRoomStore) GetByCode(code string) (*models.Room, error) {
+	for _, room := range f.rooms {
+		if room.Code == code {
+			copied := *room
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrRoomNotFound
+}
+
+func (f *fakeRoomStore) AddMember(roomID, userID uuid.UUID) error {
+	f.members[roomID] = append(f.members[roomID], models.User{ID: userID})
+	return nil
+}
+
+func (f *fakeRoomStore) GetMembers(roomID uuid.UUID) ([]models.User, error) {
+	return f.members[roomID], nil
+}
+
+func (f *fakeRoomStore) CountMembers(roomID uuid.UUID) (int, error) {
+	return len(f.members[roomID]), nil
+}
+
+func (f *fakeRoomStore) IsMember(roomID, userID uuid.UUID) (bool, error) {
+	for _, m := range f.members[roomID] {
+		if m.ID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeRoomStore) IsBanned(roomID, userID uuid.UUID) (bool, error) {
+	return f.banned[userID], nil
+}
+
+func (f *fakeRoomStore) CreateJoinRequest(roomID, userID uuid.UUID) (*models.RoomJoinRequest, error) {
+	f.joinRequests = append(f.joinRequests, userID)
+	return &models.RoomJoinRequest{RoomID: roomID, UserID: userID}, nil
+}
+
+func TestRoomHandler_JoinRoom(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	two := 2
+
+	cases := []struct {
+		name     string
+		room     models.Room
+		banned   bool
+		members  int
+		password string
+		want     int
+		joined   bool
+		pending  bool
+	}{
+		{name: "open room", room: models.Room{Status: models.RoomStatusWaiting}, want: http.StatusOK, joined: true},
+		{name: "banned", room: models.Room{Status: models.RoomStatusWaiting}, banned: true, want: http.StatusForbidden},
+		{name: "finished", room: models.Room{Status: models.RoomStatusFinished}, want: http.StatusConflict},
+		{name: "locked after start", room: models.Room{Status: models.RoomStatusActive, Settings: models.RoomSettings{LockAfterStart: true}}, want: http.StatusForbidden},
+		{name: "started without lock", room: models.Room{Status: models.RoomStatusActive}, want: http.StatusOK, joined: true},
+		{name: "wrong password", room: models.Room{Status: models.RoomStatusWaiting, Settings: models.RoomSettings{HasPassword: true, PasswordHash: string(hash)}}, password: "guess", want: http.StatusForbidden},
+		{name: "right password", room: models.Room{Status: models.RoomStatusWaiting, Settings: models.RoomSettings{HasPassword: true, PasswordHash: string(hash)}}, password: "secret", want: http.StatusOK, joined: true},
+		{name: "full", room: models.Room{Status: models.RoomStatusWaiting, Settings: models.RoomSettings{MaxMembers: &two}}, members: 2, want: http.StatusConflict},
+		{name: "requires approval", room: models.Room{Status: models.RoomStatusWaiting, Settings: models.RoomSettings{RequireApproval: true}}, want: http.StatusAccepted, pending: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			room := tc.room
+			room.ID = uuid.New()
+			room.Code = "ABC123"
+			room.HostID = uuid.New()
+			store := newFakeRoomStore(&room)
+			for i := 0; i < tc.members; i++ {
+				store.AddMember(room.ID, uuid.New())
+			}
+			userID := uuid.New()
+			store.banned[userID] = tc.banned
+
+			body, _ := json.Marshal(models.JoinRoomRequest{Password: tc.password})
+			req := httptest.NewRequest(http.MethodPost, "/rooms/"+room.Code+"/join", strings.NewReader(string(body)))
+			req = mux.SetURLVars(req, map[string]string{"code": room.Code})
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: userID}))
+			rec := httptest.NewRecorder()
+
+			(&RoomHandler{roomRepo: store}).JoinRoom(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+			if joined, _ := store.IsMember(room.ID, userID); joined != tc.joined {
+				t.Errorf("member = %v, want %v", joined, tc.joined)
+			}
+			if pending := len(store.joinRequests) > 0; pending != tc.pending {
+				t.Errorf("join request created = %v, want %v", pending, tc.pending)
+			}
+			if tc.pending {
+				var resp models.JoinRoomResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !resp.Pending {
+					t.Errorf("response = %s, want pending", rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRoomHandler_JoinRoomMemberSkipsRestrictions(t *testing.T) {
+	one := 1
+	room := &models.Room{
+		ID: uuid.New(), Code: "ABC123", HostID: uuid.New(), Status: models.RoomStatusActive,
+		Settings: models.RoomSettings{LockAfterStart: true, RequireApproval: true, MaxMembers: &one},
+	}
+	store := newFakeRoomStore(room)
+	userID := uuid.New()
+	store.AddMember(room.ID, userID)
+
+	req := httptest.NewRequest(http.MethodPost, "/rooms/"+room.Code+"/join", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": room.Code})
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: userID}))
+	rec := httptest.NewRecorder()
+
+	(&RoomHandler{roomRepo: store}).JoinRoom(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rec.Code, rec.Body.String())
+	}
+}
+
+// roomGetterFunc — загрузка комнаты, которая возвращает заданную ошибку
+type roomGetterFunc func(id uuid.UUID) (*models.Room, error)
+
+func (f roomGetterFunc) GetByID(id uuid.UUID) (*models.Room, error) { return f(id) }
+
+func TestLoadRoom(t *testing.T) {
+	roomID := uuid.New()
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"found", nil, http.StatusOK},
+		{"not found", repository.ErrRoomNotFound, http.StatusNotFound},
+		// Сбой базы не выдаётся за отсутствующую комнату
+		{"repository error", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rooms := roomGetterFunc(func(id uuid.UUID) (*models.Room, error) {
+				if tc.err != nil {
+					return nil, tc.err
+				}
+				return &models.Room{ID: id}, nil
+			})
+			rec := httptest.NewRecorder()
+			room, ok := loadRoom(rec, httptest.NewRequest(http.MethodGet, "/", nil), rooms, roomID)
+			if ok != (tc.want == http.StatusOK) || (ok && room.ID != roomID) {
+				t.Fatalf("room = %v, ok = %v", room, ok)
+			}
+			if !ok && rec.Code != tc.want {
+				t.Errorf("status = %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
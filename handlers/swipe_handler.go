@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 	"kinoswipe/repository"
 	"kinoswipe/service"
 
@@ -14,18 +17,18 @@ import (
 
 type SwipeHandler struct {
 	swipeRepo    *repository.SwipeRepository
-	matchService *service.MatchService
-	hub          *Hub
+	swipeService *service.SwipeService
 }
 
-func NewSwipeHandler(swipeRepo *repository.SwipeRepository, matchService *service.MatchService, hub *Hub) *SwipeHandler {
+func NewSwipeHandler(swipeRepo *repository.SwipeRepository, swipeService *service.SwipeService) *SwipeHandler {
 	return &SwipeHandler{
 		swipeRepo:    swipeRepo,
-		matchService: matchService,
-		hub:          hub,
+		swipeService: swipeService,
 	}
 }
 
+// CreateSwipe — POST /rooms/{room_id}/swipes. Если свайп довёл фильм до матча, в ответе {swipe, match}, иначе сам свайп.
+// Та же логика доступна WebSocket-командой swipe.
 func (h *SwipeHandler) CreateSwipe(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomID, err := uuid.Parse(vars["room_id"])
@@ -45,54 +48,19 @@ func (h *SwipeHandler) CreateSwipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем, не свайпал ли уже пользователь этот фильм
-	hasSwiped, err := h.swipeRepo.HasUserSwiped(userID, roomID, req.MovieID)
+	result, err := h.swipeService.Swipe(roomID, userID, req)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to check swipe")
+		respondWithServiceError(w, err, "Failed to create swipe")
 		return
 	}
-
-	if hasSwiped {
-		respondWithError(w, http.StatusConflict, "Already swiped this movie")
-		return
-	}
-
-	swipe := &models.Swipe{
-		ID:        uuid.New(),
-		UserID:    userID,
-		RoomID:    roomID,
-		MovieID:   req.MovieID,
-		Direction: req.Direction,
-	}
-
-	if err := h.swipeRepo.Create(swipe); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create swipe")
+	if result.Match != nil {
+		respondWithJSON(w, http.StatusOK, result)
 		return
 	}
-
-	// Если это лайк, проверяем возможность создания матча
-	if req.Direction == models.SwipeDirectionRight {
-		match, err := h.matchService.CheckAndCreateMatch(roomID, req.MovieID)
-		if err == nil && match != nil {
-			matchDetails, detailsErr := h.matchService.GetMatchWithDetails(match.ID)
-			if detailsErr == nil && matchDetails != nil {
-				// Уведомляем всех участников комнаты через WebSocket
-				if h.hub != nil {
-					h.hub.BroadcastMatch(roomID, matchDetails)
-				}
-				// Возвращаем информацию о матче пользователю, сделавшему свайп
-				respondWithJSON(w, http.StatusOK, map[string]interface{}{
-					"swipe": swipe,
-					"match": matchDetails,
-				})
-				return
-			}
-		}
-	}
-
-	respondWithJSON(w, http.StatusOK, swipe)
+	respondWithJSON(w, http.StatusOK, result.Swipe)
 }
 
+// UndoSwipe — POST /rooms/{room_id}/swipes/undo (или WebSocket-команда undo)
 func (h *SwipeHandler) UndoSwipe(w http.ResponseWriter, r *http.Request) {
 	// Получаем userID
 	userID, ok := RequireUserID(w, r)
@@ -100,26 +68,29 @@ func (h *SwipeHandler) UndoSwipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Пустое тело — отмена последнего свайпа
 	var req models.UndoSwipeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
-	// Получаем последний свайп пользователя в этой комнате
-	lastSwipe, err := h.swipeRepo.GetLastSwipe(userID, req.RoomID)
-	if err != nil {
-		respondWithError(w, http.StatusNotFound, "No swipe found to undo")
+	// room_id из пути приоритетнее тела запроса
+	roomID := req.RoomID
+	if pathRoomID, err := uuid.Parse(mux.Vars(r)["room_id"]); err == nil {
+		roomID = pathRoomID
+	}
+	if roomID == uuid.Nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
 		return
 	}
 
-	// Удаляем свайп
-	if err := h.swipeRepo.Delete(lastSwipe.ID); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to undo swipe")
+	resp, err := h.swipeService.Undo(roomID, userID, req)
+	if err != nil {
+		respondWithServiceError(w, err, "Failed to undo swipe")
 		return
 	}
-
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Swipe undone successfully"})
+	respondWithJSON(w, http.StatusOK, resp)
 }
 
 func (h *SwipeHandler) GetUserSwipes(w http.ResponseWriter, r *http.Request) {
@@ -135,12 +106,21 @@ func (h *SwipeHandler) GetUserSwipes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	swipes, err := h.swipeRepo.GetUserSwipes(userID, roomID)
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	swipes, next, err := h.swipeRepo.ListUserSwipes(userID, roomID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get swipes")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, swipes)
+	respondWithPage(w, swipes, next, page)
 }
 
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kinoswipe/middleware"
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+func TestSwipeHandler_UndoSwipeBody(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		wantMsg string
+	}{
+		// Пустое тело разбирается как «отменить последний» и доходит до проверки комнаты
+		{"empty body", "", "Invalid room ID"},
+		{"broken JSON", "{", "Invalid request payload"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/swipes/undo", strings.NewReader(tc.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: uuid.New()}))
+			rec := httptest.NewRecorder()
+
+			// Без swipeService: до отмены запрос без комнаты дойти не должен
+			(&SwipeHandler{}).UndoSwipe(rec, req)
+
+			var resp map[string]string
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if rec.Code != http.StatusBadRequest || resp["error"] != tc.wantMsg {
+				t.Errorf("status = %d, body = %s; want 400 %q", rec.Code, rec.Body.String(), tc.wantMsg)
+			}
+		})
+	}
+}
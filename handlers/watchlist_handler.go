@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+	"kinoswipe/repository"
+	"kinoswipe/service"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type watchlistRepoInterface interface {
+	Add(item *models.WatchlistItem) error
+	GetByID(id uuid.UUID) (*models.WatchlistItem, error)
+	GetByUserID(userID uuid.UUID, page pagination.Page) ([]models.WatchlistItem, string, error)
+	GetByRoomID(roomID uuid.UUID, page pagination.Page) ([]models.WatchlistItem, string, error)
+	Delete(id uuid.UUID) error
+}
+
+type watchHistoryRepoInterface interface {
+	Create(event *models.WatchEvent, attendeeIDs []uuid.UUID) error
+	GetByUserID(userID uuid.UUID, page pagination.Page) ([]models.WatchEvent, string, error)
+	GetByRoomID(roomID uuid.UUID, page pagination.Page) ([]models.WatchEvent, string, error)
+}
+
+type watchlistMatchRepoInterface interface {
+	GetByID(id uuid.UUID) (*models.Match, error)
+}
+
+type watchlistRoomRepoInterface interface {
+	IsMember(roomID, userID uuid.UUID) (bool, error)
+}
+
+type almostMatcherInterface interface {
+	GetAlmostMatches(roomID uuid.UUID) ([]models.AlmostMatch, error)
+}
+
+// WatchlistHandler — списки «посмотреть позже» (личный и комнаты) и журнал просмотров
+type WatchlistHandler struct {
+	watchlistRepo watchlistRepoInterface
+	historyRepo   watchHistoryRepoInterface
+	movieRepo     movieGetterInterface
+	matchRepo     watchlistMatchRepoInterface
+	roomRepo      watchlistRoomRepoInterface
+	matchService  almostMatcherInterface
+}
+
+func NewWatchlistHandler(
+	watchlistRepo *repository.WatchlistRepository,
+	historyRepo *repository.WatchHistoryRepository,
+	movieRepo *repository.MovieRepository,
+	matchRepo *repository.MatchRepository,
+	roomRepo *repository.RoomRepository,
+	matchService *service.MatchService,
+) *WatchlistHandler {
+	return &WatchlistHandler{
+		watchlistRepo: watchlistRepo,
+		historyRepo:   historyRepo,
+		movieRepo:     movieRepo,
+		matchRepo:     matchRepo,
+		roomRepo:      roomRepo,
+		matchService:  matchService,
+	}
+}
+
+// GetMyWatchlist — GET /watchlist
+func (h *WatchlistHandler) GetMyWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	items, next, err := h.watchlistRepo.GetByUserID(userID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting watchlist: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get watchlist")
+		return
+	}
+	respondWithPage(w, items, next, page)
+}
+
+// AddToMyWatchlist — POST /watchlist. Фильм можно взять из матча любой комнаты, где пользователь участник.
+func (h *WatchlistHandler) AddToMyWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AddToWatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	movieID, match, ok := h.resolveMovie(w, req.MovieID, req.MatchID, userID, nil)
+	if !ok {
+		return
+	}
+	h.add(w, &models.WatchlistItem{MovieID: movieID, UserID: &userID, Note: req.Note}, match, userID)
+}
+
+// RemoveFromMyWatchlist — DELETE /watchlist/{id}
+func (h *WatchlistHandler) RemoveFromMyWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+	h.remove(w, r, func(item *models.WatchlistItem) bool {
+		return item.UserID != nil && *item.UserID == userID
+	})
+}
+
+// GetRoomWatchlist — GET /rooms/{room_id}/watchlist
+func (h *WatchlistHandler) GetRoomWatchlist(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	items, next, err := h.watchlistRepo.GetByRoomID(roomID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting room watchlist: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get watchlist")
+		return
+	}
+	respondWithPage(w, items, next, page)
+}
+
+// AddToRoomWatchlist — POST /rooms/{room_id}/watchlist. Матч должен быть из этой комнаты.
+func (h *WatchlistHandler) AddToRoomWatchlist(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AddToWatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	movieID, match, ok := h.resolveMovie(w, req.MovieID, req.MatchID, userID, &roomID)
+	if !ok {
+		return
+	}
+	h.add(w, &models.WatchlistItem{MovieID: movieID, RoomID: &roomID, Note: req.Note}, match, userID)
+}
+
+// AddAlmostMatchesToRoomWatchlist — POST /rooms/{room_id}/watchlist/almost-matches.
+// Сохраняет все текущие «почти матчи» комнаты; уже сохранённые пропускаются.
+func (h *WatchlistHandler) AddAlmostMatchesToRoomWatchlist(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	almost, err := h.matchService.GetAlmostMatches(roomID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get almost matches")
+		return
+	}
+
+	added := []models.WatchlistItem{}
+	for _, a := range almost {
+		item := &models.WatchlistItem{
+			MovieID: a.MovieID,
+			RoomID:  &roomID,
+			Source:  models.WatchlistSourceAlmostMatch,
+			AddedBy: &userID,
+			Movie:   a.Movie,
+		}
+		if err := h.watchlistRepo.Add(item); err != nil {
+			if errors.Is(err, repository.ErrAlreadyInWatchlist) {
+				continue
+			}
+			log.Printf("Error adding almost match to watchlist: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to add to watchlist")
+			return
+		}
+		added = append(added, *item)
+	}
+	respondWithJSON(w, http.StatusCreated, added)
+}
+
+// RemoveFromRoomWatchlist — DELETE /rooms/{room_id}/watchlist/{id}
+func (h *WatchlistHandler) RemoveFromRoomWatchlist(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	h.remove(w, r, func(item *models.WatchlistItem) bool {
+		return item.RoomID != nil && *item.RoomID == roomID
+	})
+}
+
+// GetMyWatchHistory — GET /watched: всё, что пользователь смотрел, в том числе с комнатами
+func (h *WatchlistHandler) GetMyWatchHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	events, next, err := h.historyRepo.GetByUserID(userID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting watch history: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get watch history")
+		return
+	}
+	respondWithPage(w, events, next, page)
+}
+
+// LogMyWatched — POST /watched: пользователь посмотрел фильм сам
+func (h *WatchlistHandler) LogMyWatched(w http.ResponseWriter, r *http.Request) {
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.LogWatchedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if len(req.AttendeeIDs) > 0 {
+		respondWithError(w, http.StatusBadRequest, "attendee_ids are only allowed for room watch history")
+		return
+	}
+
+	movieID, match, ok := h.resolveMovie(w, req.MovieID, req.MatchID, userID, nil)
+	if !ok {
+		return
+	}
+	event := &models.WatchEvent{MovieID: movieID, LoggedBy: &userID}
+	if match != nil {
+		event.RoomID = &match.RoomID
+	}
+	h.logWatched(w, event, match, req.WatchedAt, []uuid.UUID{userID})
+}
+
+// GetRoomWatchHistory — GET /rooms/{room_id}/watched
+func (h *WatchlistHandler) GetRoomWatchHistory(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	page, ok := parsePage(w, r, pagination.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	events, next, err := h.historyRepo.GetByRoomID(roomID, page)
+	if err == pagination.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting room watch history: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get watch history")
+		return
+	}
+	respondWithPage(w, events, next, page)
+}
+
+// LogRoomWatched — POST /rooms/{room_id}/watched: комната посмотрела фильм.
+// Участники просмотра — attendee_ids из участников комнаты; по умолчанию только автор записи.
+func (h *WatchlistHandler) LogRoomWatched(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.LogWatchedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	movieID, match, ok := h.resolveMovie(w, req.MovieID, req.MatchID, userID, &roomID)
+	if !ok {
+		return
+	}
+
+	attendees := req.AttendeeIDs
+	if len(attendees) == 0 {
+		attendees = []uuid.UUID{userID}
+	}
+	for _, id := range attendees {
+		isMember, err := h.roomRepo.IsMember(roomID, id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to check room members")
+			return
+		}
+		if !isMember {
+			respondWithError(w, http.StatusBadRequest, "All attendees must be room members")
+			return
+		}
+	}
+
+	h.logWatched(w, &models.WatchEvent{MovieID: movieID, RoomID: &roomID, LoggedBy: &userID}, match, req.WatchedAt, attendees)
+}
+
+// resolveMovie определяет фильм по movie_id или match_id. Матч должен быть из комнаты roomID,
+// а без комнаты — из комнаты, где пользователь участник. При ошибке ответ уже отправлен.
+func (h *WatchlistHandler) resolveMovie(w http.ResponseWriter, movieID, matchID *uuid.UUID, userID uuid.UUID, roomID *uuid.UUID) (uuid.UUID, *models.Match, bool) {
+	switch {
+	case matchID != nil:
+		match, err := h.matchRepo.GetByID(*matchID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Match not found")
+			return uuid.Nil, nil, false
+		}
+		if roomID != nil && match.RoomID != *roomID {
+			respondWithError(w, http.StatusBadRequest, "Match belongs to another room")
+			return uuid.Nil, nil, false
+		}
+		if roomID == nil {
+			isMember, err := h.roomRepo.IsMember(match.RoomID, userID)
+			if err != nil || !isMember {
+				respondWithError(w, http.StatusNotFound, "Match not found")
+				return uuid.Nil, nil, false
+			}
+		}
+		return match.MovieID, match, true
+	case movieID != nil:
+		if _, err := h.movieRepo.GetByID(*movieID); err != nil {
+			if errors.Is(err, repository.ErrMovieNotFound) {
+				respondWithError(w, http.StatusNotFound, "Movie not found")
+			} else {
+				respondWithError(w, http.StatusInternalServerError, "Failed to get movie")
+			}
+			return uuid.Nil, nil, false
+		}
+		return *movieID, nil, true
+	default:
+		respondWithError(w, http.StatusBadRequest, "movie_id or match_id is required")
+		return uuid.Nil, nil, false
+	}
+}
+
+func (h *WatchlistHandler) add(w http.ResponseWriter, item *models.WatchlistItem, match *models.Match, userID uuid.UUID) {
+	item.AddedBy = &userID
+	if match != nil {
+		item.Source = models.WatchlistSourceMatch
+		item.MatchID = &match.ID
+	}
+
+	if err := h.watchlistRepo.Add(item); err != nil {
+		if errors.Is(err, repository.ErrAlreadyInWatchlist) {
+			respondWithError(w, http.StatusConflict, "Movie is already in watchlist")
+			return
+		}
+		log.Printf("Error adding to watchlist: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to add to watchlist")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, item)
+}
+
+// remove удаляет запись списка, если она принадлежит списку (owns); чужие записи выглядят как несуществующие
+func (h *WatchlistHandler) remove(w http.ResponseWriter, r *http.Request, owns func(*models.WatchlistItem) bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid watchlist item ID")
+		return
+	}
+
+	item, err := h.watchlistRepo.GetByID(id)
+	if err != nil && !errors.Is(err, repository.ErrWatchlistItemNotFound) {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get watchlist item")
+		return
+	}
+	if err != nil || !owns(item) {
+		respondWithError(w, http.StatusNotFound, "Watchlist item not found")
+		return
+	}
+	if err := h.watchlistRepo.Delete(id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete watchlist item")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Watchlist item deleted"})
+}
+
+func (h *WatchlistHandler) logWatched(w http.ResponseWriter, event *models.WatchEvent, match *models.Match, watchedAt *time.Time, attendees []uuid.UUID) {
+	if match != nil {
+		event.MatchID = &match.ID
+	}
+	event.WatchedAt = time.Now()
+	if watchedAt != nil {
+		if watchedAt.After(event.WatchedAt) {
+			respondWithError(w, http.StatusBadRequest, "watched_at must not be in the future")
+			return
+		}
+		event.WatchedAt = *watchedAt
+	}
+
+	if err := h.historyRepo.Create(event, attendees); err != nil {
+		log.Printf("Error logging watched movie: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to log watched movie")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, event)
+}
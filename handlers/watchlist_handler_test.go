@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kinoswipe/middleware"
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// fakeWatchlistStore — списки в памяти; один фильм в одном списке — как уникальные индексы watchlist_items
+type fakeWatchlistStore struct {
+	items []*models.WatchlistItem
+}
+
+func (f *fakeWatchlistStore) Add(item *models.WatchlistItem) error {
+	for _, it := range f.items {
+		sameList := (it.UserID != nil && item.UserID != nil && *it.UserID == *item.UserID) ||
+			(it.RoomID != nil && item.RoomID != nil && *it.RoomID == *item.RoomID)
+		if sameList && it.MovieID == item.MovieID {
+			return repository.ErrAlreadyInWatchlist
+		}
+	}
+	if item.ID == uuid.Nil {
+		item.ID = uuid.New()
+	}
+	f.items = append(f.items, item)
+	return nil
+}
+
+func (f *fakeWatchlistStore) GetByID(id uuid.UUID) (*models.WatchlistItem, error) {
+	for _, it := range f.items {
+		if it.ID == id {
+			return it, nil
+		}
+	}
+	return nil, repository.ErrWatchlistItemNotFound
+}
+
+func (f *fakeWatchlistStore) GetByUserID(userID uuid.UUID, page pagination.Page) ([]models.WatchlistItem, string, error) {
+	var out []models.WatchlistItem
+	for _, it := range f.items {
+		if it.UserID != nil && *it.UserID == userID {
+			out = append(out, *it)
+		}
+	}
+	return out, "", nil
+}
+
+func (f *fakeWatchlistStore) GetByRoomID(roomID uuid.UUID, page pagination.Page) ([]models.WatchlistItem, string, error) {
+	return nil, "", nil
+}
+
+func (f *fakeWatchlistStore) Delete(id uuid.UUID) error {
+	for i, it := range f.items {
+		if it.ID == id {
+			f.items = append(f.items[:i], f.items[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrWatchlistItemNotFound
+}
+
+// fakeWatchHistory запоминает записанные просмотры и их участников
+type fakeWatchHistory struct {
+	watchHistoryRepoInterface
+	events    []*models.WatchEvent
+	attendees [][]uuid.UUID
+}
+
+func (f *fakeWatchHistory) Create(event *models.WatchEvent, attendeeIDs []uuid.UUID) error {
+	f.events = append(f.events, event)
+	f.attendees = append(f.attendees, attendeeIDs)
+	return nil
+}
+
+type fakeMovieGetter map[uuid.UUID]bool
+
+func (f fakeMovieGetter) GetByID(id uuid.UUID) (*models.Movie, error) {
+	if !f[id] {
+		return nil, repository.ErrMovieNotFound
+	}
+	return &models.Movie{ID: id}, nil
+}
+
+type fakeMatchGetter map[uuid.UUID]*models.Match
+
+func (f fakeMatchGetter) GetByID(id uuid.UUID) (*models.Match, error) {
+	if m, ok := f[id]; ok {
+		return m, nil
+	}
+	return nil, errors.New("match not found")
+}
+
+// fakeRoomMembers — участники комнат; err — сбой базы при проверке
+type fakeRoomMembers struct {
+	members map[uuid.UUID][]uuid.UUID
+	err     error
+}
+
+func (f *fakeRoomMembers) IsMember(roomID, userID uuid.UUID) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	for _, id := range f.members[roomID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeAlmostMatcher []models.AlmostMatch
+
+func (f fakeAlmostMatcher) GetAlmostMatches(roomID uuid.UUID) ([]models.AlmostMatch, error) {
+	return f, nil
+}
+
+// watchlistFixture — комната с двумя участниками, фильм и матч по нему
+type watchlistFixture struct {
+	roomID, userID, otherID, movieID uuid.UUID
+	match                            *models.Match
+	lists                            *fakeWatchlistStore
+	history                          *fakeWatchHistory
+	rooms                            *fakeRoomMembers
+	handler                          *WatchlistHandler
+}
+
+func newWatchlistFixture() *watchlistFixture {
+	f := &watchlistFixture{roomID: uuid.New(), userID: uuid.New(), otherID: uuid.New(), movieID: uuid.New()}
+	f.match = &models.Match{ID: uuid.New(), RoomID: f.roomID, MovieID: f.movieID}
+	f.lists = &fakeWatchlistStore{}
+	f.history = &fakeWatchHistory{}
+	f.rooms = &fakeRoomMembers{members: map[uuid.UUID][]uuid.UUID{f.roomID: {f.userID, f.otherID}}}
+	f.handler = &WatchlistHandler{
+		watchlistRepo: f.lists,
+		historyRepo:   f.history,
+		movieRepo:     fakeMovieGetter{f.movieID: true},
+		matchRepo:     fakeMatchGetter{f.match.ID: f.match},
+		roomRepo:      f.rooms,
+	}
+	return f
+}
+
+func (f *watchlistFixture) request(method, body string) *http.Request {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"room_id": f.roomID.String()})
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: f.userID}))
+}
+
+func TestWatchlistHandler_AddToMyWatchlist(t *testing.T) {
+	f := newWatchlistFixture()
+	foreignMatch := &models.Match{ID: uuid.New(), RoomID: uuid.New(), MovieID: f.movieID}
+	f.handler.matchRepo.(fakeMatchGetter)[foreignMatch.ID] = foreignMatch
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"by movie", `{"movie_id":"` + f.movieID.String() + `"}`, http.StatusCreated},
+		{"same movie again", `{"movie_id":"` + f.movieID.String() + `"}`, http.StatusConflict},
+		{"unknown movie", `{"movie_id":"` + uuid.New().String() + `"}`, http.StatusNotFound},
+		// Матч комнаты, где пользователь не участник, выглядит как несуществующий
+		{"foreign match", `{"match_id":"` + foreignMatch.ID.String() + `"}`, http.StatusNotFound},
+		{"neither movie nor match", `{}`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			f.handler.AddToMyWatchlist(rec, f.request(http.MethodPost, tc.body))
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+	if len(f.lists.items) != 1 {
+		t.Errorf("watchlist items = %d, want 1", len(f.lists.items))
+	}
+}
+
+func TestWatchlistHandler_AddAlmostMatchesSkipsSaved(t *testing.T) {
+	f := newWatchlistFixture()
+	other := uuid.New()
+	f.handler.matchService = fakeAlmostMatcher{{MovieID: f.movieID}, {MovieID: other}}
+	f.lists.Add(&models.WatchlistItem{MovieID: f.movieID, RoomID: &f.roomID})
+
+	rec := httptest.NewRecorder()
+	f.handler.AddAlmostMatchesToRoomWatchlist(rec, f.request(http.MethodPost, ""))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (%s)", rec.Code, rec.Body.String())
+	}
+	var added []models.WatchlistItem
+	if err := json.Unmarshal(rec.Body.Bytes(), &added); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(added) != 1 || added[0].MovieID != other || added[0].Source != models.WatchlistSourceAlmostMatch {
+		t.Errorf("added = %+v, want only %s from almost matches", added, other)
+	}
+}
+
+func TestWatchlistHandler_RemoveOnlyOwnItems(t *testing.T) {
+	f := newWatchlistFixture()
+	mine := &models.WatchlistItem{MovieID: f.movieID, UserID: &f.userID}
+	theirs := &models.WatchlistItem{MovieID: f.movieID, UserID: &f.otherID}
+	f.lists.Add(mine)
+	f.lists.Add(theirs)
+
+	for _, tc := range []struct {
+		id   uuid.UUID
+		want int
+	}{{theirs.ID, http.StatusNotFound}, {mine.ID, http.StatusOK}, {mine.ID, http.StatusNotFound}} {
+		req := mux.SetURLVars(f.request(http.MethodDelete, ""), map[string]string{"id": tc.id.String()})
+		rec := httptest.NewRecorder()
+		f.handler.RemoveFromMyWatchlist(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("delete %s: status = %d, want %d", tc.id, rec.Code, tc.want)
+		}
+	}
+	if len(f.lists.items) != 1 || f.lists.items[0] != theirs {
+		t.Errorf("items left = %+v, want only the other user's", f.lists.items)
+	}
+}
+
+func TestWatchlistHandler_LogRoomWatchedAttendees(t *testing.T) {
+	cases := []struct {
+		name      string
+		attendees func(f *watchlistFixture) string
+		dbErr     error
+		want      int
+	}{
+		{"author by default", func(f *watchlistFixture) string { return "" }, nil, http.StatusCreated},
+		{"room members", func(f *watchlistFixture) string {
+			return `,"attendee_ids":["` + f.userID.String() + `","` + f.otherID.String() + `"]`
+		}, nil, http.StatusCreated},
+		{"outsider", func(f *watchlistFixture) string {
+			return `,"attendee_ids":["` + f.userID.String() + `","` + uuid.New().String() + `"]`
+		}, nil, http.StatusBadRequest},
+		{"membership check fails", func(f *watchlistFixture) string { return "" }, errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newWatchlistFixture()
+			f.rooms.err = tc.dbErr
+			body := `{"match_id":"` + f.match.ID.String() + `"` + tc.attendees(f) + `}`
+
+			rec := httptest.NewRecorder()
+			f.handler.LogRoomWatched(rec, f.request(http.MethodPost, body))
+
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+			if tc.want != http.StatusCreated {
+				if len(f.history.events) != 0 {
+					t.Errorf("rejected watch event was saved")
+				}
+				return
+			}
+			event := f.history.events[0]
+			if event.RoomID == nil || *event.RoomID != f.roomID || event.MatchID == nil || *event.MatchID != f.match.ID {
+				t.Errorf("event = %+v, want room %s and match %s", event, f.roomID, f.match.ID)
+			}
+			if attendees := f.history.attendees[0]; len(attendees) == 0 || attendees[0] != f.userID {
+				t.Errorf("attendees = %v, want the author first", attendees)
+			}
+		})
+	}
+}
+
+func TestWatchlistHandler_LogMyWatchedValidation(t *testing.T) {
+	f := newWatchlistFixture()
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"attendees outside a room", `{"movie_id":"` + f.movieID.String() + `","attendee_ids":["` + f.otherID.String() + `"]}`, http.StatusBadRequest},
+		{"watched in the future", `{"movie_id":"` + f.movieID.String() + `","watched_at":"` + future + `"}`, http.StatusBadRequest},
+		{"watched", `{"movie_id":"` + f.movieID.String() + `"}`, http.StatusCreated},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			f.handler.LogMyWatched(rec, f.request(http.MethodPost, tc.body))
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+	if len(f.history.events) != 1 || len(f.history.attendees[0]) != 1 || f.history.attendees[0][0] != f.userID {
+		t.Errorf("events = %d, attendees = %v; want one event watched by the author", len(f.history.events), f.history.attendees)
+	}
+}
+
+func TestWatchlistHandler_GetMyWatchlistPage(t *testing.T) {
+	f := newWatchlistFixture()
+	f.lists.Add(&models.WatchlistItem{MovieID: f.movieID, UserID: &f.userID})
+
+	rec := httptest.NewRecorder()
+	f.handler.GetMyWatchlist(rec, f.request(http.MethodGet, ""))
+
+	var page struct {
+		Items []models.WatchlistItem `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || rec.Code != http.StatusOK || len(page.Items) != 1 {
+		t.Fatalf("status = %d, body = %s; want one item in an envelope", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/watchlist?cursor=broken", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: f.userID}))
+	rec = httptest.NewRecorder()
+	f.handler.GetMyWatchlist(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("broken cursor: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"kinoswipe/config"
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// wsTicketTTL — сколько действует билет на подключение к WebSocket
+const wsTicketTTL = 30 * time.Second
+
+type hubTicketStoreInterface interface {
+	Create(ticketHash string, userID, roomID uuid.UUID, expiresAt time.Time) error
+	Consume(ticketHash string, roomID uuid.UUID) (uuid.UUID, error)
+}
+
+// handshakeError — отказ в подключении к WebSocket: статус и сообщение ответа
+type handshakeError struct {
+	status  int
+	message string
+}
+
+// SetHandshake задаёт настройки подключения из конфига: размеры буферов, разрешённые Origin и строгий режим авторизации.
+func (h *Hub) SetHandshake(cfg config.WebSocketConfig) {
+	if cfg.ReadBufferSize > 0 {
+		h.upgrader.ReadBufferSize = cfg.ReadBufferSize
+	}
+	if cfg.WriteBufferSize > 0 {
+		h.upgrader.WriteBufferSize = cfg.WriteBufferSize
+	}
+	h.allowedOrigins = parseOrigins(cfg.AllowedOrigins)
+	h.strictAuth = cfg.StrictAuth
+}
+
+// SetTickets включает подключение по одноразовым билетам (?ticket=), которые выдаёт IssueTicket.
+func (h *Hub) SetTickets(tickets *repository.WSTicketRepository) {
+	if tickets != nil {
+		h.tickets = tickets
+	}
+}
+
+func parseOrigins(list string) []string {
+	var origins []string
+	for _, origin := range strings.Split(list, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// checkOrigin пропускает свой Origin и Origin из списка. Запрос без Origin пришёл не из браузера:
+// чужая страница не может открыть такой сокет от имени пользователя, поэтому он тоже пропускается.
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// authenticate определяет пользователя соединения: по билету (?ticket=), по JWT (?token=) или,
+// вне строгого режима, по user_id / X-User-ID. Неверные билет или токен — отказ, без перехода к user_id.
+func (h *Hub) authenticate(r *http.Request, roomID uuid.UUID) (uuid.UUID, *handshakeError) {
+	query := r.URL.Query()
+
+	if ticket := query.Get("ticket"); ticket != "" {
+		if h.tickets == nil {
+			return uuid.Nil, &handshakeError{http.StatusUnauthorized, "Tickets are not supported"}
+		}
+		userID, err := h.tickets.Consume(hashTicket(ticket), roomID)
+		if err != nil {
+			return uuid.Nil, &handshakeError{http.StatusUnauthorized, "Invalid or expired ticket"}
+		}
+		return userID, nil
+	}
+
+	if token := query.Get("token"); token != "" {
+		userID, err := h.userFromToken(token)
+		if err != nil {
+			return uuid.Nil, &handshakeError{http.StatusUnauthorized, "Invalid or expired token"}
+		}
+		return userID, nil
+	}
+
+	if h.strictAuth {
+		return uuid.Nil, &handshakeError{http.StatusUnauthorized, "Ticket or token required"}
+	}
+	// Совместимость со старым клиентом: пользователь назван в запросе и ничем не подтверждён
+	userIDStr := query.Get("user_id")
+	if userIDStr == "" {
+		userIDStr = r.Header.Get("X-User-ID")
+	}
+	if userIDStr == "" {
+		return uuid.Nil, &handshakeError{http.StatusUnauthorized, "User ID required"}
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, &handshakeError{http.StatusBadRequest, "Invalid user ID"}
+	}
+	return userID, nil
+}
+
+// userFromToken проверяет access-токен: подпись HS256 и срок действия. В строгом режиме токен без exp не принимается.
+func (h *Hub) userFromToken(tokenStr string) (uuid.UUID, error) {
+	if h.cfg == nil {
+		return uuid.Nil, errors.New("JWT is not configured")
+	}
+	opts := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()})}
+	if h.strictAuth {
+		opts = append(opts, jwt.WithExpirationRequired())
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(h.cfg.JWT.Secret), nil
+	}, opts...)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(sub)
+}
+
+// IssueTicket выдаёт одноразовый билет на подключение к WebSocket комнаты: POST /rooms/{room_id}/ws-ticket.
+// Билет действует wsTicketTTL и передаётся в ?ticket= вместо JWT, чтобы токен не попадал в URL и логи прокси.
+func (h *Hub) IssueTicket(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	userID, ok := RequireUserID(w, r)
+	if !ok {
+		return
+	}
+	// В строгом режиме билет выдаётся только по JWT: X-User-ID ничем не подтверждён
+	if h.strictAuth {
+		tokenUser, err := h.userFromToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil || tokenUser != userID {
+			respondWithError(w, http.StatusUnauthorized, "Valid token required")
+			return
+		}
+	}
+	if h.tickets == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "WebSocket tickets are not available")
+		return
+	}
+
+	ticket, err := newTicket()
+	if err != nil {
+		log.Printf("Failed to generate WebSocket ticket: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to issue ticket")
+		return
+	}
+	expiresAt := time.Now().Add(wsTicketTTL)
+	if err := h.tickets.Create(hashTicket(ticket), userID, roomID, expiresAt); err != nil {
+		log.Printf("Failed to store WebSocket ticket: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to issue ticket")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, models.WSTicketResponse{Ticket: ticket, ExpiresAt: expiresAt})
+}
+
+func newTicket() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashTicket — в БД хранится только хеш билета, как у refresh-токенов
+func hashTicket(ticket string) string {
+	hash := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(hash[:])
+}
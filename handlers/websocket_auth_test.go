@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kinoswipe/config"
+	"kinoswipe/middleware"
+	"kinoswipe/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const testJWTSecret = "test-secret"
+
+// handshake открывает сокет комнаты с произвольным query и заголовками; возвращает статус ответа
+func handshake(t *testing.T, srv *httptest.Server, roomID uuid.UUID, query string, header http.Header) (*websocket.Conn, int) {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/rooms/" + roomID.String() + "/ws?" + query
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		if resp == nil {
+			t.Fatalf("dial: %v", err)
+		}
+		return nil, resp.StatusCode
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, http.StatusSwitchingProtocols
+}
+
+func signToken(t *testing.T, claims jwt.MapClaims, secret string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return token
+}
+
+func TestWebSocket_OriginAllowList(t *testing.T) {
+	_, srv := newTestHubServer(t, func(h *Hub) {
+		h.SetHandshake(config.WebSocketConfig{AllowedOrigins: "https://kinoswipe.app/, https://beta.kinoswipe.app"})
+	})
+	query := "user_id=" + uuid.New().String()
+
+	cases := []struct {
+		origin string
+		want   int
+	}{
+		{"https://evil.example", http.StatusForbidden},
+		{"https://kinoswipe.app", http.StatusSwitchingProtocols},
+		{"https://beta.kinoswipe.app", http.StatusSwitchingProtocols},
+		// Свой Origin разрешён всегда
+		{srv.URL, http.StatusSwitchingProtocols},
+	}
+	for _, tc := range cases {
+		if _, status := handshake(t, srv, uuid.New(), query, http.Header{"Origin": {tc.origin}}); status != tc.want {
+			t.Errorf("origin %s: status = %d, want %d", tc.origin, status, tc.want)
+		}
+	}
+}
+
+func TestWebSocket_StrictAuthRequiresValidToken(t *testing.T) {
+	_, srv := newTestHubServer(t, func(h *Hub) {
+		h.cfg = &config.Config{JWT: config.JWTConfig{Secret: testJWTSecret}}
+		h.SetHandshake(config.WebSocketConfig{StrictAuth: true})
+	})
+	userID := uuid.New()
+	now := time.Now()
+
+	cases := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"user_id only", "user_id=" + userID.String(), http.StatusUnauthorized},
+		{"valid token", "token=" + signToken(t, jwt.MapClaims{"sub": userID.String(), "exp": now.Add(time.Hour).Unix()}, testJWTSecret), http.StatusSwitchingProtocols},
+		{"expired token", "token=" + signToken(t, jwt.MapClaims{"sub": userID.String(), "exp": now.Add(-time.Minute).Unix()}, testJWTSecret), http.StatusUnauthorized},
+		{"token without exp", "token=" + signToken(t, jwt.MapClaims{"sub": userID.String()}, testJWTSecret), http.StatusUnauthorized},
+		{"foreign secret", "token=" + signToken(t, jwt.MapClaims{"sub": userID.String(), "exp": now.Add(time.Hour).Unix()}, "other"), http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		if _, status := handshake(t, srv, uuid.New(), tc.query, nil); status != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, status, tc.want)
+		}
+	}
+}
+
+func TestWebSocket_InvalidTokenDoesNotFallBackToUserID(t *testing.T) {
+	_, srv := newTestHubServer(t, func(h *Hub) {
+		h.cfg = &config.Config{JWT: config.JWTConfig{Secret: testJWTSecret}}
+	})
+	expired := signToken(t, jwt.MapClaims{"sub": uuid.New().String(), "exp": time.Now().Add(-time.Minute).Unix()}, testJWTSecret)
+
+	if _, status := handshake(t, srv, uuid.New(), "user_id="+uuid.New().String()+"&token="+expired, nil); status != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", status)
+	}
+	// Вне строгого режима старый клиент без токена по-прежнему подключается
+	if _, status := handshake(t, srv, uuid.New(), "user_id="+uuid.New().String(), nil); status != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", status)
+	}
+}
+
+type fakeTicket struct {
+	userID, roomID uuid.UUID
+	expiresAt      time.Time
+}
+
+type fakeTickets struct {
+	mu      sync.Mutex
+	tickets map[string]fakeTicket
+}
+
+func (f *fakeTickets) Create(ticketHash string, userID, roomID uuid.UUID, expiresAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tickets[ticketHash] = fakeTicket{userID: userID, roomID: roomID, expiresAt: expiresAt}
+	return nil
+}
+
+func (f *fakeTickets) Consume(ticketHash string, roomID uuid.UUID) (uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ticket, ok := f.tickets[ticketHash]
+	if !ok || ticket.roomID != roomID || time.Now().After(ticket.expiresAt) {
+		return uuid.Nil, errors.New("ticket not found")
+	}
+	delete(f.tickets, ticketHash)
+	return ticket.userID, nil
+}
+
+// issueTicket вызывает IssueTicket от имени пользователя, как после AuthMiddleware
+func issueTicket(t *testing.T, hub *Hub, roomID, userID uuid.UUID, header http.Header) (*httptest.ResponseRecorder, models.WSTicketResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/rooms/"+roomID.String()+"/ws-ticket", nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req = mux.SetURLVars(req, map[string]string{"room_id": roomID.String()})
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.User{ID: userID}))
+
+	rec := httptest.NewRecorder()
+	hub.IssueTicket(rec, req)
+	var resp models.WSTicketResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func TestWebSocket_TicketIsSingleUse(t *testing.T) {
+	userID := uuid.New()
+	hub, srv := newTestHubServer(t, func(h *Hub) {
+		h.cfg = &config.Config{JWT: config.JWTConfig{Secret: testJWTSecret}}
+		h.tickets = &fakeTickets{tickets: map[string]fakeTicket{}}
+		h.SetHandshake(config.WebSocketConfig{StrictAuth: true})
+	})
+	roomID := uuid.New()
+
+	// В строгом режиме билет выдаётся только по JWT
+	if rec, _ := issueTicket(t, hub, roomID, userID, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("issue without token: status = %d, want 401", rec.Code)
+	}
+	bearer := http.Header{"Authorization": {"Bearer " + signToken(t, jwt.MapClaims{"sub": userID.String(), "exp": time.Now().Add(time.Hour).Unix()}, testJWTSecret)}}
+	rec, ticket := issueTicket(t, hub, roomID, userID, bearer)
+	if rec.Code != http.StatusOK || ticket.Ticket == "" || time.Until(ticket.ExpiresAt) > wsTicketTTL {
+		t.Fatalf("issue: status = %d, ticket = %+v", rec.Code, ticket)
+	}
+
+	// Билет другой комнаты не подходит и при этом не сгорает
+	if _, status := handshake(t, srv, uuid.New(), "ticket="+ticket.Ticket, nil); status != http.StatusUnauthorized {
+		t.Fatalf("other room: status = %d, want 401", status)
+	}
+	conn, status := handshake(t, srv, roomID, "ticket="+ticket.Ticket, nil)
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("first use: status = %d, want 101", status)
+	}
+	expectEvent(t, conn, models.WSMessageTypeHello, 0)
+	if join := decodeData[models.WSMember](t, expectEvent(t, conn, models.WSMessageTypeJoin, 1)); join.UserID != userID {
+		t.Fatalf("join = %+v, want ticket owner", join)
+	}
+	if _, status := handshake(t, srv, roomID, "ticket="+ticket.Ticket, nil); status != http.StatusUnauthorized {
+		t.Fatalf("second use: status = %d, want 401", status)
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kinoswipe/broadcast"
+	"kinoswipe/models"
+	"kinoswipe/service"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxCommandSize — максимальный размер сообщения клиента
+	maxCommandSize = 4096
+	// maxCommandIDLength — максимальная длина id команды
+	maxCommandIDLength = 64
+	// maxReactionLength — эмодзи реакции, в рунах (с модификаторами и ZWJ-последовательностями)
+	maxReactionLength = 16
+	// reactionInterval — не чаще одной реакции за интервал с одного соединения
+	reactionInterval = 250 * time.Millisecond
+)
+
+// Коды ошибок в ответе error на команду
+const (
+	wsCodeBadRequest     = "bad_request"
+	wsCodeForbidden      = "forbidden"
+	wsCodeNotFound       = "not_found"
+	wsCodeConflict       = "conflict"
+	wsCodeRateLimited    = "rate_limited"
+	wsCodeUnknownCommand = "unknown_command"
+	wsCodeInternal       = "internal"
+)
+
+var (
+	errBadCommandData     = errors.New("invalid command data")
+	errUnknownCommand     = errors.New("unknown command")
+	errCommandUnavailable = errors.New("command is not available")
+	errRateLimited        = errors.New("too many reactions")
+)
+
+type hubSwipeServiceInterface interface {
+	Swipe(roomID, userID uuid.UUID, req models.CreateSwipeRequest) (*service.SwipeResult, error)
+	Undo(roomID, userID uuid.UUID, req models.UndoSwipeRequest) (*models.UndoSwipeResponse, error)
+}
+
+type hubRoomServiceInterface interface {
+	Start(roomID, userID uuid.UUID) (*models.Room, error)
+}
+
+// SetCommands включает команды клиента по WebSocket: swipe/undo идут через swipes, start_room — через rooms,
+// те же сервисы, что у REST-обработчиков. Без них на такие команды приходит error.
+func (h *Hub) SetCommands(swipes *service.SwipeService, rooms *service.RoomLifecycleService) {
+	if swipes != nil {
+		h.swipes = swipes
+	}
+	if rooms != nil {
+		h.roomService = rooms
+	}
+}
+
+// handleCommand выполняет команду клиента от его имени и отвечает ack или error с id команды
+func (h *Hub) handleCommand(c *Client, msg models.WSClientMessage) {
+	if msg.ID == "" || len(msg.ID) > maxCommandIDLength {
+		h.sendTo(c, encodeReply(c.roomID, "", models.WSMessageTypeError, models.WSError{Code: wsCodeBadRequest, Message: "Command id is required"}))
+		return
+	}
+
+	result, err := h.runCommand(c, msg)
+	if err != nil {
+		h.sendTo(c, encodeReply(c.roomID, msg.ID, models.WSMessageTypeError, commandError(err)))
+		return
+	}
+	h.sendTo(c, encodeReply(c.roomID, msg.ID, models.WSMessageTypeAck, result))
+}
+
+func (h *Hub) runCommand(c *Client, msg models.WSClientMessage) (interface{}, error) {
+	switch msg.Type {
+	case models.WSCommandSwipe:
+		if h.swipes == nil {
+			return nil, errCommandUnavailable
+		}
+		var req models.CreateSwipeRequest
+		if err := decodeCommand(msg.Data, &req); err != nil || req.MovieID == uuid.Nil {
+			return nil, errBadCommandData
+		}
+		return h.swipes.Swipe(c.roomID, c.userID, req)
+
+	case models.WSCommandUndo:
+		if h.swipes == nil {
+			return nil, errCommandUnavailable
+		}
+		var req models.UndoSwipeRequest
+		if err := decodeCommand(msg.Data, &req); err != nil {
+			return nil, errBadCommandData
+		}
+		return h.swipes.Undo(c.roomID, c.userID, req)
+
+	case models.WSCommandStartRoom:
+		if h.roomService == nil {
+			return nil, errCommandUnavailable
+		}
+		return h.roomService.Start(c.roomID, c.userID)
+
+	case models.WSCommandReady:
+		var req models.WSReadyCommand
+		if err := decodeCommand(msg.Data, &req); err != nil {
+			return nil, errBadCommandData
+		}
+		ready := req.Ready == nil || *req.Ready
+		return h.setReady(c, ready), nil
+
+	case models.WSMessageTypeTyping:
+		var req models.WSTypingCommand
+		if err := decodeCommand(msg.Data, &req); err != nil {
+			return nil, errBadCommandData
+		}
+		h.touch(c)
+		h.sendToOthers(c, models.WSMessageTypeTyping, models.WSTyping{UserID: c.userID, Username: c.username, Typing: req.Typing})
+		return nil, nil
+
+	case models.WSMessageTypeReaction:
+		var req models.WSReactionCommand
+		if err := decodeCommand(msg.Data, &req); err != nil {
+			return nil, errBadCommandData
+		}
+		req.Emoji = strings.TrimSpace(req.Emoji)
+		if req.Emoji == "" || utf8.RuneCountInString(req.Emoji) > maxReactionLength {
+			return nil, errBadCommandData
+		}
+		if time.Since(c.lastReaction) < reactionInterval {
+			return nil, errRateLimited
+		}
+		c.lastReaction = time.Now()
+		h.touch(c)
+		h.sendToOthers(c, models.WSMessageTypeReaction, models.WSReaction{UserID: c.userID, Username: c.username, Emoji: req.Emoji, MovieID: req.MovieID})
+		return nil, nil
+	}
+	return nil, errUnknownCommand
+}
+
+// decodeCommand разбирает data команды; пустая data — нулевое значение
+func decodeCommand(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// commandError переводит ошибку команды в код и сообщение; ошибки сервисов — как в REST
+func commandError(err error) models.WSError {
+	switch {
+	case errors.Is(err, errBadCommandData):
+		return models.WSError{Code: wsCodeBadRequest, Message: "Invalid command data"}
+	case errors.Is(err, errUnknownCommand):
+		return models.WSError{Code: wsCodeUnknownCommand, Message: "Unknown command"}
+	case errors.Is(err, errCommandUnavailable):
+		return models.WSError{Code: wsCodeUnknownCommand, Message: "Command is not available"}
+	case errors.Is(err, errRateLimited):
+		return models.WSError{Code: wsCodeRateLimited, Message: "Too many reactions"}
+	}
+
+	status, message := serviceErrorStatus(err, "Command failed")
+	codes := map[int]string{
+		http.StatusBadRequest: wsCodeBadRequest,
+		http.StatusForbidden:  wsCodeForbidden,
+		http.StatusNotFound:   wsCodeNotFound,
+		http.StatusConflict:   wsCodeConflict,
+	}
+	code, ok := codes[status]
+	if !ok {
+		log.Printf("WebSocket command failed: %v", err)
+		code = wsCodeInternal
+	}
+	return models.WSError{Code: code, Message: message}
+}
+
+// sendToOthers рассылает эфемерное событие (typing, reaction) остальным соединениям комнаты, в том числе
+// на других экземплярах сервера. Такие события не получают seq и не попадают в буфер догона.
+func (h *Hub) sendToOthers(sender *Client, eventType string, data interface{}) {
+	raw := marshalData(eventType, data)
+	now := time.Now().Unix()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.rooms[sender.roomID]
+	if s == nil {
+		return
+	}
+	h.fanOutLocked(s, encodeRaw(models.WSEvent{Type: eventType, RoomID: sender.roomID, Timestamp: now, Data: raw}), sender)
+	h.enqueue(&broadcast.Message{
+		Origin:    h.id,
+		RoomID:    sender.roomID,
+		Type:      eventType,
+		Ephemeral: true,
+		Timestamp: now,
+		Data:      raw,
+	})
+}
@@ -4,214 +4,500 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"kinoswipe/broadcast"
 	"kinoswipe/config"
+	"kinoswipe/middleware"
 	"kinoswipe/models"
 	"kinoswipe/repository"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// В production нужно проверять origin
-		return true
-	},
-}
+// defaultReplayBuffer — сколько последних событий комнаты хранится для догона после переподключения
+const defaultReplayBuffer = 256
+
+// replayRetention — сколько хранится поток комнаты без подключённых клиентов
+const replayRetention = 30 * time.Minute
 
 type Hub struct {
-	rooms      map[uuid.UUID]map[*Client]bool
+	// id отличает этот экземпляр хаба от других, подписанных на тот же broadcaster
+	id         string
+	rooms      map[uuid.UUID]*roomStream
 	register   chan *Client
 	unregister chan *Client
-	broadcast  chan *Message
-	mu         sync.RWMutex
-	// опционально: для извлечения user_id из JWT в query token=
-	userRepo *repository.UserRepository
+	mu         sync.Mutex
+	// события комнат проходят через broadcaster: он присваивает seq и доставляет их всем экземплярам
+	broadcaster broadcast.Broadcaster
+	// очередь публикации: хаб ставит события под mu, отдельная горутина отдаёт их broadcaster
+	outMu    sync.Mutex
+	outbox   []*broadcast.Message
+	outReady chan struct{}
+	// размер буфера событий для догона по ?since=
+	replayBuffer int
+	// опционально: для извлечения user_id из JWT в query token= и имён участников в событиях
+	userRepo hubUserRepoInterface
 	cfg      *config.Config
+	// опционально: проверка членства в комнате при подключении
+	roomAccess *middleware.RoomAccess
+	// настройки подключения (см. SetHandshake) и билеты (см. SetTickets)
+	upgrader       websocket.Upgrader
+	allowedOrigins []string
+	strictAuth     bool
+	tickets        hubTicketStoreInterface
+	// опционально: сервисы для команд клиента (см. SetCommands)
+	swipes      hubSwipeServiceInterface
+	roomService hubRoomServiceInterface
 }
 
-type Client struct {
-	hub    *Hub
-	conn   *websocket.Conn
-	send   chan []byte
-	roomID uuid.UUID
-	userID uuid.UUID
+type hubUserRepoInterface interface {
+	GetByID(id uuid.UUID) (*models.User, error)
 }
 
-type Message struct {
-	RoomID  uuid.UUID
-	UserID  uuid.UUID
-	Message []byte
+// roomStream — поток событий комнаты: подключённые клиенты, присутствие участников,
+// последний seq и буфер последних событий
+type roomStream struct {
+	clients  map[*Client]bool
+	members  map[uuid.UUID]*memberState
+	seq      uint64
+	events   []streamEvent
+	activity time.Time
+}
+
+type streamEvent struct {
+	seq  uint64
+	data []byte
+}
+
+type Client struct {
+	hub      *Hub
+	conn     *websocket.Conn
+	send     chan []byte
+	roomID   uuid.UUID
+	userID   uuid.UUID
+	username string
+	// since — последний seq, полученный клиентом до переподключения (?since=)
+	since  uint64
+	resume bool
+	// lastReaction — время последней реакции; читается и пишется только в readPump
+	lastReaction time.Time
 }
 
 func NewHub() *Hub {
-	return &Hub{
-		rooms:      make(map[uuid.UUID]map[*Client]bool),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		broadcast:  make(chan *Message),
+	h := &Hub{
+		id:           uuid.NewString(),
+		rooms:        make(map[uuid.UUID]*roomStream),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		broadcaster:  broadcast.NewMemory(),
+		outReady:     make(chan struct{}, 1),
+		replayBuffer: defaultReplayBuffer,
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
 	}
+	return h
 }
 
-// SetAuth задаёт репозиторий и конфиг для авторизации WebSocket по JWT (query token=).
+// SetAuth задаёт репозиторий и конфиг для авторизации WebSocket по JWT (query token=, секрет из cfg.JWT).
+// Из репозитория же берутся имена участников для join/leave, presence и progress.
 func (h *Hub) SetAuth(userRepo *repository.UserRepository, cfg *config.Config) {
-	h.userRepo = userRepo
+	if userRepo != nil {
+		h.userRepo = userRepo
+	}
 	h.cfg = cfg
 }
 
+// SetRoomAccess задаёт проверку доступа: подключаться по WebSocket смогут только участники комнаты.
+func (h *Hub) SetRoomAccess(roomAccess *middleware.RoomAccess) {
+	h.roomAccess = roomAccess
+}
+
+// SetReplayBuffer задаёт, сколько последних событий комнаты хранится для догона. Вызывать до Run.
+func (h *Hub) SetReplayBuffer(n int) {
+	if n > 0 {
+		h.replayBuffer = n
+	}
+}
+
+// SetBroadcaster задаёт, через что события комнат расходятся по экземплярам сервера
+// (по умолчанию — в памяти, для одного экземпляра). Вызывать до Run.
+func (h *Hub) SetBroadcaster(b broadcast.Broadcaster) {
+	if b != nil {
+		h.broadcaster = b
+	}
+}
+
 func (h *Hub) Run() {
+	h.broadcaster.Subscribe(h.receive)
+	go h.publishLoop()
+
+	sweep := time.NewTicker(presenceCheckInterval)
+	defer sweep.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
-			h.mu.Lock()
-			if h.rooms[client.roomID] == nil {
-				h.rooms[client.roomID] = make(map[*Client]bool)
-			}
-			h.rooms[client.roomID][client] = true
-			h.mu.Unlock()
-
-			// Отправляем уведомление о присоединении
-			joinMsg := models.JoinNotification{
-				Type:      models.WSMessageTypeJoin,
-				UserID:    client.userID.String(),
-				Username:  "", // Можно добавить получение username из репозитория
-				Timestamp: time.Now().Unix(),
-			}
-			h.broadcastToRoom(client.roomID, joinMsg)
+			h.attach(client)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.rooms[client.roomID]; ok {
-				delete(h.rooms[client.roomID], client)
-				close(client.send)
-				if len(h.rooms[client.roomID]) == 0 {
-					delete(h.rooms, client.roomID)
-				}
-			}
+			// Клиент мог быть уже удалён (переполнение очереди, DisconnectUser) — тогда ничего не делаем
+			h.dropLocked(client)
 			h.mu.Unlock()
 
-			// Отправляем уведомление об уходе
-			leaveMsg := models.JoinNotification{
-				Type:      models.WSMessageTypeLeave,
-				UserID:    client.userID.String(),
-				Timestamp: time.Now().Unix(),
-			}
-			h.broadcastToRoom(client.roomID, leaveMsg)
+		case now := <-sweep.C:
+			h.markIdle(now)
+			h.pruneStreams(now)
+		}
+	}
+}
 
-		case message := <-h.broadcast:
-			// Message уже содержит данные для отправки
-			h.mu.RLock()
-			clients, ok := h.rooms[message.RoomID]
-			if !ok {
-				h.mu.RUnlock()
-				continue
-			}
-			clientsCopy := make([]*Client, 0, len(clients))
-			for client := range clients {
-				clientsCopy = append(clientsCopy, client)
-			}
-			h.mu.RUnlock()
-
-			for _, client := range clientsCopy {
-				select {
-				case client.send <- message.Message:
-				default:
-					h.mu.Lock()
-					if h.rooms[message.RoomID] != nil {
-						delete(h.rooms[message.RoomID], client)
-					}
-					h.mu.Unlock()
-					close(client.send)
-				}
-			}
+// stream возвращает поток комнаты, создавая его при первом обращении. Вызывается под h.mu.
+func (h *Hub) stream(roomID uuid.UUID) *roomStream {
+	s := h.rooms[roomID]
+	if s == nil {
+		s = &roomStream{
+			clients:  make(map[*Client]bool),
+			members:  make(map[uuid.UUID]*memberState),
+			activity: time.Now(),
 		}
+		h.rooms[roomID] = s
 	}
+	return s
 }
 
-func (h *Hub) broadcastToRoom(roomID uuid.UUID, message interface{}) {
-	h.mu.RLock()
-	clients, ok := h.rooms[roomID]
-	if !ok {
-		h.mu.RUnlock()
+// attach регистрирует клиента и под той же блокировкой ставит в его очередь hello и пропущенные события:
+// новые события комнаты попадут в очередь только после них, порядок seq не нарушится.
+func (h *Hub) attach(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stream(client.roomID)
+	s.clients[client] = true
+	s.activity = time.Now()
+	m, first, wasIdle := s.connect(client)
+
+	hello := models.WSHello{Protocol: models.WSProtocolVersion, Seq: s.seq, Members: s.presence()}
+	client.send <- encodeEvent(client.roomID, models.WSMessageTypeHello, 0, hello)
+	h.replayLocked(s, client)
+
+	switch {
+	case first:
+		h.publishLocked(client.roomID, models.WSMessageTypeJoin, models.WSMember{UserID: client.userID, Username: m.username})
+	case wasIdle:
+		h.publishLocked(client.roomID, models.WSMessageTypePresence, m.presence(client.userID))
+	}
+}
+
+// replayLocked досылает клиенту события после since или resync, если их уже нет в буфере. Вызывается под h.mu.
+func (h *Hub) replayLocked(s *roomStream, client *Client) {
+	if !client.resume || client.since == s.seq {
 		return
 	}
 
-	// Создаем копию клиентов для безопасной итерации
-	clientsCopy := make([]*Client, 0, len(clients))
-	for client := range clients {
-		clientsCopy = append(clientsCopy, client)
+	// Догнать можно, только если буфер начинается не позже since+1 и since не из будущего
+	covered := client.since < s.seq && len(s.events) > 0 && s.events[0].seq <= client.since+1
+	if !covered {
+		client.send <- encodeEvent(client.roomID, models.WSMessageTypeResync, 0, models.WSResync{Since: client.since, Seq: s.seq})
+		return
 	}
-	h.mu.RUnlock()
+	for _, e := range s.events {
+		if e.seq > client.since {
+			client.send <- e.data
+		}
+	}
+}
+
+// dropLocked убирает клиента из комнаты и закрывает его очередь; если это было последнее соединение
+// пользователя, комната получает leave. Вызывается под h.mu.
+func (h *Hub) dropLocked(client *Client) {
+	s := h.rooms[client.roomID]
+	if s == nil || !s.clients[client] {
+		return
+	}
+	delete(s.clients, client)
+	close(client.send)
+	s.activity = time.Now()
+
+	if m, last := s.disconnect(client); last {
+		h.publishLocked(client.roomID, models.WSMessageTypeLeave, models.WSMember{UserID: client.userID, Username: m.username})
+	}
+}
+
+// pruneStreams удаляет потоки комнат, где давно нет ни клиентов, ни событий
+func (h *Hub) pruneStreams(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for roomID, s := range h.rooms {
+		if len(s.clients) == 0 && now.Sub(s.activity) > replayRetention {
+			delete(h.rooms, roomID)
+		}
+	}
+}
+
+func encodeEvent(roomID uuid.UUID, eventType string, seq uint64, data interface{}) []byte {
+	return encode(models.WSEvent{Type: eventType, RoomID: roomID, Seq: seq}, data)
+}
+
+// encodeMessage — событие от broadcaster; время то же, что у отправителя
+func encodeMessage(msg *broadcast.Message) []byte {
+	return encodeRaw(models.WSEvent{Type: msg.Type, RoomID: msg.RoomID, Seq: msg.Seq, Timestamp: msg.Timestamp, Data: msg.Data})
+}
+
+// encodeReply — ответ ack/error на команду клиента с её id
+func encodeReply(roomID uuid.UUID, id, eventType string, data interface{}) []byte {
+	return encode(models.WSEvent{Type: eventType, RoomID: roomID, ID: id}, data)
+}
+
+func encode(event models.WSEvent, data interface{}) []byte {
+	event.Timestamp = time.Now().Unix()
+	event.Data = marshalData(event.Type, data)
+	return encodeRaw(event)
+}
 
-	data, err := json.Marshal(models.WebSocketMessage{
-		Type:      "broadcast",
-		Payload:   message,
+func encodeRaw(event models.WSEvent) []byte {
+	event.V = models.WSProtocolVersion
+	out, _ := json.Marshal(event)
+	return out
+}
+
+func marshalData(eventType string, data interface{}) json.RawMessage {
+	if data == nil {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error marshaling %s event: %v", eventType, err)
+		return nil
+	}
+	return raw
+}
+
+// publish отправляет событие комнаты через broadcaster. Seq присваивает broadcaster,
+// клиентам событие уходит, когда broadcaster доставит его обратно (см. receive).
+func (h *Hub) publish(roomID uuid.UUID, eventType string, data interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.publishLocked(roomID, eventType, data)
+}
+
+func (h *Hub) publishLocked(roomID uuid.UUID, eventType string, data interface{}) {
+	h.stream(roomID).activity = time.Now()
+	h.enqueue(&broadcast.Message{
+		Origin:    h.id,
+		RoomID:    roomID,
+		Type:      eventType,
 		Timestamp: time.Now().Unix(),
+		Data:      marshalData(eventType, data),
 	})
-	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
-		return
+}
+
+// enqueue ставит сообщение в очередь публикации. Broadcaster доставляет события и самому хабу,
+// поэтому публиковать под h.mu нельзя — этим занимается publishLoop.
+func (h *Hub) enqueue(msg *broadcast.Message) {
+	h.outMu.Lock()
+	h.outbox = append(h.outbox, msg)
+	h.outMu.Unlock()
+
+	select {
+	case h.outReady <- struct{}{}:
+	default:
 	}
+}
 
-	for _, client := range clientsCopy {
-		select {
-		case client.send <- data:
-		default:
-			h.mu.Lock()
-			if h.rooms[roomID] != nil {
-				delete(h.rooms[roomID], client)
+func (h *Hub) publishLoop() {
+	for range h.outReady {
+		h.outMu.Lock()
+		batch := h.outbox
+		h.outbox = nil
+		h.outMu.Unlock()
+
+		for _, msg := range batch {
+			if err := h.broadcaster.Publish(msg); err != nil {
+				log.Printf("WebSocket broadcast failed (room=%s type=%s): %v", msg.RoomID, msg.Type, err)
+				if !msg.Ephemeral {
+					h.lost(msg.RoomID)
+				}
 			}
-			h.mu.Unlock()
-			close(client.send)
 		}
 	}
 }
 
-// BroadcastMatch отправляет матч напрямую всем в комнате с type="match" на верхнем уровне.
-// Не оборачивает в broadcastToRoom чтобы фронтенд получал правильный формат.
-func (h *Hub) BroadcastMatch(roomID uuid.UUID, match *models.MatchWithDetails) {
-	msg := map[string]interface{}{
-		"type":      models.WSMessageTypeMatch,
-		"match":     match,
-		"timestamp": time.Now().Unix(),
+// receive применяет сообщение от broadcaster: событие комнаты получает место в буфере догона
+// и рассылается клиентам, эфемерное сообщение с другого экземпляра — только рассылается.
+func (h *Hub) receive(msg *broadcast.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if msg == nil {
+		for roomID, s := range h.rooms {
+			h.resyncLocked(roomID, s)
+		}
+		return
 	}
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Error marshaling match notification: %v", err)
+	if msg.Ephemeral {
+		if msg.Origin != h.id {
+			h.receiveEphemeralLocked(msg)
+		}
+		return
+	}
+
+	s := h.stream(msg.RoomID)
+	if msg.Seq <= s.seq {
 		return
 	}
+	// Своё событие хаб уже учёл, чужое меняет присутствие участников на этом экземпляре
+	if msg.Origin != h.id {
+		s.apply(msg)
+	}
+	// Пропуск в seq (хаб подписался позже или поток комнаты удалялся): догнать через него нельзя
+	if msg.Seq != s.seq+1 {
+		s.events = nil
+	}
+	s.seq = msg.Seq
+	s.activity = time.Now()
+
+	data := encodeMessage(msg)
+	s.events = append(s.events, streamEvent{seq: s.seq, data: data})
+	if over := len(s.events) - h.replayBuffer; over > 0 {
+		s.events = append(s.events[:0:0], s.events[over:]...)
+	}
+	h.fanOutLocked(s, data, nil)
+}
 
-	h.mu.RLock()
-	clients, ok := h.rooms[roomID]
-	if !ok {
-		h.mu.RUnlock()
+func (h *Hub) receiveEphemeralLocked(msg *broadcast.Message) {
+	if msg.Type == models.WSMessageTypeKicked {
+		if msg.UserID != nil {
+			h.kickLocked(msg.RoomID, *msg.UserID)
+		}
 		return
 	}
-	clientsCopy := make([]*Client, 0, len(clients))
-	for client := range clients {
-		clientsCopy = append(clientsCopy, client)
+	if s := h.rooms[msg.RoomID]; s != nil {
+		h.fanOutLocked(s, encodeMessage(msg), nil)
 	}
-	h.mu.RUnlock()
+}
 
-	for _, client := range clientsCopy {
+// fanOutLocked рассылает сообщение клиентам комнаты, кроме skip. Клиенты с переполненной очередью отключаются —
+// после переподключения они догонят пропущенное по since. Вызывается под h.mu.
+func (h *Hub) fanOutLocked(s *roomStream, msg []byte, skip *Client) {
+	var overflowed []*Client
+	for client := range s.clients {
+		if client == skip {
+			continue
+		}
 		select {
-		case client.send <- data:
+		case client.send <- msg:
+		default:
+			overflowed = append(overflowed, client)
+		}
+	}
+	// Отключаем после рассылки: leave отставшего клиента должен идти после этого события
+	for _, client := range overflowed {
+		h.dropLocked(client)
+	}
+}
+
+// lost — событие комнаты не удалось опубликовать
+func (h *Hub) lost(roomID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s := h.rooms[roomID]; s != nil {
+		h.resyncLocked(roomID, s)
+	}
+}
+
+// resyncLocked сообщает клиентам комнаты, что часть событий потеряна, и очищает буфер догона:
+// клиенты перезагрузят состояние через REST. Вызывается под h.mu.
+func (h *Hub) resyncLocked(roomID uuid.UUID, s *roomStream) {
+	s.events = nil
+	h.fanOutLocked(s, encodeEvent(roomID, models.WSMessageTypeResync, 0, models.WSResync{Since: s.seq, Seq: s.seq}), nil)
+}
+
+// sendTo отправляет служебное сообщение одному клиенту, если он ещё подключён
+func (h *Hub) sendTo(client *Client, msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s := h.rooms[client.roomID]; s == nil || !s.clients[client] {
+		return
+	}
+	select {
+	case client.send <- msg:
+	default:
+		h.dropLocked(client)
+	}
+}
+
+// DisconnectUser отправляет пользователю сообщение type=kicked и закрывает все его соединения с комнатой,
+// в том числе на других экземплярах сервера.
+func (h *Hub) DisconnectUser(roomID, userID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.kickLocked(roomID, userID)
+	h.enqueue(&broadcast.Message{
+		Origin:    h.id,
+		RoomID:    roomID,
+		Type:      models.WSMessageTypeKicked,
+		Ephemeral: true,
+		UserID:    &userID,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+func (h *Hub) kickLocked(roomID, userID uuid.UUID) {
+	msg := encodeEvent(roomID, models.WSMessageTypeKicked, 0, nil)
+	s := h.rooms[roomID]
+	if s == nil {
+		return
+	}
+	for client := range s.clients {
+		if client.userID != userID {
+			continue
+		}
+		// writePump успеет отправить kicked до закрытия соединения: send буферизован
+		select {
+		case client.send <- msg:
 		default:
-			h.mu.Lock()
-			if h.rooms[roomID] != nil {
-				delete(h.rooms[roomID], client)
-			}
-			h.mu.Unlock()
-			close(client.send)
 		}
+		h.dropLocked(client)
+	}
+}
+
+// BroadcastHostChanged сообщает комнате о новом хосте.
+func (h *Hub) BroadcastHostChanged(roomID, hostID uuid.UUID) {
+	h.publish(roomID, models.WSMessageTypeHostChanged, models.WSHostChanged{HostID: hostID})
+}
+
+// BroadcastMatch сообщает комнате о новом матче; data — матч с фильмом.
+func (h *Hub) BroadcastMatch(roomID uuid.UUID, match *models.MatchWithDetails) {
+	h.publish(roomID, models.WSMessageTypeMatch, match)
+}
+
+// BroadcastMatchRetracted сообщает комнате, что матч отменён (после отмены свайпа).
+func (h *Hub) BroadcastMatchRetracted(roomID uuid.UUID, match *models.Match) {
+	h.publish(roomID, models.WSMessageTypeMatchRetracted, match)
+}
+
+// BroadcastRoomStarted сообщает комнате, что хост запустил сеанс. Отметки ready больше не нужны и сбрасываются.
+func (h *Hub) BroadcastRoomStarted(roomID uuid.UUID, room *models.Room) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, m := range h.stream(roomID).members {
+		m.ready = false
 	}
+	h.publishLocked(roomID, models.WSMessageTypeRoomStarted, room)
+}
+
+// BroadcastRoomFinished сообщает комнате, что сеанс завершён, и передаёт итог.
+func (h *Hub) BroadcastRoomFinished(roomID uuid.UUID, summary *models.RoomSummary) {
+	h.publish(roomID, models.WSMessageTypeRoomFinished, summary)
 }
 
 func (c *Client) readPump() {
@@ -220,6 +506,7 @@ func (c *Client) readPump() {
 		c.conn.Close()
 	}()
 
+	c.conn.SetReadLimit(maxCommandSize)
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -235,18 +522,16 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// Обрабатываем входящие сообщения (ping/pong, etc.)
-		var wsMsg models.WebSocketMessage
-		if err := json.Unmarshal(message, &wsMsg); err == nil {
-			if wsMsg.Type == models.WSMessageTypePing {
-				pong := models.WebSocketMessage{
-					Type:      models.WSMessageTypePong,
-					Timestamp: time.Now().Unix(),
-				}
-				data, _ := json.Marshal(pong)
-				c.send <- data
-			}
+		var msg models.WSClientMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			c.hub.sendTo(c, encodeReply(c.roomID, "", models.WSMessageTypeError, models.WSError{Code: wsCodeBadRequest, Message: "Invalid message"}))
+			continue
 		}
+		if msg.Type == models.WSMessageTypePing {
+			c.hub.sendTo(c, encodeReply(c.roomID, msg.ID, models.WSMessageTypePong, nil))
+			continue
+		}
+		c.hub.handleCommand(c, msg)
 	}
 }
 
@@ -266,19 +551,8 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
+			// Одно событие — один фрейм: клиент разбирает каждое сообщение как отдельный JSON
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
 
@@ -309,51 +583,53 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// userID: сначала из JWT (query token=), затем user_id или X-User-ID
-	var userID uuid.UUID
-	if tokenStr := r.URL.Query().Get("token"); tokenStr != "" && h.userRepo != nil && h.cfg != nil {
-		claims := &jwt.MapClaims{}
-		token, errJWT := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-			return []byte(h.cfg.JWT.Secret), nil
-		})
-		if errJWT == nil && token.Valid {
-			if sub, ok := (*claims)["sub"].(string); ok {
-				if uid, errParse := uuid.Parse(sub); errParse == nil {
-					userID = uid
-				}
-			}
-		}
-	}
-	if userID == uuid.Nil {
-		userIDStr := r.URL.Query().Get("user_id")
-		if userIDStr == "" {
-			userIDStr = r.Header.Get("X-User-ID")
-		}
-		if userIDStr == "" {
-			respondWithError(w, http.StatusUnauthorized, "User ID required")
+	// since — последний полученный seq: после переподключения сервер дошлёт пропущенные события
+	var since uint64
+	sinceStr := r.URL.Query().Get("since")
+	if sinceStr != "" {
+		since, err = strconv.ParseUint(sinceStr, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid since")
 			return
 		}
-		var errParse error
-		userID, errParse = uuid.Parse(userIDStr)
-		if errParse != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+	}
+
+	// Чужая страница не должна открывать сокет от имени пользователя; проверяем до того, как погасить билет
+	if !h.checkOrigin(r) {
+		respondWithError(w, http.StatusForbidden, "Origin not allowed")
+		return
+	}
+	userID, authErr := h.authenticate(r, roomID)
+	if authErr != nil {
+		respondWithError(w, authErr.status, authErr.message)
+		return
+	}
+
+	// Подключаться к комнате могут только её участники: так настройки входа (пароль, одобрение, лимит) нельзя обойти через WS
+	if h.roomAccess != nil {
+		if _, err := h.roomAccess.Check(roomID, userID, false); err != nil {
+			respondWithRoomAccessError(w, err)
 			return
 		}
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error (room=%s): %v", roomIDStr, err)
 		// Upgrade может уже отправить ответ — не пишем в w повторно
 		return
 	}
 
+	// Очередь вмещает hello и весь буфер догона: attach не блокируется на новом клиенте
 	client := &Client{
-		hub:    h,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		roomID: roomID,
-		userID: userID,
+		hub:      h,
+		conn:     conn,
+		send:     make(chan []byte, h.replayBuffer+64),
+		roomID:   roomID,
+		userID:   userID,
+		username: h.lookupUsername(userID),
+		since:    since,
+		resume:   sinceStr != "",
 	}
 
 	client.hub.register <- client
@@ -361,4 +637,3 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go client.writePump()
 	go client.readPump()
 }
-
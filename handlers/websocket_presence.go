@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"kinoswipe/broadcast"
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// presenceIdleAfter — через сколько без свайпов и команд подключённый участник считается неактивным
+	presenceIdleAfter = 2 * time.Minute
+	// presenceCheckInterval — как часто хаб ищет неактивных участников
+	presenceCheckInterval = 15 * time.Second
+)
+
+// memberState — присутствие участника в потоке комнаты. Прогресс хранится и без подключений:
+// участник мог свайпать через REST, а подключиться позже.
+type memberState struct {
+	username string
+	conns    int
+	// remote — на скольких других экземплярах сервера участник подключён (по их join/leave)
+	remote     int
+	lastActive time.Time
+	idle       bool
+	ready      bool
+	progress   *models.DeckProgress
+}
+
+func (m *memberState) online() bool {
+	return m.conns > 0 || m.remote > 0
+}
+
+func (m *memberState) finished() bool {
+	return m.progress != nil && m.progress.Finished
+}
+
+func (m *memberState) presence(userID uuid.UUID) models.WSPresence {
+	return models.WSPresence{
+		UserID:   userID,
+		Username: m.username,
+		Idle:     m.idle,
+		Finished: m.finished(),
+		Ready:    m.ready,
+		Progress: m.progress,
+	}
+}
+
+func (s *roomStream) member(userID uuid.UUID) *memberState {
+	m := s.members[userID]
+	if m == nil {
+		m = &memberState{lastActive: time.Now()}
+		s.members[userID] = m
+	}
+	return m
+}
+
+// connect учитывает новое соединение: first — первое соединение пользователя, wasIdle — он был неактивен
+func (s *roomStream) connect(client *Client) (m *memberState, first, wasIdle bool) {
+	m = s.member(client.userID)
+	if client.username != "" {
+		m.username = client.username
+	}
+	wasIdle = m.idle
+	m.conns++
+	m.idle = false
+	m.lastActive = time.Now()
+	return m, m.conns == 1, wasIdle
+}
+
+// disconnect учитывает закрытое соединение: last — у пользователя больше нет соединений
+func (s *roomStream) disconnect(client *Client) (m *memberState, last bool) {
+	m = s.member(client.userID)
+	if m.conns > 0 {
+		m.conns--
+	}
+	if m.conns == 0 {
+		if m.remote == 0 {
+			m.idle = false
+		}
+		return m, true
+	}
+	return m, false
+}
+
+// presence возвращает подключённых участников, по имени
+func (s *roomStream) presence() []models.WSPresence {
+	list := []models.WSPresence{}
+	for userID, m := range s.members {
+		if m.online() {
+			list = append(list, m.presence(userID))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Username != list[j].Username {
+			return list[i].Username < list[j].Username
+		}
+		return list[i].UserID.String() < list[j].UserID.String()
+	})
+	return list
+}
+
+// apply учитывает событие комнаты с другого экземпляра сервера: кто там подключён, его idle, ready и прогресс.
+// Неактивность участника определяет экземпляр, к которому он подключён; здесь она только отражается.
+func (s *roomStream) apply(msg *broadcast.Message) {
+	switch msg.Type {
+	case models.WSMessageTypeJoin, models.WSMessageTypeLeave:
+		var member models.WSMember
+		if json.Unmarshal(msg.Data, &member) != nil {
+			return
+		}
+		m := s.member(member.UserID)
+		if member.Username != "" {
+			m.username = member.Username
+		}
+		if msg.Type == models.WSMessageTypeJoin {
+			m.remote++
+		} else if m.remote > 0 {
+			m.remote--
+		}
+
+	case models.WSMessageTypePresence:
+		var p models.WSPresence
+		if json.Unmarshal(msg.Data, &p) != nil {
+			return
+		}
+		m := s.member(p.UserID)
+		if p.Username != "" {
+			m.username = p.Username
+		}
+		m.idle, m.ready = p.Idle, p.Ready
+		if p.Progress != nil {
+			m.progress = p.Progress
+		}
+		if !p.Idle {
+			m.lastActive = time.Now()
+		}
+
+	case models.WSMessageTypeProgress:
+		var p models.WSProgress
+		if json.Unmarshal(msg.Data, &p) != nil {
+			return
+		}
+		m := s.member(p.UserID)
+		if p.Username != "" {
+			m.username = p.Username
+		}
+		progress := p.DeckProgress
+		m.progress = &progress
+		m.idle = false
+		m.lastActive = time.Now()
+
+	case models.WSMessageTypeRoomStarted:
+		for _, m := range s.members {
+			m.ready = false
+		}
+	}
+}
+
+// markIdle помечает неактивными подключённых участников, которые ничего не делали дольше presenceIdleAfter
+func (h *Hub) markIdle(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for roomID, s := range h.rooms {
+		for userID, m := range s.members {
+			if m.conns == 0 || m.idle || m.finished() || now.Sub(m.lastActive) < presenceIdleAfter {
+				continue
+			}
+			m.idle = true
+			h.publishLocked(roomID, models.WSMessageTypePresence, m.presence(userID))
+		}
+	}
+}
+
+// ReportProgress сообщает комнате, сколько карточек прошёл участник. Направление свайпов не передаётся.
+// Участник снова считается активным; presence отправляется, если изменилось idle или finished.
+func (h *Hub) ReportProgress(roomID, userID uuid.UUID, progress models.DeckProgress) {
+	username := h.memberUsername(roomID, userID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	m := h.stream(roomID).member(userID)
+	if username != "" {
+		m.username = username
+	}
+	wasIdle, wasFinished := m.idle, m.finished()
+	m.idle = false
+	m.lastActive = time.Now()
+	m.progress = &progress
+
+	h.publishLocked(roomID, models.WSMessageTypeProgress, models.WSProgress{UserID: userID, Username: m.username, DeckProgress: progress})
+	if m.online() && (wasIdle || wasFinished != progress.Finished) {
+		h.publishLocked(roomID, models.WSMessageTypePresence, m.presence(userID))
+	}
+}
+
+// touch отмечает активность участника (команда по WebSocket); снятие idle рассылается как presence
+func (h *Hub) touch(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.rooms[client.roomID]
+	if s == nil || s.members[client.userID] == nil {
+		return
+	}
+	m := s.members[client.userID]
+	m.lastActive = time.Now()
+	if m.idle {
+		m.idle = false
+		h.publishLocked(client.roomID, models.WSMessageTypePresence, m.presence(client.userID))
+	}
+}
+
+// setReady отмечает готовность участника к старту комнаты и рассылает presence, если она изменилась
+func (h *Hub) setReady(client *Client, ready bool) models.WSPresence {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	m := h.stream(client.roomID).member(client.userID)
+	m.lastActive = time.Now()
+	changed := m.ready != ready || m.idle
+	m.ready = ready
+	m.idle = false
+	presence := m.presence(client.userID)
+	if changed {
+		h.publishLocked(client.roomID, models.WSMessageTypePresence, presence)
+	}
+	return presence
+}
+
+// memberUsername берёт имя участника из потока комнаты, а если его там ещё нет — из репозитория
+func (h *Hub) memberUsername(roomID, userID uuid.UUID) string {
+	h.mu.Lock()
+	if s := h.rooms[roomID]; s != nil {
+		if m := s.members[userID]; m != nil && m.username != "" {
+			h.mu.Unlock()
+			return m.username
+		}
+	}
+	h.mu.Unlock()
+	return h.lookupUsername(userID)
+}
+
+func (h *Hub) lookupUsername(userID uuid.UUID) string {
+	if h.userRepo == nil {
+		return ""
+	}
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		return ""
+	}
+	return user.Username
+}
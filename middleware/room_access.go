@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const roomContextKey contextKey = "room"
+
+// roomAccessRepo — методы RoomRepository, нужные для проверки доступа (позволяет подставлять моки в тестах).
+type roomAccessRepo interface {
+	GetByID(id uuid.UUID) (*models.Room, error)
+	IsMember(roomID, userID uuid.UUID) (bool, error)
+}
+
+// RoomAccess проверяет доступ к маршрутам /rooms/{room_id}/...: комната существует,
+// пользователь — её участник (или хост для управляющих действий). Комната кладётся в контекст.
+type RoomAccess struct {
+	roomRepo roomAccessRepo
+}
+
+func NewRoomAccess(roomRepo *repository.RoomRepository) *RoomAccess {
+	return &RoomAccess{roomRepo: roomRepo}
+}
+
+// RoomAccessError — отказ в доступе к комнате с HTTP-статусом для ответа.
+type RoomAccessError struct {
+	Status  int
+	Message string
+}
+
+func (e *RoomAccessError) Error() string { return e.Message }
+
+// Check загружает комнату и проверяет, что userID — её участник; hostOnly требует роль хоста.
+// Возвращает *RoomAccessError (404 — комнаты нет, 403 — нет доступа) или ошибку репозитория (её вызывающий отдаёт как 500).
+func (a *RoomAccess) Check(roomID, userID uuid.UUID, hostOnly bool) (*models.Room, error) {
+	room, err := a.roomRepo.GetByID(roomID)
+	if errors.Is(err, repository.ErrRoomNotFound) {
+		return nil, &RoomAccessError{Status: http.StatusNotFound, Message: "Room not found"}
+	}
+	if err != nil {
+		log.Printf("RoomAccess: failed to get room %s: %v", roomID, err)
+		return nil, err
+	}
+
+	if hostOnly {
+		if room.HostID != userID {
+			return nil, &RoomAccessError{Status: http.StatusForbidden, Message: "Only room host can manage the room"}
+		}
+		return room, nil
+	}
+
+	// Хост всегда имеет доступ, даже если запись в room_members потерялась
+	if room.HostID == userID {
+		return room, nil
+	}
+	isMember, err := a.roomRepo.IsMember(roomID, userID)
+	if err != nil {
+		log.Printf("RoomAccess: failed to check membership in room %s: %v", roomID, err)
+		return nil, err
+	}
+	if !isMember {
+		return nil, &RoomAccessError{Status: http.StatusForbidden, Message: "Not a room member"}
+	}
+	return room, nil
+}
+
+// RequireMember пропускает запрос, только если пользователь — участник комнаты из пути.
+func (a *RoomAccess) RequireMember(next http.Handler) http.Handler {
+	return a.require(next, false)
+}
+
+// RequireHost пропускает запрос, только если пользователь — хост комнаты из пути.
+func (a *RoomAccess) RequireHost(next http.Handler) http.Handler {
+	return a.require(next, true)
+}
+
+func (a *RoomAccess) require(next http.Handler, hostOnly bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		roomID, err := uuid.Parse(mux.Vars(r)["room_id"])
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid room ID")
+			return
+		}
+
+		userID, ok := userIDFromRequest(r)
+		if !ok {
+			writeJSONError(w, http.StatusUnauthorized, "Требуется авторизация")
+			return
+		}
+
+		room, err := a.Check(roomID, userID, hostOnly)
+		if err != nil {
+			if accessErr, ok := err.(*RoomAccessError); ok {
+				writeJSONError(w, accessErr.Status, accessErr.Message)
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, "Failed to check room access")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), roomContextKey, room)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetRoomFromRequest возвращает комнату, загруженную RoomAccess (nil, если маршрут не защищён).
+func GetRoomFromRequest(r *http.Request) *models.Room {
+	room, _ := r.Context().Value(roomContextKey).(*models.Room)
+	return room
+}
+
+// userIDFromRequest — пользователь из контекста AuthMiddleware или заголовок X-User-ID (как в handlers.UserIDFromRequest).
+func userIDFromRequest(r *http.Request) (uuid.UUID, bool) {
+	if user := GetUserFromRequest(r); user != nil {
+		return user.ID, true
+	}
+	id, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type fakeRoomAccessRepo struct {
+	rooms   map[uuid.UUID]*models.Room
+	members map[uuid.UUID][]uuid.UUID
+	err     error
+}
+
+func (f *fakeRoomAccessRepo) GetByID(id uuid.UUID) (*models.Room, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if room, ok := f.rooms[id]; ok {
+		return room, nil
+	}
+	return nil, repository.ErrRoomNotFound
+}
+
+func (f *fakeRoomAccessRepo) IsMember(roomID, userID uuid.UUID) (bool, error) {
+	for _, id := range f.members[roomID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newAccessRouter поднимает маршруты как в cmd/server: участникам — GET /rooms/{room_id}/matches, хосту — POST /rooms/{room_id}/start.
+func newAccessRouter(repo *fakeRoomAccessRepo) *mux.Router {
+	access := &RoomAccess{roomRepo: repo}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		room := GetRoomFromRequest(r)
+		if room == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(room.ID.String()))
+	})
+
+	router := mux.NewRouter()
+	router.Handle("/rooms/{room_id}/matches", access.RequireMember(ok)).Methods("GET")
+	router.Handle("/rooms/{room_id}/start", access.RequireHost(ok)).Methods("POST")
+	return router
+}
+
+func doRoomRequest(router http.Handler, method, path string, user *uuid.UUID) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if user != nil {
+		req = req.WithContext(context.WithValue(req.Context(), UserContextKey, &models.User{ID: *user}))
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRoomAccess(t *testing.T) {
+	roomID, hostID, memberID, strangerID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	router := newAccessRouter(&fakeRoomAccessRepo{
+		rooms:   map[uuid.UUID]*models.Room{roomID: {ID: roomID, HostID: hostID}},
+		members: map[uuid.UUID][]uuid.UUID{roomID: {hostID, memberID}},
+	})
+	matches := "/rooms/" + roomID.String() + "/matches"
+	start := "/rooms/" + roomID.String() + "/start"
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		user    *uuid.UUID
+		want    int
+		wantErr string
+	}{
+		{"member reads room", "GET", matches, &memberID, http.StatusOK, ""},
+		{"host reads room", "GET", matches, &hostID, http.StatusOK, ""},
+		{"stranger is forbidden", "GET", matches, &strangerID, http.StatusForbidden, "Not a room member"},
+		{"unknown room", "GET", "/rooms/" + uuid.New().String() + "/matches", &memberID, http.StatusNotFound, "Room not found"},
+		{"invalid room id", "GET", "/rooms/not-a-uuid/matches", &memberID, http.StatusBadRequest, "Invalid room ID"},
+		{"anonymous", "GET", matches, nil, http.StatusUnauthorized, "Требуется авторизация"},
+		{"host starts room", "POST", start, &hostID, http.StatusOK, ""},
+		{"member cannot start room", "POST", start, &memberID, http.StatusForbidden, "Only room host can manage the room"},
+		{"stranger cannot start room", "POST", start, &strangerID, http.StatusForbidden, "Only room host can manage the room"},
+		{"host route on unknown room", "POST", "/rooms/" + uuid.New().String() + "/start", &hostID, http.StatusNotFound, "Room not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRoomRequest(router, tt.method, tt.path, tt.user)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.wantErr == "" {
+				if rec.Body.String() != roomID.String() {
+					t.Errorf("handler got room %q, want %s", rec.Body.String(), roomID)
+				}
+				return
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("error body is not JSON: %s", rec.Body.String())
+			}
+			if body["error"] != tt.wantErr {
+				t.Errorf("error = %q, want %q", body["error"], tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoomAccess_RepositoryErrorIsNotNotFound(t *testing.T) {
+	userID := uuid.New()
+	router := newAccessRouter(&fakeRoomAccessRepo{err: errors.New("failed to get room: connection refused")})
+
+	rec := doRoomRequest(router, "GET", "/rooms/"+uuid.New().String()+"/matches", &userID)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500 (body %s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRoomAccess_XUserIDHeader(t *testing.T) {
+	roomID, hostID := uuid.New(), uuid.New()
+	router := newAccessRouter(&fakeRoomAccessRepo{
+		rooms:   map[uuid.UUID]*models.Room{roomID: {ID: roomID, HostID: hostID}},
+		members: map[uuid.UUID][]uuid.UUID{roomID: {hostID}},
+	})
+
+	req := httptest.NewRequest("GET", "/rooms/"+roomID.String()+"/matches", nil)
+	req.Header.Set("X-User-ID", hostID.String())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+}
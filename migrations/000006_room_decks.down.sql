@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS room_decks;
+ALTER TABLE rooms DROP COLUMN IF EXISTS deck_seed;
+ALTER TABLE rooms DROP COLUMN IF EXISTS deck_mode;
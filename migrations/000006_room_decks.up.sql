@@ -0,0 +1,15 @@
+-- Колода комнаты: общий для всех участников перемешанный список фильмов
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS deck_mode VARCHAR(20) NOT NULL DEFAULT 'shared';
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS deck_seed BIGINT;
+
+CREATE TABLE IF NOT EXISTS room_decks (
+    room_id UUID NOT NULL,
+    movie_id UUID NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (room_id, movie_id),
+    UNIQUE (room_id, position),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_decks_room_position ON room_decks(room_id, position);
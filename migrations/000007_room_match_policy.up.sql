@@ -0,0 +1,4 @@
+-- Правило матча комнаты: unanimous | percent | quorum | majority_host_veto
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS match_policy VARCHAR(30) NOT NULL DEFAULT 'unanimous';
+-- Порог правила: процент лайков для percent, число лайков K для quorum
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS match_threshold INTEGER;
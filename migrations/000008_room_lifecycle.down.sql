@@ -0,0 +1,3 @@
+ALTER TABLE rooms DROP COLUMN IF EXISTS finish_reason;
+ALTER TABLE rooms DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE rooms DROP COLUMN IF EXISTS finish_on_match;
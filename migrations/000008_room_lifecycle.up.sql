@@ -0,0 +1,7 @@
+-- Жизненный цикл комнаты: завершение хостом, по первому матчу или по неактивности
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS finish_on_match BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP;
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS finish_reason VARCHAR(20);
+
+-- Исторические значения статуса, которые код никогда не выставлял
+UPDATE rooms SET status = 'finished' WHERE status = 'completed';
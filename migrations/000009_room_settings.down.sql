@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS room_join_requests;
+ALTER TABLE rooms DROP COLUMN IF EXISTS lock_after_start;
+ALTER TABLE rooms DROP COLUMN IF EXISTS require_approval;
+ALTER TABLE rooms DROP COLUMN IF EXISTS join_password_hash;
+ALTER TABLE rooms DROP COLUMN IF EXISTS is_private;
+ALTER TABLE rooms DROP COLUMN IF EXISTS max_members;
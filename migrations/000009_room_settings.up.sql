@@ -0,0 +1,18 @@
+-- Настройки комнаты: лимит участников, приватность, пароль, одобрение входа хостом, закрытие после старта
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS max_members INTEGER CHECK (max_members IS NULL OR max_members >= 2);
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS is_private BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS join_password_hash VARCHAR(255);
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS require_approval BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS lock_after_start BOOLEAN NOT NULL DEFAULT false;
+
+-- Очередь заявок на вход (require_approval)
+CREATE TABLE IF NOT EXISTS room_join_requests (
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP,
+    PRIMARY KEY (room_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_join_requests_pending ON room_join_requests(room_id) WHERE status = 'pending';
@@ -0,0 +1,5 @@
+-- Новые направления нельзя сохранить под старым CHECK: superlike считаем лайком, остальные удаляем
+UPDATE swipes SET direction = 'right' WHERE direction = 'superlike';
+DELETE FROM swipes WHERE direction IN ('maybe', 'seen');
+ALTER TABLE swipes DROP CONSTRAINT IF EXISTS swipes_direction_check;
+ALTER TABLE swipes ADD CONSTRAINT swipes_direction_check CHECK (direction IN ('left', 'right'));
@@ -0,0 +1,4 @@
+-- Новые направления свайпа: superlike (сильный лайк), maybe (вернуть позже), seen (уже смотрел)
+ALTER TABLE swipes DROP CONSTRAINT IF EXISTS swipes_direction_check;
+ALTER TABLE swipes ADD CONSTRAINT swipes_direction_check
+    CHECK (direction IN ('left', 'right', 'superlike', 'maybe', 'seen'));
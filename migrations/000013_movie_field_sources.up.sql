@@ -0,0 +1,9 @@
+-- Происхождение полей фильма, заполненных обогащением метаданных: какой провайдер и когда дал значение
+CREATE TABLE IF NOT EXISTS movie_field_sources (
+    movie_id UUID NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    field VARCHAR(50) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    external_id VARCHAR(100),
+    fetched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (movie_id, field)
+);
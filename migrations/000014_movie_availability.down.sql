@@ -0,0 +1,8 @@
+ALTER TABLE filters DROP COLUMN IF EXISTS platforms;
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS streaming_url JSONB;
+UPDATE movies m SET streaming_url = (
+    SELECT jsonb_agg(a.url ORDER BY a.created_at) FROM movie_availability a WHERE a.movie_id = m.id
+);
+
+DROP TABLE IF EXISTS movie_availability;
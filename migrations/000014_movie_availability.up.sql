@@ -0,0 +1,62 @@
+-- Где посмотреть фильм: платформа, ссылка, регион и условия (подписка, аренда, покупка).
+-- Заменяет movies.streaming_url — JSON-массив ссылок без платформы и цены.
+CREATE TABLE IF NOT EXISTS movie_availability (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    movie_id UUID NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    platform VARCHAR(50) NOT NULL,
+    url TEXT NOT NULL,
+    region VARCHAR(2) NOT NULL DEFAULT 'RU',
+    offer_type VARCHAR(20) NOT NULL DEFAULT 'subscription'
+        CHECK (offer_type IN ('subscription', 'rent', 'buy', 'free')),
+    price NUMERIC(10, 2),
+    currency VARCHAR(3),
+    last_checked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (movie_id, platform, region, offer_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_movie_availability_platform ON movie_availability(platform, movie_id);
+
+DROP TRIGGER IF EXISTS update_movie_availability_updated_at ON movie_availability;
+CREATE TRIGGER update_movie_availability_updated_at BEFORE UPDATE ON movie_availability
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Переносим ссылки из streaming_url. Create сериализовал строку повторно, поэтому встречается и
+-- JSON-массив, и строка с JSON-массивом внутри, и просто строка-ссылка.
+WITH links AS (
+    SELECT m.id AS movie_id,
+        CASE
+            WHEN jsonb_typeof(m.streaming_url) = 'array' THEN m.streaming_url
+            WHEN jsonb_typeof(m.streaming_url) = 'string' AND left(m.streaming_url #>> '{}', 1) = '['
+                THEN (m.streaming_url #>> '{}')::jsonb
+            WHEN jsonb_typeof(m.streaming_url) = 'string' THEN jsonb_build_array(m.streaming_url)
+            ELSE '[]'::jsonb
+        END AS items
+    FROM movies m
+    WHERE m.streaming_url IS NOT NULL
+), urls AS (
+    SELECT l.movie_id, COALESCE(e.value ->> 'url', e.value #>> '{}') AS url
+    FROM links l, jsonb_array_elements(l.items) e
+)
+INSERT INTO movie_availability (movie_id, platform, url)
+SELECT movie_id,
+    CASE
+        WHEN url ILIKE '%kinopoisk.ru%' OR url ILIKE '%hd.kinopoisk%' THEN 'kinopoisk'
+        WHEN url ILIKE '%okko.tv%' THEN 'okko'
+        WHEN url ILIKE '%ivi.ru%' THEN 'ivi'
+        WHEN url ILIKE '%start.ru%' THEN 'start'
+        WHEN url ILIKE '%wink.ru%' THEN 'wink'
+        WHEN url ILIKE '%premier.one%' THEN 'premier'
+        WHEN url ILIKE '%kion.ru%' THEN 'kion'
+        ELSE 'other'
+    END,
+    url
+FROM urls
+WHERE url LIKE 'http%'
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS streaming_url;
+
+-- Фильтр комнаты: только фильмы, доступные на выбранных платформах
+ALTER TABLE filters ADD COLUMN IF NOT EXISTS platforms JSONB;
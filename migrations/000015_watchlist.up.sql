@@ -0,0 +1,37 @@
+-- Список «посмотреть позже»: личный (user_id) или общий для комнаты (room_id)
+CREATE TABLE IF NOT EXISTS watchlist_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    movie_id UUID NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID REFERENCES rooms(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'match', 'almost_match')),
+    match_id UUID REFERENCES matches(id) ON DELETE SET NULL,
+    added_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((user_id IS NULL) <> (room_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_watchlist_items_user_movie ON watchlist_items(user_id, movie_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_watchlist_items_room_movie ON watchlist_items(room_id, movie_id) WHERE room_id IS NOT NULL;
+
+-- Журнал просмотров: что, когда и кто смотрел. Просмотренное больше не попадает в колоды участников
+CREATE TABLE IF NOT EXISTS watch_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    movie_id UUID NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    room_id UUID REFERENCES rooms(id) ON DELETE SET NULL,
+    match_id UUID REFERENCES matches(id) ON DELETE SET NULL,
+    watched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    logged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_watch_events_room ON watch_events(room_id, watched_at DESC);
+
+CREATE TABLE IF NOT EXISTS watch_event_attendees (
+    event_id UUID NOT NULL REFERENCES watch_events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watch_event_attendees_user ON watch_event_attendees(user_id);
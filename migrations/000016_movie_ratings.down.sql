@@ -0,0 +1,4 @@
+ALTER TABLE movies DROP COLUMN IF EXISTS community_votes;
+ALTER TABLE movies DROP COLUMN IF EXISTS community_rating;
+
+DROP TABLE IF EXISTS movie_ratings;
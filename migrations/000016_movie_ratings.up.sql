@@ -0,0 +1,24 @@
+-- Оценки фильмов после просмотра (1–10) и короткие рецензии. Одна оценка пользователя на фильм;
+-- match_id — матч, после которого фильм смотрели
+CREATE TABLE IF NOT EXISTS movie_ratings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    movie_id UUID NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    match_id UUID REFERENCES matches(id) ON DELETE SET NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 10),
+    review TEXT CHECK (char_length(review) <= 1000),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, movie_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_movie_ratings_movie ON movie_ratings(movie_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_movie_ratings_match ON movie_ratings(match_id);
+
+DROP TRIGGER IF EXISTS update_movie_ratings_updated_at ON movie_ratings;
+CREATE TRIGGER update_movie_ratings_updated_at BEFORE UPDATE ON movie_ratings
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Оценка сообщества рядом с IMDb и КП; пересчитывается при каждой оценке
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS community_rating NUMERIC(3, 1);
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS community_votes INTEGER NOT NULL DEFAULT 0;
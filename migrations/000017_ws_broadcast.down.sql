@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ws_broadcast_payloads;
+DROP TABLE IF EXISTS ws_room_seq;
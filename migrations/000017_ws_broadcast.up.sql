@@ -0,0 +1,15 @@
+-- Сквозной seq событий WebSocket по комнатам, общий для всех экземпляров сервера (WS_BROADCASTER=postgres)
+CREATE TABLE IF NOT EXISTS ws_room_seq (
+    room_id UUID PRIMARY KEY,
+    seq BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- События крупнее предела NOTIFY (8000 байт): в канал уходит только id строки
+CREATE TABLE IF NOT EXISTS ws_broadcast_payloads (
+    id BIGSERIAL PRIMARY KEY,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ws_broadcast_payloads_created ON ws_broadcast_payloads(created_at);
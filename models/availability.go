@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OfferType — на каких условиях фильм доступен на платформе
+type OfferType string
+
+const (
+	OfferSubscription OfferType = "subscription"
+	OfferRent         OfferType = "rent"
+	OfferBuy          OfferType = "buy"
+	OfferFree         OfferType = "free"
+)
+
+// Valid проверяет, что тип предложения известен
+func (t OfferType) Valid() bool {
+	switch t {
+	case OfferSubscription, OfferRent, OfferBuy, OfferFree:
+		return true
+	}
+	return false
+}
+
+// StreamingPlatforms — известные платформы; по ним фильтруется колода комнаты
+var StreamingPlatforms = []string{"kinopoisk", "okko", "ivi", "start", "wink", "premier", "kion", "amediateka", "more_tv", "youtube", "other"}
+
+// ValidPlatform проверяет, что платформа из списка StreamingPlatforms
+func ValidPlatform(platform string) bool {
+	for _, p := range StreamingPlatforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// MovieAvailability — где посмотреть фильм: платформа, регион и условия
+type MovieAvailability struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	MovieID       uuid.UUID  `json:"movie_id" db:"movie_id"`
+	Platform      string     `json:"platform" db:"platform"`
+	URL           string     `json:"url" db:"url"`
+	Region        string     `json:"region" db:"region"` // ISO 3166-1 alpha-2, по умолчанию RU
+	OfferType     OfferType  `json:"offer_type" db:"offer_type"`
+	Price         *float64   `json:"price,omitempty" db:"price"` // Для аренды и покупки
+	Currency      string     `json:"currency,omitempty" db:"currency"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty" db:"last_checked_at"` // Когда ссылку последний раз проверяли
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// AvailabilityRequest — добавление или правка предложения платформы (админ)
+type AvailabilityRequest struct {
+	Platform      string     `json:"platform"`
+	URL           string     `json:"url"`
+	Region        string     `json:"region,omitempty"`
+	OfferType     OfferType  `json:"offer_type,omitempty"`
+	Price         *float64   `json:"price,omitempty"`
+	Currency      string     `json:"currency,omitempty"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MergeMoviesRequest — слить дубль SourceID в фильм из пути; дубль удаляется
+type MergeMoviesRequest struct {
+	SourceID uuid.UUID `json:"source_id"`
+}
+
+// MergeMoviesResult — итог слияния: оставшийся фильм и сколько записей перенесено с дубля
+type MergeMoviesResult struct {
+	Movie          Movie `json:"movie"`
+	SwipesMoved    int   `json:"swipes_moved"`
+	SwipesDropped  int   `json:"swipes_dropped"` // Пользователь свайпнул оба фильма в одной комнате — остаётся свайп по основному
+	MatchesMoved   int   `json:"matches_moved"`
+	MatchesMerged  int   `json:"matches_merged"` // Матч по обоим фильмам в одной комнате — ссылки и отзывы переносятся на матч основного
+	PremieresMoved int   `json:"premieres_moved"`
+}
+
+// MoviePatch — правка одного фильма в пакетном изменении; nil-поля не меняются
+type MoviePatch struct {
+	ID         uuid.UUID `json:"id"`
+	Genres     *[]string `json:"genres,omitempty"` // Заменяет список жанров целиком
+	IMDbRating *float64  `json:"imdb_rating,omitempty"`
+	KPRating   *float64  `json:"kp_rating,omitempty"`
+}
+
+// BulkMoviePatchRequest — пакетная правка жанров и рейтингов
+type BulkMoviePatchRequest struct {
+	Movies []MoviePatch `json:"movies"`
+}
+
+// BulkMoviePatchResponse — сколько фильмов обновлено
+type BulkMoviePatchResponse struct {
+	Updated int `json:"updated"`
+}
+
+// DuplicateGroup — фильмы с одинаковым нормализованным названием и годом
+type DuplicateGroup struct {
+	Title  string  `json:"title"` // Нормализованное название
+	Year   int     `json:"year"`
+	Movies []Movie `json:"movies"` // От старых к новым: первый — кандидат в основной при слиянии
+}
+
+// FieldSource — откуда взято значение поля фильма при обогащении метаданных
+type FieldSource struct {
+	Field      string    `json:"field"`
+	Provider   string    `json:"provider"`              // omdb, kinopoisk, tmdb
+	ExternalID string    `json:"external_id,omitempty"` // ID фильма у провайдера
+	FetchedAt  time.Time `json:"fetched_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,7 @@ type Filter struct {
 	DurationMax *int       `json:"duration_max,omitempty" db:"duration_max"`
 	MinRating   *float64   `json:"min_rating,omitempty" db:"min_rating"` // Минимальный рейтинг (IMDb или КП)
 	Mood        string     `json:"mood,omitempty" db:"mood"`             // Настроение (романтика, комедия и т.д.)
+	Platforms   string     `json:"platforms,omitempty" db:"platforms"`   // JSON массив платформ: только фильмы, доступные хотя бы на одной
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 }
@@ -30,5 +32,25 @@ type CreateFilterRequest struct {
 	DurationMax *int      `json:"duration_max,omitempty"`
 	MinRating   *float64  `json:"min_rating,omitempty"`
 	Mood        string    `json:"mood,omitempty"`
+	Platforms   []string  `json:"platforms,omitempty"` // Из models.StreamingPlatforms
 }
 
+// MoodGenres сопоставляет настроение фильтра с жанрами каталога.
+// В таблице movies встречаются и русские (сиды), и английские (импорт из CSV) названия жанров — перечисляем оба варианта.
+var MoodGenres = map[string][]string{
+	"романтика":  {"романтика", "мелодрама", "Romance"},
+	"комедия":    {"комедия", "Comedy"},
+	"весело":     {"комедия", "мультфильм", "семейный", "Comedy", "Animation", "Family"},
+	"драма":      {"драма", "биография", "Drama", "Biography"},
+	"напряжение": {"триллер", "детектив", "криминал", "Thriller", "Mystery", "Crime"},
+	"страшно":    {"ужасы", "Horror"},
+	"экшен":      {"боевик", "приключения", "Action", "Adventure"},
+	"фантастика": {"фантастика", "фэнтези", "Sci-Fi", "Fantasy"},
+	"семейный":   {"семейный", "мультфильм", "Family", "Animation"},
+	"задуматься": {"драма", "история", "военный", "Drama", "History", "War"},
+}
+
+// GenresForMood возвращает жанры для настроения (без учёта регистра). nil — настроение неизвестно.
+func GenresForMood(mood string) []string {
+	return MoodGenres[strings.ToLower(strings.TrimSpace(mood))]
+}
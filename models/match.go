@@ -20,14 +20,42 @@ type MatchWithDetails struct {
 	Movie Movie   `json:"movie"`
 	Room  Room    `json:"room"`
 	Users []User  `json:"users"` // Пользователи, которые лайкнули фильм
+
+	WhereToWatch []MovieAvailability `json:"where_to_watch,omitempty"` // Бесплатные и по подписке — первыми
 }
 
-// AlmostMatch — фильм, который лайкнули все активные участники кроме одного (N-1).
+// AlmostMatch — фильм, которому до матча по правилу комнаты не хватает одного лайка (для unanimous — N-1).
 type AlmostMatch struct {
-	MovieID       uuid.UUID  `json:"movie_id"`
-	Movie         *Movie     `json:"movie"`
-	LikesCount    int        `json:"likes_count"`
-	MissingUserID *uuid.UUID `json:"missing_user_id,omitempty"`
+	MovieID         uuid.UUID  `json:"movie_id"`
+	Movie           *Movie     `json:"movie"`
+	LikesCount      int        `json:"likes_count"`
+	SuperlikesCount int        `json:"superlikes_count"`
+	Score           int        `json:"score"` // Лайки плюс бонус за суперлайки
+	MissingUserID   *uuid.UUID `json:"missing_user_id,omitempty"`
+}
+
+// MemberVote — как участник комнаты проголосовал за фильм. Direction пустой — ещё не свайпал.
+type MemberVote struct {
+	UserID    uuid.UUID      `json:"user_id"`
+	Username  string         `json:"username"`
+	Direction SwipeDirection `json:"direction,omitempty"`
+}
+
+// RankedMovie — фильм в рейтинге «лучшего компромисса» комнаты
+type RankedMovie struct {
+	Rank       int          `json:"rank"`
+	MovieID    uuid.UUID    `json:"movie_id"`
+	Movie      *Movie       `json:"movie"`
+	Score      float64      `json:"score"`      // 0..100, см. service.RankMovies
+	Matched    bool         `json:"matched"`    // Фильм уже матч комнаты
+	LikeRatio  float64      `json:"like_ratio"` // Доля лайков среди голосовавших (без seen)
+	Coverage   float64      `json:"coverage"`   // Доля активных участников, которые видели карточку
+	Likes      int          `json:"likes"`
+	Superlikes int          `json:"superlikes"`
+	Dislikes   int          `json:"dislikes"`
+	Maybe      int          `json:"maybe"`
+	Seen       int          `json:"seen"`
+	Votes      []MemberVote `json:"votes"`
 }
 
 // MatchNotification представляет уведомление о матче для WebSocket
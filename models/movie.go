@@ -8,21 +8,22 @@ import (
 
 // Movie представляет карточку фильма
 type Movie struct {
-	ID             uuid.UUID `json:"id" db:"id"`
-	Title          string    `json:"title" db:"title"`
-	TitleEn        string    `json:"title_en,omitempty" db:"title_en"`
-	PosterURL      string    `json:"poster_url" db:"poster_url"`
-	ComicPosterURL string    `json:"comic_poster_url,omitempty" db:"comic_poster_url"`
-	IMDbRating     *float64  `json:"imdb_rating,omitempty" db:"imdb_rating"`
-	KPRating       *float64  `json:"kp_rating,omitempty" db:"kp_rating"`
-	Genre          string    `json:"genre" db:"genre"` // JSON массив жанров
-	Year           int       `json:"year" db:"year"`
-	Duration       int       `json:"duration" db:"duration"` // в минутах
-	Description    string    `json:"description,omitempty" db:"description"`
-	TrailerURL     string    `json:"trailer_url,omitempty" db:"trailer_url"`
-	StreamingURL   string    `json:"streaming_url,omitempty" db:"streaming_url"` // JSON массив ссылок
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID `json:"id" db:"id"`
+	Title           string    `json:"title" db:"title"`
+	TitleEn         string    `json:"title_en,omitempty" db:"title_en"`
+	PosterURL       string    `json:"poster_url" db:"poster_url"`
+	ComicPosterURL  string    `json:"comic_poster_url,omitempty" db:"comic_poster_url"`
+	IMDbRating      *float64  `json:"imdb_rating,omitempty" db:"imdb_rating"`
+	KPRating        *float64  `json:"kp_rating,omitempty" db:"kp_rating"`
+	CommunityRating *float64  `json:"community_rating,omitempty" db:"community_rating"` // Средняя оценка пользователей KinoSwipe, 1–10
+	CommunityVotes  int       `json:"community_votes,omitempty" db:"community_votes"`
+	Genre           string    `json:"genre" db:"genre"` // JSON массив жанров
+	Year            int       `json:"year" db:"year"`
+	Duration        int       `json:"duration" db:"duration"` // в минутах
+	Description     string    `json:"description,omitempty" db:"description"`
+	TrailerURL      string    `json:"trailer_url,omitempty" db:"trailer_url"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // MovieCard представляет упрощенную карточку для отображения
@@ -36,4 +37,3 @@ type MovieCard struct {
 	Year       int       `json:"year"`
 	Duration   int       `json:"duration"`
 }
-
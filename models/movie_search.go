@@ -0,0 +1,84 @@
+package models
+
+import "kinoswipe/pagination"
+
+// MovieSearchSort — порядок результатов поиска по каталогу
+type MovieSearchSort string
+
+const (
+	MovieSearchSortRelevance MovieSearchSort = "relevance" // Сначала лучшие совпадения с запросом (по умолчанию при q)
+	MovieSearchSortRating    MovieSearchSort = "rating"    // По рейтингу IMDb (или КП), по убыванию (по умолчанию без q)
+	MovieSearchSortNewest    MovieSearchSort = "newest"    // Сначала новые по году выхода
+	MovieSearchSortOldest    MovieSearchSort = "oldest"    // Сначала старые по году выхода
+	MovieSearchSortTitle     MovieSearchSort = "title"     // По названию, А→Я
+)
+
+// Valid сообщает, известен ли порядок сортировки
+func (s MovieSearchSort) Valid() bool {
+	switch s {
+	case MovieSearchSortRelevance, MovieSearchSortRating, MovieSearchSortNewest, MovieSearchSortOldest, MovieSearchSortTitle:
+		return true
+	}
+	return false
+}
+
+// RatingBand — диапазон рейтинга для фасета: Min включительно, Max не включительно (nil — без верхней границы)
+type RatingBand struct {
+	Value string
+	Min   float64
+	Max   *float64
+}
+
+func ratingBound(v float64) *float64 { return &v }
+
+// RatingBands — фасет рейтинга, от высоких к низким. Рейтинг фильма — IMDb, если есть, иначе КП.
+// Фильмы без рейтинга попадают в RatingBandUnrated.
+var RatingBands = []RatingBand{
+	{Value: "9+", Min: 9},
+	{Value: "8-9", Min: 8, Max: ratingBound(9)},
+	{Value: "7-8", Min: 7, Max: ratingBound(8)},
+	{Value: "6-7", Min: 6, Max: ratingBound(7)},
+	{Value: "0-6", Min: 0, Max: ratingBound(6)},
+}
+
+const RatingBandUnrated = "unrated"
+
+// MovieSearchParams — параметры GET /movies/search. Внутри фасета значения объединяются через ИЛИ, фасеты — через И.
+type MovieSearchParams struct {
+	Query       string
+	Genres      []string
+	Decades     []int // 1990 — фильмы 1990–1999 годов
+	RatingBands []string
+	Sort        MovieSearchSort
+	Cursor      *pagination.Cursor // next_cursor предыдущей страницы; nil — первая страница
+	Limit       int
+}
+
+// FacetCount — значение фасета и число фильмов с ним
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// MovieSearchFacets — счётчики для чипов фильтра. Счётчики фасета учитывают запрос и остальные фасеты,
+// но не выбранные значения самого фасета — так видно, сколько добавит ещё один чип.
+type MovieSearchFacets struct {
+	Genres      []FacetCount `json:"genres"`
+	Decades     []FacetCount `json:"decades"`
+	RatingBands []FacetCount `json:"rating_bands"`
+}
+
+// MovieSearchResult — страница результатов поиска из репозитория
+type MovieSearchResult struct {
+	Movies     []Movie
+	Total      int
+	Facets     MovieSearchFacets
+	NextCursor string // Пусто — страниц больше нет
+}
+
+// MovieSearchPage — ответ GET /movies/search: общий конверт списков (items, next_cursor) и, сверх него, total и facets
+type MovieSearchPage struct {
+	*pagination.Envelope
+	Total  int               `json:"total"`
+	Facets MovieSearchFacets `json:"facets"`
+}
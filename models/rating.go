@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MovieRating — оценка фильма пользователем после просмотра (1–10) с короткой рецензией
+type MovieRating struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Username  string     `json:"username,omitempty"`
+	MovieID   uuid.UUID  `json:"movie_id" db:"movie_id"`
+	MatchID   *uuid.UUID `json:"match_id,omitempty" db:"match_id"` // Матч, после которого смотрели
+	Rating    int        `json:"rating" db:"rating"`
+	Review    string     `json:"review,omitempty" db:"review"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// RatingWithMovie — оценка вместе с фильмом (для профиля вкуса)
+type RatingWithMovie struct {
+	MovieRating
+	Movie Movie `json:"movie"`
+}
+
+// RateMovieRequest — оценка фильма из матча; повторная оценка заменяет прежнюю
+type RateMovieRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=10"`
+	Review string `json:"review,omitempty"`
+}
+
+const (
+	MinMovieRating  = 1
+	MaxMovieRating  = 10
+	MaxReviewLength = 1000 // Символов
+)
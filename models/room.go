@@ -15,15 +15,89 @@ const (
 	RoomStatusFinished RoomStatus = "finished" // Сеанс завершен
 )
 
+// RoomFinishReason — почему комната завершена
+type RoomFinishReason string
+
+const (
+	RoomFinishReasonHost    RoomFinishReason = "host"    // Хост завершил сеанс
+	RoomFinishReasonMatch   RoomFinishReason = "match"   // Первый матч (finish_on_match)
+	RoomFinishReasonExpired RoomFinishReason = "expired" // Комната простаивала дольше TTL
+)
+
+// DeckMode определяет, как участники проходят колоду комнаты
+type DeckMode string
+
+const (
+	DeckModeShared   DeckMode = "shared"   // Все видят фильмы в одном порядке
+	DeckModePersonal DeckMode = "personal" // У каждого свой порядок из той же колоды
+)
+
+// MatchPolicy определяет, когда фильм считается матчем комнаты
+type MatchPolicy string
+
+const (
+	MatchPolicyUnanimous        MatchPolicy = "unanimous"          // Лайкнули все активные участники
+	MatchPolicyPercent          MatchPolicy = "percent"            // Лайкнули не меньше MatchThreshold % активных участников
+	MatchPolicyQuorum           MatchPolicy = "quorum"             // Лайкнули не меньше MatchThreshold участников
+	MatchPolicyMajorityHostVeto MatchPolicy = "majority_host_veto" // Лайкнуло большинство, и хост не поставил дизлайк
+)
+
 // Room представляет виртуальную комнату для совместного выбора фильмов
 type Room struct {
-	ID        uuid.UUID  `json:"id" db:"id"`
-	Code      string     `json:"code" db:"code"`           // Уникальный код комнаты
-	HostID    uuid.UUID  `json:"host_id" db:"host_id"`     // ID создателя комнаты
-	Status    RoomStatus `json:"status" db:"status"`
-	FilterID  *uuid.UUID `json:"filter_id,omitempty" db:"filter_id"` // Опциональный фильтр
-	CreatedAt time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID    `json:"id" db:"id"`
+	Code           string       `json:"code" db:"code"`       // Уникальный код комнаты
+	HostID         uuid.UUID    `json:"host_id" db:"host_id"` // ID создателя комнаты
+	Status         RoomStatus   `json:"status" db:"status"`
+	FilterID       *uuid.UUID   `json:"filter_id,omitempty" db:"filter_id"` // Опциональный фильтр
+	DeckMode       DeckMode     `json:"deck_mode" db:"deck_mode"`
+	DeckSeed       *int64       `json:"-" db:"deck_seed"` // Зерно перемешивания колоды, задаётся при старте
+	MatchPolicy    MatchPolicy  `json:"match_policy" db:"match_policy"`
+	MatchThreshold *int         `json:"match_threshold,omitempty" db:"match_threshold"` // Процент (percent) или K (quorum)
+	FinishOnMatch  bool         `json:"finish_on_match" db:"finish_on_match"`           // Завершить комнату при первом матче
+	FinishedAt     *time.Time   `json:"finished_at,omitempty" db:"finished_at"`
+	FinishReason   string       `json:"finish_reason,omitempty" db:"finish_reason"`
+	Settings       RoomSettings `json:"settings"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// RoomSettings — настройки доступа к комнате, редактирует хост
+type RoomSettings struct {
+	MaxMembers      *int   `json:"max_members,omitempty" db:"max_members"` // nil — без ограничения
+	IsPrivate       bool   `json:"is_private" db:"is_private"`             // Не показывать в общем списке комнат
+	HasPassword     bool   `json:"has_password"`
+	PasswordHash    string `json:"-" db:"join_password_hash"`
+	RequireApproval bool   `json:"require_approval" db:"require_approval"` // Вход только после одобрения хостом
+	LockAfterStart  bool   `json:"lock_after_start" db:"lock_after_start"` // После старта новые участники не входят
+}
+
+// UpdateRoomSettingsRequest — изменение настроек комнаты; nil-поля не меняются.
+// Password: пустая строка снимает пароль.
+type UpdateRoomSettingsRequest struct {
+	MaxMembers      *int    `json:"max_members,omitempty"` // 0 снимает ограничение
+	IsPrivate       *bool   `json:"is_private,omitempty"`
+	Password        *string `json:"password,omitempty"`
+	RequireApproval *bool   `json:"require_approval,omitempty"`
+	LockAfterStart  *bool   `json:"lock_after_start,omitempty"`
+}
+
+// JoinRequestStatus — статус заявки на вход в комнату
+type JoinRequestStatus string
+
+const (
+	JoinRequestStatusPending  JoinRequestStatus = "pending"
+	JoinRequestStatusApproved JoinRequestStatus = "approved"
+	JoinRequestStatusRejected JoinRequestStatus = "rejected"
+)
+
+// RoomJoinRequest представляет заявку пользователя на вход в комнату с одобрением хоста
+type RoomJoinRequest struct {
+	RoomID    uuid.UUID         `json:"room_id" db:"room_id"`
+	UserID    uuid.UUID         `json:"user_id" db:"user_id"`
+	Username  string            `json:"username,omitempty"`
+	Status    JoinRequestStatus `json:"status" db:"status"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	DecidedAt *time.Time        `json:"decided_at,omitempty" db:"decided_at"`
 }
 
 // RoomWithDetails представляет комнату с дополнительной информацией
@@ -38,18 +112,53 @@ type RoomWithDetails struct {
 
 // CreateRoomRequest представляет запрос на создание комнаты
 type CreateRoomRequest struct {
-	FilterID *uuid.UUID `json:"filter_id,omitempty"`
+	FilterID       *uuid.UUID                 `json:"filter_id,omitempty"`
+	DeckMode       DeckMode                   `json:"deck_mode,omitempty"`    // "shared" (по умолчанию) или "personal"
+	MatchPolicy    MatchPolicy                `json:"match_policy,omitempty"` // По умолчанию unanimous
+	MatchThreshold *int                       `json:"match_threshold,omitempty"`
+	FinishOnMatch  bool                       `json:"finish_on_match,omitempty"`
+	Settings       *UpdateRoomSettingsRequest `json:"settings,omitempty"`
 }
 
 // JoinRoomRequest представляет запрос на присоединение к комнате
 type JoinRoomRequest struct {
-	Code string `json:"code" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+	Password string `json:"password,omitempty"` // Для комнат с паролем
 }
 
 // JoinRoomResponse — ответ при присоединении к комнате (комната + список участников)
 type JoinRoomResponse struct {
 	Room    Room   `json:"room"`
 	Members []User `json:"members"`
+	Pending bool   `json:"pending,omitempty"` // Заявка ждёт одобрения хоста; участником пользователь ещё не стал
+}
+
+// TransferHostRequest представляет запрос на передачу роли хоста участнику комнаты
+type TransferHostRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// MatchedMovie — матч комнаты вместе с фильмом
+type MatchedMovie struct {
+	Match
+	Movie Movie `json:"movie"`
+}
+
+// RoomSummary представляет итог сеанса комнаты
+type RoomSummary struct {
+	Room            Room           `json:"room"`
+	MembersCount    int            `json:"members_count"`
+	SwipesCount     int            `json:"swipes_count"`
+	Matches         []MatchedMovie `json:"matches"`
+	DurationSeconds int64          `json:"duration_seconds"` // От создания до завершения (или до текущего момента)
+}
+
+// DeckProgress — сколько карточек колоды участник уже прошёл. Направление свайпов не раскрывается;
+// отложенные (maybe) фильмы не считаются пройденными.
+type DeckProgress struct {
+	Swiped   int  `json:"swiped"`
+	Total    int  `json:"total"`
+	Finished bool `json:"finished"`
 }
 
 // RoomMember представляет связь пользователя с комнатой
@@ -10,10 +10,27 @@ import (
 type SwipeDirection string
 
 const (
-	SwipeDirectionLeft  SwipeDirection = "left"  // Не нравится
-	SwipeDirectionRight SwipeDirection = "right" // Нравится
+	SwipeDirectionLeft      SwipeDirection = "left"      // Не нравится
+	SwipeDirectionRight     SwipeDirection = "right"     // Нравится
+	SwipeDirectionSuperlike SwipeDirection = "superlike" // Очень хочу: считается лайком и поднимает фильм в рейтинге комнаты
+	SwipeDirectionMaybe     SwipeDirection = "maybe"     // Не решил: фильм вернётся в конце колоды
+	SwipeDirectionSeen      SwipeDirection = "seen"      // Уже смотрел: ни лайк, ни дизлайк
 )
 
+// Valid проверяет, что направление свайпа известно.
+func (d SwipeDirection) Valid() bool {
+	switch d {
+	case SwipeDirectionLeft, SwipeDirectionRight, SwipeDirectionSuperlike, SwipeDirectionMaybe, SwipeDirectionSeen:
+		return true
+	}
+	return false
+}
+
+// IsLike — свайп засчитывается как лайк (right или superlike).
+func (d SwipeDirection) IsLike() bool {
+	return d == SwipeDirectionRight || d == SwipeDirectionSuperlike
+}
+
 // Swipe представляет действие пользователя (свайп)
 type Swipe struct {
 	ID        uuid.UUID     `json:"id" db:"id"`
@@ -27,12 +44,23 @@ type Swipe struct {
 // CreateSwipeRequest представляет запрос на создание свайпа
 type CreateSwipeRequest struct {
 	MovieID   uuid.UUID     `json:"movie_id" binding:"required"`
-	Direction SwipeDirection `json:"direction" binding:"required,oneof=left right"`
+	Direction SwipeDirection `json:"direction" binding:"required,oneof=left right superlike maybe seen"`
 }
 
-// UndoSwipeRequest представляет запрос на отмену последнего свайпа
+// UndoSwipeRequest представляет запрос на отмену свайпа.
+// По умолчанию отменяется последний свайп; SwipeID — отменить конкретный свайп,
+// WithinSeconds — отменить все свайпы пользователя в комнате за последние N секунд.
 type UndoSwipeRequest struct {
-	RoomID uuid.UUID `json:"room_id" binding:"required"`
+	RoomID        uuid.UUID  `json:"room_id"`
+	SwipeID       *uuid.UUID `json:"swipe_id,omitempty"`
+	WithinSeconds int        `json:"within_seconds,omitempty"`
+}
+
+// UndoSwipeResponse представляет результат отмены свайпов
+type UndoSwipeResponse struct {
+	Message          string  `json:"message"`
+	Undone           []Swipe `json:"undone"`
+	RetractedMatches []Match `json:"retracted_matches,omitempty"`
 }
 
 // SwipeWithMovie представляет свайп с информацией о фильме
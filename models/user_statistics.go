@@ -3,8 +3,11 @@ package models
 // UserStatistics представляет статистику пользователя
 type UserStatistics struct {
 	TotalSwipes        int `json:"total_swipes"`         // Всего просмотрено фильмов (все свайпы)
-	LikedMovies        int `json:"liked_movies"`         // Лайкнуто фильмов
+	LikedMovies        int `json:"liked_movies"`         // Лайкнуто фильмов (включая суперлайки)
 	DislikedMovies     int `json:"disliked_movies"`      // Дизлайкнуто фильмов
+	SuperlikedMovies   int `json:"superliked_movies"`    // Суперлайков
+	MaybeMovies        int `json:"maybe_movies"`         // Отложено (maybe)
+	SeenMovies         int `json:"seen_movies"`          // Отмечено как уже просмотренные
 	TotalMatches       int `json:"total_matches"`        // Всего мэтчей (участие в комнатах где был мэтч)
 	RoomsCreated       int `json:"rooms_created"`        // Создано комнат
 	RoomsJoined        int `json:"rooms_joined"`         // Присоединился к комнатам
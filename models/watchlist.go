@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WatchlistSource — откуда фильм попал в список «посмотреть позже»
+type WatchlistSource string
+
+const (
+	WatchlistSourceManual      WatchlistSource = "manual"
+	WatchlistSourceMatch       WatchlistSource = "match"        // Из матча комнаты
+	WatchlistSourceAlmostMatch WatchlistSource = "almost_match" // Из «почти матчей» (не хватает одного лайка)
+)
+
+// WatchlistItem — фильм в списке «посмотреть позже»: личном (UserID) или общем для комнаты (RoomID)
+type WatchlistItem struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	MovieID   uuid.UUID       `json:"movie_id" db:"movie_id"`
+	UserID    *uuid.UUID      `json:"user_id,omitempty" db:"user_id"`
+	RoomID    *uuid.UUID      `json:"room_id,omitempty" db:"room_id"`
+	Source    WatchlistSource `json:"source" db:"source"`
+	MatchID   *uuid.UUID      `json:"match_id,omitempty" db:"match_id"`
+	AddedBy   *uuid.UUID      `json:"added_by,omitempty" db:"added_by"`
+	Note      string          `json:"note,omitempty" db:"note"`
+	Movie     *Movie          `json:"movie,omitempty"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AddToWatchlistRequest — добавление в список: по фильму или по матчу (тогда фильм берётся из матча)
+type AddToWatchlistRequest struct {
+	MovieID *uuid.UUID `json:"movie_id,omitempty"`
+	MatchID *uuid.UUID `json:"match_id,omitempty"`
+	Note    string     `json:"note,omitempty"`
+}
+
+// WatchAttendee — участник просмотра
+type WatchAttendee struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+// WatchEvent — запись «мы это посмотрели»: фильм, дата и кто смотрел
+type WatchEvent struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	MovieID   uuid.UUID       `json:"movie_id" db:"movie_id"`
+	RoomID    *uuid.UUID      `json:"room_id,omitempty" db:"room_id"`
+	MatchID   *uuid.UUID      `json:"match_id,omitempty" db:"match_id"`
+	WatchedAt time.Time       `json:"watched_at" db:"watched_at"`
+	LoggedBy  *uuid.UUID      `json:"logged_by,omitempty" db:"logged_by"`
+	Attendees []WatchAttendee `json:"attendees"`
+	Movie     *Movie          `json:"movie,omitempty"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// LogWatchedRequest — отметка просмотра. AttendeeIDs пустой — смотрел только автор записи;
+// в комнате можно указать других участников комнаты. WatchedAt пустой — сейчас.
+type LogWatchedRequest struct {
+	MovieID     *uuid.UUID  `json:"movie_id,omitempty"`
+	MatchID     *uuid.UUID  `json:"match_id,omitempty"`
+	WatchedAt   *time.Time  `json:"watched_at,omitempty"`
+	AttendeeIDs []uuid.UUID `json:"attendee_ids,omitempty"`
+}
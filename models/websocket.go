@@ -1,37 +1,150 @@
 package models
 
-// WebSocketMessage представляет общее сообщение для WebSocket
-type WebSocketMessage struct {
-	Type      string      `json:"type"`
-	Payload   interface{} `json:"payload,omitempty"`
-	Timestamp int64       `json:"timestamp"`
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WSProtocolVersion — версия протокола событий WebSocket; меняется при несовместимых изменениях конверта
+const WSProtocolVersion = 1
+
+// WSEvent — единый конверт всех сообщений сервера.
+// События комнаты нумеруются Seq — монотонно по комнате, начиная с 1; служебные сообщения
+// (hello, resync, pong, kicked, ack, error) и эфемерные typing/reaction приходят без seq.
+type WSEvent struct {
+	V         int             `json:"v"`
+	Type      string          `json:"type"`
+	RoomID    uuid.UUID       `json:"room_id"`
+	Seq       uint64          `json:"seq,omitempty"`
+	ID        string          `json:"id,omitempty"` // в ack/error — id команды клиента
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
 }
 
-// WebSocketMessageType определяет типы сообщений
+// WSClientMessage — команда клиента серверу. На каждую команду с id приходит ack или error с тем же id.
+type WSClientMessage struct {
+	Type string          `json:"type"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Типы событий WebSocket
 const (
-	WSMessageTypeMatch    = "match"
-	WSMessageTypeSwipe    = "swipe"
-	WSMessageTypeJoin     = "join"
-	WSMessageTypeLeave    = "leave"
-	WSMessageTypeError    = "error"
-	WSMessageTypePing     = "ping"
-	WSMessageTypePong     = "pong"
+	WSMessageTypeHello          = "hello"
+	WSMessageTypeResync         = "resync"
+	WSMessageTypeMatch          = "match"
+	WSMessageTypeMatchRetracted = "match_retracted"
+	WSMessageTypeRoomFinished   = "room_finished"
+	WSMessageTypeKicked         = "kicked"
+	WSMessageTypeHostChanged    = "host_changed"
+	WSMessageTypeJoin           = "join"
+	WSMessageTypeLeave          = "leave"
+	WSMessageTypePresence       = "presence"
+	WSMessageTypeProgress       = "progress"
+	WSMessageTypeRoomStarted    = "room_started"
+	WSMessageTypeTyping         = "typing"
+	WSMessageTypeReaction       = "reaction"
+	WSMessageTypeAck            = "ack"
+	WSMessageTypeError          = "error"
+	WSMessageTypePing           = "ping"
+	WSMessageTypePong           = "pong"
 )
 
-// SwipeNotification представляет уведомление о свайпе
-type SwipeNotification struct {
-	Type      string `json:"type"`
-	UserID    string `json:"user_id"`
-	MovieID   string `json:"movie_id"`
-	Direction string `json:"direction"`
-	Timestamp int64  `json:"timestamp"`
+// Команды клиента (кроме ping, typing и reaction — они же типы событий)
+const (
+	WSCommandSwipe     = "swipe"
+	WSCommandUndo      = "undo"
+	WSCommandStartRoom = "start_room"
+	WSCommandReady     = "ready"
+)
+
+// WSHello — первое сообщение после подключения: версия протокола, последний seq комнаты
+// и кто сейчас подключён
+type WSHello struct {
+	Protocol int          `json:"protocol"`
+	Seq      uint64       `json:"seq"`
+	Members  []WSPresence `json:"members"`
+}
+
+// WSResync — пропущенные события уже вытеснены из буфера (или seq из будущего):
+// клиенту нужно заново загрузить состояние комнаты через REST и продолжить с Seq
+type WSResync struct {
+	Since uint64 `json:"since"`
+	Seq   uint64 `json:"seq"`
+}
+
+// WSMember — участник в событиях join/leave. join приходит на первое соединение пользователя,
+// leave — когда закрылось последнее.
+type WSMember struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+// WSPresence — состояние подключённого участника: idle — давно ничего не делал,
+// finished — прошёл всю колоду, ready — готов к старту комнаты.
+// Событие presence приходит при изменении idle, finished или ready.
+type WSPresence struct {
+	UserID   uuid.UUID     `json:"user_id"`
+	Username string        `json:"username"`
+	Idle     bool          `json:"idle"`
+	Finished bool          `json:"finished"`
+	Ready    bool          `json:"ready"`
+	Progress *DeckProgress `json:"progress,omitempty"`
 }
 
-// JoinNotification представляет уведомление о присоединении
-type JoinNotification struct {
-	Type      string `json:"type"`
-	UserID    string `json:"user_id"`
-	Username  string `json:"username"`
-	Timestamp int64  `json:"timestamp"`
+// WSProgress — данные события progress: «Аня прошла 14 из 50»
+type WSProgress struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	DeckProgress
 }
 
+// WSHostChanged — данные события host_changed
+type WSHostChanged struct {
+	HostID uuid.UUID `json:"host_id"`
+}
+
+// WSError — данные ответа error на команду клиента
+type WSError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WSReadyCommand — данные команды ready; без data участник считается готовым
+type WSReadyCommand struct {
+	Ready *bool `json:"ready"`
+}
+
+// WSTypingCommand — данные команды typing
+type WSTypingCommand struct {
+	Typing bool `json:"typing"`
+}
+
+// WSReactionCommand — данные команды reaction: эмодзи, опционально к фильму
+type WSReactionCommand struct {
+	Emoji   string     `json:"emoji"`
+	MovieID *uuid.UUID `json:"movie_id,omitempty"`
+}
+
+// WSTyping — событие typing для остальных участников
+type WSTyping struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	Typing   bool      `json:"typing"`
+}
+
+// WSReaction — событие reaction для остальных участников
+type WSReaction struct {
+	UserID   uuid.UUID  `json:"user_id"`
+	Username string     `json:"username"`
+	Emoji    string     `json:"emoji"`
+	MovieID  *uuid.UUID `json:"movie_id,omitempty"`
+}
+
+// WSTicketResponse — одноразовый билет для подключения к WebSocket комнаты (?ticket=)
+type WSTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,181 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLimit — размер страницы, если клиент не передал limit
+	DefaultLimit = 50
+	// MaxLimit — больше этого за один запрос не отдаётся, даже если клиент просит
+	MaxLimit = 200
+)
+
+var (
+	// ErrInvalidCursor — курсор не разобрать или он выдан для другой сортировки
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidLimit — limit не число или меньше 1
+	ErrInvalidLimit = errors.New("invalid limit")
+)
+
+// Cursor — позиция keyset-пагинации: ключ сортировки и id последней записи страницы.
+// Клиенту отдаётся непрозрачной строкой (Encode), разбирается обратно через Decode.
+type Cursor struct {
+	Sort string    `json:"s,omitempty"` // Сортировка, для которой выдан курсор (если у списка их несколько)
+	Key  string    `json:"k,omitempty"` // Значение ключа сортировки в текстовом виде (TimeKey, FloatKey или строка)
+	ID   uuid.UUID `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode разбирает курсор из строки. Пустая строка — первая страница (nil, nil).
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func TimeKey(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
+
+func FloatKey(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+
+// Time возвращает ключ курсора как время (для списков, отсортированных по created_at).
+func (c *Cursor) Time() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, c.Key)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// Float возвращает ключ курсора как число (рейтинг, год, релевантность).
+func (c *Cursor) Float() (float64, error) {
+	f, err := strconv.ParseFloat(c.Key, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return f, nil
+}
+
+// Page — запрошенная страница списка.
+type Page struct {
+	Limit  int
+	Cursor *Cursor  // nil — первая страница
+	Fields []string // Проекция ответа (?fields=id,title); пусто — все поля
+}
+
+// FromRequest читает ?limit=, ?cursor= и ?fields=. limit больше MaxLimit урезается до MaxLimit.
+func FromRequest(r *http.Request, defaultLimit int) (Page, error) {
+	query := r.URL.Query()
+	page := Page{Limit: defaultLimit}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return page, ErrInvalidLimit
+		}
+		page.Limit = limit
+	}
+	if page.Limit > MaxLimit {
+		page.Limit = MaxLimit
+	}
+
+	cursor, err := Decode(query.Get("cursor"))
+	if err != nil {
+		return page, err
+	}
+	page.Cursor = cursor
+
+	for _, f := range strings.Split(query.Get("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			page.Fields = append(page.Fields, f)
+		}
+	}
+	return page, nil
+}
+
+// After возвращает условие «строго после курсора» для ORDER BY keyExpr, idExpr (оба по убыванию при desc)
+// и дописывает значения ключа и id в args. Нумерация плейсхолдеров продолжает уже имеющиеся.
+func After(keyExpr, idExpr string, desc bool, key interface{}, id uuid.UUID, args []interface{}) (string, []interface{}) {
+	cmp := ">"
+	if desc {
+		cmp = "<"
+	}
+	args = append(args, key, id)
+	return fmt.Sprintf("(%s, %s) %s ($%d, $%d)", keyExpr, idExpr, cmp, len(args)-1, len(args)), args
+}
+
+// Trim обрезает выборку из limit+1 записей до limit и, если записи остались, возвращает курсор на последнюю.
+func Trim[T any](items []T, limit int, cursorFor func(*T) Cursor) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	return items, cursorFor(&items[limit-1]).Encode()
+}
+
+// Envelope — ответ списочного эндпоинта.
+type Envelope struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"` // Пусто — это последняя страница
+}
+
+// NewEnvelope оборачивает страницу и применяет проекцию fields. nil-срез отдаётся как [].
+func NewEnvelope(items interface{}, next string, fields []string) (*Envelope, error) {
+	if v := reflect.ValueOf(items); !v.IsValid() || (v.Kind() == reflect.Slice && v.IsNil()) {
+		items = []interface{}{}
+	}
+	projected, err := Project(items, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{Items: projected, NextCursor: next}, nil
+}
+
+// Project оставляет в каждом элементе списка только JSON-поля из fields. Неизвестные поля игнорируются.
+func Project(items interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to project fields: %w", err)
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to project fields: %w", err)
+	}
+
+	projected := make([]map[string]json.RawMessage, len(rows))
+	for i, row := range rows {
+		projected[i] = make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := row[f]; ok {
+				projected[i][f] = v
+			}
+		}
+	}
+	return projected, nil
+}
@@ -0,0 +1,127 @@
+package pagination
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+	c := Cursor{Sort: "rating", Key: TimeKey(created), ID: uuid.New()}
+
+	decoded, err := Decode(c.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *decoded != c {
+		t.Errorf("got %+v, want %+v", decoded, c)
+	}
+	got, err := decoded.Time()
+	if err != nil || !got.Equal(created) {
+		t.Errorf("time key: got %v (%v), want %v", got, err, created)
+	}
+
+	f := Cursor{Key: FloatKey(7.3), ID: uuid.New()}
+	if v, err := f.Float(); err != nil || v != 7.3 {
+		t.Errorf("float key: got %v (%v), want 7.3", v, err)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	if c, err := Decode(""); c != nil || err != nil {
+		t.Errorf("empty cursor must mean first page, got %v, %v", c, err)
+	}
+	for _, s := range []string{"not base64!", "bm90IGpzb24", Cursor{Key: "x"}.Encode()} {
+		if _, err := Decode(s); err != ErrInvalidCursor {
+			t.Errorf("Decode(%q): expected ErrInvalidCursor, got %v", s, err)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantLimit int
+		wantErr   error
+		wantField []string
+	}{
+		{name: "defaults", query: "", wantLimit: 20},
+		{name: "explicit limit", query: "?limit=5", wantLimit: 5},
+		{name: "capped", query: "?limit=100000", wantLimit: MaxLimit},
+		{name: "bad limit", query: "?limit=abc", wantErr: ErrInvalidLimit},
+		{name: "zero limit", query: "?limit=0", wantErr: ErrInvalidLimit},
+		{name: "bad cursor", query: "?cursor=abc", wantErr: ErrInvalidCursor},
+		{name: "fields", query: "?fields=id,%20title,,year", wantLimit: 20, wantField: []string{"id", "title", "year"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := FromRequest(httptest.NewRequest("GET", "/items"+tt.query, nil), 20)
+			if err != tt.wantErr {
+				t.Fatalf("error: got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if page.Limit != tt.wantLimit {
+				t.Errorf("limit: got %d, want %d", page.Limit, tt.wantLimit)
+			}
+			if len(page.Fields) != len(tt.wantField) {
+				t.Fatalf("fields: got %v, want %v", page.Fields, tt.wantField)
+			}
+			for i := range page.Fields {
+				if page.Fields[i] != tt.wantField[i] {
+					t.Errorf("fields: got %v, want %v", page.Fields, tt.wantField)
+				}
+			}
+		})
+	}
+}
+
+type item struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+	Year  int       `json:"year"`
+}
+
+func TestTrim(t *testing.T) {
+	items := []item{{ID: uuid.New()}, {ID: uuid.New()}, {ID: uuid.New()}}
+	cursorFor := func(i *item) Cursor { return Cursor{ID: i.ID} }
+
+	page, next := Trim(items, 2, cursorFor)
+	if len(page) != 2 || next == "" {
+		t.Fatalf("expected 2 items and a cursor, got %d items, cursor %q", len(page), next)
+	}
+	c, _ := Decode(next)
+	if c.ID != items[1].ID {
+		t.Error("cursor must point at the last returned item")
+	}
+
+	if page, next := Trim(items, 3, cursorFor); len(page) != 3 || next != "" {
+		t.Errorf("last page must have no cursor, got %d items, cursor %q", len(page), next)
+	}
+}
+
+func TestNewEnvelope_Projection(t *testing.T) {
+	items := []item{{ID: uuid.New(), Title: "Alien", Year: 1979}}
+
+	env, err := NewEnvelope(items, "next", []string{"title", "unknown"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := json.Marshal(env)
+	if string(data) != `{"items":[{"title":"Alien"}],"next_cursor":"next"}` {
+		t.Errorf("unexpected body: %s", data)
+	}
+
+	var none []item
+	env, _ = NewEnvelope(none, "", nil)
+	data, _ = json.Marshal(env)
+	if string(data) != `{"items":[]}` {
+		t.Errorf("empty page must be an empty array, got %s", data)
+	}
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrAvailabilityNotFound — предложения с таким id нет
+	ErrAvailabilityNotFound = errors.New("availability not found")
+	// ErrAvailabilityExists — у фильма уже есть предложение той же платформы, региона и типа
+	ErrAvailabilityExists = errors.New("availability already exists")
+)
+
+// AvailabilityRepository — где посмотреть фильмы (таблица movie_availability)
+type AvailabilityRepository struct {
+	db *sql.DB
+}
+
+func NewAvailabilityRepository(db *sql.DB) *AvailabilityRepository {
+	return &AvailabilityRepository{db: db}
+}
+
+const availabilityColumns = `id, movie_id, platform, url, region, offer_type, price, COALESCE(currency, ''), last_checked_at, created_at, updated_at`
+
+func scanAvailability(row rowScanner, a *models.MovieAvailability) error {
+	var price sql.NullFloat64
+	var checked sql.NullTime
+	if err := row.Scan(&a.ID, &a.MovieID, &a.Platform, &a.URL, &a.Region, &a.OfferType, &price, &a.Currency, &checked, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return err
+	}
+	if price.Valid {
+		a.Price = &price.Float64
+	}
+	if checked.Valid {
+		a.LastCheckedAt = &checked.Time
+	}
+	return nil
+}
+
+// GetByMovieID возвращает предложения платформ для фильма; region пустой — все регионы.
+// Сначала бесплатные и по подписке, затем аренда и покупка.
+func (r *AvailabilityRepository) GetByMovieID(movieID uuid.UUID, region string) ([]models.MovieAvailability, error) {
+	rows, err := r.db.Query(`
+		SELECT `+availabilityColumns+`
+		FROM movie_availability
+		WHERE movie_id = $1 AND ($2 = '' OR region = $2)
+		ORDER BY CASE offer_type WHEN 'free' THEN 0 WHEN 'subscription' THEN 1 WHEN 'rent' THEN 2 ELSE 3 END,
+			price NULLS FIRST, platform`,
+		movieID, region,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+	defer rows.Close()
+
+	offers := []models.MovieAvailability{}
+	for rows.Next() {
+		var a models.MovieAvailability
+		if err := scanAvailability(rows, &a); err != nil {
+			return nil, fmt.Errorf("failed to scan availability: %w", err)
+		}
+		offers = append(offers, a)
+	}
+	return offers, rows.Err()
+}
+
+// availabilitySortKey — порядок предложений одним числом для keyset-пагинации: тип предложения
+// (бесплатно, подписка, аренда, покупка), внутри типа — цена, без цены — первыми. availabilitySortValue считает то же в Go.
+const availabilitySortKey = `(CASE offer_type WHEN 'free' THEN 0 WHEN 'subscription' THEN 1 WHEN 'rent' THEN 2 ELSE 3 END) * 1000000000 + COALESCE(price, -1)`
+
+func availabilitySortValue(a *models.MovieAvailability) float64 {
+	rank := 3
+	switch a.OfferType {
+	case models.OfferFree:
+		rank = 0
+	case models.OfferSubscription:
+		rank = 1
+	case models.OfferRent:
+		rank = 2
+	}
+	price := -1.0
+	if a.Price != nil {
+		price = *a.Price
+	}
+	return float64(rank)*1000000000 + price
+}
+
+// ListByMovieID возвращает страницу предложений фильма в порядке GetByMovieID и курсор следующей страницы.
+// Предложения одного типа с одной ценой идут по id, а не по платформе.
+func (r *AvailabilityRepository) ListByMovieID(movieID uuid.UUID, region string, page pagination.Page) ([]models.MovieAvailability, string, error) {
+	args := []interface{}{movieID, region}
+	where := "movie_id = $1 AND ($2 = '' OR region = $2)"
+	if page.Cursor != nil {
+		after, err := page.Cursor.Float()
+		if err != nil {
+			return nil, "", err
+		}
+		var cond string
+		cond, args = pagination.After(availabilitySortKey, "id", false, after, page.Cursor.ID, args)
+		where += " AND " + cond
+	}
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT %s
+		FROM movie_availability
+		WHERE %s
+		ORDER BY %s, id
+		LIMIT $%d`, availabilityColumns, where, availabilitySortKey, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get availability: %w", err)
+	}
+	defer rows.Close()
+
+	var offers []models.MovieAvailability
+	for rows.Next() {
+		var a models.MovieAvailability
+		if err := scanAvailability(rows, &a); err != nil {
+			return nil, "", fmt.Errorf("failed to scan availability: %w", err)
+		}
+		offers = append(offers, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to get availability: %w", err)
+	}
+
+	offers, next := pagination.Trim(offers, page.Limit, func(a *models.MovieAvailability) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.FloatKey(availabilitySortValue(a)), ID: a.ID}
+	})
+	return offers, next, nil
+}
+
+func (r *AvailabilityRepository) GetByID(id uuid.UUID) (*models.MovieAvailability, error) {
+	a := &models.MovieAvailability{}
+	err := scanAvailability(r.db.QueryRow(`SELECT `+availabilityColumns+` FROM movie_availability WHERE id = $1`, id), a)
+	if err == sql.ErrNoRows {
+		return nil, ErrAvailabilityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+	return a, nil
+}
+
+// Upsert добавляет предложение; если у фильма уже есть предложение той же платформы, региона и типа — обновляет его
+func (r *AvailabilityRepository) Upsert(a *models.MovieAvailability) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	err := r.db.QueryRow(`
+		INSERT INTO movie_availability (id, movie_id, platform, url, region, offer_type, price, currency, last_checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+		ON CONFLICT (movie_id, platform, region, offer_type) DO UPDATE SET
+			url = EXCLUDED.url, price = EXCLUDED.price, currency = EXCLUDED.currency,
+			last_checked_at = COALESCE(EXCLUDED.last_checked_at, movie_availability.last_checked_at)
+		RETURNING id, created_at, updated_at`,
+		a.ID, a.MovieID, a.Platform, a.URL, a.Region, a.OfferType, a.Price, a.Currency, a.LastCheckedAt,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save availability: %w", err)
+	}
+	return nil
+}
+
+// Update меняет предложение целиком. Если платформа, регион и тип совпали с другим предложением фильма — ErrAvailabilityExists.
+func (r *AvailabilityRepository) Update(a *models.MovieAvailability) error {
+	err := r.db.QueryRow(`
+		UPDATE movie_availability SET
+			platform = $2, url = $3, region = $4, offer_type = $5, price = $6,
+			currency = NULLIF($7, ''), last_checked_at = $8
+		WHERE id = $1
+		RETURNING updated_at`,
+		a.ID, a.Platform, a.URL, a.Region, a.OfferType, a.Price, a.Currency, a.LastCheckedAt,
+	).Scan(&a.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrAvailabilityNotFound
+	}
+	if isUniqueViolation(err) {
+		return ErrAvailabilityExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update availability: %w", err)
+	}
+	return nil
+}
+
+func (r *AvailabilityRepository) Delete(id uuid.UUID) error {
+	res, err := r.db.Exec(`DELETE FROM movie_availability WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete availability: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAvailabilityNotFound
+	}
+	return nil
+}
+
+// isUniqueViolation — ошибка Postgres о нарушении уникального индекса
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"kinoswipe/models"
+
+	"github.com/lib/pq"
+)
+
+// Курсор выдаётся по availabilitySortValue, поэтому он должен упорядочивать так же, как availabilitySortKey
+func TestAvailabilitySortValueOrder(t *testing.T) {
+	price := func(p float64) *float64 { return &p }
+	ordered := []models.MovieAvailability{
+		{OfferType: models.OfferFree},
+		{OfferType: models.OfferSubscription},
+		{OfferType: models.OfferSubscription, Price: price(299)},
+		{OfferType: models.OfferRent, Price: price(99)},
+		{OfferType: models.OfferRent, Price: price(149.5)},
+		{OfferType: models.OfferBuy, Price: price(0.99)},
+	}
+	for i := 1; i < len(ordered); i++ {
+		prev, cur := availabilitySortValue(&ordered[i-1]), availabilitySortValue(&ordered[i])
+		if prev >= cur {
+			t.Errorf("%s/%v (%v) must go before %s/%v (%v)", ordered[i-1].OfferType, ordered[i-1].Price, prev, ordered[i].OfferType, ordered[i].Price, cur)
+		}
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "23505"}, true},
+		{fmt.Errorf("failed to update availability: %w", &pq.Error{Code: "23505"}), true},
+		{&pq.Error{Code: "23503"}, false}, // внешний ключ — не дубль
+		{errors.New("connection refused"), false},
+		{nil, false},
+	}
+	for _, tc := range cases {
+		if got := isUniqueViolation(tc.err); got != tc.want {
+			t.Errorf("isUniqueViolation(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
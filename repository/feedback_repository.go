@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 
 	"github.com/google/uuid"
 )
@@ -93,17 +94,32 @@ func (r *FeedbackRepository) GetByID(id uuid.UUID) (*models.Feedback, error) {
 	return feedback, nil
 }
 
-func (r *FeedbackRepository) GetByRoomID(roomID uuid.UUID) ([]models.Feedback, error) {
-	query := `
+// GetByRoomID возвращает страницу отзывов комнаты, от новых к старым, и курсор следующей страницы.
+func (r *FeedbackRepository) GetByRoomID(roomID uuid.UUID, page pagination.Page) ([]models.Feedback, string, error) {
+	args := []interface{}{roomID}
+	where := "room_id = $1"
+	if page.Cursor != nil {
+		after, err := page.Cursor.Time()
+		if err != nil {
+			return nil, "", err
+		}
+		var cond string
+		cond, args = pagination.After("created_at", "id", true, after, page.Cursor.ID, args)
+		where += " AND " + cond
+	}
+	args = append(args, page.Limit+1)
+
+	query := fmt.Sprintf(`
 		SELECT id, user_id, room_id, match_id, time_spent, had_arguments, rating, comment, created_at
 		FROM feedbacks
-		WHERE room_id = $1
-		ORDER BY created_at DESC
-	`
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, where, len(args))
 
-	rows, err := r.db.Query(query, roomID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get feedbacks: %w", err)
+		return nil, "", fmt.Errorf("failed to get feedbacks: %w", err)
 	}
 	defer rows.Close()
 
@@ -125,7 +141,7 @@ func (r *FeedbackRepository) GetByRoomID(roomID uuid.UUID) ([]models.Feedback, e
 			&feedback.CreatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+			return nil, "", fmt.Errorf("failed to scan feedback: %w", err)
 		}
 
 		if userID.Valid {
@@ -148,6 +164,13 @@ func (r *FeedbackRepository) GetByRoomID(roomID uuid.UUID) ([]models.Feedback, e
 		feedbacks = append(feedbacks, feedback)
 	}
 
-	return feedbacks, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to get feedbacks: %w", err)
+	}
+
+	feedbacks, next := pagination.Trim(feedbacks, page.Limit, func(f *models.Feedback) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(f.CreatedAt), ID: f.ID}
+	})
+	return feedbacks, next, nil
 }
 
@@ -19,14 +19,17 @@ func NewFilterRepository(db *sql.DB) *FilterRepository {
 }
 
 func (r *FilterRepository) Create(filter *models.Filter) error {
-	var genresJSON sql.NullString
+	var genresJSON, platformsJSON sql.NullString
 	if filter.Genres != "" {
 		genresJSON = sql.NullString{String: filter.Genres, Valid: true}
 	}
+	if filter.Platforms != "" {
+		platformsJSON = sql.NullString{String: filter.Platforms, Valid: true}
+	}
 
 	query := `
-		INSERT INTO filters (id, room_id, genres, year_from, year_to, duration_min, duration_max, min_rating, mood)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO filters (id, room_id, genres, year_from, year_to, duration_min, duration_max, min_rating, mood, platforms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING created_at, updated_at
 	`
 
@@ -41,6 +44,7 @@ func (r *FilterRepository) Create(filter *models.Filter) error {
 		filter.DurationMax,
 		filter.MinRating,
 		filter.Mood,
+		platformsJSON,
 	).Scan(&filter.CreatedAt, &filter.UpdatedAt)
 
 	if err != nil {
@@ -52,11 +56,11 @@ func (r *FilterRepository) Create(filter *models.Filter) error {
 
 func (r *FilterRepository) GetByID(id uuid.UUID) (*models.Filter, error) {
 	filter := &models.Filter{}
-	var genresJSON sql.NullString
+	var genresJSON, platformsJSON sql.NullString
 	var roomID sql.NullString
 
 	query := `
-		SELECT id, room_id, genres, year_from, year_to, duration_min, duration_max, min_rating, mood, created_at, updated_at
+		SELECT id, room_id, genres, year_from, year_to, duration_min, duration_max, min_rating, mood, platforms, created_at, updated_at
 		FROM filters
 		WHERE id = $1
 	`
@@ -71,6 +75,7 @@ func (r *FilterRepository) GetByID(id uuid.UUID) (*models.Filter, error) {
 		&filter.DurationMax,
 		&filter.MinRating,
 		&filter.Mood,
+		&platformsJSON,
 		&filter.CreatedAt,
 		&filter.UpdatedAt,
 	)
@@ -90,17 +95,20 @@ func (r *FilterRepository) GetByID(id uuid.UUID) (*models.Filter, error) {
 	if genresJSON.Valid {
 		filter.Genres = genresJSON.String
 	}
+	if platformsJSON.Valid {
+		filter.Platforms = platformsJSON.String
+	}
 
 	return filter, nil
 }
 
 func (r *FilterRepository) GetByRoomID(roomID uuid.UUID) (*models.Filter, error) {
 	filter := &models.Filter{}
-	var genresJSON sql.NullString
+	var genresJSON, platformsJSON sql.NullString
 	var roomIDStr sql.NullString
 
 	query := `
-		SELECT id, room_id, genres, year_from, year_to, duration_min, duration_max, min_rating, mood, created_at, updated_at
+		SELECT id, room_id, genres, year_from, year_to, duration_min, duration_max, min_rating, mood, platforms, created_at, updated_at
 		FROM filters
 		WHERE room_id = $1
 		ORDER BY created_at DESC
@@ -117,6 +125,7 @@ func (r *FilterRepository) GetByRoomID(roomID uuid.UUID) (*models.Filter, error)
 		&filter.DurationMax,
 		&filter.MinRating,
 		&filter.Mood,
+		&platformsJSON,
 		&filter.CreatedAt,
 		&filter.UpdatedAt,
 	)
@@ -136,15 +145,21 @@ func (r *FilterRepository) GetByRoomID(roomID uuid.UUID) (*models.Filter, error)
 	if genresJSON.Valid {
 		filter.Genres = genresJSON.String
 	}
+	if platformsJSON.Valid {
+		filter.Platforms = platformsJSON.String
+	}
 
 	return filter, nil
 }
 
 func (r *FilterRepository) Update(filter *models.Filter) error {
-	var genresJSON sql.NullString
+	var genresJSON, platformsJSON sql.NullString
 	if filter.Genres != "" {
 		genresJSON = sql.NullString{String: filter.Genres, Valid: true}
 	}
+	if filter.Platforms != "" {
+		platformsJSON = sql.NullString{String: filter.Platforms, Valid: true}
+	}
 
 	query := `
 		UPDATE filters
@@ -155,6 +170,7 @@ func (r *FilterRepository) Update(filter *models.Filter) error {
 		    duration_max = COALESCE($5, duration_max),
 		    min_rating = COALESCE($6, min_rating),
 		    mood = COALESCE($7, mood),
+		    platforms = COALESCE($9, platforms),
 		    updated_at = NOW()
 		WHERE id = $8
 	`
@@ -169,6 +185,7 @@ func (r *FilterRepository) Update(filter *models.Filter) error {
 		filter.MinRating,
 		filter.Mood,
 		filter.ID,
+		platformsJSON,
 	)
 
 	if err != nil {
@@ -180,19 +197,28 @@ func (r *FilterRepository) Update(filter *models.Filter) error {
 
 // Helper functions для работы с JSON
 func GenresToJSON(genres []string) string {
-	if len(genres) == 0 {
+	return StringsToJSON(genres)
+}
+
+func JSONToGenres(jsonStr string) ([]string, error) {
+	return JSONToStrings(jsonStr)
+}
+
+// StringsToJSON сериализует список строк (жанры, платформы) в JSON-массив; пустой список — пустая строка.
+func StringsToJSON(values []string) string {
+	if len(values) == 0 {
 		return ""
 	}
-	data, _ := json.Marshal(genres)
+	data, _ := json.Marshal(values)
 	return string(data)
 }
 
-func JSONToGenres(jsonStr string) ([]string, error) {
+// JSONToStrings разбирает JSON-массив строк; пустая строка — пустой список.
+func JSONToStrings(jsonStr string) ([]string, error) {
 	if jsonStr == "" {
 		return []string{}, nil
 	}
-	var genres []string
-	err := json.Unmarshal([]byte(jsonStr), &genres)
-	return genres, err
+	var values []string
+	err := json.Unmarshal([]byte(jsonStr), &values)
+	return values, err
 }
-
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 
 	"github.com/google/uuid"
 )
@@ -95,6 +96,54 @@ func (r *MatchRepository) GetByRoomID(roomID uuid.UUID) ([]models.Match, error)
 	return matches, nil
 }
 
+// ListByRoomID возвращает страницу матчей комнаты, от новых к старым, и курсор следующей страницы.
+// GetByRoomID отдаёт все матчи сразу — он нужен сервисам, которым важен полный список.
+func (r *MatchRepository) ListByRoomID(roomID uuid.UUID, page pagination.Page) ([]models.Match, string, error) {
+	args := []interface{}{roomID}
+	where := "room_id = $1"
+	if page.Cursor != nil {
+		after, err := page.Cursor.Time()
+		if err != nil {
+			return nil, "", err
+		}
+		var cond string
+		cond, args = pagination.After("created_at", "id", true, after, page.Cursor.ID, args)
+		where += " AND " + cond
+	}
+	args = append(args, page.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT id, room_id, movie_id, created_at
+		FROM matches
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []models.Match
+	for rows.Next() {
+		match := models.Match{}
+		if err := rows.Scan(&match.ID, &match.RoomID, &match.MovieID, &match.CreatedAt); err != nil {
+			return nil, "", fmt.Errorf("failed to scan match: %w", err)
+		}
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to get matches: %w", err)
+	}
+
+	matches, next := pagination.Trim(matches, page.Limit, func(m *models.Match) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(m.CreatedAt), ID: m.ID}
+	})
+	return matches, next, nil
+}
+
 func (r *MatchRepository) Exists(roomID, movieID uuid.UUID) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM matches WHERE room_id = $1 AND movie_id = $2)`
@@ -112,3 +161,11 @@ func (r *MatchRepository) CountByRoom(roomID uuid.UUID) (int, error) {
 	return count, nil
 }
 
+
+func (r *MatchRepository) Delete(id uuid.UUID) error {
+	_, err := r.db.Exec(`DELETE FROM matches WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete match: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+// Delete удаляет фильм. Свайпы, матчи и карточки колод по нему удаляются каскадом, премьеры отвязываются.
+func (r *MovieRepository) Delete(id uuid.UUID) error {
+	res, err := r.db.Exec(`DELETE FROM movies WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete movie: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMovieNotFound
+	}
+	return nil
+}
+
+// Merge переносит всё, что ссылается на дубль sourceID, на фильм targetID и удаляет дубль.
+// Если в одной комнате есть записи по обоим фильмам (свайп, матч, карточка колоды), остаётся запись основного фильма;
+// ссылки, отзывы, оценки, записи списков и просмотры матча дубля переходят на матч основного. Из одинаковых предложений платформ остаётся предложение основного,
+// как и из записей одного списка «посмотреть позже» и из оценок одного пользователя; журнал просмотров переносится целиком,
+// оценка сообщества пересчитывается.
+// Пустые поля основного фильма заполняются из дубля.
+func (r *MovieRepository) Merge(targetID, sourceID uuid.UUID) (*models.MergeMoviesResult, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var found int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM movies WHERE id IN ($1, $2)`, targetID, sourceID).Scan(&found); err != nil {
+		return nil, fmt.Errorf("failed to check movies: %w", err)
+	}
+	if found != 2 {
+		return nil, ErrMovieNotFound
+	}
+
+	result := &models.MergeMoviesResult{}
+	exec := func(dst *int, what, query string) error {
+		res, err := tx.Exec(query, targetID, sourceID)
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", what, err)
+		}
+		if dst != nil {
+			n, _ := res.RowsAffected()
+			*dst = int(n)
+		}
+		return nil
+	}
+
+	for _, step := range mergeSteps(result) {
+		if err := exec(step.dst, step.what, step.query); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(refreshCommunityRatingQuery, targetID); err != nil {
+		return nil, fmt.Errorf("failed to refresh community rating: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	movie, err := r.GetByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+	result.Movie = *movie
+	return result, nil
+}
+
+type mergeStep struct {
+	dst   *int // nil — число строк не попадает в итог
+	what  string
+	query string
+}
+
+// repointMatchStep переводит ссылки table.match_id с матча дубля на матч основного фильма в той же комнате.
+func repointMatchStep(table string) mergeStep {
+	return mergeStep{what: "move " + table + " match references", query: `
+			UPDATE ` + table + ` x SET match_id = t.id
+			FROM matches s JOIN matches t ON t.room_id = s.room_id AND t.movie_id = $1
+			WHERE x.match_id = s.id AND s.movie_id = $2`}
+}
+
+// mergeSteps — запросы Merge по порядку ($1 — основной фильм, $2 — дубль). Счётчики пишутся в result.
+func mergeSteps(result *models.MergeMoviesResult) []mergeStep {
+	return []mergeStep{
+		{&result.SwipesDropped, "drop conflicting swipes", `
+			DELETE FROM swipes s
+			WHERE s.movie_id = $2 AND EXISTS (
+				SELECT 1 FROM swipes t WHERE t.movie_id = $1 AND t.user_id = s.user_id AND t.room_id = s.room_id
+			)`},
+		{&result.SwipesMoved, "move swipes", `UPDATE swipes SET movie_id = $1 WHERE movie_id = $2`},
+		// Всё, что ссылается на матч дубля, переходит на матч основного фильма до удаления матча:
+		// иначе каскад удалит ссылки, а оценки, списки и просмотры потеряют связь с матчем.
+		repointMatchStep("match_links"),
+		repointMatchStep("feedbacks"),
+		repointMatchStep("movie_ratings"),
+		repointMatchStep("watchlist_items"),
+		repointMatchStep("watch_events"),
+		{&result.MatchesMerged, "drop conflicting matches", `
+			DELETE FROM matches s
+			WHERE s.movie_id = $2 AND EXISTS (SELECT 1 FROM matches t WHERE t.movie_id = $1 AND t.room_id = s.room_id)`},
+		{&result.MatchesMoved, "move matches", `UPDATE matches SET movie_id = $1 WHERE movie_id = $2`},
+		{&result.PremieresMoved, "move premieres", `UPDATE premieres SET movie_id = $1 WHERE movie_id = $2`},
+		{nil, "drop conflicting deck cards", `
+			DELETE FROM room_decks s
+			WHERE s.movie_id = $2 AND EXISTS (SELECT 1 FROM room_decks t WHERE t.movie_id = $1 AND t.room_id = s.room_id)`},
+		{nil, "move deck cards", `UPDATE room_decks SET movie_id = $1 WHERE movie_id = $2`},
+		{nil, "drop conflicting availability", `
+			DELETE FROM movie_availability s
+			WHERE s.movie_id = $2 AND EXISTS (
+				SELECT 1 FROM movie_availability t
+				WHERE t.movie_id = $1 AND t.platform = s.platform AND t.region = s.region AND t.offer_type = s.offer_type
+			)`},
+		{nil, "move availability", `UPDATE movie_availability SET movie_id = $1 WHERE movie_id = $2`},
+		{nil, "drop conflicting watchlist items", `
+			DELETE FROM watchlist_items s
+			WHERE s.movie_id = $2 AND EXISTS (
+				SELECT 1 FROM watchlist_items t
+				WHERE t.movie_id = $1 AND (t.user_id = s.user_id OR t.room_id = s.room_id)
+			)`},
+		{nil, "move watchlist items", `UPDATE watchlist_items SET movie_id = $1 WHERE movie_id = $2`},
+		{nil, "move watch history", `UPDATE watch_events SET movie_id = $1 WHERE movie_id = $2`},
+		{nil, "drop conflicting ratings", `
+			DELETE FROM movie_ratings s
+			WHERE s.movie_id = $2 AND EXISTS (SELECT 1 FROM movie_ratings t WHERE t.movie_id = $1 AND t.user_id = s.user_id)`},
+		{nil, "move ratings", `UPDATE movie_ratings SET movie_id = $1 WHERE movie_id = $2`},
+		{nil, "fill empty fields", `
+			UPDATE movies t SET
+				title_en = COALESCE(NULLIF(t.title_en, ''), s.title_en),
+				poster_url = COALESCE(NULLIF(t.poster_url, ''), s.poster_url),
+				comic_poster_url = COALESCE(NULLIF(t.comic_poster_url, ''), s.comic_poster_url),
+				imdb_rating = COALESCE(t.imdb_rating, s.imdb_rating),
+				kp_rating = COALESCE(t.kp_rating, s.kp_rating),
+				genre = CASE WHEN t.genre IS NULL OR t.genre = '[]'::jsonb THEN s.genre ELSE t.genre END,
+				duration = COALESCE(NULLIF(t.duration, 0), s.duration),
+				description = COALESCE(NULLIF(t.description, ''), s.description),
+				trailer_url = COALESCE(NULLIF(t.trailer_url, ''), s.trailer_url),
+				updated_at = CURRENT_TIMESTAMP
+			FROM movies s
+			WHERE t.id = $1 AND s.id = $2`},
+		{nil, "delete duplicate", `DELETE FROM movies WHERE id = $2 AND $1 <> $2`},
+	}
+}
+
+// BulkPatch применяет правки жанров и рейтингов в одной транзакции.
+// Если какого-то фильма нет, ничего не меняется и возвращаются ID отсутствующих.
+func (r *MovieRepository) BulkPatch(patches []models.MoviePatch) (updated int, missing []uuid.UUID, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE movies SET
+			genre = COALESCE($2, genre),
+			imdb_rating = COALESCE($3, imdb_rating),
+			kp_rating = COALESCE($4, kp_rating),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	for _, p := range patches {
+		var genre interface{} // nil — NULL, жанры не меняются
+		if p.Genres != nil {
+			data, _ := json.Marshal(*p.Genres)
+			genre = string(data)
+		}
+		var imdb, kp sql.NullFloat64
+		if p.IMDbRating != nil {
+			imdb = sql.NullFloat64{Float64: *p.IMDbRating, Valid: true}
+		}
+		if p.KPRating != nil {
+			kp = sql.NullFloat64{Float64: *p.KPRating, Valid: true}
+		}
+
+		res, err := tx.Exec(query, p.ID, genre, imdb, kp)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to patch movie: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			missing = append(missing, p.ID)
+			continue
+		}
+		updated++
+	}
+	if len(missing) > 0 {
+		return 0, missing, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return updated, nil, nil
+}
+
+// GetAllForDedup возвращает весь каталог от старых фильмов к новым — для отчёта о дублях.
+func (r *MovieRepository) GetAllForDedup() ([]models.Movie, error) {
+	rows, err := r.db.Query(`SELECT ` + movieColumns("m") + ` FROM movies m ORDER BY m.created_at, m.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movies: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMovies(rows)
+}
+
+// SaveEnrichment записывает поля, заполненные обогащением метаданных, и их происхождение в одной транзакции.
+// Меняются только поля, которые заполняет обогащение; остальные правки фильма не затрагиваются.
+func (r *MovieRepository) SaveEnrichment(movie *models.Movie, sources []models.FieldSource) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE movies SET
+			title_en = NULLIF($2, ''), poster_url = $3, imdb_rating = $4, kp_rating = $5,
+			trailer_url = NULLIF($6, ''), description = NULLIF($7, ''), duration = $8,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		movie.ID, movie.TitleEn, movie.PosterURL, movie.IMDbRating, movie.KPRating,
+		movie.TrailerURL, movie.Description, movie.Duration,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update movie: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMovieNotFound
+	}
+
+	for _, s := range sources {
+		_, err := tx.Exec(`
+			INSERT INTO movie_field_sources (movie_id, field, provider, external_id, fetched_at)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+			ON CONFLICT (movie_id, field) DO UPDATE
+				SET provider = EXCLUDED.provider, external_id = EXCLUDED.external_id, fetched_at = EXCLUDED.fetched_at`,
+			movie.ID, s.Field, s.Provider, s.ExternalID, s.FetchedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save field source: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetFieldSources возвращает происхождение полей фильма, заполненных обогащением
+func (r *MovieRepository) GetFieldSources(movieID uuid.UUID) ([]models.FieldSource, error) {
+	rows, err := r.db.Query(`
+		SELECT field, provider, COALESCE(external_id, ''), fetched_at
+		FROM movie_field_sources WHERE movie_id = $1 ORDER BY field`, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get field sources: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []models.FieldSource{}
+	for rows.Next() {
+		var s models.FieldSource
+		if err := rows.Scan(&s.Field, &s.Provider, &s.ExternalID, &s.FetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan field source: %w", err)
+		}
+		sources = append(sources, s)
+	}
+	return sources, rows.Err()
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"kinoswipe/models"
+)
+
+// Порядок шагов Merge: конфликтующие записи дубля удаляются до переноса, а ссылки на матч дубля
+// переходят на матч основного фильма до того, как матч дубля удаляется (каскад или SET NULL).
+func TestMergeStepsOrder(t *testing.T) {
+	result := &models.MergeMoviesResult{}
+	steps := mergeSteps(result)
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		index[step.what] = i
+	}
+	at := func(what string) int {
+		t.Helper()
+		i, ok := index[what]
+		if !ok {
+			t.Fatalf("step %q missing", what)
+		}
+		return i
+	}
+
+	dropMatches := at("drop conflicting matches")
+	for _, table := range []string{"match_links", "feedbacks", "movie_ratings", "watchlist_items", "watch_events"} {
+		i := at("move " + table + " match references")
+		step := steps[i]
+		if i > dropMatches {
+			t.Errorf("%s are repointed after conflicting matches are dropped", table)
+		}
+		if !strings.Contains(step.query, "UPDATE "+table+" ") || !strings.Contains(step.query, "SET match_id = t.id") {
+			t.Errorf("%s repoint query = %s", table, step.query)
+		}
+	}
+
+	for _, pair := range [][2]string{
+		{"drop conflicting swipes", "move swipes"},
+		{"drop conflicting matches", "move matches"},
+		{"drop conflicting deck cards", "move deck cards"},
+		{"drop conflicting availability", "move availability"},
+		{"drop conflicting watchlist items", "move watchlist items"},
+		{"drop conflicting ratings", "move ratings"},
+	} {
+		if at(pair[0]) > at(pair[1]) {
+			t.Errorf("%q must run before %q", pair[0], pair[1])
+		}
+	}
+	if last := steps[len(steps)-1].what; last != "delete duplicate" {
+		t.Errorf("last step = %q, want delete duplicate", last)
+	}
+
+	// Счётчики итога привязаны к своим шагам
+	counters := map[string]*int{
+		"drop conflicting swipes":  &result.SwipesDropped,
+		"move swipes":              &result.SwipesMoved,
+		"drop conflicting matches": &result.MatchesMerged,
+		"move matches":             &result.MatchesMoved,
+		"move premieres":           &result.PremieresMoved,
+	}
+	for what, dst := range counters {
+		if steps[at(what)].dst != dst {
+			t.Errorf("step %q does not count into its result field", what)
+		}
+	}
+}
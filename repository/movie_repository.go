@@ -3,13 +3,20 @@ package repository
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// ErrMovieNotFound — фильма с таким id нет
+var ErrMovieNotFound = errors.New("movie not found")
+
 type MovieRepository struct {
 	db *sql.DB
 }
@@ -20,13 +27,12 @@ func NewMovieRepository(db *sql.DB) *MovieRepository {
 
 func (r *MovieRepository) Create(movie *models.Movie) error {
 	query := `
-		INSERT INTO movies (id, title, title_en, poster_url, comic_poster_url, imdb_rating, kp_rating, genre, year, duration, description, trailer_url, streaming_url)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		INSERT INTO movies (id, title, title_en, poster_url, comic_poster_url, imdb_rating, kp_rating, genre, year, duration, description, trailer_url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING created_at, updated_at
 	`
 
 	var titleEn, description, trailerURL, comicPosterURL sql.NullString
-	var streamingURL []byte // JSONB
 
 	if movie.TitleEn != "" {
 		titleEn = sql.NullString{String: movie.TitleEn, Valid: true}
@@ -40,13 +46,6 @@ func (r *MovieRepository) Create(movie *models.Movie) error {
 	if movie.ComicPosterURL != "" {
 		comicPosterURL = sql.NullString{String: movie.ComicPosterURL, Valid: true}
 	}
-	if len(movie.StreamingURL) > 0 {
-		data, _ := json.Marshal(movie.StreamingURL)
-		streamingURL = data
-	}
-	if streamingURL == nil {
-		streamingURL = []byte("null") // JSONB требует валидный JSON или NULL через sql.Null*
-	}
 
 	var genre []byte
 	if movie.Genre != "" && json.Valid([]byte(movie.Genre)) {
@@ -70,7 +69,6 @@ func (r *MovieRepository) Create(movie *models.Movie) error {
 		movie.Duration,
 		description,
 		trailerURL,
-		streamingURL,
 	).Scan(&movie.CreatedAt, &movie.UpdatedAt)
 
 	if err != nil {
@@ -82,47 +80,16 @@ func (r *MovieRepository) Create(movie *models.Movie) error {
 
 func (r *MovieRepository) GetByID(id uuid.UUID) (*models.Movie, error) {
 	movie := &models.Movie{}
-	query := `
-		SELECT id, title, title_en, poster_url, comic_poster_url, imdb_rating, kp_rating, genre, year, duration, description, trailer_url, streaming_url, created_at, updated_at
-		FROM movies WHERE id = $1
-	`
-
-	var titleEn, description, trailerURL, streamingURL, comicPosterURL sql.NullString
-	var genre []byte
-
-	err := r.db.QueryRow(query, id).Scan(
-		&movie.ID, &movie.Title, &titleEn, &movie.PosterURL, &comicPosterURL,
-		&movie.IMDbRating, &movie.KPRating, &genre, &movie.Year,
-		&movie.Duration, &description, &trailerURL, &streamingURL,
-		&movie.CreatedAt, &movie.UpdatedAt,
-	)
+	query := `SELECT ` + movieColumns("m") + ` FROM movies m WHERE m.id = $1`
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("movie not found")
+	err := scanMovie(r.db.QueryRow(query, id), movie)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMovieNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get movie: %w", err)
 	}
 
-	if titleEn.Valid {
-		movie.TitleEn = titleEn.String
-	}
-	if description.Valid {
-		movie.Description = description.String
-	}
-	if trailerURL.Valid {
-		movie.TrailerURL = trailerURL.String
-	}
-	if comicPosterURL.Valid {
-		movie.ComicPosterURL = comicPosterURL.String
-	}
-	if streamingURL.Valid {
-		_ = json.Unmarshal([]byte(streamingURL.String), &movie.StreamingURL)
-	}
-	if len(genre) > 0 {
-		movie.Genre = string(genre)
-	}
-
 	return movie, nil
 }
 
@@ -131,13 +98,12 @@ func (r *MovieRepository) Update(movie *models.Movie) error {
 		UPDATE movies SET
 			title = $1, title_en = $2, poster_url = $3, imdb_rating = $4, kp_rating = $5,
 			genre = $6, year = $7, duration = $8, description = $9, trailer_url = $10,
-			streaming_url = $11, comic_poster_url = $12, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $13
+			comic_poster_url = $11, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $12
 		RETURNING updated_at
 	`
 
 	var titleEn, description, trailerURL, comicPosterURL sql.NullString
-	var streamingURL []byte
 	if movie.TitleEn != "" {
 		titleEn = sql.NullString{String: movie.TitleEn, Valid: true}
 	}
@@ -150,12 +116,6 @@ func (r *MovieRepository) Update(movie *models.Movie) error {
 	if movie.ComicPosterURL != "" {
 		comicPosterURL = sql.NullString{String: movie.ComicPosterURL, Valid: true}
 	}
-	if movie.StreamingURL != "" {
-		streamingURL = []byte(movie.StreamingURL)
-	}
-	if streamingURL == nil {
-		streamingURL = []byte("null")
-	}
 	var genre []byte
 	if movie.Genre != "" && json.Valid([]byte(movie.Genre)) {
 		genre = []byte(movie.Genre)
@@ -166,7 +126,7 @@ func (r *MovieRepository) Update(movie *models.Movie) error {
 
 	err := r.db.QueryRow(query,
 		movie.Title, titleEn, movie.PosterURL, movie.IMDbRating, movie.KPRating,
-		genre, movie.Year, movie.Duration, description, trailerURL, streamingURL,
+		genre, movie.Year, movie.Duration, description, trailerURL,
 		comicPosterURL, movie.ID,
 	).Scan(&movie.UpdatedAt)
 	if err != nil {
@@ -183,64 +143,54 @@ func (r *MovieRepository) UpdateComicPoster(id uuid.UUID, comicPosterURL string)
 	return err
 }
 
-func (r *MovieRepository) GetAll(limit int) ([]models.Movie, error) {
-	query := `
-		SELECT id, title, title_en, poster_url, comic_poster_url, imdb_rating, kp_rating, genre, year, duration, description, trailer_url, streaming_url, created_at, updated_at
-		FROM movies
-		ORDER BY imdb_rating DESC NULLS LAST, created_at DESC
-		LIMIT $1
-	`
-
-	rows, err := r.db.Query(query, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get movies: %w", err)
-	}
-	defer rows.Close()
+// GetAll возвращает страницу каталога, от лучших по рейтингу IMDb к худшим (без рейтинга — в конце), и курсор следующей страницы.
+func (r *MovieRepository) GetAll(page pagination.Page) ([]models.Movie, string, error) {
+	const ratingKey = "COALESCE(m.imdb_rating, -1)::float8"
 
-	var movies []models.Movie
-	for rows.Next() {
-		movie := models.Movie{}
-		var titleEn, description, trailerURL, streamingURL, comicPosterURL sql.NullString
-		var genre []byte
-
-		err := rows.Scan(
-			&movie.ID, &movie.Title, &titleEn, &movie.PosterURL, &comicPosterURL,
-			&movie.IMDbRating, &movie.KPRating, &genre, &movie.Year,
-			&movie.Duration, &description, &trailerURL, &streamingURL,
-			&movie.CreatedAt, &movie.UpdatedAt,
-		)
+	var args []interface{}
+	where := ""
+	if page.Cursor != nil {
+		after, err := page.Cursor.Float()
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan movie: %w", err)
+			return nil, "", err
 		}
+		var cond string
+		cond, args = pagination.After(ratingKey, "m.id", true, after, page.Cursor.ID, args)
+		where = "WHERE " + cond
+	}
+	args = append(args, page.Limit+1)
 
-		if titleEn.Valid {
-			movie.TitleEn = titleEn.String
-		}
-		if description.Valid {
-			movie.Description = description.String
-		}
-		if trailerURL.Valid {
-			movie.TrailerURL = trailerURL.String
-		}
-		if comicPosterURL.Valid {
-			movie.ComicPosterURL = comicPosterURL.String
-		}
-		if streamingURL.Valid {
-			_ = json.Unmarshal([]byte(streamingURL.String), &movie.StreamingURL)
-		}
-		if len(genre) > 0 {
-			movie.Genre = string(genre)
-		}
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM movies m
+		%s
+		ORDER BY %s DESC, m.id DESC
+		LIMIT $%d
+	`, movieColumns("m"), where, ratingKey, len(args))
 
-		movies = append(movies, movie)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get movies: %w", err)
 	}
+	defer rows.Close()
 
-	return movies, nil
+	movies, err := scanMovies(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	movies, next := pagination.Trim(movies, page.Limit, func(m *models.Movie) pagination.Cursor {
+		rating := -1.0
+		if m.IMDbRating != nil {
+			rating = *m.IMDbRating
+		}
+		return pagination.Cursor{Key: pagination.FloatKey(rating), ID: m.ID}
+	})
+	return movies, next, nil
 }
 
 func (r *MovieRepository) GetNotSwipedByUser(roomID, userID uuid.UUID, limit int) ([]models.Movie, error) {
 	query := `
-		SELECT m.id, m.title, m.title_en, m.poster_url, m.comic_poster_url, m.imdb_rating, m.kp_rating, m.genre, m.year, m.duration, m.description, m.trailer_url, m.streaming_url, m.created_at, m.updated_at
+		SELECT ` + movieColumns("m") + `
 		FROM movies m
 		WHERE NOT EXISTS (
 			SELECT 1 FROM swipes s
@@ -258,43 +208,218 @@ func (r *MovieRepository) GetNotSwipedByUser(roomID, userID uuid.UUID, limit int
 	}
 	defer rows.Close()
 
-	var movies []models.Movie
+	return scanMovies(rows)
+}
+
+// GetDeckForUser возвращает фильмы комнаты, которые пользователь ещё не свайпнул и не смотрел, с учётом фильтра комнаты.
+// Отложенные (maybe) фильмы возвращаются после всех несвайпнутых.
+// filter может быть nil — тогда фильтр не применяется.
+func (r *MovieRepository) GetDeckForUser(roomID, userID uuid.UUID, filter *models.Filter, limit int) ([]models.Movie, error) {
+	args := []interface{}{roomID, userID}
+	conditions := []string{`NOT EXISTS (
+			SELECT 1 FROM swipes s
+			WHERE s.movie_id = m.id
+			AND s.room_id = $1
+			AND s.user_id = $2
+			AND s.direction <> 'maybe'
+		)`, `NOT EXISTS (
+			SELECT 1 FROM watch_events e
+			JOIN watch_event_attendees a ON a.event_id = e.id
+			WHERE e.movie_id = m.id AND a.user_id = $2
+		)`}
+	filterConditions, args := FilterConditions(filter, "m", args)
+	conditions = append(conditions, filterConditions...)
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM movies m
+		WHERE %s
+		ORDER BY EXISTS (
+			SELECT 1 FROM swipes s
+			WHERE s.movie_id = m.id AND s.room_id = $1 AND s.user_id = $2
+		), m.imdb_rating DESC NULLS LAST, m.created_at DESC
+		LIMIT $%d
+	`, movieColumns("m"), strings.Join(conditions, "\n\t\tAND "), len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deck: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMovies(rows)
+}
+
+// GetIDsByFilter возвращает ID фильмов, подходящих под фильтр, от лучших по рейтингу к худшим. Используется для сборки колоды комнаты.
+func (r *MovieRepository) GetIDsByFilter(filter *models.Filter, limit int) ([]uuid.UUID, error) {
+	conditions, args := FilterConditions(filter, "m", nil)
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`
+		SELECT m.id
+		FROM movies m
+		%s
+		ORDER BY m.imdb_rating DESC NULLS LAST, m.created_at DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
 	for rows.Next() {
-		movie := models.Movie{}
-		var titleEn, description, trailerURL, streamingURL, comicPosterURL sql.NullString
-		var genre []byte
-
-		err := rows.Scan(
-			&movie.ID, &movie.Title, &titleEn, &movie.PosterURL, &comicPosterURL,
-			&movie.IMDbRating, &movie.KPRating, &genre, &movie.Year,
-			&movie.Duration, &description, &trailerURL, &streamingURL,
-			&movie.CreatedAt, &movie.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan movie: %w", err)
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan movie id: %w", err)
 		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
 
-		if titleEn.Valid {
-			movie.TitleEn = titleEn.String
-		}
-		if description.Valid {
-			movie.Description = description.String
-		}
-		if trailerURL.Valid {
-			movie.TrailerURL = trailerURL.String
-		}
-		if comicPosterURL.Valid {
-			movie.ComicPosterURL = comicPosterURL.String
-		}
-		if streamingURL.Valid {
-			_ = json.Unmarshal([]byte(streamingURL.String), &movie.StreamingURL)
-		}
-		if len(genre) > 0 {
-			movie.Genre = string(genre)
+// GetByIDs возвращает фильмы в том же порядке, что и ids. Отсутствующие в БД пропускаются.
+func (r *MovieRepository) GetByIDs(ids []uuid.UUID) ([]models.Movie, error) {
+	if len(ids) == 0 {
+		return []models.Movie{}, nil
+	}
+
+	query := `
+		SELECT ` + movieColumns("m") + `
+		FROM movies m
+		WHERE m.id = ANY($1)
+	`
+
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+
+	rows, err := r.db.Query(query, pq.Array(strIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movies by ids: %w", err)
+	}
+	defer rows.Close()
+
+	found, err := scanMovies(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]models.Movie, len(found))
+	for _, m := range found {
+		byID[m.ID] = m
+	}
+	movies := make([]models.Movie, 0, len(ids))
+	for _, id := range ids {
+		if m, ok := byID[id]; ok {
+			movies = append(movies, m)
 		}
+	}
+	return movies, nil
+}
 
+// FilterConditions превращает поля фильтра комнаты в SQL-условия над таблицей movies (alias — её псевдоним в запросе).
+// Новые параметры дописываются в args, нумерация плейсхолдеров продолжает уже имеющиеся.
+// Жанры и настроение проверяются оператором ?| — он использует GIN-индекс idx_movies_genre.
+func FilterConditions(filter *models.Filter, alias string, args []interface{}) ([]string, []interface{}) {
+	if filter == nil {
+		return nil, args
+	}
+	var conditions []string
+	col := func(name string) string { return alias + "." + name }
+	add := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if genres, err := JSONToGenres(filter.Genres); err == nil && len(genres) > 0 {
+		add(col("genre")+" ?| $%d", pq.Array(genres))
+	}
+	if moodGenres := models.GenresForMood(filter.Mood); len(moodGenres) > 0 {
+		add(col("genre")+" ?| $%d", pq.Array(moodGenres))
+	}
+	if filter.YearFrom != nil {
+		add(col("year")+" >= $%d", *filter.YearFrom)
+	}
+	if filter.YearTo != nil {
+		add(col("year")+" <= $%d", *filter.YearTo)
+	}
+	if filter.DurationMin != nil {
+		add(col("duration")+" >= $%d", *filter.DurationMin)
+	}
+	if filter.DurationMax != nil {
+		add(col("duration")+" <= $%d", *filter.DurationMax)
+	}
+	if filter.MinRating != nil {
+		args = append(args, *filter.MinRating)
+		conditions = append(conditions, fmt.Sprintf("(%s >= $%d OR %s >= $%d)", col("imdb_rating"), len(args), col("kp_rating"), len(args)))
+	}
+	if platforms, err := JSONToStrings(filter.Platforms); err == nil && len(platforms) > 0 {
+		add("EXISTS (SELECT 1 FROM movie_availability ma WHERE ma.movie_id = "+col("id")+" AND ma.platform = ANY($%d))", pq.Array(platforms))
+	}
+	return conditions, args
+}
+
+// movieColumns — список колонок movies в порядке, который ожидает scanMovies.
+func movieColumns(alias string) string {
+	cols := []string{"id", "title", "title_en", "poster_url", "comic_poster_url", "imdb_rating", "kp_rating", "community_rating", "community_votes", "genre", "year", "duration", "description", "trailer_url", "created_at", "updated_at"}
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+	return strings.Join(cols, ", ")
+}
+
+func scanMovies(rows *sql.Rows) ([]models.Movie, error) {
+	var movies []models.Movie
+	for rows.Next() {
+		movie := models.Movie{}
+		if err := scanMovie(rows, &movie); err != nil {
+			return nil, err
+		}
 		movies = append(movies, movie)
 	}
 
-	return movies, nil
+	return movies, rows.Err()
+}
+
+// scanMovie читает колонки movieColumns, за которыми могут идти дополнительные колонки (extra).
+func scanMovie(row rowScanner, movie *models.Movie, extra ...interface{}) error {
+	var titleEn, description, trailerURL, comicPosterURL sql.NullString
+	var genre []byte
+
+	dest := []interface{}{
+		&movie.ID, &movie.Title, &titleEn, &movie.PosterURL, &comicPosterURL,
+		&movie.IMDbRating, &movie.KPRating, &movie.CommunityRating, &movie.CommunityVotes, &genre, &movie.Year,
+		&movie.Duration, &description, &trailerURL,
+		&movie.CreatedAt, &movie.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return fmt.Errorf("failed to scan movie: %w", err)
+	}
+
+	if titleEn.Valid {
+		movie.TitleEn = titleEn.String
+	}
+	if description.Valid {
+		movie.Description = description.String
+	}
+	if trailerURL.Valid {
+		movie.TrailerURL = trailerURL.String
+	}
+	if comicPosterURL.Valid {
+		movie.ComicPosterURL = comicPosterURL.String
+	}
+	if len(genre) > 0 {
+		movie.Genre = string(genre)
+	}
+
+	return nil
 }
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"kinoswipe/models"
+
+	"github.com/lib/pq"
+)
+
+func TestFilterConditions(t *testing.T) {
+	year1990, year1999 := 1990, 1999
+	minDuration, maxDuration := 80, 120
+	rating := 7.5
+
+	cases := []struct {
+		name      string
+		filter    *models.Filter
+		args      []interface{} // параметры запроса до фильтра
+		wantConds []string
+		wantArgs  []interface{}
+	}{
+		{name: "nil filter", filter: nil},
+		{name: "empty filter", filter: &models.Filter{}},
+		{
+			name:      "genres",
+			filter:    &models.Filter{Genres: `["драма","Drama"]`},
+			wantConds: []string{"m.genre ?| $1"},
+			wantArgs:  []interface{}{pq.Array([]string{"драма", "Drama"})},
+		},
+		{
+			name:   "broken genres JSON is ignored",
+			filter: &models.Filter{Genres: `драма`},
+		},
+		{
+			name:      "mood",
+			filter:    &models.Filter{Mood: " Страшно "},
+			wantConds: []string{"m.genre ?| $1"},
+			wantArgs:  []interface{}{pq.Array([]string{"ужасы", "Horror"})},
+		},
+		{
+			name:   "unknown mood is ignored",
+			filter: &models.Filter{Mood: "скучно"},
+		},
+		{
+			name:      "year and duration ranges",
+			filter:    &models.Filter{YearFrom: &year1990, YearTo: &year1999, DurationMin: &minDuration, DurationMax: &maxDuration},
+			wantConds: []string{"m.year >= $1", "m.year <= $2", "m.duration >= $3", "m.duration <= $4"},
+			wantArgs:  []interface{}{1990, 1999, 80, 120},
+		},
+		{
+			name:      "min rating matches IMDb or KP",
+			filter:    &models.Filter{MinRating: &rating},
+			wantConds: []string{"(m.imdb_rating >= $1 OR m.kp_rating >= $1)"},
+			wantArgs:  []interface{}{7.5},
+		},
+		{
+			name:      "platforms",
+			filter:    &models.Filter{Platforms: `["okko"]`},
+			wantConds: []string{"EXISTS (SELECT 1 FROM movie_availability ma WHERE ma.movie_id = m.id AND ma.platform = ANY($1))"},
+			wantArgs:  []interface{}{pq.Array([]string{"okko"})},
+		},
+		{
+			name:      "several platforms",
+			filter:    &models.Filter{Platforms: `["okko","ivi","kion"]`},
+			wantConds: []string{"EXISTS (SELECT 1 FROM movie_availability ma WHERE ma.movie_id = m.id AND ma.platform = ANY($1))"},
+			wantArgs:  []interface{}{pq.Array([]string{"okko", "ivi", "kion"})},
+		},
+		{
+			name:   "empty platforms list is ignored",
+			filter: &models.Filter{Platforms: `[]`},
+		},
+		{
+			name:   "broken platforms JSON is ignored",
+			filter: &models.Filter{Platforms: `okko`},
+		},
+		{
+			name:      "platforms after genres",
+			filter:    &models.Filter{Genres: `["драма"]`, Platforms: `["okko"]`},
+			wantConds: []string{"m.genre ?| $1", "EXISTS (SELECT 1 FROM movie_availability ma WHERE ma.movie_id = m.id AND ma.platform = ANY($2))"},
+			wantArgs:  []interface{}{pq.Array([]string{"драма"}), pq.Array([]string{"okko"})},
+		},
+		{
+			name:      "numbering continues existing args",
+			filter:    &models.Filter{Genres: `["комедия"]`, YearFrom: &year1990},
+			args:      []interface{}{"room"},
+			wantConds: []string{"m.genre ?| $2", "m.year >= $3"},
+			wantArgs:  []interface{}{"room", pq.Array([]string{"комедия"}), 1990},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conds, args := FilterConditions(tc.filter, "m", tc.args)
+			if !reflect.DeepEqual(conds, tc.wantConds) {
+				t.Errorf("conditions = %q, want %q", conds, tc.wantConds)
+			}
+			if !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tc.wantArgs)
+			}
+		})
+	}
+}
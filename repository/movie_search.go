@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// searchTSQuery — запрос пользователя в синтаксисе websearch сразу для русской и английской морфологии
+const searchTSQuery = "(websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1))"
+
+// searchSortKey — SQL-выражение ключа сортировки и направление; ключи приводятся к float8/text, чтобы курсор сравнивался точно
+func searchSortKey(sort models.MovieSearchSort) (expr string, desc bool) {
+	switch sort {
+	case models.MovieSearchSortRelevance:
+		return "ts_rank(m.search_vector, " + searchTSQuery + ")::float8", true
+	case models.MovieSearchSortNewest:
+		return "COALESCE(m.year, 0)::float8", true
+	case models.MovieSearchSortOldest:
+		return "COALESCE(m.year, 0)::float8", false
+	case models.MovieSearchSortTitle:
+		return "m.title", false
+	default:
+		return "COALESCE(m.imdb_rating, m.kp_rating, 0)::float8", true
+	}
+}
+
+// ratingBandExpr — SQL CASE, относящий фильм к значению фасета рейтинга
+func ratingBandExpr() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	rating := "COALESCE(m.imdb_rating, m.kp_rating)"
+	for _, band := range models.RatingBands {
+		cond := fmt.Sprintf("%s >= %s", rating, strconv.FormatFloat(band.Min, 'f', -1, 64))
+		if band.Max != nil {
+			cond += fmt.Sprintf(" AND %s < %s", rating, strconv.FormatFloat(*band.Max, 'f', -1, 64))
+		}
+		fmt.Fprintf(&b, " WHEN %s THEN '%s'", cond, band.Value)
+	}
+	fmt.Fprintf(&b, " ELSE '%s' END", models.RatingBandUnrated)
+	return b.String()
+}
+
+// searchConditions — условия поиска: текст и фасеты. skip — фасет, который не применяется (для подсчёта его значений).
+// Текст запроса, если он есть, всегда идёт первым параметром — на $1 ссылается searchTSQuery.
+func searchConditions(params *models.MovieSearchParams, skip string) ([]string, []interface{}) {
+	var args []interface{}
+	var conditions []string
+	add := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if params.Query != "" {
+		args = append(args, params.Query)
+		// Совпадение по подстроке названия — чтобы находились недописанные слова, которые FTS не распознаёт
+		args = append(args, "%"+escapeLike(params.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(m.search_vector @@ %s OR m.title ILIKE $%d OR m.title_en ILIKE $%d)", searchTSQuery, len(args), len(args)))
+	}
+	if skip != "genre" && len(params.Genres) > 0 {
+		add("m.genre ?| $%d", pq.Array(params.Genres))
+	}
+	if skip != "decade" && len(params.Decades) > 0 {
+		decades := make([]int64, len(params.Decades))
+		for i, d := range params.Decades {
+			decades[i] = int64(d)
+		}
+		add("(m.year / 10) * 10 = ANY($%d)", pq.Array(decades))
+	}
+	if skip != "rating" && len(params.RatingBands) > 0 {
+		add("("+ratingBandExpr()+") = ANY($%d)", pq.Array(params.RatingBands))
+	}
+	return conditions, args
+}
+
+// searchCursor — курсор на фильм с ключом сортировки key (текстом sort_key из выборки)
+func searchCursor(sort models.MovieSearchSort, key string, id uuid.UUID) pagination.Cursor {
+	return pagination.Cursor{Sort: string(sort), Key: key, ID: id}
+}
+
+// searchAfterCursor — условие «после курсора» для сортировки params.Sort. Курсор другой сортировки
+// или с нечисловым ключом у числовой сортировки — pagination.ErrInvalidCursor.
+func searchAfterCursor(params *models.MovieSearchParams, sortExpr string, desc bool, args []interface{}) (string, []interface{}, error) {
+	if params.Cursor.Sort != string(params.Sort) {
+		return "", nil, pagination.ErrInvalidCursor
+	}
+	var key interface{} = params.Cursor.Key
+	if params.Sort != models.MovieSearchSortTitle {
+		f, err := params.Cursor.Float()
+		if err != nil {
+			return "", nil, err
+		}
+		key = f
+	}
+	cond, args := pagination.After(sortExpr, "m.id", desc, key, params.Cursor.ID, args)
+	return cond, args, nil
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Search ищет фильмы по тексту и фасетам и возвращает страницу, общее число найденных и счётчики фасетов.
+// Пагинация — по курсору (ключ сортировки + id), поэтому страницы не съезжают при добавлении фильмов в каталог.
+func (r *MovieRepository) Search(params *models.MovieSearchParams) (*models.MovieSearchResult, error) {
+	if params.Query == "" && params.Sort == models.MovieSearchSortRelevance {
+		params.Sort = models.MovieSearchSortRating
+	}
+	sortExpr, desc := searchSortKey(params.Sort)
+	conditions, args := searchConditions(params, "")
+
+	result := &models.MovieSearchResult{Movies: []models.Movie{}}
+	countQuery := "SELECT COUNT(*) FROM movies m " + whereClause(conditions)
+	if err := r.db.QueryRow(countQuery, args...).Scan(&result.Total); err != nil {
+		return nil, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	order := "ASC"
+	if desc {
+		order = "DESC"
+	}
+	if params.Cursor != nil {
+		cond, cursorArgs, err := searchAfterCursor(params, sortExpr, desc, args)
+		if err != nil {
+			return nil, err
+		}
+		conditions, args = append(conditions, cond), cursorArgs
+	}
+	args = append(args, params.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s, %s AS sort_key
+		FROM movies m
+		%s
+		ORDER BY sort_key %s, m.id %s
+		LIMIT $%d
+	`, movieColumns("m"), sortExpr, whereClause(conditions), order, order, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search movies: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var movie models.Movie
+		var key sql.NullString
+		if err := scanMovie(rows, &movie, &key); err != nil {
+			return nil, err
+		}
+		keys[movie.ID] = key.String
+		result.Movies = append(result.Movies, movie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search movies: %w", err)
+	}
+	result.Movies, result.NextCursor = pagination.Trim(result.Movies, params.Limit, func(m *models.Movie) pagination.Cursor {
+		return searchCursor(params.Sort, keys[m.ID], m.ID)
+	})
+
+	if result.Facets.Genres, err = r.searchFacet(params, "genre",
+		"jsonb_array_elements_text(CASE WHEN jsonb_typeof(m.genre) = 'array' THEN m.genre ELSE '[]'::jsonb END)"); err != nil {
+		return nil, err
+	}
+	if result.Facets.Decades, err = r.searchFacet(params, "decade", "CASE WHEN m.year > 0 THEN ((m.year / 10) * 10)::text END"); err != nil {
+		return nil, err
+	}
+	if result.Facets.RatingBands, err = r.searchFacet(params, "rating", ratingBandExpr()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// searchFacet считает фильмы по значениям фасета (value — SQL-выражение значения, для жанров — set-returning).
+func (r *MovieRepository) searchFacet(params *models.MovieSearchParams, facet, value string) ([]models.FacetCount, error) {
+	conditions, args := searchConditions(params, facet)
+	query := fmt.Sprintf(`
+		SELECT v, COUNT(*) FROM (
+			SELECT %s AS v FROM movies m %s
+		) f
+		WHERE v IS NOT NULL AND v <> ''
+		GROUP BY v
+		ORDER BY COUNT(*) DESC, v
+	`, value, whereClause(conditions))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s facet: %w", facet, err)
+	}
+	defer rows.Close()
+
+	counts := []models.FacetCount{}
+	for rows.Next() {
+		var fc models.FacetCount
+		var v sql.NullString
+		if err := rows.Scan(&v, &fc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s facet: %w", facet, err)
+		}
+		fc.Value = v.String
+		counts = append(counts, fc)
+	}
+	return counts, rows.Err()
+}
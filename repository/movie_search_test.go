@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+
+	"github.com/google/uuid"
+)
+
+// Курсор, выданный по sort_key строки выборки, после Encode/Decode даёт условие с тем же ключом и id
+func TestSearchCursorRoundTrip(t *testing.T) {
+	cases := []struct {
+		sort    models.MovieSearchSort
+		key     string // sort_key, как его отдаёт Postgres в текстовом виде
+		wantKey interface{}
+		wantCmp string
+	}{
+		{models.MovieSearchSortRelevance, "0.0607927", 0.0607927, "<"},
+		{models.MovieSearchSortRating, "8.1", 8.1, "<"},
+		{models.MovieSearchSortNewest, "2015", 2015.0, "<"},
+		{models.MovieSearchSortOldest, "1999", 1999.0, ">"},
+		{models.MovieSearchSortTitle, "Амели", "Амели", ">"},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.sort), func(t *testing.T) {
+			id := uuid.New()
+			cursor, err := pagination.Decode(searchCursor(tc.sort, tc.key, id).Encode())
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			sortExpr, desc := searchSortKey(tc.sort)
+			params := &models.MovieSearchParams{Query: "кот", Sort: tc.sort, Cursor: cursor}
+			_, args := searchConditions(params, "")
+			cond, args, err := searchAfterCursor(params, sortExpr, desc, args)
+			if err != nil {
+				t.Fatalf("after cursor: %v", err)
+			}
+
+			// После текста запроса и шаблона ILIKE ($1, $2) идут ключ и id курсора
+			want := "(" + sortExpr + ", m.id) " + tc.wantCmp + " ($3, $4)"
+			if cond != want {
+				t.Errorf("cond = %s, want %s", cond, want)
+			}
+			if len(args) != 4 || args[2] != tc.wantKey || args[3] != id {
+				t.Errorf("args = %v, want key %v and id %s", args, tc.wantKey, id)
+			}
+		})
+	}
+}
+
+func TestSearchCursorRejectsForeignOrBrokenCursor(t *testing.T) {
+	sortExpr, desc := searchSortKey(models.MovieSearchSortRating)
+	cases := map[string]pagination.Cursor{
+		"other sort":      searchCursor(models.MovieSearchSortNewest, "2015", uuid.New()),
+		"non-numeric key": searchCursor(models.MovieSearchSortRating, "Амели", uuid.New()),
+	}
+	for name, cursor := range cases {
+		params := &models.MovieSearchParams{Sort: models.MovieSearchSortRating, Cursor: &cursor}
+		if _, _, err := searchAfterCursor(params, sortExpr, desc, nil); err != pagination.ErrInvalidCursor {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+// Счётчики фасета не учитывают выбранные значения самого фасета, но учитывают запрос и остальные фасеты
+func TestSearchConditionsSkipFacet(t *testing.T) {
+	params := &models.MovieSearchParams{
+		Query:       "кот",
+		Genres:      []string{"комедия"},
+		Decades:     []int{1990},
+		RatingBands: []string{"8-9"},
+	}
+
+	facets := map[string]string{
+		"genre":  "m.genre ?|",
+		"decade": "(m.year / 10) * 10 = ANY",
+		"rating": "CASE WHEN",
+	}
+	for skip, marker := range facets {
+		t.Run(skip, func(t *testing.T) {
+			conditions, args := searchConditions(params, skip)
+			if len(conditions) != 3 || len(args) != 4 {
+				t.Fatalf("conditions = %v, args = %d; want query and two other facets", conditions, len(args))
+			}
+			if !strings.Contains(conditions[0], "m.search_vector @@") {
+				t.Errorf("first condition = %s, want text query", conditions[0])
+			}
+
+			where := whereClause(conditions)
+			for other, otherMarker := range facets {
+				if other != skip && !strings.Contains(where, otherMarker) {
+					t.Errorf("facet %s missing: %s", other, where)
+				}
+			}
+			if strings.Contains(where, marker) {
+				t.Errorf("skipped facet %s still applied: %s", skip, where)
+			}
+			// Плейсхолдеры нумеруются подряд, без пропуска на месте исключённого фасета
+			for i := 1; i <= len(args); i++ {
+				if !strings.Contains(where, fmt.Sprintf("$%d", i)) {
+					t.Errorf("placeholder $%d missing: %s", i, where)
+				}
+			}
+		})
+	}
+
+	if conditions, args := searchConditions(params, ""); len(conditions) != 4 || len(args) != 5 {
+		t.Errorf("without skip: conditions = %d, args = %d; want 4 and 5", len(conditions), len(args))
+	}
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+
+	"github.com/google/uuid"
+)
+
+// ErrRatingNotFound — у пользователя нет оценки этого фильма
+var ErrRatingNotFound = errors.New("rating not found")
+
+// RatingRepository — оценки и рецензии фильмов после просмотра
+type RatingRepository struct {
+	db *sql.DB
+}
+
+func NewRatingRepository(db *sql.DB) *RatingRepository {
+	return &RatingRepository{db: db}
+}
+
+const ratingColumns = `r.id, r.user_id, COALESCE(u.username, ''), r.movie_id, r.match_id, r.rating, COALESCE(r.review, ''), r.created_at, r.updated_at`
+
+func ratingDest(rt *models.MovieRating) []interface{} {
+	return []interface{}{&rt.ID, &rt.UserID, &rt.Username, &rt.MovieID, &rt.MatchID, &rt.Rating, &rt.Review, &rt.CreatedAt, &rt.UpdatedAt}
+}
+
+// refreshCommunityRatingQuery пересчитывает оценку сообщества фильма $1
+const refreshCommunityRatingQuery = `
+	UPDATE movies SET community_rating = agg.avg, community_votes = agg.votes
+	FROM (SELECT ROUND(AVG(rating), 1) AS avg, COUNT(*) AS votes FROM movie_ratings WHERE movie_id = $1) agg
+	WHERE id = $1`
+
+// Upsert сохраняет оценку пользователя; повторная оценка того же фильма заменяет прежнюю.
+// Оценка сообщества фильма пересчитывается в той же транзакции.
+func (r *RatingRepository) Upsert(rating *models.MovieRating) error {
+	if rating.ID == uuid.Nil {
+		rating.ID = uuid.New()
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO movie_ratings (id, user_id, movie_id, match_id, rating, review)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT (user_id, movie_id) DO UPDATE SET
+			rating = EXCLUDED.rating, review = EXCLUDED.review,
+			match_id = COALESCE(EXCLUDED.match_id, movie_ratings.match_id)
+		RETURNING id, match_id, created_at, updated_at`,
+		rating.ID, rating.UserID, rating.MovieID, rating.MatchID, rating.Rating, rating.Review,
+	).Scan(&rating.ID, &rating.MatchID, &rating.CreatedAt, &rating.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save rating: %w", err)
+	}
+
+	if _, err := tx.Exec(refreshCommunityRatingQuery, rating.MovieID); err != nil {
+		return fmt.Errorf("failed to refresh community rating: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete убирает оценку пользователя и пересчитывает оценку сообщества.
+// Если оценки нет — ErrRatingNotFound.
+func (r *RatingRepository) Delete(userID, movieID uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM movie_ratings WHERE user_id = $1 AND movie_id = $2`, userID, movieID)
+	if err != nil {
+		return fmt.Errorf("failed to delete rating: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrRatingNotFound
+	}
+
+	if _, err := tx.Exec(refreshCommunityRatingQuery, movieID); err != nil {
+		return fmt.Errorf("failed to refresh community rating: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetByMatchID возвращает страницу оценок, поставленных после матча, — что думают участники комнаты
+func (r *RatingRepository) GetByMatchID(matchID uuid.UUID, page pagination.Page) ([]models.MovieRating, string, error) {
+	return r.list("r.match_id = $1", matchID, page)
+}
+
+// GetByMovieID возвращает страницу оценок фильма, от свежих к старым, и курсор следующей страницы
+func (r *RatingRepository) GetByMovieID(movieID uuid.UUID, page pagination.Page) ([]models.MovieRating, string, error) {
+	return r.list("r.movie_id = $1", movieID, page)
+}
+
+func (r *RatingRepository) list(where string, id uuid.UUID, page pagination.Page) ([]models.MovieRating, string, error) {
+	args := []interface{}{id}
+	if page.Cursor != nil {
+		after, err := page.Cursor.Time()
+		if err != nil {
+			return nil, "", err
+		}
+		var cond string
+		cond, args = pagination.After("r.updated_at", "r.id", true, after, page.Cursor.ID, args)
+		where += " AND " + cond
+	}
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT %s
+		FROM movie_ratings r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE %s
+		ORDER BY r.updated_at DESC, r.id DESC
+		LIMIT $%d`, ratingColumns, where, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get ratings: %w", err)
+	}
+	defer rows.Close()
+
+	var ratings []models.MovieRating
+	for rows.Next() {
+		var rt models.MovieRating
+		if err := rows.Scan(ratingDest(&rt)...); err != nil {
+			return nil, "", fmt.Errorf("failed to scan rating: %w", err)
+		}
+		ratings = append(ratings, rt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to get ratings: %w", err)
+	}
+
+	ratings, next := pagination.Trim(ratings, page.Limit, func(rt *models.MovieRating) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(rt.UpdatedAt), ID: rt.ID}
+	})
+	return ratings, next, nil
+}
+
+// GetUserRatings возвращает последние оценки пользователя вместе с фильмами — для профиля вкуса
+func (r *RatingRepository) GetUserRatings(userID uuid.UUID, limit int) ([]models.RatingWithMovie, error) {
+	rows, err := r.db.Query(`
+		SELECT `+movieColumns("m")+`, `+ratingColumns+`
+		FROM movie_ratings r
+		JOIN movies m ON m.id = r.movie_id
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.user_id = $1
+		ORDER BY r.updated_at DESC
+		LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user ratings: %w", err)
+	}
+	defer rows.Close()
+
+	var ratings []models.RatingWithMovie
+	for rows.Next() {
+		var item models.RatingWithMovie
+		if err := scanMovie(rows, &item.Movie, ratingDest(&item.MovieRating)...); err != nil {
+			return nil, fmt.Errorf("failed to scan rating: %w", err)
+		}
+		ratings = append(ratings, item)
+	}
+	return ratings, rows.Err()
+}
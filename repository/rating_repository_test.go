@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+
+	"github.com/google/uuid"
+)
+
+// recordedQuery — запрос, дошедший до драйвера, и его параметры
+type recordedQuery struct {
+	query string
+	args  []driver.Value
+}
+
+// recordingDB — драйвер database/sql без базы: запоминает запросы, Exec отвечает rowsAffected,
+// Query — строкой row (или пустой выборкой, если row == nil)
+type recordingDB struct {
+	queries      []recordedQuery
+	rowsAffected int64
+	columns      []string
+	row          []driver.Value
+	committed    bool
+	rolledBack   bool
+}
+
+func (d *recordingDB) open() *sql.DB                                { return sql.OpenDB(d) }
+func (d *recordingDB) Connect(context.Context) (driver.Conn, error) { return d, nil }
+func (d *recordingDB) Driver() driver.Driver                        { return nil }
+func (d *recordingDB) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{db: d, query: query}, nil
+}
+func (d *recordingDB) Close() error              { return nil }
+func (d *recordingDB) Begin() (driver.Tx, error) { return d, nil }
+func (d *recordingDB) Commit() error             { d.committed = true; return nil }
+func (d *recordingDB) Rollback() error           { d.rolledBack = true; return nil }
+
+type recordingStmt struct {
+	db    *recordingDB
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.queries = append(s.db.queries, recordedQuery{s.query, args})
+	return driver.RowsAffected(s.db.rowsAffected), nil
+}
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.queries = append(s.db.queries, recordedQuery{s.query, args})
+	return &recordingRows{columns: s.db.columns, row: s.db.row}, nil
+}
+
+type recordingRows struct {
+	columns []string
+	row     []driver.Value
+}
+
+func (r *recordingRows) Columns() []string { return r.columns }
+func (r *recordingRows) Close() error      { return nil }
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if r.row == nil {
+		return io.EOF
+	}
+	copy(dest, r.row)
+	r.row = nil
+	return nil
+}
+
+// Оценка сохраняется и оценка сообщества пересчитывается в одной транзакции
+func TestRatingUpsertRefreshesCommunityRating(t *testing.T) {
+	now := time.Now()
+	ratingID, matchID := uuid.New(), uuid.New()
+	db := &recordingDB{
+		columns: []string{"id", "match_id", "created_at", "updated_at"},
+		row:     []driver.Value{ratingID.String(), matchID.String(), now, now},
+	}
+	repo := NewRatingRepository(db.open())
+
+	rating := &models.MovieRating{UserID: uuid.New(), MovieID: uuid.New(), MatchID: &matchID, Rating: 8}
+	if err := repo.Upsert(rating); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	if len(db.queries) != 2 {
+		t.Fatalf("queries = %d, want insert and refresh", len(db.queries))
+	}
+	if q := db.queries[0].query; !strings.Contains(q, "ON CONFLICT (user_id, movie_id) DO UPDATE") {
+		t.Errorf("insert = %s, want upsert by user and movie", q)
+	}
+	refresh := db.queries[1]
+	if refresh.query != refreshCommunityRatingQuery || len(refresh.args) != 1 || refresh.args[0] != rating.MovieID.String() {
+		t.Errorf("refresh = %s %v, want community rating of movie %s", refresh.query, refresh.args, rating.MovieID)
+	}
+	if !db.committed {
+		t.Error("transaction not committed")
+	}
+	if rating.ID != ratingID || !rating.UpdatedAt.Equal(now) {
+		t.Errorf("rating = %+v, want fields from RETURNING", rating)
+	}
+}
+
+func TestRatingDelete(t *testing.T) {
+	userID, movieID := uuid.New(), uuid.New()
+
+	t.Run("refreshes community rating", func(t *testing.T) {
+		db := &recordingDB{rowsAffected: 1}
+		if err := NewRatingRepository(db.open()).Delete(userID, movieID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if len(db.queries) != 2 || db.queries[1].query != refreshCommunityRatingQuery {
+			t.Fatalf("queries = %v, want delete and refresh", db.queries)
+		}
+		if !db.committed {
+			t.Error("transaction not committed")
+		}
+	})
+
+	t.Run("missing rating", func(t *testing.T) {
+		db := &recordingDB{}
+		err := NewRatingRepository(db.open()).Delete(userID, movieID)
+		if !errors.Is(err, ErrRatingNotFound) {
+			t.Fatalf("err = %v, want ErrRatingNotFound", err)
+		}
+		// Без удалённой строки пересчитывать нечего, транзакция откатывается
+		if len(db.queries) != 1 || db.committed || !db.rolledBack {
+			t.Errorf("queries = %d, committed = %v, rolled back = %v", len(db.queries), db.committed, db.rolledBack)
+		}
+	})
+}
+
+// Оценка сообщества — средняя с одним знаком после запятой и число голосов по всем оценкам фильма
+func TestRefreshCommunityRatingQuery(t *testing.T) {
+	for _, part := range []string{
+		"community_rating = agg.avg",
+		"community_votes = agg.votes",
+		"ROUND(AVG(rating), 1)",
+		"COUNT(*)",
+		"FROM movie_ratings WHERE movie_id = $1",
+		"WHERE id = $1",
+	} {
+		if !strings.Contains(refreshCommunityRatingQuery, part) {
+			t.Errorf("query lacks %q:%s", part, refreshCommunityRatingQuery)
+		}
+	}
+}
+
+// Страница оценок матча: keyset по updated_at и id, LIMIT на одну строку больше страницы
+func TestRatingListByMatchQuery(t *testing.T) {
+	matchID := uuid.New()
+	after := pagination.Cursor{Key: pagination.TimeKey(time.Now()), ID: uuid.New()}
+	db := &recordingDB{}
+
+	ratings, next, err := NewRatingRepository(db.open()).GetByMatchID(matchID, pagination.Page{Limit: 20, Cursor: &after})
+	if err != nil || len(ratings) != 0 || next != "" {
+		t.Fatalf("ratings = %v, next = %q, err = %v", ratings, next, err)
+	}
+
+	q := db.queries[0]
+	for _, part := range []string{
+		"WHERE r.match_id = $1 AND (r.updated_at, r.id) < ($2, $3)",
+		"ORDER BY r.updated_at DESC, r.id DESC",
+		"LIMIT $4",
+	} {
+		if !strings.Contains(q.query, part) {
+			t.Errorf("query lacks %q:%s", part, q.query)
+		}
+	}
+	if len(q.args) != 4 || q.args[0] != matchID.String() || q.args[2] != after.ID.String() || q.args[3] != int64(21) {
+		t.Errorf("args = %v", q.args)
+	}
+
+	broken := pagination.Cursor{Key: "вчера", ID: uuid.New()}
+	if _, _, err := NewRatingRepository(db.open()).GetByMatchID(matchID, pagination.Page{Limit: 20, Cursor: &broken}); err != pagination.ErrInvalidCursor {
+		t.Errorf("broken cursor: err = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -2,13 +2,20 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 
 	"github.com/google/uuid"
 )
 
+// ErrRoomNotFound — комнаты с таким id или кодом нет
+var ErrRoomNotFound = errors.New("room not found")
+
 type RoomRepository struct {
 	db *sql.DB
 }
@@ -17,10 +24,88 @@ func NewRoomRepository(db *sql.DB) *RoomRepository {
 	return &RoomRepository{db: db}
 }
 
+// roomColumns — колонки rooms в порядке, который ожидает scanRoom.
+const roomColumns = `id, code, host_id, status, filter_id, deck_mode, deck_seed, match_policy, match_threshold, finish_on_match, finished_at, finish_reason, max_members, is_private, join_password_hash, require_approval, lock_after_start, created_at, updated_at`
+
+// rowScanner — общий интерфейс *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRoom(row rowScanner, room *models.Room) error {
+	var filterID sql.NullString
+	var deckSeed, matchThreshold sql.NullInt64
+	var finishedAt sql.NullTime
+	var finishReason, passwordHash sql.NullString
+	var maxMembers sql.NullInt64
+
+	err := row.Scan(
+		&room.ID,
+		&room.Code,
+		&room.HostID,
+		&room.Status,
+		&filterID,
+		&room.DeckMode,
+		&deckSeed,
+		&room.MatchPolicy,
+		&matchThreshold,
+		&room.FinishOnMatch,
+		&finishedAt,
+		&finishReason,
+		&maxMembers,
+		&room.Settings.IsPrivate,
+		&passwordHash,
+		&room.Settings.RequireApproval,
+		&room.Settings.LockAfterStart,
+		&room.CreatedAt,
+		&room.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if filterID.Valid {
+		fid, _ := uuid.Parse(filterID.String)
+		room.FilterID = &fid
+	}
+	if deckSeed.Valid {
+		seed := deckSeed.Int64
+		room.DeckSeed = &seed
+	}
+	if matchThreshold.Valid {
+		threshold := int(matchThreshold.Int64)
+		room.MatchThreshold = &threshold
+	}
+	if finishedAt.Valid {
+		room.FinishedAt = &finishedAt.Time
+	}
+	if finishReason.Valid {
+		room.FinishReason = finishReason.String
+	}
+	if maxMembers.Valid {
+		limit := int(maxMembers.Int64)
+		room.Settings.MaxMembers = &limit
+	}
+	if passwordHash.Valid && passwordHash.String != "" {
+		room.Settings.PasswordHash = passwordHash.String
+		room.Settings.HasPassword = true
+	}
+
+	return nil
+}
+
 func (r *RoomRepository) Create(room *models.Room) error {
+	if room.DeckMode == "" {
+		room.DeckMode = models.DeckModeShared
+	}
+	if room.MatchPolicy == "" {
+		room.MatchPolicy = models.MatchPolicyUnanimous
+	}
+
 	query := `
-		INSERT INTO rooms (id, code, host_id, status, filter_id)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO rooms (id, code, host_id, status, filter_id, deck_mode, match_policy, match_threshold, finish_on_match,
+			max_members, is_private, join_password_hash, require_approval, lock_after_start)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, $14)
 		RETURNING created_at, updated_at
 	`
 
@@ -31,6 +116,15 @@ func (r *RoomRepository) Create(room *models.Room) error {
 		room.HostID,
 		room.Status,
 		room.FilterID,
+		room.DeckMode,
+		room.MatchPolicy,
+		room.MatchThreshold,
+		room.FinishOnMatch,
+		room.Settings.MaxMembers,
+		room.Settings.IsPrivate,
+		room.Settings.PasswordHash,
+		room.Settings.RequireApproval,
+		room.Settings.LockAfterStart,
 	).Scan(&room.CreatedAt, &room.UpdatedAt)
 
 	if err != nil {
@@ -47,131 +141,88 @@ func (r *RoomRepository) Create(room *models.Room) error {
 
 func (r *RoomRepository) GetByID(id uuid.UUID) (*models.Room, error) {
 	room := &models.Room{}
-	var filterID sql.NullString
-
-	query := `
-		SELECT id, code, host_id, status, filter_id, created_at, updated_at
-		FROM rooms WHERE id = $1
-	`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&room.ID,
-		&room.Code,
-		&room.HostID,
-		&room.Status,
-		&filterID,
-		&room.CreatedAt,
-		&room.UpdatedAt,
-	)
+	query := `SELECT ` + roomColumns + ` FROM rooms WHERE id = $1`
 
+	err := scanRoom(r.db.QueryRow(query, id), room)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("room not found")
+		return nil, ErrRoomNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get room: %w", err)
 	}
 
-	if filterID.Valid {
-		fid, _ := uuid.Parse(filterID.String)
-		room.FilterID = &fid
-	}
-
 	return room, nil
 }
 
 func (r *RoomRepository) GetByCode(code string) (*models.Room, error) {
 	room := &models.Room{}
-	var filterID sql.NullString
 
 	// Поиск без учёта регистра (код может ввести в любом регистре)
-	query := `
-		SELECT id, code, host_id, status, filter_id, created_at, updated_at
-		FROM rooms WHERE UPPER(TRIM(code)) = UPPER(TRIM($1))
-	`
-
-	err := r.db.QueryRow(query, code).Scan(
-		&room.ID,
-		&room.Code,
-		&room.HostID,
-		&room.Status,
-		&filterID,
-		&room.CreatedAt,
-		&room.UpdatedAt,
-	)
+	query := `SELECT ` + roomColumns + ` FROM rooms WHERE UPPER(TRIM(code)) = UPPER(TRIM($1))`
 
+	err := scanRoom(r.db.QueryRow(query, code), room)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("room not found")
+		return nil, ErrRoomNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get room by code: %w", err)
 	}
 
-	if filterID.Valid {
-		fid, _ := uuid.Parse(filterID.String)
-		room.FilterID = &fid
-	}
-
 	return room, nil
 }
 
-func (r *RoomRepository) GetAll(status *models.RoomStatus, limit int) ([]models.Room, error) {
-	var query string
+// GetAll возвращает страницу публичных комнат, от новых к старым, и курсор следующей страницы;
+// приватные (settings.is_private) в список не попадают.
+func (r *RoomRepository) GetAll(status *models.RoomStatus, page pagination.Page) ([]models.Room, string, error) {
+	conditions := []string{"NOT is_private"}
 	var args []interface{}
-
 	if status != nil {
-		query = `
-			SELECT id, code, host_id, status, filter_id, created_at, updated_at
-			FROM rooms
-			WHERE status = $1
-			ORDER BY created_at DESC
-			LIMIT $2
-		`
-		args = []interface{}{*status, limit}
-	} else {
-		query = `
-			SELECT id, code, host_id, status, filter_id, created_at, updated_at
-			FROM rooms
-			ORDER BY created_at DESC
-			LIMIT $1
-		`
-		args = []interface{}{limit}
+		args = append(args, *status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if page.Cursor != nil {
+		after, err := page.Cursor.Time()
+		if err != nil {
+			return nil, "", err
+		}
+		var cond string
+		cond, args = pagination.After("created_at", "id", true, after, page.Cursor.ID, args)
+		conditions = append(conditions, cond)
 	}
+	args = append(args, page.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM rooms
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, roomColumns, strings.Join(conditions, " AND "), len(args))
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get rooms: %w", err)
+		return nil, "", fmt.Errorf("failed to get rooms: %w", err)
 	}
 	defer rows.Close()
 
 	var rooms []models.Room
 	for rows.Next() {
 		room := models.Room{}
-		var filterID sql.NullString
-
-		err := rows.Scan(
-			&room.ID,
-			&room.Code,
-			&room.HostID,
-			&room.Status,
-			&filterID,
-			&room.CreatedAt,
-			&room.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan room: %w", err)
-		}
-
-		if filterID.Valid {
-			fid, _ := uuid.Parse(filterID.String)
-			room.FilterID = &fid
+		if err := scanRoom(rows, &room); err != nil {
+			return nil, "", fmt.Errorf("failed to scan room: %w", err)
 		}
-
 		rooms = append(rooms, room)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to get rooms: %w", err)
+	}
 
-	return rooms, nil
+	rooms, next := pagination.Trim(rooms, page.Limit, func(room *models.Room) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(room.CreatedAt), ID: room.ID}
+	})
+	return rooms, next, nil
 }
-
 func (r *RoomRepository) AddMember(roomID, userID uuid.UUID) error {
 	query := `
 		INSERT INTO room_members (room_id, user_id)
@@ -247,3 +298,346 @@ func (r *RoomRepository) UpdateStatus(roomID uuid.UUID, status models.RoomStatus
 
 	return nil
 }
+
+// TransitionStatus переводит комнату из статуса from в статус to. false — комната уже не в статусе from
+// (например, её одновременно запустили по REST и WebSocket).
+func (r *RoomRepository) TransitionStatus(roomID uuid.UUID, from, to models.RoomStatus) (bool, error) {
+	query := `
+		UPDATE rooms
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3
+	`
+
+	res, err := r.db.Exec(query, to, roomID, from)
+	if err != nil {
+		return false, fmt.Errorf("failed to update room status: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update room status: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SaveDeck сохраняет колоду комнаты (порядок фильмов) и зерно перемешивания. Предыдущая колода заменяется.
+func (r *RoomRepository) SaveDeck(roomID uuid.UUID, seed int64, movieIDs []uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM room_decks WHERE room_id = $1`, roomID); err != nil {
+		return fmt.Errorf("failed to clear deck: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO room_decks (room_id, movie_id, position) VALUES ($1, $2, $3)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare deck insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, movieID := range movieIDs {
+		if _, err := stmt.Exec(roomID, movieID, i); err != nil {
+			return fmt.Errorf("failed to save deck: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE rooms SET deck_seed = $1, updated_at = NOW() WHERE id = $2`, seed, roomID); err != nil {
+		return fmt.Errorf("failed to save deck seed: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetDeck возвращает ID фильмов колоды комнаты в порядке позиций. Пустой список — колода ещё не собрана.
+func (r *RoomRepository) GetDeck(roomID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`SELECT movie_id FROM room_decks WHERE room_id = $1 ORDER BY position`, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deck: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan deck movie_id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Finish переводит комнату в статус finished. finished=false — комната уже была завершена ранее.
+func (r *RoomRepository) Finish(roomID uuid.UUID, reason models.RoomFinishReason) (finished bool, err error) {
+	query := `
+		UPDATE rooms
+		SET status = $1, finished_at = NOW(), finish_reason = $2, updated_at = NOW()
+		WHERE id = $3 AND status <> $1
+	`
+
+	res, err := r.db.Exec(query, models.RoomStatusFinished, reason, roomID)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish room: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to finish room: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetIdleRoomIDs возвращает незавершённые комнаты, в которых не было активности (обновлений, свайпов, входов) с момента before.
+func (r *RoomRepository) GetIdleRoomIDs(before time.Time) ([]uuid.UUID, error) {
+	query := `
+		SELECT r.id
+		FROM rooms r
+		WHERE r.status IN ('waiting', 'active')
+		AND GREATEST(
+			r.updated_at,
+			COALESCE((SELECT MAX(s.created_at) FROM swipes s WHERE s.room_id = r.id), r.updated_at),
+			COALESCE((SELECT MAX(rm.joined_at) FROM room_members rm WHERE rm.room_id = r.id), r.updated_at)
+		) < $1
+	`
+
+	rows, err := r.db.Query(query, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idle rooms: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan room id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// RemoveMember удаляет пользователя из участников комнаты.
+func (r *RoomRepository) RemoveMember(roomID, userID uuid.UUID) error {
+	res, err := r.db.Exec(`DELETE FROM room_members WHERE room_id = $1 AND user_id = $2`, roomID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("member not found")
+	}
+	return nil
+}
+
+// BanMember банит пользователя в комнате и удаляет его из участников и очереди заявок.
+func (r *RoomRepository) BanMember(roomID, userID, bannedBy uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO room_bans (room_id, user_id, banned_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (room_id, user_id) DO NOTHING
+	`
+	if _, err := tx.Exec(query, roomID, userID, bannedBy); err != nil {
+		return fmt.Errorf("failed to ban member: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM room_members WHERE room_id = $1 AND user_id = $2`, roomID, userID); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM room_join_requests WHERE room_id = $1 AND user_id = $2`, roomID, userID); err != nil {
+		return fmt.Errorf("failed to remove join request: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UnbanMember снимает бан; пользователь сможет снова войти по коду.
+func (r *RoomRepository) UnbanMember(roomID, userID uuid.UUID) error {
+	res, err := r.db.Exec(`DELETE FROM room_bans WHERE room_id = $1 AND user_id = $2`, roomID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unban member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("ban not found")
+	}
+	return nil
+}
+
+// IsBanned проверяет, забанен ли пользователь в комнате.
+func (r *RoomRepository) IsBanned(roomID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM room_bans WHERE room_id = $1 AND user_id = $2)`
+	if err := r.db.QueryRow(query, roomID, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check ban: %w", err)
+	}
+	return exists, nil
+}
+
+// SetHost передаёт роль хоста другому пользователю.
+func (r *RoomRepository) SetHost(roomID, hostID uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE rooms SET host_id = $1, updated_at = NOW() WHERE id = $2`, hostID, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to set room host: %w", err)
+	}
+	return nil
+}
+
+// UpdateSettings сохраняет настройки доступа комнаты.
+func (r *RoomRepository) UpdateSettings(roomID uuid.UUID, settings models.RoomSettings) error {
+	query := `
+		UPDATE rooms
+		SET max_members = $1, is_private = $2, join_password_hash = NULLIF($3, ''),
+			require_approval = $4, lock_after_start = $5, updated_at = NOW()
+		WHERE id = $6
+	`
+
+	_, err := r.db.Exec(query,
+		settings.MaxMembers,
+		settings.IsPrivate,
+		settings.PasswordHash,
+		settings.RequireApproval,
+		settings.LockAfterStart,
+		roomID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update room settings: %w", err)
+	}
+
+	return nil
+}
+
+// IsMember проверяет, состоит ли пользователь в комнате.
+func (r *RoomRepository) IsMember(roomID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM room_members WHERE room_id = $1 AND user_id = $2)`
+	if err := r.db.QueryRow(query, roomID, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check membership: %w", err)
+	}
+	return exists, nil
+}
+
+// CountMembers возвращает число участников комнаты.
+func (r *RoomRepository) CountMembers(roomID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM room_members WHERE room_id = $1`, roomID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count members: %w", err)
+	}
+	return count, nil
+}
+
+// CreateJoinRequest ставит заявку на вход в очередь. Повторная заявка после решения хоста (отказ, исключение после одобрения) снова становится pending.
+func (r *RoomRepository) CreateJoinRequest(roomID, userID uuid.UUID) (*models.RoomJoinRequest, error) {
+	query := `
+		INSERT INTO room_join_requests (room_id, user_id, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (room_id, user_id) DO UPDATE
+		SET status = EXCLUDED.status, created_at = NOW(), decided_at = NULL
+		WHERE room_join_requests.status <> EXCLUDED.status
+		RETURNING created_at
+	`
+
+	req := &models.RoomJoinRequest{RoomID: roomID, UserID: userID, Status: models.JoinRequestStatusPending}
+	err := r.db.QueryRow(query, roomID, userID, models.JoinRequestStatusPending).Scan(&req.CreatedAt)
+	if err == sql.ErrNoRows {
+		// Заявка уже есть и ждёт решения — возвращаем её как есть
+		return r.GetJoinRequest(roomID, userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create join request: %w", err)
+	}
+
+	return req, nil
+}
+
+// GetJoinRequest возвращает заявку пользователя на вход в комнату.
+func (r *RoomRepository) GetJoinRequest(roomID, userID uuid.UUID) (*models.RoomJoinRequest, error) {
+	req := &models.RoomJoinRequest{}
+	var decidedAt sql.NullTime
+
+	query := `
+		SELECT room_id, user_id, status, created_at, decided_at
+		FROM room_join_requests
+		WHERE room_id = $1 AND user_id = $2
+	`
+
+	err := r.db.QueryRow(query, roomID, userID).Scan(&req.RoomID, &req.UserID, &req.Status, &req.CreatedAt, &decidedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("join request not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join request: %w", err)
+	}
+	if decidedAt.Valid {
+		req.DecidedAt = &decidedAt.Time
+	}
+
+	return req, nil
+}
+
+// GetPendingJoinRequests возвращает заявки, ожидающие решения хоста, от старых к новым.
+func (r *RoomRepository) GetPendingJoinRequests(roomID uuid.UUID) ([]models.RoomJoinRequest, error) {
+	query := `
+		SELECT jr.room_id, jr.user_id, u.username, jr.status, jr.created_at
+		FROM room_join_requests jr
+		JOIN users u ON u.id = jr.user_id
+		WHERE jr.room_id = $1 AND jr.status = $2
+		ORDER BY jr.created_at
+	`
+
+	rows, err := r.db.Query(query, roomID, models.JoinRequestStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.RoomJoinRequest{}
+	for rows.Next() {
+		var req models.RoomJoinRequest
+		if err := rows.Scan(&req.RoomID, &req.UserID, &req.Username, &req.Status, &req.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan join request: %w", err)
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+// DecideJoinRequest одобряет или отклоняет заявку. При одобрении пользователь добавляется в участники.
+func (r *RoomRepository) DecideJoinRequest(roomID, userID uuid.UUID, status models.JoinRequestStatus) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE room_join_requests
+		SET status = $1, decided_at = NOW()
+		WHERE room_id = $2 AND user_id = $3 AND status = $4
+	`
+	res, err := tx.Exec(query, status, roomID, userID, models.JoinRequestStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to update join request: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("join request not found")
+	}
+
+	if status == models.JoinRequestStatusApproved {
+		_, err := tx.Exec(`
+			INSERT INTO room_members (room_id, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT (room_id, user_id) DO NOTHING
+		`, roomID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 
 	"github.com/google/uuid"
 )
@@ -69,6 +71,68 @@ func (r *SwipeRepository) GetLastSwipe(userID, roomID uuid.UUID) (*models.Swipe,
 	return swipe, nil
 }
 
+func (r *SwipeRepository) GetByID(id uuid.UUID) (*models.Swipe, error) {
+	swipe := &models.Swipe{}
+	query := `
+		SELECT id, user_id, room_id, movie_id, direction, created_at
+		FROM swipes
+		WHERE id = $1
+	`
+
+	err := r.db.QueryRow(query, id).Scan(
+		&swipe.ID,
+		&swipe.UserID,
+		&swipe.RoomID,
+		&swipe.MovieID,
+		&swipe.Direction,
+		&swipe.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("swipe not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swipe: %w", err)
+	}
+
+	return swipe, nil
+}
+
+// GetUserSwipesSince возвращает свайпы пользователя в комнате, сделанные начиная с since (новые первыми).
+func (r *SwipeRepository) GetUserSwipesSince(userID, roomID uuid.UUID, since time.Time) ([]models.Swipe, error) {
+	query := `
+		SELECT id, user_id, room_id, movie_id, direction, created_at
+		FROM swipes
+		WHERE user_id = $1 AND room_id = $2 AND created_at >= $3
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID, roomID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user swipes: %w", err)
+	}
+	defer rows.Close()
+
+	var swipes []models.Swipe
+	for rows.Next() {
+		swipe := models.Swipe{}
+		err := rows.Scan(
+			&swipe.ID,
+			&swipe.UserID,
+			&swipe.RoomID,
+			&swipe.MovieID,
+			&swipe.Direction,
+			&swipe.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan swipe: %w", err)
+		}
+		swipes = append(swipes, swipe)
+	}
+
+	return swipes, nil
+}
+
 func (r *SwipeRepository) Delete(swipeID uuid.UUID) error {
 	query := `DELETE FROM swipes WHERE id = $1`
 	_, err := r.db.Exec(query, swipeID)
@@ -86,11 +150,90 @@ func (r *SwipeRepository) HasUserSwiped(userID, roomID, movieID uuid.UUID) (bool
 	return exists, err
 }
 
+// GetRoomSwipes возвращает все свайпы комнаты (для рейтинга фильмов).
+func (r *SwipeRepository) GetRoomSwipes(roomID uuid.UUID) ([]models.Swipe, error) {
+	query := `
+		SELECT id, user_id, room_id, movie_id, direction, created_at
+		FROM swipes
+		WHERE room_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room swipes: %w", err)
+	}
+	defer rows.Close()
+
+	var swipes []models.Swipe
+	for rows.Next() {
+		var swipe models.Swipe
+		err := rows.Scan(
+			&swipe.ID,
+			&swipe.UserID,
+			&swipe.RoomID,
+			&swipe.MovieID,
+			&swipe.Direction,
+			&swipe.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan swipe: %w", err)
+		}
+		swipes = append(swipes, swipe)
+	}
+
+	return swipes, nil
+}
+
+// GetUserSwipeForMovie возвращает свайп пользователя по фильму в комнате.
+func (r *SwipeRepository) GetUserSwipeForMovie(userID, roomID, movieID uuid.UUID) (*models.Swipe, error) {
+	swipe := &models.Swipe{}
+	query := `
+		SELECT id, user_id, room_id, movie_id, direction, created_at
+		FROM swipes
+		WHERE user_id = $1 AND room_id = $2 AND movie_id = $3
+	`
+
+	err := r.db.QueryRow(query, userID, roomID, movieID).Scan(
+		&swipe.ID,
+		&swipe.UserID,
+		&swipe.RoomID,
+		&swipe.MovieID,
+		&swipe.Direction,
+		&swipe.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("swipe not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swipe: %w", err)
+	}
+
+	return swipe, nil
+}
+
+// UpdateDirection меняет направление свайпа (решение по отложенному maybe). created_at обновляется,
+// чтобы отмена последнего свайпа отменяла именно это решение.
+func (r *SwipeRepository) UpdateDirection(swipe *models.Swipe, direction models.SwipeDirection) error {
+	query := `
+		UPDATE swipes
+		SET direction = $1, created_at = NOW()
+		WHERE id = $2
+		RETURNING created_at
+	`
+
+	if err := r.db.QueryRow(query, direction, swipe.ID).Scan(&swipe.CreatedAt); err != nil {
+		return fmt.Errorf("failed to update swipe: %w", err)
+	}
+	swipe.Direction = direction
+	return nil
+}
+
 func (r *SwipeRepository) GetLikedMovies(roomID uuid.UUID) ([]uuid.UUID, error) {
 	query := `
 		SELECT DISTINCT movie_id
 		FROM swipes
-		WHERE room_id = $1 AND direction = 'right'
+		WHERE room_id = $1 AND direction IN ('right', 'superlike')
 	`
 
 	rows, err := r.db.Query(query, roomID)
@@ -116,7 +259,7 @@ func (r *SwipeRepository) CountLikesByMovie(roomID, movieID uuid.UUID) (int, err
 	query := `
 		SELECT COUNT(*)
 		FROM swipes
-		WHERE room_id = $1 AND movie_id = $2 AND direction = 'right'
+		WHERE room_id = $1 AND movie_id = $2 AND direction IN ('right', 'superlike')
 	`
 
 	err := r.db.QueryRow(query, roomID, movieID).Scan(&count)
@@ -127,6 +270,15 @@ func (r *SwipeRepository) CountLikesByMovie(roomID, movieID uuid.UUID) (int, err
 	return count, nil
 }
 
+func (r *SwipeRepository) CountByRoom(roomID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM swipes WHERE room_id = $1`, roomID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count swipes: %w", err)
+	}
+	return count, nil
+}
+
 func (r *SwipeRepository) GetUserSwipes(userID, roomID uuid.UUID) ([]models.Swipe, error) {
 	query := `
 		SELECT id, user_id, room_id, movie_id, direction, created_at
@@ -161,12 +313,62 @@ func (r *SwipeRepository) GetUserSwipes(userID, roomID uuid.UUID) ([]models.Swip
 	return swipes, nil
 }
 
+// ListUserSwipes возвращает страницу свайпов пользователя в комнате, от новых к старым, и курсор следующей страницы.
+// GetUserSwipes отдаёт все свайпы сразу — он нужен колоде и матчам.
+func (r *SwipeRepository) ListUserSwipes(userID, roomID uuid.UUID, page pagination.Page) ([]models.Swipe, string, error) {
+	args := []interface{}{userID, roomID}
+	where := "user_id = $1 AND room_id = $2"
+	if page.Cursor != nil {
+		after, err := page.Cursor.Time()
+		if err != nil {
+			return nil, "", err
+		}
+		var cond string
+		cond, args = pagination.After("created_at", "id", true, after, page.Cursor.ID, args)
+		where += " AND " + cond
+	}
+	args = append(args, page.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, room_id, movie_id, direction, created_at
+		FROM swipes
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user swipes: %w", err)
+	}
+	defer rows.Close()
+
+	var swipes []models.Swipe
+	for rows.Next() {
+		swipe := models.Swipe{}
+		if err := rows.Scan(&swipe.ID, &swipe.UserID, &swipe.RoomID, &swipe.MovieID, &swipe.Direction, &swipe.CreatedAt); err != nil {
+			return nil, "", fmt.Errorf("failed to scan swipe: %w", err)
+		}
+		swipes = append(swipes, swipe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to get user swipes: %w", err)
+	}
+
+	swipes, next := pagination.Trim(swipes, page.Limit, func(s *models.Swipe) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(s.CreatedAt), ID: s.ID}
+	})
+	return swipes, next, nil
+}
+
 // GetUserIDsWhoSwipedInRoom возвращает ID пользователей, сделавших хотя бы один свайп в комнате (активные участники).
+// Свайпы вышедших, исключённых и забаненных пользователей не учитываются — их нет в room_members.
 func (r *SwipeRepository) GetUserIDsWhoSwipedInRoom(roomID uuid.UUID) ([]uuid.UUID, error) {
 	query := `
-		SELECT DISTINCT user_id
-		FROM swipes
-		WHERE room_id = $1
+		SELECT DISTINCT s.user_id
+		FROM swipes s
+		JOIN room_members rm ON rm.room_id = s.room_id AND rm.user_id = s.user_id
+		WHERE s.room_id = $1
 	`
 	rows, err := r.db.Query(query, roomID)
 	if err != nil {
@@ -219,3 +421,80 @@ func (r *SwipeRepository) GetAllSwipesByMovie(roomID, movieID uuid.UUID) ([]mode
 	return swipes, nil
 }
 
+
+// GetUserHistory возвращает свайпы пользователя во всех комнатах вместе с фильмами, от новых к старым.
+func (r *SwipeRepository) GetUserHistory(userID uuid.UUID, limit int) ([]models.SwipeWithMovie, error) {
+	query := `
+		SELECT ` + movieColumns("m") + `, s.id, s.user_id, s.room_id, s.movie_id, s.direction, s.created_at
+		FROM swipes s
+		JOIN movies m ON m.id = s.movie_id
+		WHERE s.user_id = $1
+		ORDER BY s.created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swipe history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.SwipeWithMovie
+	for rows.Next() {
+		var item models.SwipeWithMovie
+		err := scanMovie(rows, &item.Movie,
+			&item.ID, &item.UserID, &item.RoomID, &item.MovieID, &item.Direction, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, item)
+	}
+
+	return history, rows.Err()
+}
+
+// GetCoLikerLikes возвращает лайки пользователя и всех, кто лайкнул хотя бы один общий с ним фильм
+// (во всех комнатах). Это исходные данные для коллаборативной фильтрации.
+func (r *SwipeRepository) GetCoLikerLikes(userID uuid.UUID, limit int) ([]models.Swipe, error) {
+	query := `
+		WITH mine AS (
+			SELECT DISTINCT movie_id FROM swipes
+			WHERE user_id = $1 AND direction IN ('right', 'superlike')
+		), neighbours AS (
+			SELECT DISTINCT s.user_id FROM swipes s
+			JOIN mine ON mine.movie_id = s.movie_id
+			WHERE s.direction IN ('right', 'superlike')
+		)
+		SELECT s.id, s.user_id, s.room_id, s.movie_id, s.direction, s.created_at
+		FROM swipes s
+		JOIN neighbours n ON n.user_id = s.user_id
+		WHERE s.direction IN ('right', 'superlike')
+		ORDER BY s.created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get co-liker likes: %w", err)
+	}
+	defer rows.Close()
+
+	var swipes []models.Swipe
+	for rows.Next() {
+		var swipe models.Swipe
+		err := rows.Scan(
+			&swipe.ID,
+			&swipe.UserID,
+			&swipe.RoomID,
+			&swipe.MovieID,
+			&swipe.Direction,
+			&swipe.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan swipe: %w", err)
+		}
+		swipes = append(swipes, swipe)
+	}
+
+	return swipes, nil
+}
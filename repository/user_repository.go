@@ -228,20 +228,18 @@ func (r *UserRepository) GetStatistics(userID uuid.UUID) (*models.UserStatistics
 		return nil, fmt.Errorf("failed to get total swipes: %w", err)
 	}
 
-	// Лайкнуто фильмов
+	// Свайпы по направлениям (лайки включают суперлайки)
 	err = r.db.QueryRow(`
-		SELECT COUNT(*) FROM swipes WHERE user_id = $1 AND direction = 'right'
-	`, userID).Scan(&stats.LikedMovies)
+		SELECT
+			COUNT(*) FILTER (WHERE direction IN ('right', 'superlike')),
+			COUNT(*) FILTER (WHERE direction = 'left'),
+			COUNT(*) FILTER (WHERE direction = 'superlike'),
+			COUNT(*) FILTER (WHERE direction = 'maybe'),
+			COUNT(*) FILTER (WHERE direction = 'seen')
+		FROM swipes WHERE user_id = $1
+	`, userID).Scan(&stats.LikedMovies, &stats.DislikedMovies, &stats.SuperlikedMovies, &stats.MaybeMovies, &stats.SeenMovies)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get liked movies: %w", err)
-	}
-
-	// Дизлайкнуто фильмов
-	err = r.db.QueryRow(`
-		SELECT COUNT(*) FROM swipes WHERE user_id = $1 AND direction = 'left'
-	`, userID).Scan(&stats.DislikedMovies)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get disliked movies: %w", err)
+		return nil, fmt.Errorf("failed to get swipes by direction: %w", err)
 	}
 
 	// Всего мэтчей (комнаты где пользователь участвовал и был мэтч)
@@ -287,7 +285,7 @@ func (r *UserRepository) GetStatistics(userID uuid.UUID) (*models.UserStatistics
 		SELECT COUNT(DISTINCT r.id)
 		FROM rooms r
 		INNER JOIN room_members rm ON r.id = rm.room_id
-		WHERE rm.user_id = $1 AND r.status = 'completed'
+		WHERE rm.user_id = $1 AND r.status = 'finished'
 	`, userID).Scan(&stats.CompletedRooms)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get completed rooms: %w", err)
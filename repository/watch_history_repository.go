@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WatchHistoryRepository — журнал «мы это посмотрели»
+type WatchHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewWatchHistoryRepository(db *sql.DB) *WatchHistoryRepository {
+	return &WatchHistoryRepository{db: db}
+}
+
+const watchEventColumns = `e.id, e.movie_id, e.room_id, e.match_id, e.watched_at, e.logged_by, e.created_at`
+
+func watchEventDest(e *models.WatchEvent) []interface{} {
+	return []interface{}{&e.ID, &e.MovieID, &e.RoomID, &e.MatchID, &e.WatchedAt, &e.LoggedBy, &e.CreatedAt}
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return strs
+}
+
+// Create записывает просмотр с участниками. Фильм заодно убирается из личных списков участников
+// и из списка комнаты, если просмотр был в комнате.
+func (r *WatchHistoryRepository) Create(event *models.WatchEvent, attendeeIDs []uuid.UUID) error {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO watch_events (id, movie_id, room_id, match_id, watched_at, logged_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`,
+		event.ID, event.MovieID, event.RoomID, event.MatchID, event.WatchedAt, event.LoggedBy,
+	).Scan(&event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create watch event: %w", err)
+	}
+
+	ids := pq.Array(uuidStrings(attendeeIDs))
+	if _, err := tx.Exec(`
+		INSERT INTO watch_event_attendees (event_id, user_id)
+		SELECT $1, unnest($2::uuid[])
+		ON CONFLICT DO NOTHING`,
+		event.ID, ids,
+	); err != nil {
+		return fmt.Errorf("failed to save attendees: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM watchlist_items
+		WHERE movie_id = $1 AND (user_id = ANY($2::uuid[]) OR room_id = $3)`,
+		event.MovieID, ids, event.RoomID,
+	); err != nil {
+		return fmt.Errorf("failed to clean up watchlists: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.loadAttendees([]*models.WatchEvent{event})
+}
+
+// GetByUserID возвращает страницу просмотров, в которых участвовал пользователь, новые сверху, и курсор следующей страницы
+func (r *WatchHistoryRepository) GetByUserID(userID uuid.UUID, page pagination.Page) ([]models.WatchEvent, string, error) {
+	return r.list(`EXISTS (SELECT 1 FROM watch_event_attendees a WHERE a.event_id = e.id AND a.user_id = $1)`, userID, page)
+}
+
+// GetByRoomID возвращает страницу просмотров, отмеченных в комнате, новые сверху, и курсор следующей страницы
+func (r *WatchHistoryRepository) GetByRoomID(roomID uuid.UUID, page pagination.Page) ([]models.WatchEvent, string, error) {
+	return r.list(`e.room_id = $1`, roomID, page)
+}
+
+func (r *WatchHistoryRepository) list(where string, id uuid.UUID, page pagination.Page) ([]models.WatchEvent, string, error) {
+	args := []interface{}{id}
+	if page.Cursor != nil {
+		after, err := page.Cursor.Time()
+		if err != nil {
+			return nil, "", err
+		}
+		var cond string
+		cond, args = pagination.After("e.watched_at", "e.id", true, after, page.Cursor.ID, args)
+		where += " AND " + cond
+	}
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT %s, %s
+		FROM watch_events e
+		JOIN movies m ON m.id = e.movie_id
+		WHERE %s
+		ORDER BY e.watched_at DESC, e.id DESC
+		LIMIT $%d`, movieColumns("m"), watchEventColumns, where, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get watch history: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.WatchEvent
+	for rows.Next() {
+		event := models.WatchEvent{Movie: &models.Movie{}}
+		if err := scanMovie(rows, event.Movie, watchEventDest(&event)...); err != nil {
+			return nil, "", fmt.Errorf("failed to scan watch event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to get watch history: %w", err)
+	}
+
+	events, next := pagination.Trim(events, page.Limit, func(e *models.WatchEvent) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(e.WatchedAt), ID: e.ID}
+	})
+	ptrs := make([]*models.WatchEvent, len(events))
+	for i := range events {
+		ptrs[i] = &events[i]
+	}
+	if err := r.loadAttendees(ptrs); err != nil {
+		return nil, "", err
+	}
+	return events, next, nil
+}
+
+// loadAttendees заполняет участников просмотров одним запросом
+func (r *WatchHistoryRepository) loadAttendees(events []*models.WatchEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*models.WatchEvent, len(events))
+	ids := make([]uuid.UUID, len(events))
+	for i, e := range events {
+		e.Attendees = []models.WatchAttendee{}
+		byID[e.ID] = e
+		ids[i] = e.ID
+	}
+
+	rows, err := r.db.Query(`
+		SELECT a.event_id, u.id, u.username
+		FROM watch_event_attendees a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.event_id = ANY($1::uuid[])
+		ORDER BY u.username`,
+		pq.Array(uuidStrings(ids)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get attendees: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID uuid.UUID
+		var a models.WatchAttendee
+		if err := rows.Scan(&eventID, &a.UserID, &a.Username); err != nil {
+			return fmt.Errorf("failed to scan attendee: %w", err)
+		}
+		if e := byID[eventID]; e != nil {
+			e.Attendees = append(e.Attendees, a)
+		}
+	}
+	return rows.Err()
+}
+
+// GetWatchedMovieIDs возвращает фильмы, которые пользователь уже посмотрел, — они исключаются из его колод
+func (r *WatchHistoryRepository) GetWatchedMovieIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT e.movie_id
+		FROM watch_events e
+		JOIN watch_event_attendees a ON a.event_id = e.id
+		WHERE a.user_id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watched movies: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan movie id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
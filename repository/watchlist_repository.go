@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"kinoswipe/models"
+	"kinoswipe/pagination"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAlreadyInWatchlist — фильм уже есть в этом списке
+	ErrAlreadyInWatchlist = errors.New("movie already in watchlist")
+	// ErrWatchlistItemNotFound — записи списка с таким id нет
+	ErrWatchlistItemNotFound = errors.New("watchlist item not found")
+)
+
+// WatchlistRepository — списки «посмотреть позже»: личные и общие для комнаты
+type WatchlistRepository struct {
+	db *sql.DB
+}
+
+func NewWatchlistRepository(db *sql.DB) *WatchlistRepository {
+	return &WatchlistRepository{db: db}
+}
+
+const watchlistColumns = `w.id, w.movie_id, w.user_id, w.room_id, w.source, w.match_id, w.added_by, COALESCE(w.note, ''), w.created_at`
+
+func watchlistItemDest(item *models.WatchlistItem) []interface{} {
+	return []interface{}{&item.ID, &item.MovieID, &item.UserID, &item.RoomID, &item.Source, &item.MatchID, &item.AddedBy, &item.Note, &item.CreatedAt}
+}
+
+// Add кладёт фильм в список. Если фильм уже в этом списке — ErrAlreadyInWatchlist.
+func (r *WatchlistRepository) Add(item *models.WatchlistItem) error {
+	if item.ID == uuid.Nil {
+		item.ID = uuid.New()
+	}
+	if item.Source == "" {
+		item.Source = models.WatchlistSourceManual
+	}
+	err := r.db.QueryRow(`
+		INSERT INTO watchlist_items (id, movie_id, user_id, room_id, source, match_id, added_by, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		ON CONFLICT DO NOTHING
+		RETURNING created_at`,
+		item.ID, item.MovieID, item.UserID, item.RoomID, item.Source, item.MatchID, item.AddedBy, item.Note,
+	).Scan(&item.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrAlreadyInWatchlist
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add to watchlist: %w", err)
+	}
+	return nil
+}
+
+func (r *WatchlistRepository) GetByID(id uuid.UUID) (*models.WatchlistItem, error) {
+	item := &models.WatchlistItem{}
+	err := r.db.QueryRow(`SELECT `+watchlistColumns+` FROM watchlist_items w WHERE w.id = $1`, id).Scan(watchlistItemDest(item)...)
+	if err == sql.ErrNoRows {
+		return nil, ErrWatchlistItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist item: %w", err)
+	}
+	return item, nil
+}
+
+// GetByUserID возвращает страницу личного списка пользователя, новые сверху, и курсор следующей страницы
+func (r *WatchlistRepository) GetByUserID(userID uuid.UUID, page pagination.Page) ([]models.WatchlistItem, string, error) {
+	return r.list(`w.user_id = $1`, userID, page)
+}
+
+// GetByRoomID возвращает страницу общего списка комнаты, новые сверху, и курсор следующей страницы
+func (r *WatchlistRepository) GetByRoomID(roomID uuid.UUID, page pagination.Page) ([]models.WatchlistItem, string, error) {
+	return r.list(`w.room_id = $1`, roomID, page)
+}
+
+func (r *WatchlistRepository) list(where string, id uuid.UUID, page pagination.Page) ([]models.WatchlistItem, string, error) {
+	args := []interface{}{id}
+	if page.Cursor != nil {
+		after, err := page.Cursor.Time()
+		if err != nil {
+			return nil, "", err
+		}
+		var cond string
+		cond, args = pagination.After("w.created_at", "w.id", true, after, page.Cursor.ID, args)
+		where += " AND " + cond
+	}
+	args = append(args, page.Limit+1)
+
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT %s, %s
+		FROM watchlist_items w
+		JOIN movies m ON m.id = w.movie_id
+		WHERE %s
+		ORDER BY w.created_at DESC, w.id DESC
+		LIMIT $%d`, movieColumns("m"), watchlistColumns, where, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get watchlist: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.WatchlistItem
+	for rows.Next() {
+		item := models.WatchlistItem{Movie: &models.Movie{}}
+		if err := scanMovie(rows, item.Movie, watchlistItemDest(&item)...); err != nil {
+			return nil, "", fmt.Errorf("failed to scan watchlist item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to get watchlist: %w", err)
+	}
+
+	items, next := pagination.Trim(items, page.Limit, func(item *models.WatchlistItem) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.TimeKey(item.CreatedAt), ID: item.ID}
+	})
+	return items, next, nil
+}
+
+func (r *WatchlistRepository) Delete(id uuid.UUID) error {
+	res, err := r.db.Exec(`DELETE FROM watchlist_items WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete watchlist item: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrWatchlistItemNotFound
+	}
+	return nil
+}
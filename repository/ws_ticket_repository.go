@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WSTicketRepository — одноразовые билеты для подключения к WebSocket. Хранятся в БД,
+// чтобы билет, выданный одним экземпляром сервера, принимал любой другой.
+type WSTicketRepository struct {
+	db *sql.DB
+}
+
+func NewWSTicketRepository(db *sql.DB) *WSTicketRepository {
+	return &WSTicketRepository{db: db}
+}
+
+// Create сохраняет билет (по хешу) и заодно удаляет просроченные.
+func (r *WSTicketRepository) Create(ticketHash string, userID, roomID uuid.UUID, expiresAt time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM ws_tickets WHERE expires_at < $1`, time.Now()); err != nil {
+		return fmt.Errorf("failed to delete expired tickets: %w", err)
+	}
+	_, err := r.db.Exec(
+		`INSERT INTO ws_tickets (ticket_hash, user_id, room_id, expires_at) VALUES ($1, $2, $3, $4)`,
+		ticketHash, userID, roomID, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create ticket: %w", err)
+	}
+	return nil
+}
+
+// Consume гасит билет комнаты и возвращает его владельца. Билет удаляется в том же запросе,
+// поэтому второй раз его не примет ни один экземпляр.
+func (r *WSTicketRepository) Consume(ticketHash string, roomID uuid.UUID) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.db.QueryRow(
+		`DELETE FROM ws_tickets WHERE ticket_hash = $1 AND room_id = $2 AND expires_at > $3 RETURNING user_id`,
+		ticketHash, roomID, time.Now(),
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("ticket not found")
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to consume ticket: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"kinoswipe/models"
+)
+
+// NormalizeTitle приводит название к ключу для поиска дублей: нижний регистр, ё → е,
+// остаются только буквы и цифры («Матрица: Перезагрузка» и «матрица перезагрузка» совпадают).
+func NormalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == 'ё':
+			b.WriteRune('е')
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// FindDuplicates группирует фильмы одного года, у которых совпадает нормализованное русское или английское название.
+// Порядок фильмов в группе сохраняется (каталог приходит от старых к новым), группы сортируются по названию и году.
+func FindDuplicates(movies []models.Movie) []models.DuplicateGroup {
+	// Система непересекающихся множеств: фильмы, совпавшие хотя бы по одному ключу, попадают в одну группу
+	parent := make([]int, len(movies))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	type dedupKey struct {
+		title string
+		year  int
+	}
+	firstByKey := make(map[dedupKey]int)
+	for i, m := range movies {
+		for _, title := range []string{m.Title, m.TitleEn} {
+			norm := NormalizeTitle(title)
+			if norm == "" {
+				continue
+			}
+			key := dedupKey{norm, m.Year}
+			if j, ok := firstByKey[key]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				firstByKey[key] = i
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range movies {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+
+	groups := []models.DuplicateGroup{}
+	for _, idx := range members {
+		if len(idx) < 2 {
+			continue
+		}
+		sort.Ints(idx)
+		group := models.DuplicateGroup{Title: NormalizeTitle(movies[idx[0]].Title), Year: movies[idx[0]].Year}
+		for _, i := range idx {
+			group.Movies = append(group.Movies, movies[i])
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Title != groups[j].Title {
+			return groups[i].Title < groups[j].Title
+		}
+		return groups[i].Year < groups[j].Year
+	})
+	return groups
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := map[string]string{
+		"Матрица: Перезагрузка":  "матрицаперезагрузка",
+		"  матрица перезагрузка": "матрицаперезагрузка",
+		"Ёлки 2": "елки2",
+		"WALL·E": "walle",
+		"...":    "",
+	}
+	for in, want := range tests {
+		if got := NormalizeTitle(in); got != want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	matrix := models.Movie{ID: uuid.New(), Title: "Матрица", TitleEn: "The Matrix", Year: 1999}
+	matrixCSV := models.Movie{ID: uuid.New(), Title: "The Matrix", Year: 1999}
+	matrixCopy := models.Movie{ID: uuid.New(), Title: "матрица!", Year: 1999}
+	remake := models.Movie{ID: uuid.New(), Title: "Дюна", Year: 1984}
+	dune := models.Movie{ID: uuid.New(), Title: "Дюна", Year: 2021}
+
+	groups := FindDuplicates([]models.Movie{matrix, remake, matrixCSV, dune, matrixCopy})
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group (same title in different years is not a duplicate), got %d", len(groups))
+	}
+	g := groups[0]
+	if g.Year != 1999 || g.Title != "матрица" {
+		t.Errorf("unexpected group key %q/%d", g.Title, g.Year)
+	}
+	if len(g.Movies) != 3 || g.Movies[0].ID != matrix.ID || g.Movies[1].ID != matrixCSV.ID || g.Movies[2].ID != matrixCopy.ID {
+		t.Errorf("group must contain all three copies in catalogue order, got %+v", g.Movies)
+	}
+}
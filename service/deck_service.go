@@ -0,0 +1,378 @@
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// deckMaxSize — сколько фильмов попадает в колоду комнаты при старте
+	deckMaxSize = 500
+	// deckTiers — на сколько рейтинговых слоёв делится пул перед чередованием
+	deckTiers = 3
+	// deckBlockSize — в личном режиме порядок перемешивается только внутри блоков такого размера,
+	// поэтому первые карточки у всех участников одни и те же и лайки быстрее пересекаются
+	deckBlockSize = 10
+	// deckRescoreWindow — сколько следующих карточек сверх страницы переоценивается групповой стратегией:
+	// фильм из окна может подняться наверх, но колода не перетасовывается целиком на каждый запрос
+	deckRescoreWindow = 50
+)
+
+type deckRoomRepoInterface interface {
+	GetDeck(roomID uuid.UUID) ([]uuid.UUID, error)
+	SaveDeck(roomID uuid.UUID, seed int64, movieIDs []uuid.UUID) error
+	GetMembers(roomID uuid.UUID) ([]models.User, error)
+}
+
+type deckMovieRepoInterface interface {
+	GetIDsByFilter(filter *models.Filter, limit int) ([]uuid.UUID, error)
+	GetByIDs(ids []uuid.UUID) ([]models.Movie, error)
+}
+
+type deckSwipeRepoInterface interface {
+	GetUserSwipes(userID, roomID uuid.UUID) ([]models.Swipe, error)
+	GetRoomSwipes(roomID uuid.UUID) ([]models.Swipe, error)
+}
+
+type deckWatchedRepoInterface interface {
+	GetWatchedMovieIDs(userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+type tasteProfiler interface {
+	Profile(userID uuid.UUID) (*TasteProfile, error)
+}
+
+type filterRepoInterface interface {
+	GetByID(id uuid.UUID) (*models.Filter, error)
+	GetByRoomID(roomID uuid.UUID) (*models.Filter, error)
+}
+
+// DeckService собирает колоду комнаты и выдаёт её участникам постранично.
+type DeckService struct {
+	roomRepo   deckRoomRepoInterface
+	movieRepo  deckMovieRepoInterface
+	swipeRepo  deckSwipeRepoInterface
+	filterRepo filterRepoInterface
+	profiler   tasteProfiler
+	scorers    []GroupScorer
+
+	watchedRepo deckWatchedRepoInterface // nil — просмотренные фильмы не исключаются
+}
+
+func NewDeckService(
+	roomRepo *repository.RoomRepository,
+	movieRepo *repository.MovieRepository,
+	swipeRepo *repository.SwipeRepository,
+	filterRepo *repository.FilterRepository,
+) *DeckService {
+	return &DeckService{
+		roomRepo:   roomRepo,
+		movieRepo:  movieRepo,
+		swipeRepo:  swipeRepo,
+		filterRepo: filterRepo,
+	}
+}
+
+// SetGroupScoring включает групповую оценку колоды: комнаты делятся между scorers (A/B-тест),
+// вкус участников берётся из profiler. Без стратегий колода выдаётся в исходном порядке.
+// В комнате со стратегией карточки упорядочивает RankForGroup: персональный порядок (deck_mode=personal)
+// и ранжирование по вкусу участника в GetRoomMovies для неё не применяются.
+func (s *DeckService) SetGroupScoring(profiler *RecommendationService, scorers ...GroupScorer) {
+	if profiler != nil {
+		s.profiler = profiler
+	}
+	s.scorers = scorers
+}
+
+// SetWatchHistory исключает из колоды участника фильмы, которые он уже посмотрел.
+func (s *DeckService) SetWatchHistory(watchedRepo *repository.WatchHistoryRepository) {
+	if watchedRepo != nil {
+		s.watchedRepo = watchedRepo
+	}
+}
+
+// GroupStrategy возвращает имя стратегии групповой оценки комнаты ("" — выключена).
+func (s *DeckService) GroupStrategy(room *models.Room) string {
+	if scorer := PickGroupScorer(s.scorers, room.ID); scorer != nil {
+		return scorer.Name()
+	}
+	return ""
+}
+
+// RoomFilter возвращает фильтр комнаты: привязанный через filter_id или последний созданный для комнаты. nil — фильтра нет.
+func (s *DeckService) RoomFilter(room *models.Room) *models.Filter {
+	if room.FilterID != nil {
+		if filter, err := s.filterRepo.GetByID(*room.FilterID); err == nil {
+			return filter
+		}
+	}
+	filter, err := s.filterRepo.GetByRoomID(room.ID)
+	if err != nil {
+		return nil
+	}
+	return filter
+}
+
+// BuildDeck собирает колоду комнаты из фильмов, подходящих под фильтр, и сохраняет её. Вызывается при старте комнаты.
+func (s *DeckService) BuildDeck(room *models.Room) error {
+	ids, err := s.movieRepo.GetIDsByFilter(s.RoomFilter(room), deckMaxSize)
+	if err != nil {
+		return fmt.Errorf("failed to get deck candidates: %w", err)
+	}
+
+	seed := DeckSeed(room.ID)
+	if err := s.roomRepo.SaveDeck(room.ID, seed, InterleaveTiers(ids, seed, deckTiers)); err != nil {
+		return fmt.Errorf("failed to save deck: %w", err)
+	}
+	room.DeckSeed = &seed
+	return nil
+}
+
+// NextCards возвращает следующую страницу колоды для пользователя (фильмы, которые он ещё не свайпнул и не смотрел).
+// ok=false — колода комнаты ещё не собрана (комната не стартовала).
+func (s *DeckService) NextCards(room *models.Room, userID uuid.UUID, limit int) (movies []models.Movie, ok bool, err error) {
+	deck, err := s.roomRepo.GetDeck(room.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get deck: %w", err)
+	}
+	if len(deck) == 0 {
+		return nil, false, nil
+	}
+
+	if room.DeckMode == models.DeckModePersonal && room.DeckSeed != nil {
+		deck = PersonalOrder(deck, *room.DeckSeed, userID, deckBlockSize)
+	}
+
+	swipes, err := s.swipeRepo.GetUserSwipes(userID, room.ID)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to get user swipes: %w", err)
+	}
+	if s.watchedRepo != nil {
+		watched, err := s.watchedRepo.GetWatchedMovieIDs(userID)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to get watched movies: %w", err)
+		}
+		deck = ExcludeMovies(deck, watched)
+	}
+
+	scorer := PickGroupScorer(s.scorers, room.ID)
+	window := limit
+	if scorer != nil {
+		window += deckRescoreWindow
+	}
+	movies, err = s.movieRepo.GetByIDs(DeckPage(deck, swipes, window))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to get deck movies: %w", err)
+	}
+	if scorer == nil {
+		return movies, true, nil
+	}
+
+	group, err := s.groupContext(room)
+	if err != nil {
+		return nil, true, err
+	}
+	deferred := make(map[uuid.UUID]bool)
+	for _, sw := range swipes {
+		if sw.Direction == models.SwipeDirectionMaybe {
+			deferred[sw.MovieID] = true
+		}
+	}
+	movies = RankForGroup(scorer, group, movies, deferred)
+	if len(movies) > limit {
+		movies = movies[:limit]
+	}
+	return movies, true, nil
+}
+
+// Progress возвращает, сколько карточек колоды пользователь уже прошёл (без направления свайпов).
+// ok=false — колода комнаты ещё не собрана.
+func (s *DeckService) Progress(room *models.Room, userID uuid.UUID) (progress models.DeckProgress, ok bool, err error) {
+	deck, err := s.roomRepo.GetDeck(room.ID)
+	if err != nil {
+		return progress, false, fmt.Errorf("failed to get deck: %w", err)
+	}
+	if len(deck) == 0 {
+		return progress, false, nil
+	}
+
+	swipes, err := s.swipeRepo.GetUserSwipes(userID, room.ID)
+	if err != nil {
+		return progress, true, fmt.Errorf("failed to get user swipes: %w", err)
+	}
+	if s.watchedRepo != nil {
+		watched, err := s.watchedRepo.GetWatchedMovieIDs(userID)
+		if err != nil {
+			return progress, true, fmt.Errorf("failed to get watched movies: %w", err)
+		}
+		deck = ExcludeMovies(deck, watched)
+	}
+	return DeckProgressOf(deck, swipes), true, nil
+}
+
+// groupContext собирает участников, их профили вкуса и текущие свайпы комнаты.
+// Свайпы читаются на каждый запрос, поэтому оценки сразу учитывают новые лайки в комнате.
+func (s *DeckService) groupContext(room *models.Room) (*GroupContext, error) {
+	members, err := s.roomRepo.GetMembers(room.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room members: %w", err)
+	}
+	roomSwipes, err := s.swipeRepo.GetRoomSwipes(room.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room swipes: %w", err)
+	}
+
+	group := &GroupContext{
+		Room:   room,
+		Filter: s.RoomFilter(room),
+		Swipes: make(map[uuid.UUID][]models.Swipe),
+	}
+	for _, sw := range roomSwipes {
+		group.Swipes[sw.MovieID] = append(group.Swipes[sw.MovieID], sw)
+	}
+	for _, m := range members {
+		group.Members = append(group.Members, m.ID)
+		if s.profiler == nil {
+			continue
+		}
+		profile, err := s.profiler.Profile(m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build taste profile: %w", err)
+		}
+		group.Profiles = append(group.Profiles, profile)
+	}
+	return group, nil
+}
+
+// DeckPage выбирает следующие карточки колоды: сначала ещё не свайпнутые, затем отложенные (maybe)
+// в порядке колоды. Фильмы с остальными свайпами больше не показываются.
+func DeckPage(deck []uuid.UUID, swipes []models.Swipe, limit int) []uuid.UUID {
+	swiped := make(map[uuid.UUID]models.SwipeDirection, len(swipes))
+	for _, sw := range swipes {
+		swiped[sw.MovieID] = sw.Direction
+	}
+
+	page := make([]uuid.UUID, 0, limit)
+	var maybe []uuid.UUID
+	for _, id := range deck {
+		if len(page) >= limit {
+			return page
+		}
+		direction, ok := swiped[id]
+		switch {
+		case !ok:
+			page = append(page, id)
+		case direction == models.SwipeDirectionMaybe:
+			maybe = append(maybe, id)
+		}
+	}
+	for _, id := range maybe {
+		if len(page) >= limit {
+			break
+		}
+		page = append(page, id)
+	}
+	return page
+}
+
+// DeckProgressOf считает пройденные карточки колоды: решённые свайпы по фильмам колоды.
+// Отложенные (maybe) ещё вернутся в колоду, поэтому не считаются.
+func DeckProgressOf(deck []uuid.UUID, swipes []models.Swipe) models.DeckProgress {
+	decided := make(map[uuid.UUID]bool, len(swipes))
+	for _, sw := range swipes {
+		if sw.Direction != models.SwipeDirectionMaybe {
+			decided[sw.MovieID] = true
+		}
+	}
+
+	progress := models.DeckProgress{Total: len(deck)}
+	for _, id := range deck {
+		if decided[id] {
+			progress.Swiped++
+		}
+	}
+	progress.Finished = progress.Swiped == progress.Total
+	return progress
+}
+
+// ExcludeMovies возвращает колоду без фильмов exclude, сохраняя порядок.
+func ExcludeMovies(deck, exclude []uuid.UUID) []uuid.UUID {
+	if len(exclude) == 0 {
+		return deck
+	}
+	skip := make(map[uuid.UUID]bool, len(exclude))
+	for _, id := range exclude {
+		skip[id] = true
+	}
+	result := make([]uuid.UUID, 0, len(deck))
+	for _, id := range deck {
+		if !skip[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// DeckSeed детерминированно выводит зерно перемешивания из ID комнаты.
+func DeckSeed(roomID uuid.UUID) int64 {
+	h := fnv.New64a()
+	h.Write(roomID[:])
+	return int64(h.Sum64())
+}
+
+// InterleaveTiers делит отсортированный по рейтингу пул на tiers слоёв, перемешивает каждый слой
+// и выкладывает их по очереди: в начале колоды классика чередуется с «длинным хвостом».
+func InterleaveTiers(ids []uuid.UUID, seed int64, tiers int) []uuid.UUID {
+	if tiers < 1 {
+		tiers = 1
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	size := (len(ids) + tiers - 1) / tiers
+	var layers [][]uuid.UUID
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		layer := append([]uuid.UUID(nil), ids[start:end]...)
+		rng.Shuffle(len(layer), func(i, j int) { layer[i], layer[j] = layer[j], layer[i] })
+		layers = append(layers, layer)
+	}
+
+	result := make([]uuid.UUID, 0, len(ids))
+	for i := 0; len(result) < len(ids); i++ {
+		for _, layer := range layers {
+			if i < len(layer) {
+				result = append(result, layer[i])
+			}
+		}
+	}
+	return result
+}
+
+// PersonalOrder возвращает личный порядок колоды для участника: внутри каждого блока из blockSize карточек
+// порядок свой, но сами блоки у всех одинаковые.
+func PersonalOrder(deck []uuid.UUID, seed int64, userID uuid.UUID, blockSize int) []uuid.UUID {
+	if blockSize < 1 {
+		blockSize = 1
+	}
+	rng := rand.New(rand.NewSource(seed ^ DeckSeed(userID)))
+
+	result := append([]uuid.UUID(nil), deck...)
+	for start := 0; start < len(result); start += blockSize {
+		end := start + blockSize
+		if end > len(result) {
+			end = len(result)
+		}
+		block := result[start:end]
+		rng.Shuffle(len(block), func(i, j int) { block[i], block[j] = block[j], block[i] })
+	}
+	return result
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"testing"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+func makeIDs(n int) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	return ids
+}
+
+func TestInterleaveTiers_DeterministicPermutation(t *testing.T) {
+	ids := makeIDs(30)
+	seed := DeckSeed(uuid.New())
+
+	a := InterleaveTiers(ids, seed, 3)
+	b := InterleaveTiers(ids, seed, 3)
+	if len(a) != len(ids) {
+		t.Fatalf("expected %d cards, got %d", len(ids), len(a))
+	}
+	seen := make(map[uuid.UUID]bool)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("same seed must give same order, differs at %d", i)
+		}
+		seen[a[i]] = true
+	}
+	if len(seen) != len(ids) {
+		t.Errorf("deck must be a permutation of the pool, got %d unique cards", len(seen))
+	}
+
+	// Первые три карточки — по одной из каждого рейтингового слоя
+	tierOf := make(map[uuid.UUID]int)
+	for i, id := range ids {
+		tierOf[id] = i / 10
+	}
+	for i := 0; i < 3; i++ {
+		if tierOf[a[i]] != i {
+			t.Errorf("card %d: expected tier %d, got %d", i, i, tierOf[a[i]])
+		}
+	}
+}
+
+func TestPersonalOrder_SameBlocksDifferentOrder(t *testing.T) {
+	deck := makeIDs(25)
+	seed := DeckSeed(uuid.New())
+	u1, u2 := uuid.New(), uuid.New()
+
+	o1 := PersonalOrder(deck, seed, u1, 10)
+	o2 := PersonalOrder(deck, seed, u2, 10)
+
+	for start := 0; start < len(deck); start += 10 {
+		end := start + 10
+		if end > len(deck) {
+			end = len(deck)
+		}
+		inBlock := make(map[uuid.UUID]bool)
+		for _, id := range deck[start:end] {
+			inBlock[id] = true
+		}
+		for i := start; i < end; i++ {
+			if !inBlock[o1[i]] || !inBlock[o2[i]] {
+				t.Fatalf("card %d left its block", i)
+			}
+		}
+	}
+
+	same := true
+	for i := range o1 {
+		if o1[i] != o2[i] {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Error("expected different personal orders for different users")
+	}
+}
+
+func TestDeckPage_MaybeComesBackLast(t *testing.T) {
+	deck := makeIDs(5)
+	swipes := []models.Swipe{
+		{MovieID: deck[0], Direction: models.SwipeDirectionMaybe},
+		{MovieID: deck[1], Direction: models.SwipeDirectionRight},
+		{MovieID: deck[3], Direction: models.SwipeDirectionSeen},
+	}
+
+	page := DeckPage(deck, swipes, 10)
+	want := []uuid.UUID{deck[2], deck[4], deck[0]}
+	if len(page) != len(want) {
+		t.Fatalf("page = %v, want %v", page, want)
+	}
+	for i := range want {
+		if page[i] != want[i] {
+			t.Fatalf("page[%d] = %s, want %s", i, page[i], want[i])
+		}
+	}
+
+	if page := DeckPage(deck, swipes, 1); len(page) != 1 || page[0] != deck[2] {
+		t.Errorf("limited page = %v, want only %s", page, deck[2])
+	}
+}
+
+type fakeDeckRooms struct{ deck []uuid.UUID }
+
+func (f *fakeDeckRooms) GetDeck(roomID uuid.UUID) ([]uuid.UUID, error) { return f.deck, nil }
+func (f *fakeDeckRooms) SaveDeck(roomID uuid.UUID, seed int64, movieIDs []uuid.UUID) error {
+	f.deck = movieIDs
+	return nil
+}
+func (f *fakeDeckRooms) GetMembers(roomID uuid.UUID) ([]models.User, error) { return nil, nil }
+
+type fakeDeckMovies struct{}
+
+func (fakeDeckMovies) GetIDsByFilter(filter *models.Filter, limit int) ([]uuid.UUID, error) {
+	return nil, nil
+}
+func (fakeDeckMovies) GetByIDs(ids []uuid.UUID) ([]models.Movie, error) {
+	movies := make([]models.Movie, len(ids))
+	for i, id := range ids {
+		movies[i] = models.Movie{ID: id}
+	}
+	return movies, nil
+}
+
+type fakeDeckSwipes struct{ swipes []models.Swipe }
+
+func (f *fakeDeckSwipes) GetUserSwipes(userID, roomID uuid.UUID) ([]models.Swipe, error) {
+	return f.swipes, nil
+}
+func (f *fakeDeckSwipes) GetRoomSwipes(roomID uuid.UUID) ([]models.Swipe, error) {
+	return f.swipes, nil
+}
+
+type fakeWatched map[uuid.UUID][]uuid.UUID
+
+func (f fakeWatched) GetWatchedMovieIDs(userID uuid.UUID) ([]uuid.UUID, error) { return f[userID], nil }
+
+func TestNextCards_SkipsWatchedMovies(t *testing.T) {
+	deck := makeIDs(4)
+	viewer, other := uuid.New(), uuid.New()
+	s := &DeckService{
+		roomRepo:    &fakeDeckRooms{deck: deck},
+		movieRepo:   fakeDeckMovies{},
+		swipeRepo:   &fakeDeckSwipes{swipes: []models.Swipe{{MovieID: deck[0], Direction: models.SwipeDirectionLeft}}},
+		watchedRepo: fakeWatched{viewer: {deck[2]}},
+	}
+	room := &models.Room{ID: uuid.New(), DeckMode: models.DeckModeShared}
+
+	movies, ok, err := s.NextCards(room, viewer, 10)
+	if err != nil || !ok {
+		t.Fatalf("unexpected result: ok=%v err=%v", ok, err)
+	}
+	if len(movies) != 2 || movies[0].ID != deck[1] || movies[1].ID != deck[3] {
+		t.Errorf("watched and swiped movies must be skipped, got %+v", movies)
+	}
+
+	// Другой участник комнаты этот фильм не смотрел — для него он остаётся в колоде
+	movies, _, _ = s.NextCards(room, other, 10)
+	if len(movies) != 3 {
+		t.Errorf("expected 3 cards for another member, got %d", len(movies))
+	}
+}
+
+func TestDeckProgressOf_MaybeIsNotDone(t *testing.T) {
+	deck := makeIDs(4)
+	swipes := []models.Swipe{
+		{MovieID: deck[0], Direction: models.SwipeDirectionLeft},
+		{MovieID: deck[1], Direction: models.SwipeDirectionMaybe},
+		{MovieID: deck[2], Direction: models.SwipeDirectionSuperlike},
+		{MovieID: uuid.New(), Direction: models.SwipeDirectionRight}, // не из колоды
+	}
+
+	got := DeckProgressOf(deck, swipes)
+	if got.Swiped != 2 || got.Total != 4 || got.Finished {
+		t.Fatalf("progress = %+v, want 2/4 unfinished", got)
+	}
+
+	swipes[1].Direction = models.SwipeDirectionRight
+	swipes = append(swipes, models.Swipe{MovieID: deck[3], Direction: models.SwipeDirectionSeen})
+	if got := DeckProgressOf(deck, swipes); !got.Finished || got.Swiped != 4 {
+		t.Fatalf("progress = %+v, want finished 4/4", got)
+	}
+}
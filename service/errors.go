@@ -0,0 +1,15 @@
+package service
+
+import "errors"
+
+// Ошибки сервисов, которые REST-обработчики и WebSocket-команды переводят в ответ клиенту одинаково
+var (
+	ErrRoomNotFound      = errors.New("room not found")
+	ErrRoomFinished      = errors.New("room is finished")
+	ErrRoomNotWaiting    = errors.New("room is already started or finished")
+	ErrNotRoomHost       = errors.New("only room host can do this")
+	ErrInvalidDirection  = errors.New("invalid swipe direction")
+	ErrAlreadySwiped     = errors.New("already swiped this movie")
+	ErrInvalidUndoWindow = errors.New("within_seconds must be positive")
+	ErrNothingToUndo     = errors.New("no swipe found to undo")
+)
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Стратегии групповой оценки колоды (значения DECK_GROUP_STRATEGIES)
+	GroupStrategyDeck        = "deck"         // Без переупорядочивания: порядок колоды как есть (контрольная группа)
+	GroupStrategyAverage     = "average"      // Средний вкус участников
+	GroupStrategyLeastMisery = "least_misery" // Вкус самого недовольного участника: фильмы, против которых никто не будет
+
+	// liveVoteWeight — вклад лайков, уже поставленных в этой комнате, относительно вкуса участников
+	liveVoteWeight = 1.0
+	// filterFitWeight — вклад совпадения с жанрами и настроением фильтра комнаты
+	filterFitWeight = 0.5
+)
+
+// GroupContext — всё, что стратегия знает о комнате при оценке карточки.
+type GroupContext struct {
+	Room     *models.Room
+	Filter   *models.Filter               // nil — у комнаты нет фильтра
+	Profiles []*TasteProfile              // Профили вкуса участников по свайпам во всех комнатах
+	Members  []uuid.UUID                  // Участники комнаты
+	Swipes   map[uuid.UUID][]models.Swipe // Свайпы текущей комнаты по фильмам — обновляются с каждым новым свайпом
+}
+
+// GroupScorer — стратегия групповой оценки: чем выше оценка, тем раньше фильм в колоде комнаты.
+type GroupScorer interface {
+	Name() string
+	Score(group *GroupContext, movie *models.Movie) float64
+}
+
+// GroupScorers — зарегистрированные стратегии по имени.
+var GroupScorers = map[string]GroupScorer{
+	GroupStrategyDeck:        deckOrderScorer{},
+	GroupStrategyAverage:     averageScorer{},
+	GroupStrategyLeastMisery: leastMiseryScorer{},
+}
+
+// ParseGroupScorers разбирает список стратегий через запятую; неизвестные имена пропускаются.
+func ParseGroupScorers(names string) []GroupScorer {
+	var scorers []GroupScorer
+	for _, name := range strings.Split(names, ",") {
+		if scorer, ok := GroupScorers[strings.TrimSpace(strings.ToLower(name))]; ok {
+			scorers = append(scorers, scorer)
+		}
+	}
+	return scorers
+}
+
+// PickGroupScorer детерминированно выбирает стратегию для комнаты: комнаты поровну делятся между стратегиями (A/B-тест).
+func PickGroupScorer(scorers []GroupScorer, roomID uuid.UUID) GroupScorer {
+	if len(scorers) == 0 {
+		return nil
+	}
+	return scorers[uint64(DeckSeed(roomID))%uint64(len(scorers))]
+}
+
+// RankForGroup упорядочивает карточки по убыванию групповой оценки. Отложенные (deferred, maybe) остаются в конце,
+// при равных оценках сохраняется порядок колоды.
+func RankForGroup(scorer GroupScorer, group *GroupContext, movies []models.Movie, deferred map[uuid.UUID]bool) []models.Movie {
+	scores := make(map[uuid.UUID]float64, len(movies))
+	for i := range movies {
+		scores[movies[i].ID] = scorer.Score(group, &movies[i])
+	}
+
+	ranked := make([]models.Movie, len(movies))
+	copy(ranked, movies)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i].ID, ranked[j].ID
+		if deferred[a] != deferred[b] {
+			return !deferred[a]
+		}
+		return scores[a] > scores[b]
+	})
+	return ranked
+}
+
+type deckOrderScorer struct{}
+
+func (deckOrderScorer) Name() string { return GroupStrategyDeck }
+
+func (deckOrderScorer) Score(*GroupContext, *models.Movie) float64 { return 0 }
+
+type averageScorer struct{}
+
+func (averageScorer) Name() string { return GroupStrategyAverage }
+
+func (averageScorer) Score(group *GroupContext, movie *models.Movie) float64 {
+	taste := 0.0
+	if len(group.Profiles) > 0 {
+		for _, p := range group.Profiles {
+			taste += ScoreMovie(p, movie)
+		}
+		taste /= float64(len(group.Profiles))
+	}
+	return taste + liveVoteWeight*LiveVoteScore(group, movie.ID) + filterFitWeight*FilterFit(group.Filter, movie)
+}
+
+type leastMiseryScorer struct{}
+
+func (leastMiseryScorer) Name() string { return GroupStrategyLeastMisery }
+
+func (leastMiseryScorer) Score(group *GroupContext, movie *models.Movie) float64 {
+	taste := 0.0
+	for i, p := range group.Profiles {
+		score := ScoreMovie(p, movie)
+		if i == 0 || score < taste {
+			taste = score
+		}
+	}
+	return taste + liveVoteWeight*LiveVoteScore(group, movie.ID) + filterFitWeight*FilterFit(group.Filter, movie)
+}
+
+// LiveVoteScore — доля очков, уже набранных фильмом в комнате (лайки и суперлайки от активных участников), 0..1+.
+// Дизлайк хоста или любого участника снижает шанс матча, поэтому такие фильмы уходят вниз.
+func LiveVoteScore(group *GroupContext, movieID uuid.UUID) float64 {
+	swipes := group.Swipes[movieID]
+	if len(swipes) == 0 || len(group.Members) == 0 {
+		return 0
+	}
+	votes := CountVotes(group.Room, group.Members, swipes)
+	if votes.Active <= 0 {
+		return 0
+	}
+	dislikes := 0
+	for _, sw := range swipes {
+		if sw.Direction == models.SwipeDirectionLeft && !votes.Abstained[sw.UserID] && isMember(group.Members, sw.UserID) {
+			dislikes++
+		}
+	}
+	return float64(votes.Score()-dislikes) / float64(votes.Active)
+}
+
+// FilterFit — доля жанров фильтра (и жанров его настроения), которые есть у фильма, 0..1.
+// Колода уже собрана по фильтру, поэтому это лишь предпочтение фильмов, попадающих в несколько выбранных жанров сразу.
+func FilterFit(filter *models.Filter, movie *models.Movie) float64 {
+	if filter == nil {
+		return 0
+	}
+	wanted := make(map[string]bool)
+	if genres, err := repository.JSONToGenres(filter.Genres); err == nil {
+		for _, g := range genres {
+			wanted[strings.ToLower(strings.TrimSpace(g))] = true
+		}
+	}
+	for _, g := range models.GenresForMood(filter.Mood) {
+		wanted[strings.ToLower(g)] = true
+	}
+	if len(wanted) == 0 {
+		return 0
+	}
+
+	hits := 0
+	for _, g := range movieGenres(movie) {
+		if wanted[g] {
+			hits++
+		}
+	}
+	return math.Min(1, float64(hits)/math.Min(float64(len(wanted)), 3))
+}
+
+func isMember(members []uuid.UUID, userID uuid.UUID) bool {
+	for _, id := range members {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
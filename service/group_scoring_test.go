@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+func groupMovie(genre string) models.Movie {
+	return models.Movie{ID: uuid.New(), Title: genre, Genre: `["` + genre + `"]`}
+}
+
+func TestGroupScorers_AverageVsLeastMisery(t *testing.T) {
+	// Один участник обожает ужасы и терпеть не может комедии, второй к комедиям относится тепло
+	fan := &TasteProfile{GenreAffinity: map[string]float64{"ужасы": 2, "комедия": -1}}
+	mild := &TasteProfile{GenreAffinity: map[string]float64{"ужасы": -0.5, "комедия": 0.5, "драма": 0.4}}
+	group := &GroupContext{Room: &models.Room{}, Profiles: []*TasteProfile{fan, mild}}
+
+	horror, drama := groupMovie("ужасы"), groupMovie("драма")
+	movies := []models.Movie{drama, horror}
+
+	avg := RankForGroup(GroupScorers[GroupStrategyAverage], group, movies, nil)
+	if avg[0].ID != horror.ID {
+		t.Errorf("average: strong fan must pull %q up, got %q", horror.Title, avg[0].Title)
+	}
+	misery := RankForGroup(GroupScorers[GroupStrategyLeastMisery], group, movies, nil)
+	if misery[0].ID != drama.ID {
+		t.Errorf("least_misery: movie nobody dislikes must go first, got %q", misery[0].Title)
+	}
+}
+
+func TestRankForGroup_LiveVotes(t *testing.T) {
+	host, u1, u2 := uuid.New(), uuid.New(), uuid.New()
+	a, b, c := groupMovie("драма"), groupMovie("драма"), groupMovie("драма")
+	group := &GroupContext{
+		Room:    &models.Room{HostID: host},
+		Members: []uuid.UUID{host, u1, u2},
+		Swipes: map[uuid.UUID][]models.Swipe{
+			b.ID: {{UserID: host, MovieID: b.ID, Direction: models.SwipeDirectionRight}, {UserID: u1, MovieID: b.ID, Direction: models.SwipeDirectionSuperlike}},
+			c.ID: {{UserID: u1, MovieID: c.ID, Direction: models.SwipeDirectionLeft}},
+		},
+	}
+
+	ranked := RankForGroup(GroupScorers[GroupStrategyLeastMisery], group, []models.Movie{a, c, b}, nil)
+	if ranked[0].ID != b.ID || ranked[1].ID != a.ID || ranked[2].ID != c.ID {
+		t.Errorf("expected liked movie first and disliked last, got %s, %s, %s", ranked[0].ID, ranked[1].ID, ranked[2].ID)
+	}
+
+	// Тот же порядок колоды без стратегии не меняется
+	deck := RankForGroup(GroupScorers[GroupStrategyDeck], group, []models.Movie{a, c, b}, nil)
+	if deck[0].ID != a.ID || deck[1].ID != c.ID || deck[2].ID != b.ID {
+		t.Error("deck strategy must keep the deck order")
+	}
+}
+
+func TestRankForGroup_DeferredStayLast(t *testing.T) {
+	liked, plain := groupMovie("фантастика"), groupMovie("драма")
+	group := &GroupContext{
+		Room:     &models.Room{},
+		Profiles: []*TasteProfile{{GenreAffinity: map[string]float64{"фантастика": 2}}},
+	}
+
+	ranked := RankForGroup(GroupScorers[GroupStrategyAverage], group, []models.Movie{plain, liked}, map[uuid.UUID]bool{liked.ID: true})
+	if ranked[0].ID != plain.ID {
+		t.Error("maybe cards must stay after unswiped ones whatever their score")
+	}
+}
+
+func TestFilterFit(t *testing.T) {
+	filter := &models.Filter{Genres: `["Комедия","Драма"]`}
+	both := models.Movie{Genre: `["комедия","драма"]`}
+	one := models.Movie{Genre: `["комедия","ужасы"]`}
+
+	if got := FilterFit(filter, &both); got != 1 {
+		t.Errorf("movie with all filter genres: got %v, want 1", got)
+	}
+	if got := FilterFit(filter, &one); got != 0.5 {
+		t.Errorf("movie with one of two filter genres: got %v, want 0.5", got)
+	}
+	if got := FilterFit(nil, &both); got != 0 {
+		t.Errorf("no filter: got %v, want 0", got)
+	}
+}
+
+func TestPickGroupScorer_SplitsRooms(t *testing.T) {
+	scorers := ParseGroupScorers("least_misery, average, unknown")
+	if len(scorers) != 2 {
+		t.Fatalf("expected 2 known strategies, got %d", len(scorers))
+	}
+	if PickGroupScorer(nil, uuid.New()) != nil {
+		t.Error("no strategies must disable group scoring")
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		roomID := uuid.New()
+		picked := PickGroupScorer(scorers, roomID)
+		if PickGroupScorer(scorers, roomID) != picked {
+			t.Fatal("strategy must be stable for a room")
+		}
+		counts[picked.Name()]++
+	}
+	if counts[GroupStrategyAverage] == 0 || counts[GroupStrategyLeastMisery] == 0 {
+		t.Errorf("rooms must be split between strategies, got %v", counts)
+	}
+}
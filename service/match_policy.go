@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// minMatchLikes — меньше двух лайков матчем не считается ни при каком правиле
+	minMatchLikes = 2
+	// defaultMatchPercent — порог правила percent, если хост его не задал
+	defaultMatchPercent = 60
+	// defaultMatchQuorum — K правила quorum, если хост его не задал
+	defaultMatchQuorum = 2
+	// superlikeBonus — сколько очков суперлайк добавляет к обычному лайку в рейтинге фильма
+	superlikeBonus = 1
+)
+
+// MatchVotes — голоса активных участников комнаты за один фильм.
+type MatchVotes struct {
+	Active     int                // Активных участников (сделавших хотя бы один свайп), кроме отметивших фильм seen
+	Likes      int                // Сколько из них лайкнули фильм (right или superlike)
+	Superlikes int                // Сколько из лайков — суперлайки
+	HostVetoed bool               // Хост поставил фильму дизлайк
+	LikedBy    map[uuid.UUID]bool // Кто лайкнул
+	Abstained  map[uuid.UUID]bool // Кто уже смотрел фильм (seen) и не голосует за него
+}
+
+// Score — очки фильма в комнате: лайк даёт 1, суперлайк — 1 + superlikeBonus.
+func (v MatchVotes) Score() int {
+	return v.Likes + v.Superlikes*superlikeBonus
+}
+
+// CountVotes собирает голоса за фильм по его свайпам. Учитываются только активные участники.
+// seen — воздержание: участник не считается ни «за», ни «против» и не входит в Active для этого фильма.
+// maybe — ещё не решил: участник остаётся в Active, но голоса не отдаёт.
+func CountVotes(room *models.Room, activeMemberIDs []uuid.UUID, swipes []models.Swipe) MatchVotes {
+	active := make(map[uuid.UUID]bool, len(activeMemberIDs))
+	for _, id := range activeMemberIDs {
+		active[id] = true
+	}
+
+	votes := MatchVotes{
+		Active:    len(activeMemberIDs),
+		LikedBy:   make(map[uuid.UUID]bool),
+		Abstained: make(map[uuid.UUID]bool),
+	}
+	for _, sw := range swipes {
+		if !active[sw.UserID] {
+			continue
+		}
+		switch sw.Direction {
+		case models.SwipeDirectionRight:
+			votes.LikedBy[sw.UserID] = true
+		case models.SwipeDirectionSuperlike:
+			if !votes.LikedBy[sw.UserID] {
+				votes.Superlikes++
+			}
+			votes.LikedBy[sw.UserID] = true
+		case models.SwipeDirectionLeft:
+			if sw.UserID == room.HostID {
+				votes.HostVetoed = true
+			}
+		case models.SwipeDirectionSeen:
+			votes.Abstained[sw.UserID] = true
+		}
+	}
+	votes.Likes = len(votes.LikedBy)
+	votes.Active -= len(votes.Abstained)
+	return votes
+}
+
+// PolicySatisfied проверяет, является ли фильм с такими голосами матчем по правилу комнаты.
+func PolicySatisfied(room *models.Room, votes MatchVotes) bool {
+	if votes.Active < 2 || votes.Likes < minMatchLikes {
+		return false
+	}
+
+	switch room.MatchPolicy {
+	case models.MatchPolicyPercent:
+		percent := defaultMatchPercent
+		if room.MatchThreshold != nil {
+			percent = *room.MatchThreshold
+		}
+		return votes.Likes*100 >= percent*votes.Active
+	case models.MatchPolicyQuorum:
+		quorum := defaultMatchQuorum
+		if room.MatchThreshold != nil {
+			quorum = *room.MatchThreshold
+		}
+		return votes.Likes >= quorum
+	case models.MatchPolicyMajorityHostVeto:
+		return !votes.HostVetoed && votes.Likes*2 > votes.Active
+	default:
+		return votes.Likes >= votes.Active
+	}
+}
+
+// ValidateMatchPolicy проверяет правило и порог, переданные хостом при создании комнаты.
+func ValidateMatchPolicy(policy models.MatchPolicy, threshold *int) error {
+	switch policy {
+	case "", models.MatchPolicyUnanimous, models.MatchPolicyMajorityHostVeto:
+		return nil
+	case models.MatchPolicyPercent:
+		if threshold != nil && (*threshold < 1 || *threshold > 100) {
+			return fmt.Errorf("match_threshold for percent policy must be between 1 and 100")
+		}
+		return nil
+	case models.MatchPolicyQuorum:
+		if threshold != nil && *threshold < minMatchLikes {
+			return fmt.Errorf("match_threshold for quorum policy must be at least %d", minMatchLikes)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown match policy %q", policy)
+	}
+}
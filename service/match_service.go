@@ -1,9 +1,12 @@
 package service
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
 	"kinoswipe/repository"
 
 	"github.com/google/uuid"
@@ -15,6 +18,7 @@ type matchRepoInterface interface {
 	GetByRoomID(roomID uuid.UUID) ([]models.Match, error)
 	Create(match *models.Match) error
 	GetByID(id uuid.UUID) (*models.Match, error)
+	Delete(id uuid.UUID) error
 }
 
 type swipeRepoInterface interface {
@@ -24,6 +28,7 @@ type swipeRepoInterface interface {
 	CountLikesByMovie(roomID, movieID uuid.UUID) (int, error)
 	HasUserSwiped(userID, roomID, movieID uuid.UUID) (bool, error)
 	GetUserSwipes(userID, roomID uuid.UUID) ([]models.Swipe, error)
+	GetRoomSwipes(roomID uuid.UUID) ([]models.Swipe, error)
 }
 
 type roomRepoInterface interface {
@@ -35,11 +40,18 @@ type movieRepoInterface interface {
 	GetByID(id uuid.UUID) (*models.Movie, error)
 }
 
+type availabilityRepoInterface interface {
+	GetByMovieID(movieID uuid.UUID, region string) ([]models.MovieAvailability, error)
+}
+
 // MatchServiceInterface — интерфейс для тестов и подмены.
 type MatchServiceInterface interface {
 	CheckAndCreateMatch(roomID, movieID uuid.UUID) (*models.Match, error)
+	ReevaluateMatch(roomID, movieID uuid.UUID) (created, retracted *models.Match, err error)
+	ReevaluateRoom(roomID uuid.UUID) (created, retracted []models.Match, err error)
 	GetMatchWithDetails(matchID uuid.UUID) (*models.MatchWithDetails, error)
 	GetAlmostMatches(roomID uuid.UUID) ([]models.AlmostMatch, error)
+	GetRanking(roomID uuid.UUID, limit int) ([]models.RankedMovie, error)
 }
 
 type MatchService struct {
@@ -47,6 +59,8 @@ type MatchService struct {
 	swipeRepo swipeRepoInterface
 	roomRepo  roomRepoInterface
 	movieRepo movieRepoInterface
+
+	availabilityRepo availabilityRepoInterface // nil — без блока «где посмотреть»
 }
 
 // Проверка, что MatchService реализует интерфейс.
@@ -67,79 +81,163 @@ func NewMatchService(
 	}
 }
 
-// CheckAndCreateMatch проверяет, все ли активные участники комнаты (сделавшие хотя бы один свайп) лайкнули фильм, и создаёт матч.
-// Неактивные (никогда не свайпавшие) в расчёт не берутся.
+// SetAvailability подключает предложения платформ: матч с деталями получает блок «где посмотреть».
+func (s *MatchService) SetAvailability(availabilityRepo *repository.AvailabilityRepository) {
+	if availabilityRepo != nil {
+		s.availabilityRepo = availabilityRepo
+	}
+}
+
+// CheckAndCreateMatch проверяет голоса активных участников комнаты (сделавших хотя бы один свайп) за фильм
+// по правилу матча комнаты и создаёт матч. Неактивные (никогда не свайпавшие) в расчёт не берутся.
 func (s *MatchService) CheckAndCreateMatch(roomID, movieID uuid.UUID) (*models.Match, error) {
-	activeMemberIDs, err := s.swipeRepo.GetUserIDsWhoSwipedInRoom(roomID)
+	holds, err := s.policyHolds(roomID, movieID)
+	if err != nil || !holds {
+		return nil, err
+	}
+
+	// Идемпотентность
+	existing, err := s.findMatch(roomID, movieID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get active members: %w", err)
+		return nil, err
 	}
-	// Матч возможен только если хотя бы 2 человека активны в комнате
-	if len(activeMemberIDs) < 2 {
-		return nil, nil
+	if existing != nil {
+		return existing, nil
 	}
 
-	// Уникальные пользователи, лайкнувшие этот фильм в этой комнате
-	uniqueUserLikes := make(map[uuid.UUID]bool)
-	allSwipes, err := s.swipeRepo.GetAllSwipesByMovie(roomID, movieID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get swipes: %w", err)
+	match := &models.Match{
+		ID:      uuid.New(),
+		RoomID:  roomID,
+		MovieID: movieID,
 	}
-	for _, swipe := range allSwipes {
-		if swipe.Direction == models.SwipeDirectionRight {
-			uniqueUserLikes[swipe.UserID] = true
-		}
+	if err := s.matchRepo.Create(match); err != nil {
+		return nil, fmt.Errorf("failed to create match: %w", err)
 	}
+	return match, nil
+}
 
-	// Матч только если каждый активный участник лайкнул фильм
-	if len(uniqueUserLikes) < len(activeMemberIDs) {
-		return nil, nil
+// ReevaluateMatch пересчитывает матч по фильму после отмены свайпа.
+// Если матч больше не проходит по правилу комнаты — удаляет его и возвращает в retracted;
+// если правило теперь выполняется (например, участник перестал быть активным) — создаёт матч и возвращает в created.
+func (s *MatchService) ReevaluateMatch(roomID, movieID uuid.UUID) (created, retracted *models.Match, err error) {
+	holds, err := s.policyHolds(roomID, movieID)
+	if err != nil {
+		return nil, nil, err
 	}
-	for _, id := range activeMemberIDs {
-		if !uniqueUserLikes[id] {
-			return nil, nil
+	existing, err := s.findMatch(roomID, movieID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case existing != nil && !holds:
+		if err := s.matchRepo.Delete(existing.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to retract match: %w", err)
 		}
+		return nil, existing, nil
+	case existing == nil && holds:
+		match, err := s.CheckAndCreateMatch(roomID, movieID)
+		return match, nil, err
 	}
+	return nil, nil, nil
+}
 
-	// Идемпотентность
-	exists, err := s.matchRepo.Exists(roomID, movieID)
+// ReevaluateRoom пересчитывает все матчи комнаты: фильмы с лайками и уже существующие матчи.
+// Нужен, когда меняется состав участников (выход, исключение, бан).
+func (s *MatchService) ReevaluateRoom(roomID uuid.UUID) (created, retracted []models.Match, err error) {
+	movieIDs, err := s.swipeRepo.GetLikedMovies(roomID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check match existence: %w", err)
+		return nil, nil, fmt.Errorf("failed to get liked movies: %w", err)
 	}
-	if exists {
-		matches, err := s.matchRepo.GetByRoomID(roomID)
+	matches, err := s.matchRepo.GetByRoomID(roomID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get matches: %w", err)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(movieIDs)+len(matches))
+	for _, m := range matches {
+		movieIDs = append(movieIDs, m.MovieID)
+	}
+	for _, movieID := range movieIDs {
+		if seen[movieID] {
+			continue
+		}
+		seen[movieID] = true
+
+		c, r, err := s.ReevaluateMatch(roomID, movieID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get matches: %w", err)
+			return created, retracted, err
 		}
-		for _, m := range matches {
-			if m.MovieID == movieID {
-				return &m, nil
-			}
+		if c != nil {
+			created = append(created, *c)
+		}
+		if r != nil {
+			retracted = append(retracted, *r)
 		}
 	}
+	return created, retracted, nil
+}
 
-	match := &models.Match{
-		ID:      uuid.New(),
-		RoomID:  roomID,
-		MovieID: movieID,
+// policyHolds проверяет, проходит ли фильм по правилу матча комнаты при текущих свайпах.
+func (s *MatchService) policyHolds(roomID, movieID uuid.UUID) (bool, error) {
+	activeMemberIDs, err := s.swipeRepo.GetUserIDsWhoSwipedInRoom(roomID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get active members: %w", err)
 	}
-	if err := s.matchRepo.Create(match); err != nil {
-		return nil, fmt.Errorf("failed to create match: %w", err)
+	// Матч возможен только если хотя бы 2 человека активны в комнате
+	if len(activeMemberIDs) < 2 {
+		return false, nil
 	}
-	return match, nil
+
+	room, err := s.roomRepo.GetByID(roomID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get room: %w", err)
+	}
+
+	allSwipes, err := s.swipeRepo.GetAllSwipesByMovie(roomID, movieID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get swipes: %w", err)
+	}
+	return PolicySatisfied(room, CountVotes(room, activeMemberIDs, allSwipes)), nil
 }
 
-// GetAlmostMatches возвращает фильмы, которые лайкнули все активные участники кроме одного (N-1).
+// findMatch возвращает матч комнаты по фильму или nil, если его нет.
+func (s *MatchService) findMatch(roomID, movieID uuid.UUID) (*models.Match, error) {
+	exists, err := s.matchRepo.Exists(roomID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check match existence: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	matches, err := s.matchRepo.GetByRoomID(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matches: %w", err)
+	}
+	for _, m := range matches {
+		if m.MovieID == movieID {
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetAlmostMatches возвращает фильмы, которым до матча по правилу комнаты не хватает одного лайка.
+// Для правила unanimous это фильмы, которые лайкнули все активные участники кроме одного (N-1).
 func (s *MatchService) GetAlmostMatches(roomID uuid.UUID) ([]models.AlmostMatch, error) {
 	activeMemberIDs, err := s.swipeRepo.GetUserIDsWhoSwipedInRoom(roomID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active members: %w", err)
 	}
-	n := len(activeMemberIDs)
-	if n <= 1 {
+	if len(activeMemberIDs) <= 1 {
 		return nil, nil
 	}
 
+	room, err := s.roomRepo.GetByID(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+
 	likedMovieIDs, err := s.swipeRepo.GetLikedMovies(roomID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get liked movies: %w", err)
@@ -156,39 +254,86 @@ func (s *MatchService) GetAlmostMatches(roomID uuid.UUID) ([]models.AlmostMatch,
 		if existingMatchMovieIDs[movieID] {
 			continue
 		}
-		count, err := s.swipeRepo.CountLikesByMovie(roomID, movieID)
-		if err != nil || count != n-1 {
+		allSwipes, err := s.swipeRepo.GetAllSwipesByMovie(roomID, movieID)
+		if err != nil {
+			continue
+		}
+		votes := CountVotes(room, activeMemberIDs, allSwipes)
+		if PolicySatisfied(room, votes) {
+			continue
+		}
+		withOneMore := votes
+		withOneMore.Likes++
+		if !PolicySatisfied(room, withOneMore) {
 			continue
 		}
 		movie, err := s.movieRepo.GetByID(movieID)
 		if err != nil {
 			continue
 		}
-		// Кто не лайкнул (один человек)
-		allSwipes, _ := s.swipeRepo.GetAllSwipesByMovie(roomID, movieID)
-		whoLiked := make(map[uuid.UUID]bool)
-		for _, sw := range allSwipes {
-			if sw.Direction == models.SwipeDirectionRight {
-				whoLiked[sw.UserID] = true
-			}
-		}
-		var missingUserID uuid.UUID
+
+		// Кто не лайкнул — указываем, только если такой участник один
+		var missing []uuid.UUID
 		for _, uid := range activeMemberIDs {
-			if !whoLiked[uid] {
-				missingUserID = uid
-				break
+			if !votes.LikedBy[uid] && !votes.Abstained[uid] {
+				missing = append(missing, uid)
 			}
 		}
-		result = append(result, models.AlmostMatch{
-			MovieID:       movieID,
-			Movie:         movie,
-			LikesCount:    n - 1,
-			MissingUserID: &missingUserID,
-		})
+		almost := models.AlmostMatch{
+			MovieID:         movieID,
+			Movie:           movie,
+			LikesCount:      votes.Likes,
+			SuperlikesCount: votes.Superlikes,
+			Score:           votes.Score(),
+		}
+		if len(missing) == 1 {
+			almost.MissingUserID = &missing[0]
+		}
+		result = append(result, almost)
 	}
+
+	// Суперлайки поднимают фильм выше; при равных очках порядок по id фильма, чтобы по списку работал курсор
+	sort.Slice(result, func(i, j int) bool { return almostMatchBefore(&result[i], float64(result[j].Score), result[j].MovieID) })
 	return result, nil
 }
 
+// ListAlmostMatches возвращает страницу «почти матчей» в порядке GetAlmostMatches и курсор следующей страницы.
+// Список считается целиком, курсор — очки и id фильма последней записи страницы.
+func (s *MatchService) ListAlmostMatches(roomID uuid.UUID, page pagination.Page) ([]models.AlmostMatch, string, error) {
+	var after float64
+	if page.Cursor != nil {
+		var err error
+		if after, err = page.Cursor.Float(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	list, err := s.GetAlmostMatches(roomID)
+	if err != nil {
+		return nil, "", err
+	}
+	if page.Cursor != nil {
+		start := sort.Search(len(list), func(i int) bool { return !almostMatchBefore(&list[i], after, page.Cursor.ID) })
+		if start < len(list) && list[start].MovieID == page.Cursor.ID {
+			start++
+		}
+		list = list[start:]
+	}
+
+	list, next := pagination.Trim(list, page.Limit, func(a *models.AlmostMatch) pagination.Cursor {
+		return pagination.Cursor{Key: pagination.FloatKey(float64(a.Score)), ID: a.MovieID}
+	})
+	return list, next, nil
+}
+
+// almostMatchBefore — идёт ли a в списке раньше позиции (score, movieID): больше очков, при равных — меньший id.
+func almostMatchBefore(a *models.AlmostMatch, score float64, movieID uuid.UUID) bool {
+	if s := float64(a.Score); s != score {
+		return s > score
+	}
+	return bytes.Compare(a.MovieID[:], movieID[:]) < 0
+}
+
 // GetMatchWithDetails получает матч с полной информацией
 func (s *MatchService) GetMatchWithDetails(matchID uuid.UUID) (*models.MatchWithDetails, error) {
 	match, err := s.matchRepo.GetByID(matchID)
@@ -217,7 +362,7 @@ func (s *MatchService) GetMatchWithDetails(matchID uuid.UUID) (*models.MatchWith
 		if err == nil && hasSwiped {
 			swipes, _ := s.swipeRepo.GetUserSwipes(member.ID, match.RoomID)
 			for _, swipe := range swipes {
-				if swipe.MovieID == match.MovieID && swipe.Direction == models.SwipeDirectionRight {
+				if swipe.MovieID == match.MovieID && swipe.Direction.IsLike() {
 					users = append(users, member)
 					break
 				}
@@ -225,10 +370,17 @@ func (s *MatchService) GetMatchWithDetails(matchID uuid.UUID) (*models.MatchWith
 		}
 	}
 
-	return &models.MatchWithDetails{
+	details := &models.MatchWithDetails{
 		Match: *match,
 		Movie: *movie,
 		Room:  *room,
 		Users: users,
-	}, nil
+	}
+	if s.availabilityRepo != nil {
+		// Без предложений матч всё равно показываем
+		if offers, err := s.availabilityRepo.GetByMovieID(match.MovieID, ""); err == nil {
+			details.WhereToWatch = offers
+		}
+	}
+	return details, nil
 }
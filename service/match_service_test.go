@@ -2,9 +2,12 @@ package service
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
 	"kinoswipe/models"
+	"kinoswipe/pagination"
+	"kinoswipe/repository"
 
 	"github.com/google/uuid"
 )
@@ -15,6 +18,7 @@ type mockMatchRepo struct {
 	exists     map[string]bool
 	byRoom     map[uuid.UUID][]models.Match
 	created    []models.Match
+	deleted    []uuid.UUID
 	getByID    map[uuid.UUID]*models.Match
 	createErr  error
 	getByIDErr error
@@ -47,6 +51,11 @@ func (m *mockMatchRepo) Create(match *models.Match) error {
 	return nil
 }
 
+func (m *mockMatchRepo) Delete(id uuid.UUID) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
 func (m *mockMatchRepo) GetByID(id uuid.UUID) (*models.Match, error) {
 	if m.getByIDErr != nil {
 		return nil, m.getByIDErr
@@ -107,6 +116,16 @@ func (m *mockSwipeRepo) HasUserSwiped(userID, roomID, movieID uuid.UUID) (bool,
 	return false, nil
 }
 
+func (m *mockSwipeRepo) GetRoomSwipes(roomID uuid.UUID) ([]models.Swipe, error) {
+	var swipes []models.Swipe
+	for k, list := range m.swipesByMovie {
+		if strings.HasPrefix(k, roomID.String()+":") {
+			swipes = append(swipes, list...)
+		}
+	}
+	return swipes, nil
+}
+
 func (m *mockSwipeRepo) GetUserSwipes(userID, roomID uuid.UUID) ([]models.Swipe, error) {
 	if m.userSwipes != nil {
 		return m.userSwipes[key2(userID, roomID)], nil
@@ -130,7 +149,11 @@ func (m *mockRoomRepo) GetByID(id uuid.UUID) (*models.Room, error) {
 	if m.rooms != nil {
 		return m.rooms[id], nil
 	}
-	return nil, errors.New("room not found")
+	return nil, repository.ErrRoomNotFound
+}
+
+func roomRepoWith(room *models.Room) *mockRoomRepo {
+	return &mockRoomRepo{rooms: map[uuid.UUID]*models.Room{room.ID: room}}
 }
 
 type mockMovieRepo struct {
@@ -146,6 +169,15 @@ func (m *mockMovieRepo) GetByID(id uuid.UUID) (*models.Movie, error) {
 	return nil, errors.New("movie not found")
 }
 
+type mockAvailabilityRepo struct {
+	offers map[uuid.UUID][]models.MovieAvailability
+	err    error
+}
+
+func (m *mockAvailabilityRepo) GetByMovieID(movieID uuid.UUID, region string) ([]models.MovieAvailability, error) {
+	return m.offers[movieID], m.err
+}
+
 // Тесты CheckAndCreateMatch и GetAlmostMatches.
 func TestCheckAndCreateMatch_NoActiveMembers(t *testing.T) {
 	roomID := uuid.New()
@@ -153,7 +185,7 @@ func TestCheckAndCreateMatch_NoActiveMembers(t *testing.T) {
 	swipe := &mockSwipeRepo{
 		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: {}},
 	}
-	ms := &MatchService{swipeRepo: swipe, matchRepo: &mockMatchRepo{}, roomRepo: &mockRoomRepo{}, movieRepo: &mockMovieRepo{}}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: &mockMatchRepo{}, roomRepo: roomRepoWith(&models.Room{ID: roomID, MatchPolicy: models.MatchPolicyUnanimous}), movieRepo: &mockMovieRepo{}}
 
 	match, err := ms.CheckAndCreateMatch(roomID, movieID)
 	if err != nil {
@@ -176,7 +208,7 @@ func TestCheckAndCreateMatch_NotAllLiked(t *testing.T) {
 			},
 		},
 	}
-	ms := &MatchService{swipeRepo: swipe, matchRepo: &mockMatchRepo{}, roomRepo: &mockRoomRepo{}, movieRepo: &mockMovieRepo{}}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: &mockMatchRepo{}, roomRepo: roomRepoWith(&models.Room{ID: roomID, MatchPolicy: models.MatchPolicyUnanimous}), movieRepo: &mockMovieRepo{}}
 
 	match, err := ms.CheckAndCreateMatch(roomID, movieID)
 	if err != nil {
@@ -202,7 +234,7 @@ func TestCheckAndCreateMatch_AllLiked_CreatesMatch(t *testing.T) {
 		},
 	}
 	matchRepo := &mockMatchRepo{created: created}
-	ms := &MatchService{swipeRepo: swipe, matchRepo: matchRepo, roomRepo: &mockRoomRepo{}, movieRepo: &mockMovieRepo{}}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: matchRepo, roomRepo: roomRepoWith(&models.Room{ID: roomID, MatchPolicy: models.MatchPolicyUnanimous}), movieRepo: &mockMovieRepo{}}
 
 	match, err := ms.CheckAndCreateMatch(roomID, movieID)
 	if err != nil {
@@ -225,7 +257,7 @@ func TestGetAlmostMatches_Empty(t *testing.T) {
 		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: {uuid.New(), uuid.New()}},
 		likedMovies:   map[uuid.UUID][]uuid.UUID{roomID: {}},
 	}
-	ms := &MatchService{swipeRepo: swipe, matchRepo: &mockMatchRepo{}, roomRepo: &mockRoomRepo{}, movieRepo: &mockMovieRepo{}}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: &mockMatchRepo{}, roomRepo: roomRepoWith(&models.Room{ID: roomID, MatchPolicy: models.MatchPolicyUnanimous}), movieRepo: &mockMovieRepo{}}
 
 	list, err := ms.GetAlmostMatches(roomID)
 	if err != nil {
@@ -252,7 +284,7 @@ func TestGetAlmostMatches_OneAlmostMatch(t *testing.T) {
 	ms := &MatchService{
 		swipeRepo: swipe,
 		matchRepo: &mockMatchRepo{},
-		roomRepo:  &mockRoomRepo{},
+		roomRepo:  roomRepoWith(&models.Room{ID: roomID, MatchPolicy: models.MatchPolicyUnanimous}),
 		movieRepo: &mockMovieRepo{movies: map[uuid.UUID]*models.Movie{movieID: movie}},
 	}
 
@@ -270,3 +302,443 @@ func TestGetAlmostMatches_OneAlmostMatch(t *testing.T) {
 		t.Errorf("expected missing user u2, got %v", list[0].MissingUserID)
 	}
 }
+
+// Тесты правил матча комнаты.
+
+func likes(users ...uuid.UUID) []models.Swipe {
+	swipes := make([]models.Swipe, 0, len(users))
+	for _, u := range users {
+		swipes = append(swipes, models.Swipe{UserID: u, Direction: models.SwipeDirectionRight})
+	}
+	return swipes
+}
+
+func intPtr(v int) *int { return &v }
+
+func checkPolicy(t *testing.T, room *models.Room, active []uuid.UUID, swipes []models.Swipe) *models.Match {
+	t.Helper()
+	movieID := uuid.New()
+	swipe := &mockSwipeRepo{
+		userIDsInRoom: map[uuid.UUID][]uuid.UUID{room.ID: active},
+		swipesByMovie: map[string][]models.Swipe{key(room.ID, movieID): swipes},
+	}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: &mockMatchRepo{}, roomRepo: roomRepoWith(room), movieRepo: &mockMovieRepo{}}
+	match, err := ms.CheckAndCreateMatch(room.ID, movieID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return match
+}
+
+func TestCheckAndCreateMatch_PercentPolicy(t *testing.T) {
+	u := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	room := &models.Room{ID: uuid.New(), MatchPolicy: models.MatchPolicyPercent, MatchThreshold: intPtr(60)}
+
+	if m := checkPolicy(t, room, u, likes(u[0], u[1])); m != nil {
+		t.Error("40% of likes must not match with 60% threshold")
+	}
+	if m := checkPolicy(t, room, u, likes(u[0], u[1], u[2])); m == nil {
+		t.Error("60% of likes must match with 60% threshold")
+	}
+}
+
+func TestCheckAndCreateMatch_QuorumPolicy(t *testing.T) {
+	u := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	room := &models.Room{ID: uuid.New(), MatchPolicy: models.MatchPolicyQuorum, MatchThreshold: intPtr(3)}
+
+	if m := checkPolicy(t, room, u, likes(u[0], u[1])); m != nil {
+		t.Error("2 likes must not match with quorum 3")
+	}
+	if m := checkPolicy(t, room, u, likes(u[0], u[1], u[2])); m == nil {
+		t.Error("3 likes must match with quorum 3")
+	}
+}
+
+func TestCheckAndCreateMatch_MajorityHostVetoPolicy(t *testing.T) {
+	host := uuid.New()
+	u := []uuid.UUID{host, uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	room := &models.Room{ID: uuid.New(), HostID: host, MatchPolicy: models.MatchPolicyMajorityHostVeto}
+
+	if m := checkPolicy(t, room, u, likes(u[1], u[2])); m != nil {
+		t.Error("2 of 5 likes is not a majority")
+	}
+	if m := checkPolicy(t, room, u, likes(u[1], u[2], u[3])); m == nil {
+		t.Error("3 of 5 likes without veto must match")
+	}
+	vetoed := append(likes(u[1], u[2], u[3], u[4]), models.Swipe{UserID: host, Direction: models.SwipeDirectionLeft})
+	if m := checkPolicy(t, room, u, vetoed); m != nil {
+		t.Error("host dislike must veto the match")
+	}
+}
+
+func TestCheckAndCreateMatch_UnanimousIgnoresNonActiveLikes(t *testing.T) {
+	u1, u2, outsider := uuid.New(), uuid.New(), uuid.New()
+	room := &models.Room{ID: uuid.New(), MatchPolicy: models.MatchPolicyUnanimous}
+
+	if m := checkPolicy(t, room, []uuid.UUID{u1, u2}, likes(u1, outsider)); m != nil {
+		t.Error("like from a non-active user must not complete a unanimous match")
+	}
+}
+
+func TestGetAlmostMatches_QuorumPolicy(t *testing.T) {
+	roomID := uuid.New()
+	almostID, farID := uuid.New(), uuid.New()
+	u := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	room := &models.Room{ID: roomID, MatchPolicy: models.MatchPolicyQuorum, MatchThreshold: intPtr(3)}
+	swipe := &mockSwipeRepo{
+		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: u},
+		likedMovies:   map[uuid.UUID][]uuid.UUID{roomID: {almostID, farID}},
+		swipesByMovie: map[string][]models.Swipe{
+			key(roomID, almostID): likes(u[0], u[1]),
+			key(roomID, farID):    likes(u[0]),
+		},
+	}
+	ms := &MatchService{
+		swipeRepo: swipe,
+		matchRepo: &mockMatchRepo{},
+		roomRepo:  roomRepoWith(room),
+		movieRepo: &mockMovieRepo{movies: map[uuid.UUID]*models.Movie{
+			almostID: {ID: almostID},
+			farID:    {ID: farID},
+		}},
+	}
+
+	list, err := ms.GetAlmostMatches(roomID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].MovieID != almostID {
+		t.Fatalf("expected only the movie one like short of quorum, got %+v", list)
+	}
+	if list[0].LikesCount != 2 || list[0].MissingUserID != nil {
+		t.Errorf("almost match: likes=%d missing=%v", list[0].LikesCount, list[0].MissingUserID)
+	}
+}
+
+func TestListAlmostMatches_Pages(t *testing.T) {
+	roomID := uuid.New()
+	u := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	room := &models.Room{ID: roomID, MatchPolicy: models.MatchPolicyQuorum, MatchThreshold: intPtr(3)}
+	movieIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	swipe := &mockSwipeRepo{
+		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: u},
+		likedMovies:   map[uuid.UUID][]uuid.UUID{roomID: movieIDs},
+		swipesByMovie: map[string][]models.Swipe{},
+	}
+	movies := map[uuid.UUID]*models.Movie{}
+	for _, id := range movieIDs {
+		// Одинаковые очки у всех — порядок задаёт id фильма
+		swipe.swipesByMovie[key(roomID, id)] = likes(u[0], u[1])
+		movies[id] = &models.Movie{ID: id}
+	}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: &mockMatchRepo{}, roomRepo: roomRepoWith(room), movieRepo: &mockMovieRepo{movies: movies}}
+
+	all, err := ms.GetAlmostMatches(roomID)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("almost matches = %d, err = %v; want 3", len(all), err)
+	}
+
+	var got []uuid.UUID
+	page := pagination.Page{Limit: 2}
+	for i := 0; i < 3; i++ {
+		list, next, err := ms.ListAlmostMatches(roomID, page)
+		if err != nil {
+			t.Fatalf("page %d: %v", i, err)
+		}
+		for _, a := range list {
+			got = append(got, a.MovieID)
+		}
+		if next == "" {
+			break
+		}
+		if page.Cursor, err = pagination.Decode(next); err != nil {
+			t.Fatalf("decode cursor: %v", err)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("paged = %d movies, want 3", len(got))
+	}
+	for i := range all {
+		if got[i] != all[i].MovieID {
+			t.Errorf("paged[%d] = %s, want %s", i, got[i], all[i].MovieID)
+		}
+	}
+}
+
+func TestGetAlmostMatches_HostVetoExcluded(t *testing.T) {
+	roomID := uuid.New()
+	movieID := uuid.New()
+	host, u1, u2 := uuid.New(), uuid.New(), uuid.New()
+	room := &models.Room{ID: roomID, HostID: host, MatchPolicy: models.MatchPolicyMajorityHostVeto}
+	swipe := &mockSwipeRepo{
+		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: {host, u1, u2}},
+		likedMovies:   map[uuid.UUID][]uuid.UUID{roomID: {movieID}},
+		swipesByMovie: map[string][]models.Swipe{
+			key(roomID, movieID): append(likes(u1), models.Swipe{UserID: host, Direction: models.SwipeDirectionLeft}),
+		},
+	}
+	ms := &MatchService{
+		swipeRepo: swipe,
+		matchRepo: &mockMatchRepo{},
+		roomRepo:  roomRepoWith(room),
+		movieRepo: &mockMovieRepo{movies: map[uuid.UUID]*models.Movie{movieID: {ID: movieID}}},
+	}
+
+	list, err := ms.GetAlmostMatches(roomID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("vetoed movie must not be an almost match, got %d", len(list))
+	}
+}
+
+// Тесты пересчёта матча после отмены свайпа.
+
+func TestReevaluateMatch_RetractsBrokenMatch(t *testing.T) {
+	roomID, movieID := uuid.New(), uuid.New()
+	u1, u2 := uuid.New(), uuid.New()
+	existing := models.Match{ID: uuid.New(), RoomID: roomID, MovieID: movieID}
+	swipe := &mockSwipeRepo{
+		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: {u1, u2}},
+		swipesByMovie: map[string][]models.Swipe{key(roomID, movieID): likes(u1)},
+	}
+	matchRepo := &mockMatchRepo{
+		exists: map[string]bool{key(roomID, movieID): true},
+		byRoom: map[uuid.UUID][]models.Match{roomID: {existing}},
+	}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: matchRepo, roomRepo: roomRepoWith(&models.Room{ID: roomID}), movieRepo: &mockMovieRepo{}}
+
+	created, retracted, err := ms.ReevaluateMatch(roomID, movieID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != nil {
+		t.Error("no match must be created")
+	}
+	if retracted == nil || retracted.ID != existing.ID {
+		t.Fatalf("expected match %s to be retracted, got %v", existing.ID, retracted)
+	}
+	if len(matchRepo.deleted) != 1 || matchRepo.deleted[0] != existing.ID {
+		t.Errorf("expected match to be deleted, got %v", matchRepo.deleted)
+	}
+}
+
+func TestReevaluateMatch_HostVetoRetractsMatch(t *testing.T) {
+	roomID, movieID := uuid.New(), uuid.New()
+	host, u1, u2 := uuid.New(), uuid.New(), uuid.New()
+	room := &models.Room{ID: roomID, HostID: host, MatchPolicy: models.MatchPolicyMajorityHostVeto}
+	existing := models.Match{ID: uuid.New(), RoomID: roomID, MovieID: movieID}
+	// Большинство уже собрало матч, после чего хост смахнул фильм влево
+	swipe := &mockSwipeRepo{
+		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: {host, u1, u2}},
+		swipesByMovie: map[string][]models.Swipe{
+			key(roomID, movieID): append(likes(u1, u2), models.Swipe{UserID: host, Direction: models.SwipeDirectionLeft}),
+		},
+	}
+	matchRepo := &mockMatchRepo{
+		exists: map[string]bool{key(roomID, movieID): true},
+		byRoom: map[uuid.UUID][]models.Match{roomID: {existing}},
+	}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: matchRepo, roomRepo: roomRepoWith(room), movieRepo: &mockMovieRepo{}}
+
+	created, retracted, err := ms.ReevaluateMatch(roomID, movieID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != nil {
+		t.Error("no match must be created")
+	}
+	if retracted == nil || retracted.ID != existing.ID {
+		t.Fatalf("expected vetoed match %s to be retracted, got %v", existing.ID, retracted)
+	}
+}
+
+func TestReevaluateMatch_KeepsValidMatch(t *testing.T) {
+	roomID, movieID := uuid.New(), uuid.New()
+	u1, u2 := uuid.New(), uuid.New()
+	existing := models.Match{ID: uuid.New(), RoomID: roomID, MovieID: movieID}
+	swipe := &mockSwipeRepo{
+		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: {u1, u2}},
+		swipesByMovie: map[string][]models.Swipe{key(roomID, movieID): likes(u1, u2)},
+	}
+	matchRepo := &mockMatchRepo{
+		exists: map[string]bool{key(roomID, movieID): true},
+		byRoom: map[uuid.UUID][]models.Match{roomID: {existing}},
+	}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: matchRepo, roomRepo: roomRepoWith(&models.Room{ID: roomID}), movieRepo: &mockMovieRepo{}}
+
+	created, retracted, err := ms.ReevaluateMatch(roomID, movieID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != nil || retracted != nil {
+		t.Errorf("expected no changes, got created=%v retracted=%v", created, retracted)
+	}
+	if len(matchRepo.deleted) != 0 {
+		t.Errorf("valid match must not be deleted")
+	}
+}
+
+func TestReevaluateRoom_MemberRemoved(t *testing.T) {
+	roomID, kept, fresh := uuid.New(), uuid.New(), uuid.New()
+	u1, u2 := uuid.New(), uuid.New()
+	// Третий участник исключён: его дизлайк больше не мешает fresh, а его лайк больше не держит kept
+	existing := models.Match{ID: uuid.New(), RoomID: roomID, MovieID: kept}
+	swipe := &mockSwipeRepo{
+		userIDsInRoom: map[uuid.UUID][]uuid.UUID{roomID: {u1, u2}},
+		swipesByMovie: map[string][]models.Swipe{
+			key(roomID, kept):  likes(u1),
+			key(roomID, fresh): likes(u1, u2),
+		},
+		likedMovies: map[uuid.UUID][]uuid.UUID{roomID: {kept, fresh}},
+	}
+	matchRepo := &mockMatchRepo{
+		exists: map[string]bool{key(roomID, kept): true},
+		byRoom: map[uuid.UUID][]models.Match{roomID: {existing}},
+	}
+	ms := &MatchService{swipeRepo: swipe, matchRepo: matchRepo, roomRepo: roomRepoWith(&models.Room{ID: roomID}), movieRepo: &mockMovieRepo{}}
+
+	created, retracted, err := ms.ReevaluateRoom(roomID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(created) != 1 || created[0].MovieID != fresh {
+		t.Errorf("expected match for %s to be created, got %v", fresh, created)
+	}
+	if len(retracted) != 1 || retracted[0].ID != existing.ID {
+		t.Errorf("expected match %s to be retracted, got %v", existing.ID, retracted)
+	}
+}
+
+func TestCheckAndCreateMatch_SwipeDirections(t *testing.T) {
+	u := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	room := &models.Room{ID: uuid.New(), MatchPolicy: models.MatchPolicyUnanimous}
+	swipe := func(user uuid.UUID, d models.SwipeDirection) models.Swipe {
+		return models.Swipe{UserID: user, Direction: d}
+	}
+
+	superlikes := []models.Swipe{swipe(u[0], models.SwipeDirectionSuperlike), swipe(u[1], models.SwipeDirectionRight), swipe(u[2], models.SwipeDirectionSuperlike)}
+	if m := checkPolicy(t, room, u, superlikes); m == nil {
+		t.Error("superlike must count as a like")
+	}
+
+	seen := []models.Swipe{swipe(u[0], models.SwipeDirectionRight), swipe(u[1], models.SwipeDirectionRight), swipe(u[2], models.SwipeDirectionSeen)}
+	if m := checkPolicy(t, room, u, seen); m == nil {
+		t.Error("member who has seen the movie must not block a unanimous match")
+	}
+
+	maybe := []models.Swipe{swipe(u[0], models.SwipeDirectionRight), swipe(u[1], models.SwipeDirectionRight), swipe(u[2], models.SwipeDirectionMaybe)}
+	if m := checkPolicy(t, room, u, maybe); m != nil {
+		t.Error("undecided (maybe) member must still block a unanimous match")
+	}
+}
+
+func TestCountVotes_SuperlikeRaisesScore(t *testing.T) {
+	u := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	room := &models.Room{ID: uuid.New()}
+
+	plain := CountVotes(room, u, likes(u[0], u[1]))
+	super := CountVotes(room, u, []models.Swipe{
+		{UserID: u[0], Direction: models.SwipeDirectionSuperlike},
+		{UserID: u[1], Direction: models.SwipeDirectionRight},
+	})
+
+	if plain.Likes != super.Likes {
+		t.Errorf("likes = %d and %d, want equal", plain.Likes, super.Likes)
+	}
+	if super.Superlikes != 1 || super.Score() <= plain.Score() {
+		t.Errorf("superlike must raise score: plain %d, super %d", plain.Score(), super.Score())
+	}
+}
+
+func TestRankMovies_BestCompromise(t *testing.T) {
+	u := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	members := []models.User{{ID: u[0], Username: "a"}, {ID: u[1], Username: "b"}, {ID: u[2], Username: "c"}}
+	room := &models.Room{ID: uuid.New()}
+	popular, superliked, disliked, unliked := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	sw := func(user, movie uuid.UUID, d models.SwipeDirection) models.Swipe {
+		return models.Swipe{UserID: user, MovieID: movie, Direction: d}
+	}
+	swipes := []models.Swipe{
+		sw(u[0], popular, models.SwipeDirectionRight), sw(u[1], popular, models.SwipeDirectionRight), sw(u[2], popular, models.SwipeDirectionLeft),
+		sw(u[0], superliked, models.SwipeDirectionSuperlike), sw(u[1], superliked, models.SwipeDirectionSuperlike), sw(u[2], superliked, models.SwipeDirectionLeft),
+		sw(u[0], disliked, models.SwipeDirectionRight), sw(u[1], disliked, models.SwipeDirectionLeft), sw(u[2], disliked, models.SwipeDirectionLeft),
+		sw(u[0], unliked, models.SwipeDirectionLeft),
+	}
+
+	ranked := RankMovies(room, members, u, swipes, map[uuid.UUID]bool{}, map[uuid.UUID]float64{})
+	if len(ranked) != 3 {
+		t.Fatalf("expected 3 ranked movies (no unliked), got %d", len(ranked))
+	}
+	want := []uuid.UUID{superliked, popular, disliked}
+	for i, id := range want {
+		if ranked[i].MovieID != id || ranked[i].Rank != i+1 {
+			t.Errorf("rank %d: got %s (rank %d), want %s", i+1, ranked[i].MovieID, ranked[i].Rank, id)
+		}
+	}
+
+	top := ranked[0]
+	if top.Likes != 2 || top.Superlikes != 2 || top.Dislikes != 1 || top.Coverage != 1 {
+		t.Errorf("unexpected breakdown: %+v", top)
+	}
+	if len(top.Votes) != 3 || top.Votes[2].Username != "c" || top.Votes[2].Direction != models.SwipeDirectionLeft {
+		t.Errorf("unexpected per-member votes: %+v", top.Votes)
+	}
+}
+
+func TestRankMovies_RatingBreaksTies(t *testing.T) {
+	u := []uuid.UUID{uuid.New(), uuid.New()}
+	room := &models.Room{ID: uuid.New()}
+	low, high := uuid.New(), uuid.New()
+	swipes := []models.Swipe{
+		{UserID: u[0], MovieID: low, Direction: models.SwipeDirectionRight},
+		{UserID: u[0], MovieID: high, Direction: models.SwipeDirectionRight},
+	}
+
+	ranked := RankMovies(room, nil, u, swipes, nil, map[uuid.UUID]float64{low: 5.1, high: 8.7})
+	if len(ranked) != 2 || ranked[0].MovieID != high {
+		t.Fatalf("higher rated movie must rank first, got %+v", ranked)
+	}
+	if ranked[0].Coverage != 0.5 {
+		t.Errorf("coverage = %v, want 0.5", ranked[0].Coverage)
+	}
+}
+
+// Тесты блока «где посмотреть» в деталях матча.
+
+func TestGetMatchWithDetails_WhereToWatch(t *testing.T) {
+	room := &models.Room{ID: uuid.New()}
+	movieID := uuid.New()
+	match := &models.Match{ID: uuid.New(), RoomID: room.ID, MovieID: movieID}
+	ms := &MatchService{
+		swipeRepo: &mockSwipeRepo{},
+		matchRepo: &mockMatchRepo{getByID: map[uuid.UUID]*models.Match{match.ID: match}},
+		roomRepo:  roomRepoWith(room),
+		movieRepo: &mockMovieRepo{movies: map[uuid.UUID]*models.Movie{movieID: {ID: movieID, Title: "Брат"}}},
+	}
+
+	details, err := ms.GetMatchWithDetails(match.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if details.WhereToWatch != nil {
+		t.Error("without availability repo where_to_watch must be empty")
+	}
+
+	offers := []models.MovieAvailability{{MovieID: movieID, Platform: "kinopoisk", URL: "https://hd.kinopoisk.ru/film/41519", Region: "RU", OfferType: models.OfferSubscription}}
+	ms.availabilityRepo = &mockAvailabilityRepo{offers: map[uuid.UUID][]models.MovieAvailability{movieID: offers}}
+	details, err = ms.GetMatchWithDetails(match.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(details.WhereToWatch) != 1 || details.WhereToWatch[0].Platform != "kinopoisk" {
+		t.Errorf("expected kinopoisk offer, got %+v", details.WhereToWatch)
+	}
+
+	// Ошибка справочника не ломает карточку матча
+	ms.availabilityRepo = &mockAvailabilityRepo{err: errors.New("db down")}
+	if _, err := ms.GetMatchWithDetails(match.ID); err != nil {
+		t.Errorf("availability errors must be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+// Веса составляющих рейтинга «лучшего компромисса» (в сумме 1).
+const (
+	rankWeightLikeRatio = 0.50 // Доля лайков среди голосовавших
+	rankWeightSuperlike = 0.15 // Доля суперлайков среди голосовавших
+	rankWeightCoverage  = 0.20 // Сколько участников вообще видели карточку
+	rankWeightRating    = 0.15 // Рейтинг IMDb/Кинопоиска
+
+	defaultRankingLimit = 20
+	maxRankingLimit     = 100
+)
+
+// RankMovies оценивает каждый свайпнутый в комнате фильм и возвращает список по убыванию очков (0..100).
+// Фильмы без единого лайка в рейтинг не попадают. Movie не заполняется — это делает вызывающий.
+//   - like ratio: лайки / голосовавшие (активные участники без отметивших seen);
+//   - superlikes: суперлайки / голосовавшие;
+//   - coverage: участники, которые свайпнули фильм, / активные участники;
+//   - rating: лучший из рейтингов IMDb и Кинопоиска / 10 (ratings может не содержать фильм).
+//
+// Votes содержит голос каждого участника комнаты, включая тех, кто ещё не видел фильм.
+func RankMovies(
+	room *models.Room,
+	members []models.User,
+	activeMemberIDs []uuid.UUID,
+	swipes []models.Swipe,
+	matched map[uuid.UUID]bool,
+	ratings map[uuid.UUID]float64,
+) []models.RankedMovie {
+	byMovie := make(map[uuid.UUID][]models.Swipe)
+	var order []uuid.UUID
+	for _, sw := range swipes {
+		if _, ok := byMovie[sw.MovieID]; !ok {
+			order = append(order, sw.MovieID)
+		}
+		byMovie[sw.MovieID] = append(byMovie[sw.MovieID], sw)
+	}
+
+	active := len(activeMemberIDs)
+	ranked := make([]models.RankedMovie, 0, len(order))
+	for _, movieID := range order {
+		movieSwipes := byMovie[movieID]
+		votes := CountVotes(room, activeMemberIDs, movieSwipes)
+		if votes.Likes == 0 {
+			continue
+		}
+
+		directions := make(map[uuid.UUID]models.SwipeDirection, len(movieSwipes))
+		for _, sw := range movieSwipes {
+			directions[sw.UserID] = sw.Direction
+		}
+		item := models.RankedMovie{
+			MovieID:    movieID,
+			Matched:    matched[movieID],
+			Likes:      votes.Likes,
+			Superlikes: votes.Superlikes,
+			Votes:      memberVotes(members, directions),
+		}
+		swiped := 0
+		for _, uid := range activeMemberIDs {
+			d, ok := directions[uid]
+			if !ok {
+				continue
+			}
+			swiped++
+			switch d {
+			case models.SwipeDirectionLeft:
+				item.Dislikes++
+			case models.SwipeDirectionMaybe:
+				item.Maybe++
+			case models.SwipeDirectionSeen:
+				item.Seen++
+			}
+		}
+
+		if votes.Active > 0 {
+			item.LikeRatio = float64(votes.Likes) / float64(votes.Active)
+		}
+		if active > 0 {
+			item.Coverage = float64(swiped) / float64(active)
+		}
+		superRatio := 0.0
+		if votes.Active > 0 {
+			superRatio = float64(votes.Superlikes) / float64(votes.Active)
+		}
+		rating := math.Min(ratings[movieID]/10, 1)
+
+		score := rankWeightLikeRatio*item.LikeRatio +
+			rankWeightSuperlike*superRatio +
+			rankWeightCoverage*item.Coverage +
+			rankWeightRating*rating
+		item.Score = math.Round(score*1000) / 10
+		item.LikeRatio = math.Round(item.LikeRatio*100) / 100
+		item.Coverage = math.Round(item.Coverage*100) / 100
+		ranked = append(ranked, item)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Likes > ranked[j].Likes
+	})
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+	return ranked
+}
+
+// memberVotes — голос каждого участника за фильм; участники без свайпа получают пустое направление.
+func memberVotes(members []models.User, directions map[uuid.UUID]models.SwipeDirection) []models.MemberVote {
+	votes := make([]models.MemberVote, 0, len(members))
+	for _, m := range members {
+		votes = append(votes, models.MemberVote{UserID: m.ID, Username: m.Username, Direction: directions[m.ID]})
+	}
+	return votes
+}
+
+// GetRanking возвращает рейтинг «лучшего компромисса» комнаты: топ-limit фильмов с разбивкой голосов по участникам.
+func (s *MatchService) GetRanking(roomID uuid.UUID, limit int) ([]models.RankedMovie, error) {
+	if limit <= 0 {
+		limit = defaultRankingLimit
+	}
+	if limit > maxRankingLimit {
+		limit = maxRankingLimit
+	}
+
+	room, err := s.roomRepo.GetByID(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+	members, err := s.roomRepo.GetMembers(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	activeMemberIDs, err := s.swipeRepo.GetUserIDsWhoSwipedInRoom(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active members: %w", err)
+	}
+	swipes, err := s.swipeRepo.GetRoomSwipes(roomID)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.matchRepo.GetByRoomID(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matches: %w", err)
+	}
+	matched := make(map[uuid.UUID]bool, len(matches))
+	for _, m := range matches {
+		matched[m.MovieID] = true
+	}
+
+	// Рейтинги фильмов нужны до сортировки: загружаем каждый свайпнутый фильм один раз
+	movies := make(map[uuid.UUID]*models.Movie)
+	ratings := make(map[uuid.UUID]float64)
+	for _, sw := range swipes {
+		if _, ok := movies[sw.MovieID]; ok {
+			continue
+		}
+		movie, err := s.movieRepo.GetByID(sw.MovieID)
+		if err != nil {
+			movies[sw.MovieID] = nil
+			continue
+		}
+		movies[sw.MovieID] = movie
+		ratings[sw.MovieID] = bestRating(movie)
+	}
+
+	ranked := RankMovies(room, members, activeMemberIDs, swipes, matched, ratings)
+	result := make([]models.RankedMovie, 0, limit)
+	for _, item := range ranked {
+		if len(result) >= limit {
+			break
+		}
+		item.Movie = movies[item.MovieID]
+		if item.Movie == nil {
+			continue
+		}
+		item.Rank = len(result) + 1
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// bestRating — лучший из рейтингов IMDb и Кинопоиска (0, если рейтингов нет).
+func bestRating(movie *models.Movie) float64 {
+	best := 0.0
+	if movie.IMDbRating != nil {
+		best = *movie.IMDbRating
+	}
+	if movie.KPRating != nil && *movie.KPRating > best {
+		best = *movie.KPRating
+	}
+	return best
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// historyLimit — сколько последних свайпов пользователя учитывается в профиле вкуса
+	historyLimit = 2000
+	// ratingsLimit — сколько последних оценок после просмотра учитывается в профиле вкуса
+	ratingsLimit = 500
+	// coLikesLimit — сколько лайков соседей загружается для коллаборативной фильтрации
+	coLikesLimit = 20000
+	// eraScale и durationScale — на сколько лет/минут отклонение от предпочтений снижает оценку в e раз
+	eraScale      = 15.0
+	durationScale = 40.0
+	// collaborativeWeight — вклад коллаборативной оценки относительно профиля вкуса
+	collaborativeWeight = 0.5
+)
+
+// Вес свайпа в профиле вкуса: суперлайк сильнее лайка, seen — слабый плюс (фильм в целом интересен), maybe не учитывается.
+var swipeTasteWeight = map[models.SwipeDirection]float64{
+	models.SwipeDirectionSuperlike: 2,
+	models.SwipeDirectionRight:     1,
+	models.SwipeDirectionSeen:      0.5,
+	models.SwipeDirectionLeft:      -1,
+}
+
+// RatingTasteWeight переводит оценку после просмотра (1–10) в вес профиля вкуса: 10 — как суперлайк (2),
+// 1 — вдвое сильнее дизлайка (-2), середина шкалы около нуля.
+func RatingTasteWeight(rating int) float64 {
+	return (float64(rating) - 5.5) / 2.25
+}
+
+// TasteProfile — вкус пользователя по всем его свайпам во всех комнатах и оценкам после просмотра.
+type TasteProfile struct {
+	UserID            uuid.UUID          `json:"user_id"`
+	Swipes            int                `json:"swipes"`             // Сколько свайпов учтено
+	Ratings           int                `json:"ratings"`            // Сколько оценок после просмотра учтено
+	GenreAffinity     map[string]float64 `json:"genre_affinity"`     // -2..2: средний вес свайпов и оценок по жанру
+	PreferredYear     float64            `json:"preferred_year"`     // Средний год понравившихся фильмов (0 — нет данных)
+	PreferredDuration float64            `json:"preferred_duration"` // Средняя длительность понравившихся фильмов, мин (0 — нет данных)
+}
+
+// recSwipeRepoInterface — методы SwipeRepository, нужные рекомендациям (позволяет подставлять фикстуры в тестах).
+type recSwipeRepoInterface interface {
+	GetUserHistory(userID uuid.UUID, limit int) ([]models.SwipeWithMovie, error)
+	GetCoLikerLikes(userID uuid.UUID, limit int) ([]models.Swipe, error)
+}
+
+// recRatingRepoInterface — оценки после просмотра для профиля вкуса.
+type recRatingRepoInterface interface {
+	GetUserRatings(userID uuid.UUID, limit int) ([]models.RatingWithMovie, error)
+}
+
+// RecommendationService упорядочивает фильмы для пользователя по его вкусу и, опционально,
+// по лайкам пользователей с похожими лайками (коллаборативная фильтрация).
+type RecommendationService struct {
+	swipeRepo     recSwipeRepoInterface
+	ratingRepo    recRatingRepoInterface // nil — профиль только по свайпам
+	collaborative bool
+}
+
+func NewRecommendationService(swipeRepo *repository.SwipeRepository, collaborative bool) *RecommendationService {
+	return &RecommendationService{
+		swipeRepo:     swipeRepo,
+		collaborative: collaborative,
+	}
+}
+
+// SetRatings подключает оценки после просмотра к профилю вкуса.
+func (s *RecommendationService) SetRatings(ratingRepo *repository.RatingRepository) {
+	if ratingRepo != nil {
+		s.ratingRepo = ratingRepo
+	}
+}
+
+// Profile строит профиль вкуса пользователя по истории свайпов и оценкам после просмотра.
+func (s *RecommendationService) Profile(userID uuid.UUID) (*TasteProfile, error) {
+	history, err := s.swipeRepo.GetUserHistory(userID, historyLimit)
+	if err != nil {
+		return nil, err
+	}
+	var ratings []models.RatingWithMovie
+	if s.ratingRepo != nil {
+		if ratings, err = s.ratingRepo.GetUserRatings(userID, ratingsLimit); err != nil {
+			return nil, fmt.Errorf("failed to get user ratings: %w", err)
+		}
+	}
+	profile := BuildTasteProfile(userID, history, ratings)
+	return &profile, nil
+}
+
+// Rank возвращает movies в порядке убывания персональной оценки. Без истории свайпов и оценок порядок не меняется.
+func (s *RecommendationService) Rank(userID uuid.UUID, movies []models.Movie) ([]models.Movie, error) {
+	if len(movies) < 2 {
+		return movies, nil
+	}
+
+	profile, err := s.Profile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var collab map[uuid.UUID]float64
+	if s.collaborative {
+		likes, err := s.swipeRepo.GetCoLikerLikes(userID, coLikesLimit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build collaborative scores: %w", err)
+		}
+		collab = CollaborativeScores(userID, likes)
+	}
+	if profile.Swipes == 0 && profile.Ratings == 0 && len(collab) == 0 {
+		return movies, nil
+	}
+
+	scores := make(map[uuid.UUID]float64, len(movies))
+	for _, m := range movies {
+		scores[m.ID] = ScoreMovie(profile, &m) + collaborativeWeight*collab[m.ID]
+	}
+
+	ranked := make([]models.Movie, len(movies))
+	copy(ranked, movies)
+	sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i].ID] > scores[ranked[j].ID] })
+	return ranked, nil
+}
+
+// BuildTasteProfile считает жанровые предпочтения и предпочитаемые эпоху и длительность.
+// Эпоха и длительность — средние по фильмам с положительным весом (лайк, суперлайк, seen, высокая оценка).
+// Оценка после просмотра точнее свайпа, поэтому свайпы по оценённым фильмам не учитываются.
+func BuildTasteProfile(userID uuid.UUID, history []models.SwipeWithMovie, ratings []models.RatingWithMovie) TasteProfile {
+	profile := TasteProfile{UserID: userID, GenreAffinity: make(map[string]float64)}
+
+	genreSum := make(map[string]float64)
+	genreCount := make(map[string]int)
+	var yearSum, yearWeight, durationSum, durationWeight float64
+
+	add := func(movie *models.Movie, weight float64) {
+		for _, g := range movieGenres(movie) {
+			genreSum[g] += weight
+			genreCount[g]++
+		}
+		if weight <= 0 {
+			return
+		}
+		if movie.Year > 0 {
+			yearSum += float64(movie.Year) * weight
+			yearWeight += weight
+		}
+		if movie.Duration > 0 {
+			durationSum += float64(movie.Duration) * weight
+			durationWeight += weight
+		}
+	}
+
+	rated := make(map[uuid.UUID]bool, len(ratings))
+	for _, item := range ratings {
+		rated[item.MovieID] = true
+		profile.Ratings++
+		add(&item.Movie, RatingTasteWeight(item.Rating))
+	}
+	for _, item := range history {
+		weight, ok := swipeTasteWeight[item.Direction]
+		if !ok || rated[item.MovieID] {
+			continue
+		}
+		profile.Swipes++
+		add(&item.Movie, weight)
+	}
+
+	for g, sum := range genreSum {
+		profile.GenreAffinity[g] = sum / float64(genreCount[g])
+	}
+	if yearWeight > 0 {
+		profile.PreferredYear = yearSum / yearWeight
+	}
+	if durationWeight > 0 {
+		profile.PreferredDuration = durationSum / durationWeight
+	}
+	return profile
+}
+
+// ScoreMovie оценивает фильм по профилю: средняя близость по жанрам плюс близость к эпохе и длительности (по 0..1).
+func ScoreMovie(profile *TasteProfile, movie *models.Movie) float64 {
+	score := 0.0
+
+	genres := movieGenres(movie)
+	if len(genres) > 0 {
+		sum := 0.0
+		for _, g := range genres {
+			sum += profile.GenreAffinity[g]
+		}
+		score += sum / float64(len(genres))
+	}
+	if profile.PreferredYear > 0 && movie.Year > 0 {
+		score += math.Exp(-math.Abs(float64(movie.Year)-profile.PreferredYear) / eraScale)
+	}
+	if profile.PreferredDuration > 0 && movie.Duration > 0 {
+		score += math.Exp(-math.Abs(float64(movie.Duration)-profile.PreferredDuration) / durationScale)
+	}
+	return score
+}
+
+// CollaborativeScores — item-based оценка по со-лайкам: для каждого фильма, который пользователь ещё не лайкал,
+// суммируется сходство (коэффициент Жаккара по лайкам) с каждым соседом, который этот фильм лайкнул.
+// Результат нормирован к 0..1.
+func CollaborativeScores(userID uuid.UUID, likes []models.Swipe) map[uuid.UUID]float64 {
+	likedBy := make(map[uuid.UUID]map[uuid.UUID]bool)
+	for _, sw := range likes {
+		if !sw.Direction.IsLike() {
+			continue
+		}
+		if likedBy[sw.UserID] == nil {
+			likedBy[sw.UserID] = make(map[uuid.UUID]bool)
+		}
+		likedBy[sw.UserID][sw.MovieID] = true
+	}
+
+	mine := likedBy[userID]
+	scores := make(map[uuid.UUID]float64)
+	if len(mine) == 0 {
+		return scores
+	}
+
+	for neighbour, theirs := range likedBy {
+		if neighbour == userID {
+			continue
+		}
+		common := 0
+		for movieID := range theirs {
+			if mine[movieID] {
+				common++
+			}
+		}
+		if common == 0 {
+			continue
+		}
+		similarity := float64(common) / float64(len(mine)+len(theirs)-common)
+		for movieID := range theirs {
+			if !mine[movieID] {
+				scores[movieID] += similarity
+			}
+		}
+	}
+
+	best := 0.0
+	for _, v := range scores {
+		best = math.Max(best, v)
+	}
+	for id := range scores {
+		scores[id] /= best
+	}
+	return scores
+}
+
+// movieGenres разбирает JSON-массив жанров фильма; названия приводятся к нижнему регистру.
+func movieGenres(movie *models.Movie) []string {
+	genres, err := repository.JSONToGenres(movie.Genre)
+	if err != nil {
+		return nil
+	}
+	for i, g := range genres {
+		genres[i] = strings.ToLower(strings.TrimSpace(g))
+	}
+	return genres
+}
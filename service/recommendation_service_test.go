@@ -0,0 +1,223 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+// recFixture — история свайпов из testdata/swipe_history.json: Анна любит старую фантастику
+// и не любит мелодрамы, Борис лайкает ту же фантастику и «Дюну», Вера — мелодрамы.
+type recFixture struct {
+	Users  map[string]uuid.UUID `json:"users"`
+	Movies []models.Movie       `json:"movies"`
+	Swipes []models.Swipe       `json:"swipes"`
+}
+
+func loadRecFixture(t *testing.T) *recFixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/swipe_history.json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	var f recFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("parse fixture: %v", err)
+	}
+	return &f
+}
+
+func (f *recFixture) movie(t *testing.T, title string) models.Movie {
+	t.Helper()
+	for _, m := range f.Movies {
+		if m.Title == title {
+			return m
+		}
+	}
+	t.Fatalf("no movie %q in fixture", title)
+	return models.Movie{}
+}
+
+// fixtureSwipeRepo отдаёт историю и со-лайки из фикстуры так же, как SwipeRepository из БД.
+type fixtureSwipeRepo struct{ f *recFixture }
+
+func (r *fixtureSwipeRepo) GetUserHistory(userID uuid.UUID, limit int) ([]models.SwipeWithMovie, error) {
+	movies := make(map[uuid.UUID]models.Movie)
+	for _, m := range r.f.Movies {
+		movies[m.ID] = m
+	}
+	var history []models.SwipeWithMovie
+	for _, sw := range r.f.Swipes {
+		if sw.UserID == userID {
+			history = append(history, models.SwipeWithMovie{Swipe: sw, Movie: movies[sw.MovieID]})
+		}
+	}
+	return history, nil
+}
+
+func (r *fixtureSwipeRepo) GetCoLikerLikes(userID uuid.UUID, limit int) ([]models.Swipe, error) {
+	return r.f.Swipes, nil
+}
+
+func TestBuildTasteProfile_Fixture(t *testing.T) {
+	f := loadRecFixture(t)
+	rs := &RecommendationService{swipeRepo: &fixtureSwipeRepo{f}}
+
+	profile, err := rs.Profile(f.Users["anna"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Swipes != 5 {
+		t.Errorf("swipes = %d, want 5", profile.Swipes)
+	}
+	if profile.GenreAffinity["фантастика"] <= 0 {
+		t.Errorf("sci-fi affinity = %v, want positive", profile.GenreAffinity["фантастика"])
+	}
+	if profile.GenreAffinity["мелодрама"] >= 0 {
+		t.Errorf("romance affinity = %v, want negative", profile.GenreAffinity["мелодрама"])
+	}
+	if profile.PreferredYear < 1979 || profile.PreferredYear > 1999 {
+		t.Errorf("preferred year = %v, want within liked movies' years", profile.PreferredYear)
+	}
+	if profile.PreferredDuration < 117 || profile.PreferredDuration > 136 {
+		t.Errorf("preferred duration = %v, want within liked movies' durations", profile.PreferredDuration)
+	}
+}
+
+func TestRecommendationRank_OrdersByTaste(t *testing.T) {
+	f := loadRecFixture(t)
+	rs := &RecommendationService{swipeRepo: &fixtureSwipeRepo{f}}
+	dune, lalaland := f.movie(t, "dune"), f.movie(t, "lalaland")
+
+	anna, err := rs.Rank(f.Users["anna"], []models.Movie{lalaland, dune})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if anna[0].ID != dune.ID {
+		t.Errorf("sci-fi fan must get %q first, got %q", dune.Title, anna[0].Title)
+	}
+
+	vera, err := rs.Rank(f.Users["vera"], []models.Movie{dune, lalaland})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vera[0].ID != lalaland.ID {
+		t.Errorf("romance fan must get %q first, got %q", lalaland.Title, vera[0].Title)
+	}
+}
+
+func TestRecommendationRank_NoHistoryKeepsOrder(t *testing.T) {
+	f := loadRecFixture(t)
+	rs := &RecommendationService{swipeRepo: &fixtureSwipeRepo{f}, collaborative: true}
+	movies := []models.Movie{f.movie(t, "lalaland"), f.movie(t, "dune")}
+
+	ranked, err := rs.Rank(uuid.New(), movies)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ranked[0].ID != movies[0].ID || ranked[1].ID != movies[1].ID {
+		t.Error("order must not change for a user without swipes")
+	}
+}
+
+func TestCollaborativeScores_Fixture(t *testing.T) {
+	f := loadRecFixture(t)
+	dune, lalaland := f.movie(t, "dune"), f.movie(t, "lalaland")
+
+	scores := CollaborativeScores(f.Users["anna"], f.Swipes)
+	if scores[dune.ID] != 1 {
+		t.Errorf("movie liked by the closest co-liker must score 1, got %v", scores[dune.ID])
+	}
+	if scores[lalaland.ID] != 0 {
+		t.Errorf("movie liked only by a user without common likes must score 0, got %v", scores[lalaland.ID])
+	}
+	if _, ok := scores[f.movie(t, "alien").ID]; ok {
+		t.Error("already liked movies must not be recommended")
+	}
+}
+
+func TestRecommendationRank_Collaborative(t *testing.T) {
+	f := loadRecFixture(t)
+	// Без жанров, года и длительности профиль вкуса не различает фильмы — решают только со-лайки
+	dune := models.Movie{ID: f.movie(t, "dune").ID}
+	lalaland := models.Movie{ID: f.movie(t, "lalaland").ID}
+
+	plain := &RecommendationService{swipeRepo: &fixtureSwipeRepo{f}}
+	ranked, err := plain.Rank(f.Users["anna"], []models.Movie{lalaland, dune})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ranked[0].ID != lalaland.ID {
+		t.Error("without collaborative filtering equal scores must keep order")
+	}
+
+	collab := &RecommendationService{swipeRepo: &fixtureSwipeRepo{f}, collaborative: true}
+	ranked, err = collab.Rank(f.Users["anna"], []models.Movie{lalaland, dune})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ranked[0].ID != dune.ID {
+		t.Error("collaborative filtering must put the co-liked movie first")
+	}
+}
+
+// fixtureRatingRepo отдаёт оценки после просмотра с фильмами из фикстуры.
+type fixtureRatingRepo struct {
+	f       *recFixture
+	ratings map[uuid.UUID]map[string]int // пользователь → название фильма → оценка
+}
+
+func (r *fixtureRatingRepo) GetUserRatings(userID uuid.UUID, limit int) ([]models.RatingWithMovie, error) {
+	var result []models.RatingWithMovie
+	for _, m := range r.f.Movies {
+		if rating, ok := r.ratings[userID][m.Title]; ok {
+			result = append(result, models.RatingWithMovie{
+				MovieRating: models.MovieRating{UserID: userID, MovieID: m.ID, Rating: rating},
+				Movie:       m,
+			})
+		}
+	}
+	return result, nil
+}
+
+func TestBuildTasteProfile_RatingsOverrideSwipes(t *testing.T) {
+	f := loadRecFixture(t)
+	vera := f.Users["vera"]
+	// Вера лайкала мелодрамы и дизлайкнула «Чужого», а посмотрев, разочаровалась в мелодрамах и полюбила фантастику
+	rs := &RecommendationService{swipeRepo: &fixtureSwipeRepo{f}, ratingRepo: &fixtureRatingRepo{f: f, ratings: map[uuid.UUID]map[string]int{
+		vera: {"notebook": 2, "titanic": 1, "alien": 10},
+	}}}
+
+	profile, err := rs.Profile(vera)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Ratings != 3 || profile.Swipes != 1 {
+		t.Errorf("rated movies must replace their swipes: ratings = %d, swipes = %d", profile.Ratings, profile.Swipes)
+	}
+	if profile.GenreAffinity["фантастика"] <= 0 {
+		t.Errorf("sci-fi affinity = %v, want positive after a 10/10 rating", profile.GenreAffinity["фантастика"])
+	}
+
+	dune, lalaland := f.movie(t, "dune"), f.movie(t, "lalaland")
+	ranked, err := rs.Rank(vera, []models.Movie{lalaland, dune})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ranked[0].ID != dune.ID {
+		t.Errorf("ratings must outweigh old swipes, got %q first", ranked[0].Title)
+	}
+}
+
+func TestRatingTasteWeight(t *testing.T) {
+	if RatingTasteWeight(10) != 2 || RatingTasteWeight(1) != -2 {
+		t.Errorf("scale ends must map to ±2, got %v and %v", RatingTasteWeight(10), RatingTasteWeight(1))
+	}
+	if w := RatingTasteWeight(6); w <= 0 || w >= RatingTasteWeight(7) {
+		t.Errorf("weight must grow with the rating, got %v for 6", w)
+	}
+}
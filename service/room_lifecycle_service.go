@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+)
+
+// RoomEventNotifier сообщает участникам комнаты о старте и завершении сеанса (реализуется WebSocket Hub).
+type RoomEventNotifier interface {
+	BroadcastRoomStarted(roomID uuid.UUID, room *models.Room)
+	BroadcastRoomFinished(roomID uuid.UUID, summary *models.RoomSummary)
+}
+
+type lifecycleRoomRepoInterface interface {
+	GetByID(id uuid.UUID) (*models.Room, error)
+	GetMembers(roomID uuid.UUID) ([]models.User, error)
+	TransitionStatus(roomID uuid.UUID, from, to models.RoomStatus) (bool, error)
+	Finish(roomID uuid.UUID, reason models.RoomFinishReason) (finished bool, err error)
+	GetIdleRoomIDs(before time.Time) ([]uuid.UUID, error)
+}
+
+type lifecycleSwipeRepoInterface interface {
+	CountByRoom(roomID uuid.UUID) (int, error)
+}
+
+type lifecycleMatchRepoInterface interface {
+	GetByRoomID(roomID uuid.UUID) ([]models.Match, error)
+}
+
+type deckBuilderInterface interface {
+	BuildDeck(room *models.Room) error
+}
+
+// RoomLifecycleService запускает комнаты, завершает их (хостом, по первому матчу, по неактивности) и строит итог сеанса.
+type RoomLifecycleService struct {
+	roomRepo    lifecycleRoomRepoInterface
+	swipeRepo   lifecycleSwipeRepoInterface
+	matchRepo   lifecycleMatchRepoInterface
+	movieRepo   movieRepoInterface
+	deckService deckBuilderInterface
+	notifier    RoomEventNotifier
+}
+
+func NewRoomLifecycleService(
+	roomRepo *repository.RoomRepository,
+	swipeRepo *repository.SwipeRepository,
+	matchRepo *repository.MatchRepository,
+	movieRepo *repository.MovieRepository,
+	deckService *DeckService,
+) *RoomLifecycleService {
+	return &RoomLifecycleService{
+		roomRepo:    roomRepo,
+		swipeRepo:   swipeRepo,
+		matchRepo:   matchRepo,
+		movieRepo:   movieRepo,
+		deckService: deckService,
+	}
+}
+
+// SetNotifier задаёт, кому сообщать о завершении комнат.
+func (s *RoomLifecycleService) SetNotifier(notifier RoomEventNotifier) {
+	s.notifier = notifier
+}
+
+// Start запускает комнату по команде хоста: статус active и общая колода по фильтру комнаты.
+func (s *RoomLifecycleService) Start(roomID, userID uuid.UUID) (*models.Room, error) {
+	room, err := s.roomRepo.GetByID(roomID)
+	if errors.Is(err, repository.ErrRoomNotFound) {
+		return nil, ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+	if room.HostID != userID {
+		return nil, ErrNotRoomHost
+	}
+	if room.Status != models.RoomStatusWaiting {
+		return nil, ErrRoomNotWaiting
+	}
+
+	// Условный переход: из двух одновременных стартов (REST и WebSocket) проходит только один
+	started, err := s.roomRepo.TransitionStatus(roomID, models.RoomStatusWaiting, models.RoomStatusActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start room: %w", err)
+	}
+	if !started {
+		return nil, ErrRoomNotWaiting
+	}
+	room.Status = models.RoomStatusActive
+
+	// Без колоды комната не может работать: возвращаем её в ожидание, чтобы хост повторил старт
+	if err := s.deckService.BuildDeck(room); err != nil {
+		if _, rbErr := s.roomRepo.TransitionStatus(roomID, models.RoomStatusActive, models.RoomStatusWaiting); rbErr != nil {
+			log.Printf("Start: failed to revert room %s to waiting: %v", roomID, rbErr)
+		}
+		return nil, fmt.Errorf("failed to build deck: %w", err)
+	}
+	if s.notifier != nil {
+		s.notifier.BroadcastRoomStarted(roomID, room)
+	}
+	return room, nil
+}
+
+// Finish завершает комнату и возвращает итог сеанса. Повторное завершение не меняет причину и время.
+func (s *RoomLifecycleService) Finish(roomID uuid.UUID, reason models.RoomFinishReason) (*models.RoomSummary, error) {
+	finished, err := s.roomRepo.Finish(roomID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.Summary(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if finished && s.notifier != nil {
+		s.notifier.BroadcastRoomFinished(roomID, summary)
+	}
+	return summary, nil
+}
+
+// OnMatch завершает комнату, если хост включил finish_on_match. Возвращает nil, если комната продолжает работу.
+func (s *RoomLifecycleService) OnMatch(room *models.Room) (*models.RoomSummary, error) {
+	if !room.FinishOnMatch || room.Status == models.RoomStatusFinished {
+		return nil, nil
+	}
+	return s.Finish(room.ID, models.RoomFinishReasonMatch)
+}
+
+// Summary строит итог сеанса комнаты: участники, свайпы, матчи с фильмами, длительность.
+func (s *RoomLifecycleService) Summary(roomID uuid.UUID) (*models.RoomSummary, error) {
+	room, err := s.roomRepo.GetByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.roomRepo.GetMembers(roomID)
+	if err != nil {
+		return nil, err
+	}
+	swipesCount, err := s.swipeRepo.CountByRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.matchRepo.GetByRoomID(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.RoomSummary{
+		Room:         *room,
+		MembersCount: len(members),
+		SwipesCount:  swipesCount,
+		Matches:      []models.MatchedMovie{},
+	}
+	for _, m := range matches {
+		movie, err := s.movieRepo.GetByID(m.MovieID)
+		if err != nil {
+			continue
+		}
+		summary.Matches = append(summary.Matches, models.MatchedMovie{Match: m, Movie: *movie})
+	}
+
+	end := time.Now()
+	if room.FinishedAt != nil {
+		end = *room.FinishedAt
+	}
+	summary.DurationSeconds = int64(end.Sub(room.CreatedAt).Seconds())
+
+	return summary, nil
+}
+
+// ExpireIdleRooms завершает комнаты в статусе waiting/active без активности дольше ttl. Возвращает число завершённых.
+func (s *RoomLifecycleService) ExpireIdleRooms(ttl time.Duration) (int, error) {
+	ids, err := s.roomRepo.GetIdleRoomIDs(time.Now().Add(-ttl))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get idle rooms: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		if _, err := s.Finish(id, models.RoomFinishReasonExpired); err != nil {
+			log.Printf("Room sweeper: failed to expire room %s: %v", id, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// RunSweeper периодически завершает простаивающие комнаты, пока не отменён ctx.
+func (s *RoomLifecycleService) RunSweeper(ctx context.Context, interval, ttl time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ExpireIdleRooms(ttl)
+			if err != nil {
+				log.Printf("Room sweeper: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Room sweeper: expired %d idle rooms", n)
+			}
+		}
+	}
+}
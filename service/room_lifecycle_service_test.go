@@ -0,0 +1,291 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakeLifecycleRooms хранит комнаты в памяти и завершает их, как RoomRepository.Finish
+type fakeLifecycleRooms struct {
+	rooms   map[uuid.UUID]*models.Room
+	members map[uuid.UUID][]models.User
+	idle    []uuid.UUID
+}
+
+func (f *fakeLifecycleRooms) GetByID(id uuid.UUID) (*models.Room, error) {
+	room, ok := f.rooms[id]
+	if !ok {
+		return nil, repository.ErrRoomNotFound
+	}
+	copied := *room
+	return &copied, nil
+}
+
+func (f *fakeLifecycleRooms) GetMembers(roomID uuid.UUID) ([]models.User, error) {
+	return f.members[roomID], nil
+}
+
+func (f *fakeLifecycleRooms) TransitionStatus(roomID uuid.UUID, from, to models.RoomStatus) (bool, error) {
+	room := f.rooms[roomID]
+	if room.Status != from {
+		return false, nil
+	}
+	room.Status = to
+	return true, nil
+}
+
+func (f *fakeLifecycleRooms) Finish(roomID uuid.UUID, reason models.RoomFinishReason) (bool, error) {
+	room, ok := f.rooms[roomID]
+	if !ok {
+		return false, repository.ErrRoomNotFound
+	}
+	if room.Status == models.RoomStatusFinished {
+		return false, nil
+	}
+	now := time.Now()
+	room.Status = models.RoomStatusFinished
+	room.FinishedAt = &now
+	room.FinishReason = string(reason)
+	return true, nil
+}
+
+func (f *fakeLifecycleRooms) GetIdleRoomIDs(before time.Time) ([]uuid.UUID, error) {
+	return f.idle, nil
+}
+
+type fakeSwipeCounter int
+
+func (f fakeSwipeCounter) CountByRoom(roomID uuid.UUID) (int, error) { return int(f), nil }
+
+type fakeDeckBuilder struct {
+	built int
+	err   error
+}
+
+func (f *fakeDeckBuilder) BuildDeck(room *models.Room) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.built++
+	return nil
+}
+
+type fakeRoomNotifier struct{ started, finished int }
+
+func (f *fakeRoomNotifier) BroadcastRoomStarted(roomID uuid.UUID, room *models.Room) { f.started++ }
+func (f *fakeRoomNotifier) BroadcastRoomFinished(roomID uuid.UUID, summary *models.RoomSummary) {
+	f.finished++
+}
+
+func newTestLifecycleService(rooms ...*models.Room) (*RoomLifecycleService, *fakeLifecycleRooms, *fakeDeckBuilder, *fakeRoomNotifier) {
+	store := &fakeLifecycleRooms{rooms: map[uuid.UUID]*models.Room{}, members: map[uuid.UUID][]models.User{}}
+	for _, room := range rooms {
+		store.rooms[room.ID] = room
+	}
+	deck := &fakeDeckBuilder{}
+	notifier := &fakeRoomNotifier{}
+	s := &RoomLifecycleService{
+		roomRepo:    store,
+		swipeRepo:   fakeSwipeCounter(0),
+		matchRepo:   &mockMatchRepo{},
+		movieRepo:   &mockMovieRepo{},
+		deckService: deck,
+		notifier:    notifier,
+	}
+	return s, store, deck, notifier
+}
+
+func TestRoomLifecycle_StartOnlyOnce(t *testing.T) {
+	host := uuid.New()
+	room := &models.Room{ID: uuid.New(), HostID: host, Status: models.RoomStatusWaiting}
+	s, store, deck, notifier := newTestLifecycleService(room)
+
+	if _, err := s.Start(room.ID, uuid.New()); !errors.Is(err, ErrNotRoomHost) {
+		t.Fatalf("start by guest: err = %v, want ErrNotRoomHost", err)
+	}
+	started, err := s.Start(room.ID, host)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if started.Status != models.RoomStatusActive || store.rooms[room.ID].Status != models.RoomStatusActive {
+		t.Errorf("status = %s, want active", started.Status)
+	}
+	if deck.built != 1 || notifier.started != 1 {
+		t.Errorf("built = %d, started = %d, want 1 and 1", deck.built, notifier.started)
+	}
+
+	if _, err := s.Start(room.ID, host); !errors.Is(err, ErrRoomNotWaiting) {
+		t.Fatalf("second start: err = %v, want ErrRoomNotWaiting", err)
+	}
+	if deck.built != 1 || notifier.started != 1 {
+		t.Errorf("second start rebuilt deck or notified: built = %d, started = %d", deck.built, notifier.started)
+	}
+	if _, err := s.Start(uuid.New(), host); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("unknown room: err = %v, want ErrRoomNotFound", err)
+	}
+}
+
+// Комната, которую запустили между чтением и обновлением, второй раз не стартует
+func TestRoomLifecycle_StartRace(t *testing.T) {
+	host := uuid.New()
+	room := &models.Room{ID: uuid.New(), HostID: host, Status: models.RoomStatusWaiting}
+	s, store, deck, notifier := newTestLifecycleService(room)
+	s.roomRepo = &racingRooms{store}
+
+	if _, err := s.Start(room.ID, host); !errors.Is(err, ErrRoomNotWaiting) {
+		t.Fatalf("err = %v, want ErrRoomNotWaiting", err)
+	}
+	if deck.built != 0 || notifier.started != 0 {
+		t.Errorf("lost start built deck or notified: built = %d, started = %d", deck.built, notifier.started)
+	}
+}
+
+// racingRooms отдаёт комнату в ожидании, хотя её уже запустил параллельный запрос
+type racingRooms struct{ *fakeLifecycleRooms }
+
+func (r *racingRooms) GetByID(id uuid.UUID) (*models.Room, error) {
+	room, err := r.fakeLifecycleRooms.GetByID(id)
+	if err == nil {
+		r.rooms[id].Status = models.RoomStatusActive
+		room.Status = models.RoomStatusWaiting
+	}
+	return room, err
+}
+
+func TestRoomLifecycle_StartFailsWithoutDeck(t *testing.T) {
+	host := uuid.New()
+	room := &models.Room{ID: uuid.New(), HostID: host, Status: models.RoomStatusWaiting}
+	s, store, deck, notifier := newTestLifecycleService(room)
+	deck.err = errors.New("db is down")
+
+	if _, err := s.Start(room.ID, host); err == nil {
+		t.Fatal("start without deck must fail")
+	}
+	if store.rooms[room.ID].Status != models.RoomStatusWaiting || notifier.started != 0 {
+		t.Errorf("status = %s, started = %d; want waiting and no notification", store.rooms[room.ID].Status, notifier.started)
+	}
+
+	// После восстановления хост запускает комнату повторно
+	deck.err = nil
+	if _, err := s.Start(room.ID, host); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+}
+
+func TestRoomLifecycle_FinishTwiceKeepsReason(t *testing.T) {
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive, CreatedAt: time.Now().Add(-time.Minute)}
+	s, store, _, notifier := newTestLifecycleService(room)
+
+	summary, err := s.Finish(room.ID, models.RoomFinishReasonHost)
+	if err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if summary.Room.Status != models.RoomStatusFinished || summary.Room.FinishReason != string(models.RoomFinishReasonHost) {
+		t.Errorf("summary room = %+v, want finished by host", summary.Room)
+	}
+	finishedAt := *store.rooms[room.ID].FinishedAt
+
+	// Повторное завершение возвращает тот же итог и никого не уведомляет
+	summary, err = s.Finish(room.ID, models.RoomFinishReasonExpired)
+	if err != nil {
+		t.Fatalf("second finish: %v", err)
+	}
+	if summary.Room.FinishReason != string(models.RoomFinishReasonHost) || !summary.Room.FinishedAt.Equal(finishedAt) {
+		t.Errorf("second finish changed room: %+v", summary.Room)
+	}
+	if notifier.finished != 1 {
+		t.Errorf("finished notifications = %d, want 1", notifier.finished)
+	}
+}
+
+func TestRoomLifecycle_OnMatch(t *testing.T) {
+	keep := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive}
+	finish := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive, FinishOnMatch: true}
+	s, store, _, notifier := newTestLifecycleService(keep, finish)
+
+	if summary, err := s.OnMatch(keep); err != nil || summary != nil {
+		t.Fatalf("without finish_on_match: summary = %+v, err = %v", summary, err)
+	}
+	if store.rooms[keep.ID].Status != models.RoomStatusActive {
+		t.Errorf("room without finish_on_match was finished")
+	}
+
+	summary, err := s.OnMatch(finish)
+	if err != nil || summary == nil {
+		t.Fatalf("with finish_on_match: summary = %+v, err = %v", summary, err)
+	}
+	if summary.Room.FinishReason != string(models.RoomFinishReasonMatch) {
+		t.Errorf("reason = %s, want match", summary.Room.FinishReason)
+	}
+
+	// Комната, уже завершённая к моменту матча, не завершается повторно
+	finish.Status = models.RoomStatusFinished
+	if summary, err := s.OnMatch(finish); err != nil || summary != nil {
+		t.Errorf("finished room: summary = %+v, err = %v", summary, err)
+	}
+	if notifier.finished != 1 {
+		t.Errorf("finished notifications = %d, want 1", notifier.finished)
+	}
+}
+
+func TestRoomLifecycle_Summary(t *testing.T) {
+	createdAt := time.Now().Add(-10 * time.Minute)
+	finishedAt := createdAt.Add(5 * time.Minute)
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusFinished, CreatedAt: createdAt, FinishedAt: &finishedAt}
+	s, store, _, _ := newTestLifecycleService(room)
+
+	store.members[room.ID] = []models.User{{ID: uuid.New()}, {ID: uuid.New()}}
+	movie := &models.Movie{ID: uuid.New(), Title: "Movie"}
+	s.swipeRepo = fakeSwipeCounter(7)
+	s.matchRepo = &mockMatchRepo{byRoom: map[uuid.UUID][]models.Match{room.ID: {
+		{ID: uuid.New(), RoomID: room.ID, MovieID: movie.ID},
+		// Фильм удалён из каталога — матч пропускается
+		{ID: uuid.New(), RoomID: room.ID, MovieID: uuid.New()},
+	}}}
+	s.movieRepo = &mockMovieRepo{movies: map[uuid.UUID]*models.Movie{movie.ID: movie}}
+
+	summary, err := s.Summary(room.ID)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if summary.MembersCount != 2 || summary.SwipesCount != 7 {
+		t.Errorf("members = %d, swipes = %d, want 2 and 7", summary.MembersCount, summary.SwipesCount)
+	}
+	if len(summary.Matches) != 1 || summary.Matches[0].Movie.ID != movie.ID {
+		t.Errorf("matches = %+v, want only %s", summary.Matches, movie.ID)
+	}
+	if summary.DurationSeconds != 300 {
+		t.Errorf("duration = %d, want 300", summary.DurationSeconds)
+	}
+}
+
+func TestRoomLifecycle_ExpireIdleRooms(t *testing.T) {
+	idle := &models.Room{ID: uuid.New(), Status: models.RoomStatusWaiting}
+	busy := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive}
+	s, store, _, notifier := newTestLifecycleService(idle, busy)
+	// Одна из комнат выборки исчезла до завершения — её ошибка не прерывает обход
+	store.idle = []uuid.UUID{uuid.New(), idle.ID}
+
+	n, err := s.ExpireIdleRooms(time.Hour)
+	if err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expired = %d, want 1", n)
+	}
+	if store.rooms[idle.ID].FinishReason != string(models.RoomFinishReasonExpired) {
+		t.Errorf("idle room = %+v, want expired", store.rooms[idle.ID])
+	}
+	if store.rooms[busy.ID].Status != models.RoomStatusActive {
+		t.Errorf("busy room status = %s, want active", store.rooms[busy.ID].Status)
+	}
+	if notifier.finished != 1 {
+		t.Errorf("finished notifications = %d, want 1", notifier.finished)
+	}
+}
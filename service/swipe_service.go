@@ -0,0 +1,302 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kinoswipe/models"
+	"kinoswipe/repository"
+
+	"github.com/google/uuid"
+)
+
+// SwipeEventNotifier сообщает участникам комнаты о матчах и прогрессе по колоде (реализуется WebSocket Hub).
+type SwipeEventNotifier interface {
+	BroadcastMatch(roomID uuid.UUID, match *models.MatchWithDetails)
+	BroadcastMatchRetracted(roomID uuid.UUID, match *models.Match)
+	ReportProgress(roomID, userID uuid.UUID, progress models.DeckProgress)
+}
+
+type swipeServiceRepoInterface interface {
+	Create(swipe *models.Swipe) error
+	GetByID(id uuid.UUID) (*models.Swipe, error)
+	GetLastSwipe(userID, roomID uuid.UUID) (*models.Swipe, error)
+	GetUserSwipesSince(userID, roomID uuid.UUID, since time.Time) ([]models.Swipe, error)
+	GetUserSwipeForMovie(userID, roomID, movieID uuid.UUID) (*models.Swipe, error)
+	HasUserSwiped(userID, roomID, movieID uuid.UUID) (bool, error)
+	UpdateDirection(swipe *models.Swipe, direction models.SwipeDirection) error
+	Delete(swipeID uuid.UUID) error
+}
+
+type swipeMatcherInterface interface {
+	CheckAndCreateMatch(roomID, movieID uuid.UUID) (*models.Match, error)
+	ReevaluateMatch(roomID, movieID uuid.UUID) (created, retracted *models.Match, err error)
+	ReevaluateRoom(roomID uuid.UUID) (created, retracted []models.Match, err error)
+	GetMatchWithDetails(matchID uuid.UUID) (*models.MatchWithDetails, error)
+}
+
+type swipeFinisherInterface interface {
+	OnMatch(room *models.Room) (*models.RoomSummary, error)
+}
+
+type swipeProgressInterface interface {
+	Progress(room *models.Room, userID uuid.UUID) (models.DeckProgress, bool, error)
+}
+
+// SwipeResult — итог свайпа; Match заполнен, если свайп довёл фильм до матча
+type SwipeResult struct {
+	Swipe *models.Swipe            `json:"swipe"`
+	Match *models.MatchWithDetails `json:"match,omitempty"`
+}
+
+// SwipeService принимает свайпы и отмены свайпов. Общий для REST и WebSocket-команд,
+// поэтому проверки, матчи и уведомления комнаты в обоих случаях одинаковые.
+type SwipeService struct {
+	swipeRepo swipeServiceRepoInterface
+	roomRepo  roomRepoInterface
+	matcher   swipeMatcherInterface
+	finisher  swipeFinisherInterface
+	deck      swipeProgressInterface
+	notifier  SwipeEventNotifier
+}
+
+func NewSwipeService(
+	swipeRepo *repository.SwipeRepository,
+	roomRepo *repository.RoomRepository,
+	matchService *MatchService,
+	lifecycleService *RoomLifecycleService,
+	deckService *DeckService,
+) *SwipeService {
+	return &SwipeService{
+		swipeRepo: swipeRepo,
+		roomRepo:  roomRepo,
+		matcher:   matchService,
+		finisher:  lifecycleService,
+		deck:      deckService,
+	}
+}
+
+// SetNotifier задаёт, кому сообщать о матчах и прогрессе участников.
+func (s *SwipeService) SetNotifier(notifier SwipeEventNotifier) {
+	s.notifier = notifier
+}
+
+// openRoom загружает комнату и отклоняет действие, если сеанс уже завершён.
+func (s *SwipeService) openRoom(roomID uuid.UUID) (*models.Room, error) {
+	room, err := s.roomRepo.GetByID(roomID)
+	if errors.Is(err, repository.ErrRoomNotFound) {
+		return nil, ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+	if room.Status == models.RoomStatusFinished {
+		return nil, ErrRoomFinished
+	}
+	return room, nil
+}
+
+// Swipe сохраняет решение пользователя по фильму и проверяет матч.
+// Отложенный (maybe) фильм можно свайпнуть ещё раз — новое решение заменяет старое.
+func (s *SwipeService) Swipe(roomID, userID uuid.UUID, req models.CreateSwipeRequest) (*SwipeResult, error) {
+	if !req.Direction.Valid() {
+		return nil, ErrInvalidDirection
+	}
+	room, err := s.openRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasSwiped, err := s.swipeRepo.HasUserSwiped(userID, roomID, req.MovieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check swipe: %w", err)
+	}
+
+	var swipe *models.Swipe
+	if hasSwiped {
+		existing, err := s.swipeRepo.GetUserSwipeForMovie(userID, roomID, req.MovieID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check swipe: %w", err)
+		}
+		if existing.Direction != models.SwipeDirectionMaybe {
+			return nil, ErrAlreadySwiped
+		}
+		if err := s.swipeRepo.UpdateDirection(existing, req.Direction); err != nil {
+			return nil, fmt.Errorf("failed to update swipe: %w", err)
+		}
+		swipe = existing
+	} else {
+		swipe = &models.Swipe{
+			ID:        uuid.New(),
+			UserID:    userID,
+			RoomID:    roomID,
+			MovieID:   req.MovieID,
+			Direction: req.Direction,
+		}
+		if err := s.swipeRepo.Create(swipe); err != nil {
+			return nil, fmt.Errorf("failed to create swipe: %w", err)
+		}
+	}
+	s.reportProgress(room, userID)
+
+	result := &SwipeResult{Swipe: swipe}
+	var match *models.Match
+	switch {
+	// Дизлайк хоста (вето majority_host_veto) и замена решения по отложенному фильму
+	// могут как создать матч, так и отменить уже существующий — пересчитываем, как при Undo.
+	case hasSwiped || (req.Direction == models.SwipeDirectionLeft && userID == room.HostID):
+		created, retracted, err := s.matcher.ReevaluateMatch(roomID, req.MovieID)
+		if err != nil {
+			log.Printf("Swipe: failed to reevaluate match (room=%s movie=%s): %v", roomID, req.MovieID, err)
+			return result, nil
+		}
+		if retracted != nil && s.notifier != nil {
+			s.notifier.BroadcastMatchRetracted(roomID, retracted)
+		}
+		match = created
+	// Лайк (в т.ч. суперлайк) может довести фильм до матча.
+	// seen уменьшает число голосующих по фильму, поэтому тоже может.
+	case req.Direction.IsLike() || req.Direction == models.SwipeDirectionSeen:
+		match, err = s.matcher.CheckAndCreateMatch(roomID, req.MovieID)
+		if err != nil {
+			log.Printf("Swipe: failed to check match (room=%s movie=%s): %v", roomID, req.MovieID, err)
+			return result, nil
+		}
+	}
+	if match == nil {
+		return result, nil
+	}
+	// Матч уже сохранён: свайп успешен и комната завершается, даже если детали для ответа и рассылки не загрузились
+	details, err := s.matcher.GetMatchWithDetails(match.ID)
+	if err != nil {
+		log.Printf("Swipe: failed to get match details (match=%s): %v", match.ID, err)
+	} else if details != nil {
+		result.Match = details
+		if s.notifier != nil {
+			s.notifier.BroadcastMatch(roomID, details)
+		}
+	}
+	// Комната с finish_on_match завершается на первом матче (lifecycle сам уведомит комнату)
+	if _, err := s.finisher.OnMatch(room); err != nil {
+		log.Printf("Swipe: failed to finish room %s on match: %v", roomID, err)
+	}
+	return result, nil
+}
+
+// Undo отменяет свайпы пользователя: конкретный (swipe_id), все за последние within_seconds или последний.
+// Матчи по затронутым фильмам пересчитываются, комната получает match_retracted / match.
+func (s *SwipeService) Undo(roomID, userID uuid.UUID, req models.UndoSwipeRequest) (*models.UndoSwipeResponse, error) {
+	if req.WithinSeconds < 0 {
+		return nil, ErrInvalidUndoWindow
+	}
+	room, err := s.openRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	var toUndo []models.Swipe
+	switch {
+	case req.SwipeID != nil:
+		swipe, err := s.swipeRepo.GetByID(*req.SwipeID)
+		if err != nil || swipe.UserID != userID || swipe.RoomID != roomID {
+			return nil, ErrNothingToUndo
+		}
+		toUndo = []models.Swipe{*swipe}
+	case req.WithinSeconds > 0:
+		since := time.Now().Add(-time.Duration(req.WithinSeconds) * time.Second)
+		swipes, err := s.swipeRepo.GetUserSwipesSince(userID, roomID, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get swipes: %w", err)
+		}
+		toUndo = swipes
+	default:
+		lastSwipe, err := s.swipeRepo.GetLastSwipe(userID, roomID)
+		if err != nil {
+			return nil, ErrNothingToUndo
+		}
+		toUndo = []models.Swipe{*lastSwipe}
+	}
+	if len(toUndo) == 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	for _, swipe := range toUndo {
+		if err := s.swipeRepo.Delete(swipe.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete swipe: %w", err)
+		}
+	}
+	s.reportProgress(room, userID)
+
+	resp := &models.UndoSwipeResponse{Message: "Swipe undone successfully", Undone: toUndo}
+	rechecked := make(map[uuid.UUID]bool)
+	for _, swipe := range toUndo {
+		if rechecked[swipe.MovieID] {
+			continue
+		}
+		rechecked[swipe.MovieID] = true
+
+		created, retracted, err := s.matcher.ReevaluateMatch(roomID, swipe.MovieID)
+		if err != nil {
+			log.Printf("Undo: failed to reevaluate match (room=%s movie=%s): %v", roomID, swipe.MovieID, err)
+			continue
+		}
+		if retracted != nil {
+			resp.RetractedMatches = append(resp.RetractedMatches, *retracted)
+			if s.notifier != nil {
+				s.notifier.BroadcastMatchRetracted(roomID, retracted)
+			}
+		}
+		if created != nil && s.notifier != nil {
+			if details, err := s.matcher.GetMatchWithDetails(created.ID); err == nil {
+				s.notifier.BroadcastMatch(roomID, details)
+			} else {
+				log.Printf("Undo: failed to get match details (match=%s): %v", created.ID, err)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// ReevaluateRoom пересчитывает матчи комнаты после ухода участника (выход, кик, бан): его свайпы больше не считаются.
+// Комната получает match_retracted / match, а новый матч завершает комнату с finish_on_match, как при свайпе.
+func (s *SwipeService) ReevaluateRoom(room *models.Room) {
+	created, retracted, err := s.matcher.ReevaluateRoom(room.ID)
+	if err != nil {
+		log.Printf("ReevaluateRoom: failed to reevaluate matches in room %s: %v", room.ID, err)
+	}
+	if s.notifier != nil {
+		for i := range retracted {
+			s.notifier.BroadcastMatchRetracted(room.ID, &retracted[i])
+		}
+		for _, match := range created {
+			if details, err := s.matcher.GetMatchWithDetails(match.ID); err == nil {
+				s.notifier.BroadcastMatch(room.ID, details)
+			} else {
+				log.Printf("ReevaluateRoom: failed to get match details (match=%s): %v", match.ID, err)
+			}
+		}
+	}
+	if len(created) == 0 {
+		return
+	}
+	if _, err := s.finisher.OnMatch(room); err != nil {
+		log.Printf("ReevaluateRoom: failed to finish room %s on match: %v", room.ID, err)
+	}
+}
+
+// reportProgress сообщает комнате, сколько карточек прошёл участник (без направления свайпа)
+func (s *SwipeService) reportProgress(room *models.Room, userID uuid.UUID) {
+	if s.notifier == nil || s.deck == nil {
+		return
+	}
+	progress, ok, err := s.deck.Progress(room, userID)
+	if err != nil {
+		log.Printf("Failed to get deck progress (room=%s user=%s): %v", room.ID, userID, err)
+		return
+	}
+	if ok {
+		s.notifier.ReportProgress(room.ID, userID, progress)
+	}
+}
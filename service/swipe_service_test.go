@@ -0,0 +1,323 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"kinoswipe/models"
+
+	"github.com/google/uuid"
+)
+
+type fakeSwipeStore struct {
+	swipes []*models.Swipe
+}
+
+func (f *fakeSwipeStore) find(userID, roomID, movieID uuid.UUID) *models.Swipe {
+	for _, sw := range f.swipes {
+		if sw.UserID == userID && sw.RoomID == roomID && sw.MovieID == movieID {
+			return sw
+		}
+	}
+	return nil
+}
+
+func (f *fakeSwipeStore) Create(swipe *models.Swipe) error {
+	swipe.CreatedAt = time.Now()
+	f.swipes = append(f.swipes, swipe)
+	return nil
+}
+func (f *fakeSwipeStore) GetByID(id uuid.UUID) (*models.Swipe, error) {
+	for _, sw := range f.swipes {
+		if sw.ID == id {
+			return sw, nil
+		}
+	}
+	return nil, errors.New("swipe not found")
+}
+func (f *fakeSwipeStore) GetLastSwipe(userID, roomID uuid.UUID) (*models.Swipe, error) {
+	for i := len(f.swipes) - 1; i >= 0; i-- {
+		if sw := f.swipes[i]; sw.UserID == userID && sw.RoomID == roomID {
+			return sw, nil
+		}
+	}
+	return nil, errors.New("swipe not found")
+}
+func (f *fakeSwipeStore) GetUserSwipesSince(userID, roomID uuid.UUID, since time.Time) ([]models.Swipe, error) {
+	return nil, nil
+}
+func (f *fakeSwipeStore) GetUserSwipeForMovie(userID, roomID, movieID uuid.UUID) (*models.Swipe, error) {
+	if sw := f.find(userID, roomID, movieID); sw != nil {
+		return sw, nil
+	}
+	return nil, errors.New("swipe not found")
+}
+func (f *fakeSwipeStore) HasUserSwiped(userID, roomID, movieID uuid.UUID) (bool, error) {
+	return f.find(userID, roomID, movieID) != nil, nil
+}
+func (f *fakeSwipeStore) UpdateDirection(swipe *models.Swipe, direction models.SwipeDirection) error {
+	swipe.Direction = direction
+	return nil
+}
+func (f *fakeSwipeStore) Delete(swipeID uuid.UUID) error {
+	for i, sw := range f.swipes {
+		if sw.ID == swipeID {
+			f.swipes = append(f.swipes[:i], f.swipes[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("swipe not found")
+}
+
+// fakeMatcher считает матчем любой лайк фильма match
+type fakeMatcher struct {
+	match     uuid.UUID
+	retracted []uuid.UUID
+	// reevaluated — что вернёт пересчёт всей комнаты
+	reevaluated, reevaluatedRetracted []models.Match
+	detailsErr                        error
+}
+
+func (f *fakeMatcher) CheckAndCreateMatch(roomID, movieID uuid.UUID) (*models.Match, error) {
+	if movieID != f.match {
+		return nil, nil
+	}
+	return &models.Match{ID: uuid.New(), RoomID: roomID, MovieID: movieID}, nil
+}
+func (f *fakeMatcher) ReevaluateMatch(roomID, movieID uuid.UUID) (created, retracted *models.Match, err error) {
+	if movieID != f.match {
+		return nil, nil, nil
+	}
+	f.retracted = append(f.retracted, movieID)
+	return nil, &models.Match{ID: uuid.New(), RoomID: roomID, MovieID: movieID}, nil
+}
+func (f *fakeMatcher) ReevaluateRoom(roomID uuid.UUID) (created, retracted []models.Match, err error) {
+	return f.reevaluated, f.reevaluatedRetracted, nil
+}
+func (f *fakeMatcher) GetMatchWithDetails(matchID uuid.UUID) (*models.MatchWithDetails, error) {
+	if f.detailsErr != nil {
+		return nil, f.detailsErr
+	}
+	return &models.MatchWithDetails{Match: models.Match{ID: matchID, MovieID: f.match}}, nil
+}
+
+type fakeFinisher struct{ calls int }
+
+func (f *fakeFinisher) OnMatch(room *models.Room) (*models.RoomSummary, error) {
+	f.calls++
+	return nil, nil
+}
+
+type fakeSwipeNotifier struct {
+	matches, retracted int
+	progress           []models.DeckProgress
+}
+
+func (f *fakeSwipeNotifier) BroadcastMatch(roomID uuid.UUID, match *models.MatchWithDetails) {
+	f.matches++
+}
+func (f *fakeSwipeNotifier) BroadcastMatchRetracted(roomID uuid.UUID, match *models.Match) {
+	f.retracted++
+}
+func (f *fakeSwipeNotifier) ReportProgress(roomID, userID uuid.UUID, progress models.DeckProgress) {
+	f.progress = append(f.progress, progress)
+}
+
+func newTestSwipeService(room *models.Room, deck []uuid.UUID, matchMovie uuid.UUID) (*SwipeService, *fakeSwipeStore, *fakeSwipeNotifier) {
+	store := &fakeSwipeStore{}
+	notifier := &fakeSwipeNotifier{}
+	swipesView := &storeSwipesView{store}
+	s := &SwipeService{
+		swipeRepo: store,
+		roomRepo:  roomRepoWith(room),
+		matcher:   &fakeMatcher{match: matchMovie},
+		finisher:  &fakeFinisher{},
+		deck:      &DeckService{roomRepo: &fakeDeckRooms{deck: deck}, swipeRepo: swipesView},
+		notifier:  notifier,
+	}
+	return s, store, notifier
+}
+
+// storeSwipesView отдаёт DeckService свайпы из fakeSwipeStore
+type storeSwipesView struct{ store *fakeSwipeStore }
+
+func (v *storeSwipesView) GetUserSwipes(userID, roomID uuid.UUID) ([]models.Swipe, error) {
+	var out []models.Swipe
+	for _, sw := range v.store.swipes {
+		if sw.UserID == userID && sw.RoomID == roomID {
+			out = append(out, *sw)
+		}
+	}
+	return out, nil
+}
+func (v *storeSwipesView) GetRoomSwipes(roomID uuid.UUID) ([]models.Swipe, error) { return nil, nil }
+
+func TestSwipe_MaybeCanBeDecidedOnce(t *testing.T) {
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive}
+	deck := makeIDs(3)
+	s, _, notifier := newTestSwipeService(room, deck, uuid.Nil)
+	userID := uuid.New()
+
+	if _, err := s.Swipe(room.ID, userID, models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionMaybe}); err != nil {
+		t.Fatalf("maybe: %v", err)
+	}
+	res, err := s.Swipe(room.ID, userID, models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionLeft})
+	if err != nil || res.Swipe.Direction != models.SwipeDirectionLeft {
+		t.Fatalf("decision after maybe: %+v, %v", res, err)
+	}
+	if _, err := s.Swipe(room.ID, userID, models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionRight}); !errors.Is(err, ErrAlreadySwiped) {
+		t.Fatalf("second decision: err = %v, want ErrAlreadySwiped", err)
+	}
+
+	// Прогресс: maybe ещё не пройден, решение после него — пройден
+	want := []models.DeckProgress{{Swiped: 0, Total: 3}, {Swiped: 1, Total: 3}}
+	if len(notifier.progress) != len(want) {
+		t.Fatalf("progress = %+v, want %+v", notifier.progress, want)
+	}
+	for i := range want {
+		if notifier.progress[i] != want[i] {
+			t.Errorf("progress[%d] = %+v, want %+v", i, notifier.progress[i], want[i])
+		}
+	}
+}
+
+func TestSwipe_MatchNotifiesAndFinishes(t *testing.T) {
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive, FinishOnMatch: true}
+	deck := makeIDs(2)
+	s, _, notifier := newTestSwipeService(room, deck, deck[1])
+
+	res, err := s.Swipe(room.ID, uuid.New(), models.CreateSwipeRequest{MovieID: deck[1], Direction: models.SwipeDirectionRight})
+	if err != nil || res.Match == nil {
+		t.Fatalf("expected match, got %+v, %v", res, err)
+	}
+	if notifier.matches != 1 || s.finisher.(*fakeFinisher).calls != 1 {
+		t.Errorf("matches broadcast = %d, OnMatch calls = %d; want 1 and 1", notifier.matches, s.finisher.(*fakeFinisher).calls)
+	}
+}
+
+// Матч сохранён, но детали не загрузились: свайп успешен, комната с finish_on_match всё равно завершается
+func TestSwipe_MatchDetailsErrorStillFinishes(t *testing.T) {
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive, FinishOnMatch: true}
+	deck := makeIDs(1)
+	s, _, notifier := newTestSwipeService(room, deck, deck[0])
+	s.matcher.(*fakeMatcher).detailsErr = errors.New("db is down")
+
+	res, err := s.Swipe(room.ID, uuid.New(), models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionRight})
+	if err != nil || res.Match != nil {
+		t.Fatalf("res = %+v, err = %v; want swipe without match details", res, err)
+	}
+	if notifier.matches != 0 || s.finisher.(*fakeFinisher).calls != 1 {
+		t.Errorf("matches broadcast = %d, OnMatch calls = %d; want 0 and 1", notifier.matches, s.finisher.(*fakeFinisher).calls)
+	}
+}
+
+func TestSwipe_HostDislikeReevaluatesMatch(t *testing.T) {
+	host := uuid.New()
+	room := &models.Room{ID: uuid.New(), HostID: host, Status: models.RoomStatusActive, MatchPolicy: models.MatchPolicyMajorityHostVeto}
+	deck := makeIDs(2)
+	s, _, notifier := newTestSwipeService(room, deck, deck[0])
+	matcher := s.matcher.(*fakeMatcher)
+
+	// Дизлайк обычного участника уже собранный матч не трогает
+	if _, err := s.Swipe(room.ID, uuid.New(), models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionLeft}); err != nil {
+		t.Fatalf("guest dislike: %v", err)
+	}
+	if len(matcher.retracted) != 0 || notifier.retracted != 0 {
+		t.Fatalf("guest dislike reevaluated match: %v", matcher.retracted)
+	}
+
+	// Дизлайк хоста — вето: матч пересчитывается и отзывается
+	if _, err := s.Swipe(room.ID, host, models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionLeft}); err != nil {
+		t.Fatalf("host dislike: %v", err)
+	}
+	if len(matcher.retracted) != 1 || notifier.retracted != 1 {
+		t.Errorf("reevaluated = %d, retracted broadcasts = %d; want 1 and 1", len(matcher.retracted), notifier.retracted)
+	}
+}
+
+func TestSwipe_DecisionAfterMaybeReevaluatesMatch(t *testing.T) {
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive}
+	deck := makeIDs(2)
+	s, _, notifier := newTestSwipeService(room, deck, deck[0])
+	userID := uuid.New()
+
+	s.Swipe(room.ID, userID, models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionMaybe})
+	if _, err := s.Swipe(room.ID, userID, models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionLeft}); err != nil {
+		t.Fatalf("decision after maybe: %v", err)
+	}
+	if got := len(s.matcher.(*fakeMatcher).retracted); got != 1 || notifier.retracted != 1 {
+		t.Errorf("reevaluated = %d, retracted broadcasts = %d; want 1 and 1", got, notifier.retracted)
+	}
+}
+
+func TestReevaluateRoom_MatchAfterMemberLeftFinishesRoom(t *testing.T) {
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive, FinishOnMatch: true}
+	s, _, notifier := newTestSwipeService(room, makeIDs(1), uuid.Nil)
+	lifecycle, rooms, _, roomNotifier := newTestLifecycleService(room)
+	s.finisher = lifecycle
+	matcher := s.matcher.(*fakeMatcher)
+
+	// Пересчёт только отозвал матч — комната продолжает работу
+	matcher.reevaluatedRetracted = []models.Match{{ID: uuid.New(), RoomID: room.ID}}
+	s.ReevaluateRoom(room)
+	if notifier.retracted != 1 || rooms.rooms[room.ID].Status != models.RoomStatusActive {
+		t.Fatalf("retracted = %d, status = %s; want 1 and active", notifier.retracted, rooms.rooms[room.ID].Status)
+	}
+
+	// Без ушедшего участника фильм набрал матч — комната с finish_on_match завершается
+	matcher.reevaluatedRetracted = nil
+	matcher.reevaluated = []models.Match{{ID: uuid.New(), RoomID: room.ID, MovieID: uuid.New()}}
+	s.ReevaluateRoom(room)
+	if notifier.matches != 1 {
+		t.Errorf("matches broadcast = %d, want 1", notifier.matches)
+	}
+	if got := rooms.rooms[room.ID]; got.Status != models.RoomStatusFinished || got.FinishReason != string(models.RoomFinishReasonMatch) {
+		t.Errorf("room = %s/%s, want finished by match", got.Status, got.FinishReason)
+	}
+	if roomNotifier.finished != 1 {
+		t.Errorf("room_finished notifications = %d, want 1", roomNotifier.finished)
+	}
+}
+
+func TestSwipe_RejectsInvalidAndFinishedRoom(t *testing.T) {
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusFinished}
+	s, _, _ := newTestSwipeService(room, makeIDs(1), uuid.Nil)
+
+	if _, err := s.Swipe(room.ID, uuid.New(), models.CreateSwipeRequest{MovieID: uuid.New(), Direction: "sideways"}); !errors.Is(err, ErrInvalidDirection) {
+		t.Errorf("err = %v, want ErrInvalidDirection", err)
+	}
+	if _, err := s.Swipe(room.ID, uuid.New(), models.CreateSwipeRequest{MovieID: uuid.New(), Direction: models.SwipeDirectionLeft}); !errors.Is(err, ErrRoomFinished) {
+		t.Errorf("err = %v, want ErrRoomFinished", err)
+	}
+	s.roomRepo = &mockRoomRepo{}
+	if _, err := s.Undo(uuid.New(), uuid.New(), models.UndoSwipeRequest{}); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("err = %v, want ErrRoomNotFound", err)
+	}
+}
+
+func TestUndo_RetractsMatchAndReportsProgress(t *testing.T) {
+	room := &models.Room{ID: uuid.New(), Status: models.RoomStatusActive}
+	deck := makeIDs(2)
+	s, store, notifier := newTestSwipeService(room, deck, deck[0])
+	userID := uuid.New()
+
+	if _, err := s.Undo(room.ID, userID, models.UndoSwipeRequest{}); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("err = %v, want ErrNothingToUndo", err)
+	}
+
+	s.Swipe(room.ID, userID, models.CreateSwipeRequest{MovieID: deck[0], Direction: models.SwipeDirectionRight})
+	resp, err := s.Undo(room.ID, userID, models.UndoSwipeRequest{})
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if len(resp.Undone) != 1 || len(resp.RetractedMatches) != 1 || notifier.retracted != 1 {
+		t.Errorf("undo = %+v, retracted broadcasts = %d", resp, notifier.retracted)
+	}
+	if len(store.swipes) != 0 {
+		t.Errorf("swipe must be deleted, left %d", len(store.swipes))
+	}
+	if last := notifier.progress[len(notifier.progress)-1]; last.Swiped != 0 {
+		t.Errorf("progress after undo = %+v, want 0 swiped", last)
+	}
+}
@@ -3,6 +3,9 @@
 # Использование:
 #   ./импорт_csv.sh
 #   ./импорт_csv.sh путь/к/films.csv
+#   DRY_RUN=1 ./импорт_csv.sh   — только показать разницу с каталогом
+#
+# Повторный запуск не создаёт дублей: фильмы с тем же названием и годом обновляются.
 #
 # 1. Положите CSV в корень проекта (например imdb_top_1000.csv) или укажите путь.
 # 2. В .env должен быть DATABASE_URL (как для приложения и миграций).
@@ -57,5 +60,9 @@ fi
 echo "Подключение: $(echo "$DATABASE_URL" | sed 's/:[^:@]*@/:***@/')"
 
 echo "Импорт из $CSV в БД..."
-go run ./cmd/import_csv "$CSV"
+if [ "$DRY_RUN" = "1" ] || [ "$DRY_RUN" = "true" ]; then
+  go run ./cmd/catalog import --source imdb-csv --dry-run "$CSV"
+  exit 0
+fi
+go run ./cmd/catalog import --source imdb-csv "$CSV"
 echo "Готово. Проверьте фильмы в приложении или в pgAdmin (таблица movies)."
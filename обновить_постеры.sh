@@ -1,6 +1,7 @@
 #!/bin/bash
 # Подставляет постеры для фильмов без картинки (запрос к OMDb API).
 # Нужен бесплатный API-ключ: https://www.omdbapi.com/apikey.aspx
+# Остальные поля и другие провайдеры: go run ./cmd/catalog enrich -h
 #
 # Использование:
 #   export OMDB_API_KEY=ваш_ключ
@@ -26,10 +27,6 @@ if [ -f .env ] && grep -q '^DATABASE_URL=' .env; then
   export DATABASE_URL=$(grep '^DATABASE_URL=' .env | cut -d= -f2-)
 fi
 
-if [ "$UPDATE_ALL" = "1" ] || [ "$UPDATE_ALL" = "true" ]; then
-  echo "Обновление постеров у всех фильмов (UPDATE_ALL=1)..."
-else
-  echo "Обновление постеров (только у фильмов без картинки). Чтобы обновить у всех: UPDATE_ALL=1 $0"
-fi
-go run ./cmd/update_posters
+echo "Обновление постеров у фильмов без картинки..."
+go run ./cmd/catalog enrich --providers omdb --fields poster_url
 echo "Готово."